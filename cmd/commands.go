@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jmontesinos91/omnilogger/internal/utils/export"
+)
+
+// Exit codes of the offline commands
+const (
+	exitOK     = 0
+	exitFailed = 1
+	exitUsage  = 2
+)
+
+// runCommand runs an offline command, they need neither the configurations nor the connections of the service
+func runCommand(name string, args []string, stdout, stderr io.Writer) int {
+	switch name {
+	case "verify":
+		return runVerify(args, stdout, stderr)
+	case "keygen":
+		return runKeygen(stdout, stderr)
+	default:
+		_, _ = fmt.Fprintf(stderr, "unknown command %q, available commands: verify, keygen\n", name)
+		return exitUsage
+	}
+}
+
+// runVerify validates that an export file matches its manifest and that the manifest was signed by omnilogger
+func runVerify(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	file := flags.String("file", "", "exported file, e.g. logs.xlsx or logs.csv")
+	manifestFile := flags.String("manifest", "", "manifest of the export, as json or as the base64 value of the X-Export-Manifest trailer")
+	publicKey := flags.String("public-key", "", "base64 Ed25519 public key of the omnilogger export signing key")
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if *file == "" || *manifestFile == "" || *publicKey == "" {
+		_, _ = fmt.Fprintln(stderr, "usage: omnilogger verify -file <export> -manifest <manifest> -public-key <base64 key>")
+		return exitUsage
+	}
+
+	key, err := export.ParsePublicKey(*publicKey)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "invalid public key: %v\n", err)
+		return exitUsage
+	}
+
+	rawManifest, err := os.ReadFile(*manifestFile)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "failed to read manifest: %v\n", err)
+		return exitUsage
+	}
+
+	manifest, err := export.ParseManifest(rawManifest)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "invalid manifest: %v\n", err)
+		return exitUsage
+	}
+
+	content, err := os.ReadFile(*file)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "failed to read file: %v\n", err)
+		return exitUsage
+	}
+
+	if err := export.Verify(manifest, content, key); err != nil {
+		_, _ = fmt.Fprintf(stderr, "FAILED: %v\n", err)
+		return exitFailed
+	}
+
+	_, _ = fmt.Fprintf(stdout, "OK: %s, %d rows, sha256 %s, signed with key %q at %s\n",
+		manifest.File, manifest.RowCount, manifest.SHA256, manifest.KeyID, manifest.CreatedAt.Format("2006-01-02T15:04:05Z07:00"))
+	_, _ = fmt.Fprintf(stdout, "filter: %s\n", manifest.Filter)
+
+	return exitOK
+}
+
+// runKeygen prints a new export signing key pair
+func runKeygen(stdout, stderr io.Writer) int {
+	privateKey, publicKey, err := export.GenerateKey()
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "failed to generate key: %v\n", err)
+		return exitFailed
+	}
+
+	_, _ = fmt.Fprintf(stdout, "signing-key: %s\npublic-key: %s\n", privateKey, publicKey)
+
+	return exitOK
+}
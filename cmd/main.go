@@ -2,23 +2,54 @@ package main
 
 import (
 	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/jmontesinos91/ologs/logger"
 	"github.com/jmontesinos91/omnilogger/config"
 	"github.com/jmontesinos91/omnilogger/internal/adapters/api"
 	"github.com/jmontesinos91/omnilogger/internal/adapters/db"
+	"github.com/jmontesinos91/omnilogger/internal/adapters/objectstore"
 	"github.com/jmontesinos91/omnilogger/internal/adapters/stream"
+	"github.com/jmontesinos91/omnilogger/internal/adapters/syslog"
+	alrepository "github.com/jmontesinos91/omnilogger/internal/repositories/access_log"
+	akrepository "github.com/jmontesinos91/omnilogger/internal/repositories/api_key"
+	arrepository "github.com/jmontesinos91/omnilogger/internal/repositories/archive"
+	ejrepository "github.com/jmontesinos91/omnilogger/internal/repositories/export_job"
+	lhrepository "github.com/jmontesinos91/omnilogger/internal/repositories/legal_hold"
 	lmrepository "github.com/jmontesinos91/omnilogger/internal/repositories/log_message"
 	repository "github.com/jmontesinos91/omnilogger/internal/repositories/logs"
+	"github.com/jmontesinos91/omnilogger/internal/services/access_log"
+	"github.com/jmontesinos91/omnilogger/internal/services/api_key"
+	"github.com/jmontesinos91/omnilogger/internal/services/archive"
+	"github.com/jmontesinos91/omnilogger/internal/services/enricher"
+	"github.com/jmontesinos91/omnilogger/internal/services/export_job"
+	"github.com/jmontesinos91/omnilogger/internal/services/legal_hold"
 	"github.com/jmontesinos91/omnilogger/internal/services/log_message"
 	"github.com/jmontesinos91/omnilogger/internal/services/logs"
+	"github.com/jmontesinos91/omnilogger/internal/services/partition"
+	"github.com/jmontesinos91/omnilogger/internal/services/ratelimit"
+	"github.com/jmontesinos91/omnilogger/internal/services/retention"
+	"github.com/jmontesinos91/omnilogger/internal/services/rollup"
 	"github.com/jmontesinos91/omnilogger/internal/services/worker"
+	"github.com/jmontesinos91/omnilogger/internal/utils/export"
 	"github.com/jmontesinos91/osecurity/services/omnibackend"
 	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/sirupsen/logrus"
 )
 
+// shutdownTimeout time given to the requests and background jobs in progress to finish once the service is stopped
+const shutdownTimeout = 25 * time.Second
+
 func main() {
+	// Offline commands such as "verify" run without configurations nor connections
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1], os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	// Logger
 	contextLogger := logger.NewContextLogger("OMNILOGGER", "debug", logger.TextFormat)
 
@@ -37,7 +68,7 @@ func main() {
 	validate := validator.New()
 
 	// DB Connection
-	conn := db.NewDatabaseConnection(contextLogger, configs.Database)
+	conn := db.NewDatabaseConnection(contextLogger, configs.Database, configs.Service.IsProduction())
 
 	// Kafka
 	kafka, closer := stream.NewKafkaConnection(contextLogger, configs.Kafka)
@@ -48,19 +79,76 @@ func main() {
 	// - Initialize repository -
 	omniLoggerRepo := repository.NewDatabaseRepository(contextLogger, conn)
 	logMessageRepo := lmrepository.NewDatabaseRepository(contextLogger, conn)
+	apiKeyRepo := akrepository.NewDatabaseRepository(contextLogger, conn)
+	legalHoldRepo := lhrepository.NewDatabaseRepository(contextLogger, conn)
+	accessLogRepo := alrepository.NewDatabaseRepository(contextLogger, conn)
+	archiveRepo := arrepository.NewDatabaseRepository(contextLogger, conn)
+	exportJobRepo := ejrepository.NewDatabaseRepository(contextLogger, conn)
 
 	// - Initialize service -
-	omniLoggerSvc := logs.NewDefaultService(contextLogger, omniLoggerRepo)
+	enrichmentChain, err := enricher.NewChain(contextLogger, configs.Enrichment, enricher.Factories())
+	if err != nil {
+		contextLogger.Error(logrus.FatalLevel, "main", "Failed to build the enrichment chain", err)
+	}
+	exportSigner, err := export.NewSigner(configs.Export.SigningKey, configs.Export.KeyID)
+	if err != nil {
+		contextLogger.Error(logrus.FatalLevel, "main", "Failed to load the export signing key", err)
+	}
+	if !exportSigner.Enabled() {
+		contextLogger.Log(logrus.WarnLevel, "main", "Export signing key not configured, export manifests will not be signed")
+	}
+	accessLogSvc := access_log.NewDefaultService(contextLogger, accessLogRepo)
+	omniLoggerSvc := logs.NewDefaultService(contextLogger, omniLoggerRepo, configs.Logs, enrichmentChain, exportSigner, legalHoldRepo, accessLogSvc)
 	logMessageSvc := log_message.NewDefaultService(contextLogger, validate, logMessageRepo)
+	apiKeySvc := api_key.NewDefaultService(contextLogger, validate, apiKeyRepo)
+	legalHoldSvc := legal_hold.NewDefaultService(contextLogger, validate, legalHoldRepo)
+	rateLimitSvc := ratelimit.NewDefaultService(contextLogger, configs.RateLimit)
+	retentionSvc, err := retention.NewDefaultService(contextLogger, omniLoggerRepo, configs.Retention)
+	if err != nil {
+		contextLogger.Error(logrus.FatalLevel, "main", "Failed to load the retention policies", err)
+	}
+	partitionSvc, err := partition.NewDefaultService(contextLogger, omniLoggerRepo, configs.Partitions, configs.Retention)
+	if err != nil {
+		contextLogger.Error(logrus.FatalLevel, "main", "Failed to load the partitions settings", err)
+	}
+	var archiveStore objectstore.IStore
+	if configs.Archive.Enabled {
+		archiveStore, err = objectstore.NewStore(contextLogger, configs.Archive.Store)
+		if err != nil {
+			contextLogger.Error(logrus.FatalLevel, "main", "Failed to open the archive store", err)
+		}
+	}
+	archiveSvc, err := archive.NewDefaultService(contextLogger, omniLoggerRepo, archiveRepo, archiveStore, exportSigner, configs.Archive)
+	if err != nil {
+		contextLogger.Error(logrus.FatalLevel, "main", "Failed to load the archive settings", err)
+	}
+
+	rollupSvc := rollup.NewDefaultService(contextLogger, omniLoggerRepo, configs.Rollups)
+
+	var exportStore objectstore.IStore
+	if configs.Export.Jobs.Enabled {
+		exportStore, err = objectstore.NewStore(contextLogger, configs.Export.Jobs.Store)
+		if err != nil {
+			contextLogger.Error(logrus.FatalLevel, "main", "Failed to open the export store", err)
+		}
+	}
+	exportJobSvc := export_job.NewDefaultService(contextLogger, omniLoggerSvc, exportJobRepo, exportStore, configs.Export.Jobs)
 
 	api.NewHealthController(httpServer)
-	api.NewOmniLoggerController(httpServer, validate, omniLoggerSvc, stsClient)
+	api.NewOmniLoggerController(httpServer, validate, omniLoggerSvc, stsClient, apiKeySvc, rateLimitSvc)
 	api.NewLogMessageController(httpServer, validate, logMessageSvc, stsClient)
+	api.NewOTLPController(httpServer, omniLoggerSvc, stsClient, apiKeySvc, rateLimitSvc)
+	api.NewAPIKeyController(httpServer, validate, apiKeySvc, stsClient)
+	api.NewLegalHoldController(httpServer, validate, legalHoldSvc, stsClient)
+	api.NewAccessLogController(httpServer, accessLogSvc, stsClient)
+	api.NewRetentionController(httpServer, retentionSvc, stsClient)
+	api.NewArchiveController(httpServer, archiveSvc, stsClient)
+	api.NewExportJobController(httpServer, exportJobSvc, stsClient)
 	// -- End dependency injection section --
 
 	// Initialize kafka workers
 	defaultWorker := worker.NewDefaultWorker(contextLogger)
-	logWorker := worker.NewLogCreatedWorker(contextLogger, omniLoggerSvc, kafka)
+	logWorker := worker.NewLogCreatedWorker(contextLogger, omniLoggerSvc, rateLimitSvc, kafka)
 
 	opts := worker.EventRoutingStrategyOpts{
 		Logger:           contextLogger,
@@ -82,6 +170,54 @@ func main() {
 	// Initialize consumer
 	mainConsumer.Start(context.Background())
 
+	// Background workers stop once the service is stopped
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Initialize syslog listener
+	syslogListener := syslog.NewListener(contextLogger, configs.Syslog, omniLoggerSvc)
+	syslogListener.Start(ctx)
+
+	// Initialize retention purge worker
+	retentionSvc.Start(ctx)
+
+	// Initialize logs partition manager
+	partitionSvc.Start(ctx)
+
+	// Initialize logs archive worker
+	archiveSvc.Start(ctx)
+
+	// Initialize logs rollup worker
+	rollupSvc.Start(ctx)
+
+	// Initialize export jobs workers
+	exportJobSvc.Start(ctx)
+
 	// Let the party started!
-	httpServer.Start()
+	go httpServer.Start()
+
+	<-ctx.Done()
+	contextLogger.Log(logrus.InfoLevel, "main", "Shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		contextLogger.Error(logrus.WarnLevel, "main", "Requests still in progress were interrupted", err)
+	}
+
+	// The syslog listeners closed with ctx, the messages already received are still stored
+	if err := syslogListener.Wait(shutdownCtx); err != nil {
+		contextLogger.Error(logrus.WarnLevel, "main", "Syslog messages still in progress were interrupted", err)
+	}
+
+	// Restores are started by requests, no new one starts once the server is shut down
+	if err := archiveSvc.Wait(shutdownCtx); err != nil {
+		contextLogger.Error(logrus.WarnLevel, "main", "Restores still in progress were interrupted, they are failed once stale", err)
+	}
+
+	// The export workers stopped taking jobs with ctx, the ones still queued are failed by the next start
+	if err := exportJobSvc.Wait(shutdownCtx); err != nil {
+		contextLogger.Error(logrus.WarnLevel, "main", "Export jobs still in progress were interrupted, they are failed once stale", err)
+	}
 }
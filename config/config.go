@@ -24,9 +24,18 @@ type KeysConfigurations struct {
 	Public string `koanf:"public"`
 }
 
+// EnvironmentProduction environment name of production deployments
+const EnvironmentProduction = "production"
+
 // Service configurations
 type Service struct {
-	Name string `koanf:"name"`
+	Name        string `koanf:"name"`
+	Environment string `koanf:"environment"`
+}
+
+// IsProduction whether the service runs in production mode
+func (s Service) IsProduction() bool {
+	return strings.EqualFold(s.Environment, EnvironmentProduction)
 }
 
 // DatabaseConfigurations database configurations
@@ -55,14 +64,205 @@ type KafkaConsumerConfigurations struct {
 	MaxRecords int      `koanf:"max-records"`
 }
 
+// SyslogConfigurations syslog listener configurations, an empty address disables that network
+type SyslogConfigurations struct {
+	Enabled        bool   `koanf:"enabled"`
+	UDPAddress     string `koanf:"udp-address"`
+	TCPAddress     string `koanf:"tcp-address"`
+	TLSAddress     string `koanf:"tls-address"`
+	CertFile       string `koanf:"cert-file"`
+	KeyFile        string `koanf:"key-file"`
+	MaxMessageSize int    `koanf:"max-message-size"`
+	TenantCat      string `koanf:"tenant-cat"`
+}
+
+// LimitConfigurations token bucket settings, rate is the number of logs per second refilled and burst
+// the bucket size, a zero rate disables the limit
+type LimitConfigurations struct {
+	Rate  float64 `koanf:"rate"`
+	Burst int     `koanf:"burst"`
+}
+
+// WorkerRateLimitConfigurations overflow policy applied by the kafka workers when a producer is over its limit
+type WorkerRateLimitConfigurations struct {
+	Policy           string `koanf:"policy"`
+	MaxWaitInSeconds int    `koanf:"max-wait-in-seconds"`
+}
+
+// RateLimitConfigurations ingestion rate limits by tenant, provider and api client,
+// tenants overrides the tenant limit for specific tenant ids
+type RateLimitConfigurations struct {
+	Enabled  bool                           `koanf:"enabled"`
+	Tenant   LimitConfigurations            `koanf:"tenant"`
+	Provider LimitConfigurations            `koanf:"provider"`
+	Client   LimitConfigurations            `koanf:"client"`
+	Tenants  map[string]LimitConfigurations `koanf:"tenants"`
+	Worker   WorkerRateLimitConfigurations  `koanf:"worker"`
+}
+
+// RetentionPolicyConfigurations days logs are kept, levels and resources override the days for logs of
+// a level (syslog severity number) or resource, 0 inherits the global policy and a negative value keeps forever
+type RetentionPolicyConfigurations struct {
+	Days      int            `koanf:"days"`
+	Levels    map[string]int `koanf:"levels"`
+	Resources map[string]int `koanf:"resources"`
+}
+
+// RetentionConfigurations retention of the logs, the purge worker deletes expired logs in batches every interval,
+// tenants overrides the global policy for specific tenant ids and dry-run only counts the expired logs, archived-only
+// restricts the purge to the logs of the tenant days already archived
+type RetentionConfigurations struct {
+	Enabled           bool                                     `koanf:"enabled"`
+	DryRun            bool                                     `koanf:"dry-run"`
+	IntervalInMinutes int                                      `koanf:"interval-in-minutes"`
+	BatchSize         int                                      `koanf:"batch-size"`
+	Policy            RetentionPolicyConfigurations            `koanf:"policy"`
+	Tenants           map[string]RetentionPolicyConfigurations `koanf:"tenants"`
+	ArchivedOnly      bool                                     `koanf:"archived-only"`
+}
+
+// PartitionsConfigurations partitioning of the logs table on created_at, every interval the manager creates the
+// premake next monthly or daily partitions and handles the partitions whose whole range is past the retention
+// of every policy according to expired-action: none keeps them, detach detaches them and drop drops them
+type PartitionsConfigurations struct {
+	Enabled           bool   `koanf:"enabled"`
+	Granularity       string `koanf:"granularity"`
+	Premake           int    `koanf:"premake"`
+	IntervalInMinutes int    `koanf:"interval-in-minutes"`
+	ExpiredAction     string `koanf:"expired-action"`
+}
+
+// ArchiveStoreConfigurations object store holding the archive or export files, type local writes them under path and
+// type s3 uploads them to the bucket of any S3-compatible endpoint such as MinIO
+type ArchiveStoreConfigurations struct {
+	Type      string `koanf:"type"`
+	Path      string `koanf:"path"`
+	Endpoint  string `koanf:"endpoint"`
+	Region    string `koanf:"region"`
+	Bucket    string `koanf:"bucket"`
+	AccessKey string `koanf:"access-key"`
+	SecretKey string `koanf:"secret-key"`
+	UseSSL    bool   `koanf:"use-ssl"`
+}
+
+// ArchiveConfigurations archive of the logs, every interval the logs created more than after-days ago are written
+// per tenant and day as gzip NDJSON or Parquet files with a manifest, delete-after-archive removes the archived
+// logs once the upload is verified. Restored archive files stay searchable for restore-ttl-in-hours
+type ArchiveConfigurations struct {
+	Enabled            bool                       `koanf:"enabled"`
+	IntervalInMinutes  int                        `koanf:"interval-in-minutes"`
+	AfterDays          int                        `koanf:"after-days"`
+	Format             string                     `koanf:"format"`
+	Prefix             string                     `koanf:"prefix"`
+	MaxDaysPerRun      int                        `koanf:"max-days-per-run"`
+	DeleteAfterArchive bool                       `koanf:"delete-after-archive"`
+	BatchSize          int                        `koanf:"batch-size"`
+	RestoreTTLInHours  int                        `koanf:"restore-ttl-in-hours"`
+	MaxRestores        int                        `koanf:"max-restores"`
+	Store              ArchiveStoreConfigurations `koanf:"store"`
+}
+
+// RollupsConfigurations hourly and daily counts of the logs read by the stats and dashboard endpoints, every
+// interval the hours closed for more than lag-in-minutes are rolled up, at most max-hours-per-run of them. The first
+// run starts backfill-days ago
+type RollupsConfigurations struct {
+	Enabled           bool `koanf:"enabled"`
+	IntervalInMinutes int  `koanf:"interval-in-minutes"`
+	LagInMinutes      int  `koanf:"lag-in-minutes"`
+	BackfillDays      int  `koanf:"backfill-days"`
+	MaxHoursPerRun    int  `koanf:"max-hours-per-run"`
+}
+
+// LogsConfigurations logs ingestion configurations, entries whose occurred_at differs from the
+// ingestion time by more than the threshold are flagged with clock_skew
+type LogsConfigurations struct {
+	ClockSkewThresholdInSeconds int                  `koanf:"clock-skew-threshold-in-seconds"`
+	Labels                      LabelsConfigurations `koanf:"labels"`
+}
+
+// LabelsConfigurations limits of the labels attached to a log, zero values use the service defaults
+type LabelsConfigurations struct {
+	MaxCount       int `koanf:"max-count"`
+	MaxKeyLength   int `koanf:"max-key-length"`
+	MaxValueLength int `koanf:"max-value-length"`
+}
+
+// EnrichmentConfigurations ordered enrichment stages applied to every log before it is stored
+type EnrichmentConfigurations struct {
+	Stages      []EnrichmentStageConfigurations `koanf:"stages"`
+	TenantNames map[string]string               `koanf:"tenant-names"`
+	GeoIP       GeoIPConfigurations             `koanf:"geoip"`
+	Redaction   RedactionConfigurations         `koanf:"redaction"`
+	Rules       []LabelRuleConfigurations       `koanf:"rules"`
+}
+
+// EnrichmentStageConfigurations a stage of the enrichment chain, failure-mode is open (keep the log as is)
+// or closed (reject the log) when the stage fails
+type EnrichmentStageConfigurations struct {
+	Name        string `koanf:"name"`
+	Enabled     bool   `koanf:"enabled"`
+	FailureMode string `koanf:"failure-mode"`
+}
+
+// GeoIPConfigurations csv file with network,country,city rows used to locate the origin ip
+type GeoIPConfigurations struct {
+	DatabaseFile string `koanf:"database-file"`
+}
+
+// RedactionConfigurations data keys and patterns whose values are replaced before storing a log
+type RedactionConfigurations struct {
+	Keys        []string `koanf:"keys"`
+	Patterns    []string `koanf:"patterns"`
+	Replacement string   `koanf:"replacement"`
+}
+
+// LabelRuleConfigurations labels added to the logs matching every non empty field of the rule
+type LabelRuleConfigurations struct {
+	Provider string            `koanf:"provider"`
+	Resource string            `koanf:"resource"`
+	Action   string            `koanf:"action"`
+	Path     string            `koanf:"path"`
+	Level    int               `koanf:"level"`
+	Labels   map[string]string `koanf:"labels"`
+}
+
+// ExportConfigurations exports configurations, signing-key is the base64 Ed25519 private key used to sign
+// the export manifests, exports are not signed when it is empty
+type ExportConfigurations struct {
+	SigningKey string                   `koanf:"signing-key"`
+	KeyID      string                   `koanf:"key-id"`
+	Jobs       ExportJobsConfigurations `koanf:"jobs"`
+}
+
+// ExportJobsConfigurations asynchronous exports, workers run the queued jobs and upload their files to the store
+// where they can be downloaded for ttl-in-hours. Every interval the expired jobs are deleted with their files
+type ExportJobsConfigurations struct {
+	Enabled           bool                       `koanf:"enabled"`
+	Workers           int                        `koanf:"workers"`
+	QueueSize         int                        `koanf:"queue-size"`
+	TTLInHours        int                        `koanf:"ttl-in-hours"`
+	IntervalInMinutes int                        `koanf:"interval-in-minutes"`
+	Prefix            string                     `koanf:"prefix"`
+	Store             ArchiveStoreConfigurations `koanf:"store"`
+}
+
 // Configurations Application wide configurations
 type Configurations struct {
-	Server   ServerConfigurations               `koanf:"server"`
-	Keys     KeysConfigurations                 `koanf:"keys"`
-	Service  Service                            `koanf:"service"`
-	Database DatabaseConfigurations             `koanf:"database"`
-	OmniView omnibackend.OmniViewConfigurations `koanf:"omniview"`
-	Kafka    KafkaConfigurations                `koanf:"kafka"`
+	Server     ServerConfigurations               `koanf:"server"`
+	Keys       KeysConfigurations                 `koanf:"keys"`
+	Service    Service                            `koanf:"service"`
+	Database   DatabaseConfigurations             `koanf:"database"`
+	OmniView   omnibackend.OmniViewConfigurations `koanf:"omniview"`
+	Kafka      KafkaConfigurations                `koanf:"kafka"`
+	Syslog     SyslogConfigurations               `koanf:"syslog"`
+	RateLimit  RateLimitConfigurations            `koanf:"rate-limit"`
+	Logs       LogsConfigurations                 `koanf:"logs"`
+	Enrichment EnrichmentConfigurations           `koanf:"enrichment"`
+	Export     ExportConfigurations               `koanf:"export"`
+	Retention  RetentionConfigurations            `koanf:"retention"`
+	Partitions PartitionsConfigurations           `koanf:"partitions"`
+	Archive    ArchiveConfigurations              `koanf:"archive"`
+	Rollups    RollupsConfigurations              `koanf:"rollups"`
 }
 
 // LoadConfig Loads configurations depending upon the environment
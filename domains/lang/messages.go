@@ -50,6 +50,84 @@ var failDelete = map[string]map[string]string{
 	"pt": {"message": "FALHA AO DELETAR O {resource}"},
 }
 
+// header labels of the export columns, keyed by the json name of the column. The english labels are the historical
+// headers of the exports. Before and after qualify the expanded data columns and count is the column of the summary
+// sheet
+var header = map[string]map[string]string{
+	"en": {
+		"id":          "ID",
+		"ipAddress":   "IpAddress",
+		"clientHost":  "ClientHost",
+		"provider":    "Provider",
+		"level":       "Level",
+		"message":     "Message",
+		"logMessage":  "LogMessage",
+		"description": "Description",
+		"path":        "Path",
+		"resource":    "Resource",
+		"action":      "Action",
+		"data":        "Data",
+		"oldData":     "OldData",
+		"tenantCat":   "TenantCat",
+		"userId":      "UserID",
+		"target":      "Target",
+		"createdAt":   "CreatedAt",
+		"occurredAt":  "OccurredAt",
+		"clockSkew":   "ClockSkew",
+		"before":      "Before",
+		"after":       "After",
+		"count":       "Count",
+	},
+	"es": {
+		"id":          "ID",
+		"ipAddress":   "Dirección IP",
+		"clientHost":  "Host del cliente",
+		"provider":    "Proveedor",
+		"level":       "Nivel",
+		"message":     "Mensaje",
+		"logMessage":  "Texto del mensaje",
+		"description": "Descripción",
+		"path":        "Ruta",
+		"resource":    "Recurso",
+		"action":      "Acción",
+		"data":        "Datos",
+		"oldData":     "Datos anteriores",
+		"tenantCat":   "Inquilinos",
+		"userId":      "ID de usuario",
+		"target":      "Destino",
+		"createdAt":   "Fecha de creación",
+		"occurredAt":  "Fecha del evento",
+		"clockSkew":   "Desfase de reloj",
+		"before":      "Antes",
+		"after":       "Después",
+		"count":       "Total",
+	},
+	"pt": {
+		"id":          "ID",
+		"ipAddress":   "Endereço IP",
+		"clientHost":  "Host do cliente",
+		"provider":    "Provedor",
+		"level":       "Nível",
+		"message":     "Mensagem",
+		"logMessage":  "Texto da mensagem",
+		"description": "Descrição",
+		"path":        "Caminho",
+		"resource":    "Recurso",
+		"action":      "Ação",
+		"data":        "Dados",
+		"oldData":     "Dados anteriores",
+		"tenantCat":   "Locatários",
+		"userId":      "ID do usuário",
+		"target":      "Destino",
+		"createdAt":   "Data de criação",
+		"occurredAt":  "Data do evento",
+		"clockSkew":   "Desvio de relógio",
+		"before":      "Antes",
+		"after":       "Depois",
+		"count":       "Total",
+	},
+}
+
 func BuildMessage(resource string, messageId int, lang string) string {
 
 	var template = ""
@@ -89,3 +167,17 @@ func BuildMessage(resource string, messageId int, lang string) string {
 
 	return strings.ToUpper(result)
 }
+
+// BuildHeader label of an export column in the language, the english label is used for the languages without
+// translation and the column itself for the columns without label
+func BuildHeader(column string, lang string) string {
+	if label, ok := header[lang][column]; ok {
+		return label
+	}
+
+	if label, ok := header["en"][column]; ok {
+		return label
+	}
+
+	return column
+}
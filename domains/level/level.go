@@ -0,0 +1,51 @@
+package level
+
+// Log levels stored in logs.level, they follow the syslog severity numbering (RFC 5424)
+const (
+	// Emergency system is unusable
+	Emergency = 0
+	// Alert action must be taken immediately
+	Alert = 1
+	// Critical critical conditions
+	Critical = 2
+	// Error error conditions
+	Error = 3
+	// Warning warning conditions
+	Warning = 4
+	// Notice normal but significant condition
+	Notice = 5
+	// Informational informational messages
+	Informational = 6
+	// Debug debug-level messages
+	Debug = 7
+)
+
+// FromSyslogSeverity maps a syslog severity into a log level, unknown values are stored as Informational
+func FromSyslogSeverity(severity int) int {
+	if severity < Emergency || severity > Debug {
+		return Informational
+	}
+
+	return severity
+}
+
+// FromOTLPSeverity maps an OpenTelemetry severity number (1-24) into a log level,
+// unspecified or unknown values are stored as Informational
+func FromOTLPSeverity(number int32) int {
+	switch {
+	case number >= 1 && number <= 8:
+		// TRACE and DEBUG ranges
+		return Debug
+	case number >= 9 && number <= 12:
+		return Informational
+	case number >= 13 && number <= 16:
+		return Warning
+	case number >= 17 && number <= 20:
+		return Error
+	case number >= 21 && number <= 24:
+		// FATAL range
+		return Critical
+	default:
+		return Informational
+	}
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/omnilogger/internal/services/access_log"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+)
+
+// AccessLogController access logs controller
+type AccessLogController struct {
+	log           *logger.ContextLogger
+	accessLogSvc  access_log.IService
+	stsClient     sts.ISTSClient
+	counterMetric prometheus.Counter
+}
+
+// NewAccessLogController Constructor
+func NewAccessLogController(server *HTTPServer, als access_log.IService, sts sts.ISTSClient) *AccessLogController {
+	ac := &AccessLogController{
+		log:          server.Logger,
+		accessLogSvc: als,
+		stsClient:    sts,
+		counterMetric: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "access_logs_reqs_total",
+			Help: "The total number of requests to access logs endpoints",
+		}),
+	}
+
+	server.Router.Group(func(r chi.Router) {
+		r.Use(JwtVerifyMiddleware(server.Logger, sts))
+		r.Get("/v1/access_logs", ac.handleRetrieve)
+	})
+
+	return ac
+}
+
+func (ac *AccessLogController) handleRetrieve(w http.ResponseWriter, r *http.Request) {
+	// Increment metric
+	ac.counterMetric.Inc()
+
+	filter, err := access_log.ToParseFilterRequest(r)
+	if err != nil {
+		ac.log.Error(logrus.ErrorLevel, "handleRetrieve", "Invalid request parameters", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	res, err := ac.accessLogSvc.Retrieve(r.Context(), filter)
+	if err != nil {
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	RenderJSON(r.Context(), w, http.StatusOK, res)
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-playground/validator/v10"
+	"github.com/jmontesinos91/ologs/logger"
+	tracekey "github.com/jmontesinos91/ologs/logger/v2"
+	"github.com/jmontesinos91/omnilogger/internal/services/api_key"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/jmontesinos91/terrors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+)
+
+// APIKeyController api keys controller
+type APIKeyController struct {
+	log           *logger.ContextLogger
+	validate      *validator.Validate
+	apiKeySvc     api_key.IService
+	stsClient     sts.ISTSClient
+	counterMetric prometheus.Counter
+}
+
+// NewAPIKeyController Constructor
+func NewAPIKeyController(server *HTTPServer, validator *validator.Validate, aks api_key.IService, sts sts.ISTSClient) *APIKeyController {
+	ac := &APIKeyController{
+		log:       server.Logger,
+		validate:  validator,
+		apiKeySvc: aks,
+		stsClient: sts,
+		counterMetric: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "api_keys_reqs_total",
+			Help: "The total number of requests to api keys endpoints",
+		}),
+	}
+
+	// Api keys are managed by users only, an api key can not create other api keys
+	server.Router.Group(func(r chi.Router) {
+		r.Use(JwtVerifyMiddleware(server.Logger, sts))
+		r.Post("/v1/api_keys", ac.handleCreate)
+		r.Get("/v1/api_keys", ac.handleRetrieve)
+		r.Delete("/v1/api_keys/{id}", ac.handleRevoke)
+	})
+
+	return ac
+}
+
+func (ac *APIKeyController) handleCreate(w http.ResponseWriter, r *http.Request) {
+	// Increment metric
+	ac.counterMetric.Inc()
+
+	var payload api_key.Payload
+	requestID := r.Context().Value(middleware.RequestIDKey).(string)
+
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		ac.log.WithContext(
+			logrus.ErrorLevel,
+			"handleCreate",
+			"Error while parsing request payload: %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+			},
+			err)
+		terr := terrors.BadRequest(terrors.ErrBadRequest, "Malformed body", map[string]string{})
+		RenderError(r.Context(), w, terr)
+		return
+	}
+
+	res, err := ac.apiKeySvc.Create(r.Context(), &payload)
+	if err != nil {
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	RenderJSON(r.Context(), w, http.StatusCreated, res)
+}
+
+func (ac *APIKeyController) handleRetrieve(w http.ResponseWriter, r *http.Request) {
+	// Increment metric
+	ac.counterMetric.Inc()
+
+	filter, err := api_key.ToParseFilterRequest(r)
+	if err != nil {
+		ac.log.Error(logrus.ErrorLevel, "handleRetrieve", "Invalid request parameters", err)
+		terr := terrors.BadRequest(terrors.ErrBadRequest, "Invalid request parameters", map[string]string{})
+		RenderError(r.Context(), w, terr)
+		return
+	}
+
+	res, err := ac.apiKeySvc.Retrieve(r.Context(), filter)
+	if err != nil {
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	RenderJSON(r.Context(), w, http.StatusOK, res)
+}
+
+func (ac *APIKeyController) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	// Increment metric
+	ac.counterMetric.Inc()
+
+	id := chi.URLParam(r, "id")
+
+	err := ac.apiKeySvc.Revoke(r.Context(), id)
+	if err != nil {
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	w.Header().Set(middleware.RequestIDHeader, middleware.GetReqID(r.Context()))
+	w.WriteHeader(http.StatusNoContent)
+}
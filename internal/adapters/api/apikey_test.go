@@ -0,0 +1,198 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-playground/validator/v10"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/omnilogger/internal/services/api_key"
+	"github.com/jmontesinos91/omnilogger/internal/services/api_key/apikeysvcmock"
+	"github.com/jmontesinos91/omnilogger/internal/services/logs/logssvcmock"
+	"github.com/jmontesinos91/omnilogger/internal/services/ratelimit/ratelimitsvcmock"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIngestionAuthMiddleware(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestIngestionAuthMiddleware", "debug", logger.TextFormat)
+	stsClient := sts.NewDefaultISTSClient(ctxLogger, nil)
+	principal := &api_key.Principal{KeyID: "key-1", Name: "billing", TenantID: 7, TenantName: "Acme", Scopes: []string{api_key.ScopeLogsWrite}}
+
+	tests := []struct {
+		name             string
+		headers          map[string]string
+		mockSvc          *apikeysvcmock.IService
+		expectedCode     int
+		expectedTenant   string
+		expectAuthCalled bool
+	}{
+		{
+			name:             "XAPIKeyHeader_Success",
+			headers:          map[string]string{APIKeyHeader: "olk_valid"},
+			mockSvc:          &apikeysvcmock.IService{AuthenticateRes: principal},
+			expectedCode:     http.StatusCreated,
+			expectedTenant:   `[{"id":7,"name":"Acme"}]`,
+			expectAuthCalled: true,
+		},
+		{
+			name:             "AuthorizationApiKey_Success",
+			headers:          map[string]string{"Authorization": "ApiKey olk_valid"},
+			mockSvc:          &apikeysvcmock.IService{AuthenticateRes: principal},
+			expectedCode:     http.StatusCreated,
+			expectedTenant:   `[{"id":7,"name":"Acme"}]`,
+			expectAuthCalled: true,
+		},
+		{
+			name:             "InvalidKey_Unauthorized",
+			headers:          map[string]string{APIKeyHeader: "olk_invalid"},
+			mockSvc:          &apikeysvcmock.IService{AuthenticateErr: errors.New("invalid")},
+			expectedCode:     http.StatusUnauthorized,
+			expectAuthCalled: true,
+		},
+		{
+			name:    "MissingScope_Unauthorized",
+			headers: map[string]string{APIKeyHeader: "olk_valid"},
+			mockSvc: &apikeysvcmock.IService{AuthenticateRes: &api_key.Principal{
+				KeyID: "key-2", TenantID: 7,
+			}},
+			expectedCode:     http.StatusUnauthorized,
+			expectAuthCalled: true,
+		},
+		{
+			name:         "NoKey_FallsBackToJWT",
+			headers:      map[string]string{},
+			mockSvc:      &apikeysvcmock.IService{},
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logsSvc := &logssvcmock.IService{}
+			sc := &OmniLoggerController{
+				log:          ctxLogger,
+				validate:     validator.New(),
+				logsSvc:      logsSvc,
+				rateLimitSvc: &ratelimitsvcmock.IService{},
+				counterMetric: prometheus.NewCounter(prometheus.CounterOpts{
+					Name: "test_ingestion_" + tt.name,
+					Help: "test counter",
+				}),
+			}
+
+			router := chi.NewRouter()
+			router.Use(middleware.RequestID)
+			router.With(IngestionAuthMiddleware(ctxLogger, stsClient, tt.mockSvc, api_key.ScopeLogsWrite)).
+				Post("/v1/logs", sc.handleCreate)
+
+			// The tenant sent by the client is always replaced by the api key tenant
+			req := httptest.NewRequest(http.MethodPost, "/v1/logs", strings.NewReader(`{"message":1,"tenant_cat":"[{\"id\":1}]"}`))
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code, rr.Body.String())
+			assert.Equal(t, tt.expectAuthCalled, tt.mockSvc.AuthenticateCalled)
+			if tt.expectedTenant != "" {
+				assert.Equal(t, tt.expectedTenant, logsSvc.CreatePayload.TenantCat)
+			} else {
+				assert.False(t, logsSvc.CreateCalled)
+			}
+		})
+	}
+}
+
+func TestAPIKeyController(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestAPIKeyController", "debug", logger.TextFormat)
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		mockSvc      *apikeysvcmock.IService
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "Create_Success",
+			method:       http.MethodPost,
+			path:         "/v1/api_keys",
+			body:         `{"name":"billing","tenant_id":1,"scopes":["logs:write"]}`,
+			mockSvc:      &apikeysvcmock.IService{},
+			expectedCode: http.StatusCreated,
+			expectedBody: `"key":"olk_00000000_secret"`,
+		},
+		{
+			name:         "Create_MalformedBody",
+			method:       http.MethodPost,
+			path:         "/v1/api_keys",
+			body:         `{`,
+			mockSvc:      &apikeysvcmock.IService{},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Retrieve_Success",
+			method:       http.MethodGet,
+			path:         "/v1/api_keys?tenant_id[]=1",
+			mockSvc:      &apikeysvcmock.IService{RetrieveRes: &api_key.PaginatedRes{Data: []api_key.Response{{ID: "key-1"}}, Total: 1}},
+			expectedCode: http.StatusOK,
+			expectedBody: `"id":"key-1"`,
+		},
+		{
+			name:         "Retrieve_InvalidParams",
+			method:       http.MethodGet,
+			path:         "/v1/api_keys?tenant_id[]=abc",
+			mockSvc:      &apikeysvcmock.IService{},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Revoke_Success",
+			method:       http.MethodDelete,
+			path:         "/v1/api_keys/key-1",
+			mockSvc:      &apikeysvcmock.IService{},
+			expectedCode: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ac := &APIKeyController{
+				log:       ctxLogger,
+				validate:  validator.New(),
+				apiKeySvc: tt.mockSvc,
+				counterMetric: prometheus.NewCounter(prometheus.CounterOpts{
+					Name: "test_api_keys_" + tt.name,
+					Help: "test counter",
+				}),
+			}
+
+			router := chi.NewRouter()
+			router.Post("/v1/api_keys", ac.handleCreate)
+			router.Get("/v1/api_keys", ac.handleRetrieve)
+			router.Delete("/v1/api_keys/{id}", ac.handleRevoke)
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), middleware.RequestIDKey, "rid-api-keys"))
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code, rr.Body.String())
+			if tt.expectedBody != "" {
+				assert.Contains(t, rr.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+}
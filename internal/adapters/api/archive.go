@@ -0,0 +1,98 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/omnilogger/internal/services/archive"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/jmontesinos91/terrors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+)
+
+// restoreRetryAfter seconds a client should wait before requesting a restore again when too many are running
+const restoreRetryAfter = "60"
+
+// ArchiveController archives controller
+type ArchiveController struct {
+	log           *logger.ContextLogger
+	archiveSvc    archive.IService
+	stsClient     sts.ISTSClient
+	counterMetric prometheus.Counter
+}
+
+// NewArchiveController Constructor
+func NewArchiveController(server *HTTPServer, as archive.IService, sts sts.ISTSClient) *ArchiveController {
+	ac := &ArchiveController{
+		log:        server.Logger,
+		archiveSvc: as,
+		stsClient:  sts,
+		counterMetric: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "archives_reqs_total",
+			Help: "The total number of requests to archives endpoints",
+		}),
+	}
+
+	server.Router.Group(func(r chi.Router) {
+		r.Use(JwtVerifyMiddleware(server.Logger, sts))
+		r.Get("/v1/archives", ac.handleRetrieve)
+		r.Post("/v1/archives/{id}/restore", ac.handleRestore)
+		r.Get("/v1/archives/restores/{id}", ac.handleGetRestore)
+	})
+
+	return ac
+}
+
+func (ac *ArchiveController) handleRetrieve(w http.ResponseWriter, r *http.Request) {
+	// Increment metric
+	ac.counterMetric.Inc()
+
+	filter, err := archive.ToParseFilterRequest(r)
+	if err != nil {
+		ac.log.Error(logrus.ErrorLevel, "handleRetrieve", "Invalid request parameters", err)
+		terr := terrors.BadRequest(terrors.ErrBadRequest, "Invalid request parameters", map[string]string{})
+		RenderError(r.Context(), w, terr)
+		return
+	}
+
+	res, err := ac.archiveSvc.Retrieve(r.Context(), filter)
+	if err != nil {
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	RenderJSON(r.Context(), w, http.StatusOK, res)
+}
+
+func (ac *ArchiveController) handleRestore(w http.ResponseWriter, r *http.Request) {
+	// Increment metric
+	ac.counterMetric.Inc()
+
+	res, err := ac.archiveSvc.Restore(r.Context(), chi.URLParam(r, "id"))
+	if terrors.Is(err, terrors.ErrRateLimited) {
+		RenderTooManyRequests(r.Context(), w, restoreRetryAfter)
+		return
+	}
+	if err != nil {
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	RenderJSON(r.Context(), w, http.StatusAccepted, res)
+}
+
+func (ac *ArchiveController) handleGetRestore(w http.ResponseWriter, r *http.Request) {
+	// Increment metric
+	ac.counterMetric.Inc()
+
+	res, err := ac.archiveSvc.GetRestore(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	RenderJSON(r.Context(), w, http.StatusOK, res)
+}
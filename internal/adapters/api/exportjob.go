@@ -0,0 +1,139 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/omnilogger/internal/services/export_job"
+	"github.com/jmontesinos91/omnilogger/internal/services/logs"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/jmontesinos91/terrors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+)
+
+// exportQueueRetryAfter seconds a client should wait before queuing an export again when the queue is full
+const exportQueueRetryAfter = "60"
+
+// ExportJobController asynchronous exports controller
+type ExportJobController struct {
+	log           *logger.ContextLogger
+	exportJobSvc  export_job.IService
+	stsClient     sts.ISTSClient
+	counterMetric prometheus.Counter
+}
+
+// NewExportJobController Constructor
+func NewExportJobController(server *HTTPServer, es export_job.IService, sts sts.ISTSClient) *ExportJobController {
+	ec := &ExportJobController{
+		log:          server.Logger,
+		exportJobSvc: es,
+		stsClient:    sts,
+		counterMetric: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "export_jobs_reqs_total",
+			Help: "The total number of requests to export jobs endpoints",
+		}),
+	}
+
+	server.Router.Group(func(r chi.Router) {
+		r.Use(JwtVerifyMiddleware(server.Logger, sts))
+		r.Post("/v1/logs/exports", ec.handleCreate)
+		r.Get("/v1/logs/exports/{id}", ec.handleGet)
+		r.Get("/v1/logs/exports/{id}/download", ec.handleDownload)
+		r.Get("/v1/logs/exports/{id}/manifest", ec.handleManifest)
+	})
+
+	return ec
+}
+
+func (ec *ExportJobController) handleCreate(w http.ResponseWriter, r *http.Request) {
+	// Increment metric
+	ec.counterMetric.Inc()
+
+	// Export jobs take the same parameters as the streamed exports
+	filter, opts, err := logs.ToParseExportRequest(r)
+	if err != nil {
+		ec.log.Error(logrus.ErrorLevel, "handleCreate", "Invalid request parameters", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	res, err := ec.exportJobSvc.Create(r.Context(), filter, opts)
+	if terrors.Is(err, terrors.ErrRateLimited) {
+		RenderTooManyRequests(r.Context(), w, exportQueueRetryAfter)
+		return
+	}
+	if err != nil {
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	RenderJSON(r.Context(), w, http.StatusAccepted, res)
+}
+
+func (ec *ExportJobController) handleGet(w http.ResponseWriter, r *http.Request) {
+	// Increment metric
+	ec.counterMetric.Inc()
+
+	res, err := ec.exportJobSvc.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	RenderJSON(r.Context(), w, http.StatusOK, res)
+}
+
+func (ec *ExportJobController) handleDownload(w http.ResponseWriter, r *http.Request) {
+	// Increment metric
+	ec.counterMetric.Inc()
+
+	// Large files take longer than the request timeout to send
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), exportTimeout)
+	defer cancel()
+
+	file, err := ec.exportJobSvc.Download(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		RenderError(r.Context(), w, err)
+		return
+	}
+	defer file.Content.Close() //nolint:errcheck
+
+	// The manifest is known before the file is sent, unlike the streamed exports it is sent as headers
+	header := w.Header()
+	header.Set(middleware.RequestIDHeader, middleware.GetReqID(r.Context()))
+	header.Set("Content-Type", file.ContentType)
+	header.Set("Content-Disposition", "attachment; filename="+file.Name)
+	header.Set("Content-Length", strconv.FormatInt(file.Size, 10))
+	if file.Manifest != "" {
+		header.Set(ExportManifestHeader, file.Manifest)
+		header.Set(ExportDigestHeader, file.SHA256)
+	}
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, file.Content); err != nil {
+		ec.log.Error(logrus.ErrorLevel, "handleDownload", "Export download interrupted", err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// handleManifest sends the manifest of an export job as json, for the clients that can not read the headers of the
+// download or the trailers of the streamed exports
+func (ec *ExportJobController) handleManifest(w http.ResponseWriter, r *http.Request) {
+	// Increment metric
+	ec.counterMetric.Inc()
+
+	res, err := ec.exportJobSvc.Manifest(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	RenderJSON(r.Context(), w, http.StatusOK, res)
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/omnilogger/internal/services/export_job"
+	"github.com/jmontesinos91/omnilogger/internal/services/export_job/exportjobsvcmock"
+	"github.com/jmontesinos91/omnilogger/internal/services/logs"
+	"github.com/jmontesinos91/omnilogger/internal/utils/export"
+	"github.com/jmontesinos91/terrors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestExportJobController(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestExportJobController", "debug", logger.TextFormat)
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		handler      func(ec *ExportJobController) http.HandlerFunc
+		mockSvc      *exportjobsvcmock.IService
+		expectedCode int
+		expectedBody string
+		expectHeader map[string]string
+	}{
+		{
+			name:         "Create_Accepted",
+			method:       http.MethodPost,
+			path:         "/v1/logs/exports?format=csv&compress=gzip",
+			handler:      func(ec *ExportJobController) http.HandlerFunc { return ec.handleCreate },
+			mockSvc:      &exportjobsvcmock.IService{CreateRes: &export_job.Response{ID: "j1", Status: "queued"}},
+			expectedCode: http.StatusAccepted,
+		},
+		{
+			name:         "Create_InvalidFormat",
+			method:       http.MethodPost,
+			path:         "/v1/logs/exports?format=pdf",
+			handler:      func(ec *ExportJobController) http.HandlerFunc { return ec.handleCreate },
+			mockSvc:      &exportjobsvcmock.IService{},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Create_QueueFull",
+			method:       http.MethodPost,
+			path:         "/v1/logs/exports",
+			handler:      func(ec *ExportJobController) http.HandlerFunc { return ec.handleCreate },
+			mockSvc:      &exportjobsvcmock.IService{CreateErr: terrors.New(terrors.ErrRateLimited, "Too many export jobs queued, try again later", map[string]string{})},
+			expectedCode: http.StatusTooManyRequests,
+			expectHeader: map[string]string{"Retry-After": exportQueueRetryAfter},
+		},
+		{
+			name:         "Get_NotFound",
+			method:       http.MethodGet,
+			path:         "/v1/logs/exports/j1",
+			handler:      func(ec *ExportJobController) http.HandlerFunc { return ec.handleGet },
+			mockSvc:      &exportjobsvcmock.IService{GetErr: terrors.New(terrors.ErrNotFound, "Export job not found", map[string]string{})},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:    "Download_Success",
+			method:  http.MethodGet,
+			path:    "/v1/logs/exports/j1/download",
+			handler: func(ec *ExportJobController) http.HandlerFunc { return ec.handleDownload },
+			mockSvc: &exportjobsvcmock.IService{
+				DownloadRes:     &export_job.Download{Name: logs.ExportFileCSV, ContentType: logs.ContentTypeCSV, Size: 7, SHA256: "digest", Manifest: "manifest"},
+				DownloadContent: "content",
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: "content",
+			expectHeader: map[string]string{
+				"Content-Type":        logs.ContentTypeCSV,
+				"Content-Disposition": "attachment; filename=" + logs.ExportFileCSV,
+				"Content-Length":      "7",
+				ExportManifestHeader:  "manifest",
+				ExportDigestHeader:    "digest",
+			},
+		},
+		{
+			name:         "Download_NotReady",
+			method:       http.MethodGet,
+			path:         "/v1/logs/exports/j1/download",
+			handler:      func(ec *ExportJobController) http.HandlerFunc { return ec.handleDownload },
+			mockSvc:      &exportjobsvcmock.IService{DownloadErr: terrors.New(terrors.ErrPreconditionFailed, "Export job is running", map[string]string{})},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Manifest_Success",
+			method:       http.MethodGet,
+			path:         "/v1/logs/exports/j1/manifest",
+			handler:      func(ec *ExportJobController) http.HandlerFunc { return ec.handleManifest },
+			mockSvc:      &exportjobsvcmock.IService{ManifestRes: &export.Manifest{Version: export.ManifestVersion, File: logs.ExportFileCSV, SHA256: "digest"}},
+			expectedCode: http.StatusOK,
+			expectHeader: map[string]string{"Content-Type": "application/json"},
+		},
+		{
+			name:         "Manifest_NotFound",
+			method:       http.MethodGet,
+			path:         "/v1/logs/exports/j1/manifest",
+			handler:      func(ec *ExportJobController) http.HandlerFunc { return ec.handleManifest },
+			mockSvc:      &exportjobsvcmock.IService{ManifestErr: terrors.New(terrors.ErrNotFound, "Export manifest not found", map[string]string{})},
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ec := &ExportJobController{
+				log:          ctxLogger,
+				exportJobSvc: tt.mockSvc,
+				counterMetric: prometheus.NewCounter(prometheus.CounterOpts{
+					Name: "test_export_jobs_reqs_total",
+					Help: "test counter",
+				}),
+			}
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "j1")
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			req = req.WithContext(context.WithValue(ctx, middleware.RequestIDKey, "rid-export-job"))
+			rr := httptest.NewRecorder()
+
+			tt.handler(ec)(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Fatalf("expected status %d, got %d, body: %s", tt.expectedCode, rr.Code, rr.Body.String())
+			}
+			if tt.expectedBody != "" && rr.Body.String() != tt.expectedBody {
+				t.Fatalf("expected body %q, got %q", tt.expectedBody, rr.Body.String())
+			}
+			for key, value := range tt.expectHeader {
+				if rr.Header().Get(key) != value {
+					t.Fatalf("expected header %s %q, got %q", key, value, rr.Header().Get(key))
+				}
+			}
+			if tt.mockSvc.DownloadCalled && tt.mockSvc.DownloadID != "j1" {
+				t.Fatalf("expected the id param, got %q", tt.mockSvc.DownloadID)
+			}
+			if tt.mockSvc.ManifestCalled && tt.mockSvc.ManifestID != "j1" {
+				t.Fatalf("expected the id param, got %q", tt.mockSvc.ManifestID)
+			}
+		})
+	}
+}
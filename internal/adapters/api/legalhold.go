@@ -0,0 +1,157 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-playground/validator/v10"
+	"github.com/jmontesinos91/ologs/logger"
+	tracekey "github.com/jmontesinos91/ologs/logger/v2"
+	"github.com/jmontesinos91/omnilogger/internal/services/legal_hold"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/jmontesinos91/terrors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+)
+
+// LegalHoldController legal holds controller
+type LegalHoldController struct {
+	log           *logger.ContextLogger
+	validate      *validator.Validate
+	legalHoldSvc  legal_hold.IService
+	stsClient     sts.ISTSClient
+	counterMetric prometheus.Counter
+}
+
+// NewLegalHoldController Constructor
+func NewLegalHoldController(server *HTTPServer, validator *validator.Validate, lhs legal_hold.IService, sts sts.ISTSClient) *LegalHoldController {
+	lc := &LegalHoldController{
+		log:          server.Logger,
+		validate:     validator,
+		legalHoldSvc: lhs,
+		stsClient:    sts,
+		counterMetric: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "legal_holds_reqs_total",
+			Help: "The total number of requests to legal holds endpoints",
+		}),
+	}
+
+	server.Router.Group(func(r chi.Router) {
+		r.Use(JwtVerifyMiddleware(server.Logger, sts))
+		r.Post("/v1/legal_holds", lc.handleCreate)
+		r.Get("/v1/legal_holds", lc.handleRetrieve)
+		r.Get("/v1/legal_holds/{id}", lc.handleGetByID)
+		r.Put("/v1/legal_holds/{id}", lc.handleUpdate)
+		r.Delete("/v1/legal_holds/{id}", lc.handleRelease)
+	})
+
+	return lc
+}
+
+func (lc *LegalHoldController) handleCreate(w http.ResponseWriter, r *http.Request) {
+	// Increment metric
+	lc.counterMetric.Inc()
+
+	payload, ok := lc.decodePayload(w, r, "handleCreate")
+	if !ok {
+		return
+	}
+
+	res, err := lc.legalHoldSvc.Create(r.Context(), payload)
+	if err != nil {
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	RenderJSON(r.Context(), w, http.StatusCreated, res)
+}
+
+func (lc *LegalHoldController) handleRetrieve(w http.ResponseWriter, r *http.Request) {
+	// Increment metric
+	lc.counterMetric.Inc()
+
+	filter, err := legal_hold.ToParseFilterRequest(r)
+	if err != nil {
+		lc.log.Error(logrus.ErrorLevel, "handleRetrieve", "Invalid request parameters", err)
+		terr := terrors.BadRequest(terrors.ErrBadRequest, "Invalid request parameters", map[string]string{})
+		RenderError(r.Context(), w, terr)
+		return
+	}
+
+	res, err := lc.legalHoldSvc.Retrieve(r.Context(), filter)
+	if err != nil {
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	RenderJSON(r.Context(), w, http.StatusOK, res)
+}
+
+func (lc *LegalHoldController) handleGetByID(w http.ResponseWriter, r *http.Request) {
+	// Increment metric
+	lc.counterMetric.Inc()
+
+	res, err := lc.legalHoldSvc.GetByID(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	RenderJSON(r.Context(), w, http.StatusOK, res)
+}
+
+func (lc *LegalHoldController) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	// Increment metric
+	lc.counterMetric.Inc()
+
+	payload, ok := lc.decodePayload(w, r, "handleUpdate")
+	if !ok {
+		return
+	}
+
+	res, err := lc.legalHoldSvc.Update(r.Context(), chi.URLParam(r, "id"), payload)
+	if err != nil {
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	RenderJSON(r.Context(), w, http.StatusOK, res)
+}
+
+func (lc *LegalHoldController) handleRelease(w http.ResponseWriter, r *http.Request) {
+	// Increment metric
+	lc.counterMetric.Inc()
+
+	err := lc.legalHoldSvc.Release(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	w.Header().Set(middleware.RequestIDHeader, middleware.GetReqID(r.Context()))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (lc *LegalHoldController) decodePayload(w http.ResponseWriter, r *http.Request, caller string) (*legal_hold.Payload, bool) {
+	var payload legal_hold.Payload
+	requestID := r.Context().Value(middleware.RequestIDKey).(string)
+
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		lc.log.WithContext(
+			logrus.ErrorLevel,
+			caller,
+			"Error while parsing request payload: %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+			},
+			err)
+		terr := terrors.BadRequest(terrors.ErrBadRequest, "Malformed body", map[string]string{})
+		RenderError(r.Context(), w, terr)
+		return nil, false
+	}
+
+	return &payload, true
+}
@@ -2,10 +2,12 @@ package api
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/jmontesinos91/ologs/logger"
 	"github.com/jmontesinos91/omnilogger/internal/repositories/middleware"
+	"github.com/jmontesinos91/omnilogger/internal/services/api_key"
 	"github.com/jmontesinos91/osecurity/services/omnibackend/enum"
 	"github.com/jmontesinos91/osecurity/sts"
 	"github.com/jmontesinos91/terrors"
@@ -41,6 +43,70 @@ func JwtVerifyMiddleware(logger *logger.ContextLogger, stsClient sts.ISTSClient)
 	}
 }
 
+// APIKeyHeader header used by services and devices to send their api key
+const APIKeyHeader = "X-API-Key"
+
+// IngestionAuthMiddleware accepts either a tenant api key with the given scope or a user JWT,
+// requests authenticated with an api key get claims scoped to the api key tenant
+func IngestionAuthMiddleware(logger *logger.ContextLogger, stsClient sts.ISTSClient, apiKeySvc api_key.IService, scope string) func(http.Handler) http.Handler {
+	jwtMiddleware := JwtVerifyMiddleware(logger, stsClient)
+
+	return func(next http.Handler) http.Handler {
+		jwtNext := jwtMiddleware(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := apiKeyFromRequest(r)
+			if key == "" {
+				jwtNext.ServeHTTP(w, r)
+				return
+			}
+
+			logger.Log(logrus.DebugLevel, "IngestionAuthMiddleware", "start api key validation")
+			principal, err := apiKeySvc.Authenticate(r.Context(), key)
+			if err != nil {
+				logger.Error(logrus.ErrorLevel, "IngestionAuthMiddleware", "Api key validation failure: %v", err)
+				terr := terrors.Unauthorized(terrors.ErrUnauthorized, "Invalid credentials", map[string]string{})
+				RenderError(r.Context(), w, terr)
+				return
+			}
+
+			if !principal.HasScope(scope) {
+				logger.Log(logrus.ErrorLevel, "IngestionAuthMiddleware", "Api key "+principal.KeyID+" lacks scope "+scope)
+				terr := terrors.Unauthorized(terrors.ErrUnauthorized, "Invalid credentials", map[string]string{})
+				RenderError(r.Context(), w, terr)
+				return
+			}
+
+			// All good, propagate the api key and its tenant using context
+			ctx := api_key.StorePrincipalInContext(r.Context(), principal)
+			ctx = stsClient.StoreClaimsV2InContext(ctx, &sts.Claims{
+				User:    "api_key:" + principal.Name,
+				Name:    principal.Name,
+				Role:    "api_key",
+				Active:  1,
+				Tenants: []int{principal.TenantID},
+			})
+
+			// Continue the chain
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// apiKeyFromRequest reads the api key either from X-API-Key or from an "Authorization: ApiKey <key>" header
+func apiKeyFromRequest(r *http.Request) string {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return key
+	}
+
+	scheme, key, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if found && strings.EqualFold(scheme, "ApiKey") {
+		return strings.TrimSpace(key)
+	}
+
+	return ""
+}
+
 func validateAccess(r *http.Request, permissions *[]sts.Permission, logger *logger.ContextLogger) error {
 	logger.Log(logrus.DebugLevel, "validateAccess", "start validate access")
 	route := chi.RouteContext(r.Context()).RoutePattern()
@@ -1,38 +1,58 @@
 package api
 
 import (
+	"compress/gzip"
+	"context"
 	"encoding/json"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-playground/validator/v10"
 	"github.com/jmontesinos91/ologs/logger"
 	tracekey "github.com/jmontesinos91/ologs/logger/v2"
+	"github.com/jmontesinos91/omnilogger/internal/services/api_key"
 	"github.com/jmontesinos91/omnilogger/internal/services/logs"
+	"github.com/jmontesinos91/omnilogger/internal/services/ratelimit"
 	"github.com/jmontesinos91/osecurity/sts"
 	"github.com/jmontesinos91/terrors"
 	"github.com/sirupsen/logrus"
 )
 
+// Trailers sent with the streamed export files, and headers of the export job downloads. The streamed exports send
+// them as trailers since the manifest is only known once the file is written, clients that drop the trailers get
+// the manifest from an export job with GET /v1/logs/exports/{id}/manifest
+const (
+	ExportManifestHeader = "X-Export-Manifest"
+	ExportDigestHeader   = "X-Export-SHA256"
+)
+
+// exportTimeout longest an export can stream
+const exportTimeout = 2 * time.Hour
+
 // OmniLoggerController OmniLogger controller
 type OmniLoggerController struct {
 	log           *logger.ContextLogger
 	validate      *validator.Validate
 	logsSvc       logs.IService
+	rateLimitSvc  ratelimit.IService
 	stsClient     sts.ISTSClient
 	counterMetric prometheus.Counter
 }
 
 // NewOmniLoggerController Constructor
-func NewOmniLoggerController(server *HTTPServer, validator *validator.Validate, ss logs.IService, sts sts.ISTSClient) *OmniLoggerController {
+func NewOmniLoggerController(server *HTTPServer, validator *validator.Validate, ss logs.IService, sts sts.ISTSClient, aks api_key.IService, rls ratelimit.IService) *OmniLoggerController {
 	sc := &OmniLoggerController{
-		log:       server.Logger,
-		validate:  validator,
-		logsSvc:   ss,
-		stsClient: sts,
+		log:          server.Logger,
+		validate:     validator,
+		logsSvc:      ss,
+		rateLimitSvc: rls,
+		stsClient:    sts,
 		counterMetric: promauto.NewCounter(prometheus.CounterOpts{
 			Name: "omni_logger_reqs_total",
 			Help: "The total number of requests to omni logger endpoints",
@@ -43,9 +63,18 @@ func NewOmniLoggerController(server *HTTPServer, validator *validator.Validate,
 	server.Router.Group(func(r chi.Router) {
 		r.Use(JwtVerifyMiddleware(server.Logger, sts))
 		r.Get("/v1/logs/{id}", sc.handleGetLog)
-		r.Post("/v1/logs", sc.handleCreate)
 		r.Get("/v1/logs", sc.handleRetrieve)
 		r.Get("/v1/logs/export", sc.handleExport)
+		r.Get("/v1/logs/labels/facets", sc.handleLabelFacets)
+		r.Get("/v1/logs/verify", sc.handleVerify)
+		r.Get("/v1/logs/stats", sc.handleStats)
+		r.Get("/v1/logs/dashboard", sc.handleDashboard)
+	})
+
+	// Ingestion endpoints also accept tenant api keys for backend jobs and devices
+	server.Router.Group(func(r chi.Router) {
+		r.Use(IngestionAuthMiddleware(server.Logger, sts, aks, api_key.ScopeLogsWrite))
+		r.Post("/v1/logs", sc.handleCreate)
 	})
 
 	return sc
@@ -94,6 +123,28 @@ func (sc *OmniLoggerController) handleCreate(w http.ResponseWriter, r *http.Requ
 		RenderError(r.Context(), w, terr)
 		return
 	}
+
+	// Origin observed by the server is stored next to the client reported ip_address and client_host
+	payload.Origin = originFromRequest(r)
+
+	// Logs sent with an api key always belong to the api key tenant
+	if principal, ok := api_key.PrincipalFromContext(r.Context()); ok {
+		payload.TenantCat = principal.TenantCat()
+	}
+
+	if decision := sc.rateLimitSvc.Allow(ratelimit.SourceHTTP, ratelimit.ToKey(r.Context(), &payload), 1); !decision.Allowed {
+		sc.log.WithContext(
+			logrus.WarnLevel,
+			"handleCreate",
+			"Rate limit exceeded by "+decision.Dimension,
+			logger.Context{
+				tracekey.TrackingID: requestID,
+			},
+			nil)
+		RenderTooManyRequests(r.Context(), w, decision.RetryAfterSeconds())
+		return
+	}
+
 	// Call the service
 	res, err := sc.logsSvc.Create(r.Context(), &payload)
 	if err != nil {
@@ -124,24 +175,203 @@ func (sc *OmniLoggerController) handleRetrieve(w http.ResponseWriter, r *http.Re
 	RenderJSON(r.Context(), w, http.StatusOK, res)
 }
 
-func (sc *OmniLoggerController) handleExport(w http.ResponseWriter, r *http.Request) {
+func (sc *OmniLoggerController) handleLabelFacets(w http.ResponseWriter, r *http.Request) {
 	// Increment metric
 	sc.counterMetric.Inc()
 
 	filter, err := logs.ToParseFilterRequest(r)
 	if err != nil {
-		sc.log.Error(logrus.ErrorLevel, "handleRetrieve", "Invalid request parameters", err)
+		sc.log.Error(logrus.ErrorLevel, "handleLabelFacets", "Invalid request parameters", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	res, err := sc.logsSvc.LabelFacets(r.Context(), filter, r.URL.Query()["key[]"])
+	if err != nil {
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	RenderJSON(r.Context(), w, http.StatusOK, res)
+}
+
+func (sc *OmniLoggerController) handleStats(w http.ResponseWriter, r *http.Request) {
+	// Increment metric
+	sc.counterMetric.Inc()
+
+	filter, err := logs.ToParseStatsRequest(r)
+	if err != nil {
+		sc.log.Error(logrus.ErrorLevel, "handleStats", "Invalid request parameters", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	res, err := sc.logsSvc.Stats(r.Context(), filter)
+	if err != nil {
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	RenderJSON(r.Context(), w, http.StatusOK, res)
+}
+
+func (sc *OmniLoggerController) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	// Increment metric
+	sc.counterMetric.Inc()
+
+	filter, top, err := logs.ToParseDashboardRequest(r)
+	if err != nil {
+		sc.log.Error(logrus.ErrorLevel, "handleDashboard", "Invalid request parameters", err)
 		RenderError(r.Context(), w, err)
 		return
 	}
 
-	bytes, err := sc.logsSvc.Export(r.Context(), filter)
+	res, err := sc.logsSvc.Dashboard(r.Context(), filter, top)
 	if err != nil {
 		RenderError(r.Context(), w, err)
 		return
 	}
 
-	w.Header().Set("Content-Disposition", "attachment; filename=logs.xlsx")
+	RenderJSON(r.Context(), w, http.StatusOK, res)
+}
+
+func (sc *OmniLoggerController) handleVerify(w http.ResponseWriter, r *http.Request) {
+	// Increment metric
+	sc.counterMetric.Inc()
+
+	filter, err := logs.ToParseVerifyRequest(r)
+	if err != nil {
+		sc.log.Error(logrus.ErrorLevel, "handleVerify", "Invalid request parameters", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	res, err := sc.logsSvc.Verify(r.Context(), filter)
+	if err != nil {
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	RenderJSON(r.Context(), w, http.StatusOK, res)
+}
+
+func (sc *OmniLoggerController) handleExport(w http.ResponseWriter, r *http.Request) {
+	// Increment metric
+	sc.counterMetric.Inc()
+
+	filter, opts, err := logs.ToParseExportRequest(r)
+	if err != nil {
+		sc.log.Error(logrus.ErrorLevel, "handleExport", "Invalid request parameters", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	// Exports stream for as long as they take, the request timeout would cut the larger ones. A client going away
+	// fails the next write and stops the export
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), exportTimeout)
+	defer cancel()
+
+	// Files that are not compressed yet are gzip encoded for the clients accepting it, xlsx files are zip archives
+	encode := !opts.Compress && opts.Format != logs.ExportFormatXLSX && acceptsGzip(r)
+	res := &exportResponse{w: w, requestID: middleware.GetReqID(r.Context()), file: logs.ToExportFile(opts), encode: encode}
+
+	file, err := sc.logsSvc.Export(ctx, filter, opts, res)
+	if err == nil {
+		err = res.Close()
+	}
+	if err != nil {
+		if !res.started {
+			RenderError(r.Context(), w, err)
+			return
+		}
+
+		// The status is already sent, aborting the connection keeps the client from taking the truncated file
+		// for a complete one
+		sc.log.Error(logrus.ErrorLevel, "handleExport", "Export failed after it started streaming", err)
+		panic(http.ErrAbortHandler)
+	}
+
+	// The manifest lets auditors verify the file offline with "omnilogger verify", it is only known once the file
+	// is written so it is sent as trailers
+	if file.Manifest != nil {
+		manifest, err := file.Manifest.Encode()
+		if err != nil {
+			// Aborting keeps the client from taking a file without manifest for a verifiable one
+			sc.log.Error(logrus.ErrorLevel, "handleExport", "Failed to encode the export manifest", err)
+			panic(http.ErrAbortHandler)
+		}
+		w.Header().Set(ExportManifestHeader, manifest)
+		w.Header().Set(ExportDigestHeader, file.Manifest.SHA256)
+	}
+}
+
+// exportResponse sends the headers of the export file with its first bytes, so errors returned before can still
+// be rendered. The manifest headers are announced as trailers
+type exportResponse struct {
+	w         http.ResponseWriter
+	requestID string
+	file      *logs.ExportFile
+	encode    bool
+	gz        *gzip.Writer
+	started   bool
+}
+
+func (e *exportResponse) Write(p []byte) (int, error) {
+	if !e.started {
+		e.start()
+	}
+
+	if e.gz != nil {
+		return e.gz.Write(p)
+	}
+	return e.w.Write(p)
+}
+
+// Close sends the headers of an empty file and ends the gzip encoding
+func (e *exportResponse) Close() error {
+	if !e.started {
+		e.start()
+	}
+
+	if e.gz != nil {
+		return e.gz.Close()
+	}
+	return nil
+}
+
+func (e *exportResponse) start() {
+	e.started = true
+
+	header := e.w.Header()
+	header.Set(middleware.RequestIDHeader, e.requestID)
+	header.Set("Content-Type", e.file.ContentType)
+	header.Set("Content-Disposition", "attachment; filename="+e.file.Name)
+	header.Set("Trailer", ExportManifestHeader+", "+ExportDigestHeader)
+	if e.encode {
+		header.Set("Content-Encoding", logs.CompressGzip)
+		header.Add("Vary", "Accept-Encoding")
+		e.gz = gzip.NewWriter(e.w)
+	}
+
+	e.w.WriteHeader(http.StatusOK)
+}
+
+// acceptsGzip tells whether the Accept-Encoding header of the request accepts gzip
+func acceptsGzip(r *http.Request) bool {
+	for _, value := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		encoding, params, _ := strings.Cut(value, ";")
+		if !strings.EqualFold(strings.TrimSpace(encoding), logs.CompressGzip) {
+			continue
+		}
+
+		// A zero quality value refuses the encoding
+		quality, found := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !found {
+			return true
+		}
+		q, err := strconv.ParseFloat(quality, 64)
+		return err == nil && q > 0
+	}
 
-	RenderFile(r.Context(), w, http.StatusOK, bytes)
+	return false
 }
@@ -2,20 +2,27 @@ package api
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-playground/validator/v10"
 	"github.com/jmontesinos91/ologs/logger"
 	"github.com/jmontesinos91/omnilogger/internal/services/logs"
 	"github.com/jmontesinos91/omnilogger/internal/services/logs/logssvcmock"
+	"github.com/jmontesinos91/omnilogger/internal/services/ratelimit"
+	"github.com/jmontesinos91/omnilogger/internal/services/ratelimit/ratelimitsvcmock"
+	"github.com/jmontesinos91/omnilogger/internal/utils/export"
+	"github.com/jmontesinos91/terrors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 )
@@ -33,6 +40,7 @@ func TestOmniLoggerController_TableDriven(t *testing.T) {
 		reqID                string
 		chiParams            map[string]string
 		mockSvc              *logssvcmock.IService
+		rateLimitSvc         *ratelimitsvcmock.IService
 		expectedCode         int
 		expectedNot          []int
 		expectedCounter      float64
@@ -56,6 +64,20 @@ func TestOmniLoggerController_TableDriven(t *testing.T) {
 			expectedCounter: 1,
 			expectedRespID:  1,
 		},
+		{
+			name:    "HandleCreate_RateLimited_ReturnsTooManyRequests",
+			handler: "create",
+			method:  http.MethodPost,
+			path:    "/v1/logs",
+			body:    `{"message":1,"provider":"billing"}`,
+			reqID:   "rid-rl",
+			mockSvc: &logssvcmock.IService{},
+			rateLimitSvc: &ratelimitsvcmock.IService{
+				AllowRes: &ratelimit.Decision{Allowed: false, RetryAfter: 1500 * time.Millisecond, Dimension: ratelimit.DimensionProvider},
+			},
+			expectedCode:    http.StatusTooManyRequests,
+			expectedCounter: 1,
+		},
 		{
 			name:            "HandleGet_Success_IncrementsCounter",
 			handler:         "get",
@@ -136,11 +158,21 @@ func TestOmniLoggerController_TableDriven(t *testing.T) {
 			method:              http.MethodGet,
 			path:                "/v1/logs/export",
 			query:               "?page=1&per_page=10&max=10",
-			mockSvc:             &logssvcmock.IService{ExportRes: []byte("excel-bytes")},
+			mockSvc:             &logssvcmock.IService{ExportContent: []byte("excel-bytes")},
 			expectExportCalled:  true,
 			expectedCounter:     1,
 			expectedExportBytes: []byte("excel-bytes"),
 		},
+		{
+			name:               "Export_InvalidFormat",
+			handler:            "export",
+			method:             http.MethodGet,
+			path:               "/v1/logs/export",
+			query:              "?page=1&max=10&format=pdf",
+			mockSvc:            &logssvcmock.IService{},
+			expectExportCalled: false,
+			expectedCounter:    1,
+		},
 		{
 			name:               "Export_ServiceError",
 			handler:            "export",
@@ -165,10 +197,15 @@ func TestOmniLoggerController_TableDriven(t *testing.T) {
 				Help: "test counter",
 			})
 
+			if tt.rateLimitSvc == nil {
+				tt.rateLimitSvc = &ratelimitsvcmock.IService{}
+			}
+
 			sc := &OmniLoggerController{
 				log:           ctxLogger,
 				validate:      validator.New(),
 				logsSvc:       tt.mockSvc,
+				rateLimitSvc:  tt.rateLimitSvc,
 				counterMetric: counter,
 			}
 
@@ -219,6 +256,15 @@ func TestOmniLoggerController_TableDriven(t *testing.T) {
 				}
 			}
 
+			if rr.Code == http.StatusTooManyRequests {
+				if rr.Header().Get("Retry-After") != "2" {
+					t.Fatalf("expected Retry-After 2, got %q", rr.Header().Get("Retry-After"))
+				}
+				if tt.mockSvc.CreateCalled {
+					t.Fatalf("did not expect Create to be called when rate limited")
+				}
+			}
+
 			// retrieve call expectations (only meaningful for retrieve cases)
 			if tt.handler == "retrieve" {
 				if tt.expectRetrieveCalled {
@@ -297,3 +343,236 @@ func TestOmniLoggerController_TableDriven(t *testing.T) {
 		})
 	}
 }
+
+func TestOmniLoggerController_LabelFacets(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestControllerLabelFacets", "debug", logger.TextFormat)
+
+	tests := []struct {
+		name         string
+		query        string
+		mockSvc      *logssvcmock.IService
+		expectedCode int
+		expectCalled bool
+	}{
+		{
+			name:  "Success",
+			query: "?page=1&max=5&key[]=env&label.team=ops",
+			mockSvc: &logssvcmock.IService{
+				LabelFacetsRes: []logs.LabelFacet{{Key: "env", Values: []logs.FacetValue{{Value: "prod", Count: 3}}}},
+			},
+			expectedCode: http.StatusOK,
+			expectCalled: true,
+		},
+		{
+			name:         "Invalid label filter",
+			query:        "?page=1&max=5&label.=ops",
+			mockSvc:      &logssvcmock.IService{},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Service error",
+			query:        "?page=1&max=5",
+			mockSvc:      &logssvcmock.IService{LabelFacetsErr: errors.New("svc fail")},
+			expectedCode: http.StatusInternalServerError,
+			expectCalled: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := &OmniLoggerController{
+				log:     ctxLogger,
+				logsSvc: tt.mockSvc,
+				counterMetric: prometheus.NewCounter(prometheus.CounterOpts{
+					Name: "test_omnilogger_facets",
+					Help: "test counter",
+				}),
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/logs/labels/facets"+tt.query, nil)
+			req = req.WithContext(context.WithValue(req.Context(), middleware.RequestIDKey, "rid-facets"))
+			rr := httptest.NewRecorder()
+
+			sc.handleLabelFacets(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Fatalf("expected status %d, got %d, body: %s", tt.expectedCode, rr.Code, rr.Body.String())
+			}
+			if tt.mockSvc.LabelFacetsCalled != tt.expectCalled {
+				t.Fatalf("expected LabelFacets called %v, got %v", tt.expectCalled, tt.mockSvc.LabelFacetsCalled)
+			}
+
+			if rr.Code == http.StatusOK {
+				var resp []logs.LabelFacet
+				if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+					t.Fatalf("invalid JSON response: %v", err)
+				}
+				if len(resp) != 1 || resp[0].Key != "env" || resp[0].Values[0].Count != 3 {
+					t.Fatalf("unexpected facets %+v", resp)
+				}
+				if len(tt.mockSvc.LabelFacetsKeys) != 1 || tt.mockSvc.LabelFacetsKeys[0] != "env" {
+					t.Fatalf("expected keys [env], got %v", tt.mockSvc.LabelFacetsKeys)
+				}
+			}
+		})
+	}
+}
+
+func TestOmniLoggerController_Verify(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestControllerVerify", "debug", logger.TextFormat)
+
+	tests := []struct {
+		name         string
+		query        string
+		mockSvc      *logssvcmock.IService
+		expectedCode int
+		expectCalled bool
+	}{
+		{
+			name:  "Broken chain",
+			query: "?tenant_id=1&from=2024-01-01T00:00:00",
+			mockSvc: &logssvcmock.IService{VerifyRes: &logs.VerifyResult{
+				TenantID:   1,
+				BrokenLink: &logs.BrokenLink{ID: "b", Seq: 2, Reason: logs.ReasonHashMismatch},
+			}},
+			expectedCode: http.StatusOK,
+			expectCalled: true,
+		},
+		{
+			name:         "Missing tenant",
+			query:        "",
+			mockSvc:      &logssvcmock.IService{},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Tenant not allowed",
+			query:        "?tenant_id=2",
+			mockSvc:      &logssvcmock.IService{VerifyErr: terrors.Unauthorized(terrors.ErrUnauthorized, "Tenant not allowed", map[string]string{})},
+			expectedCode: http.StatusUnauthorized,
+			expectCalled: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := &OmniLoggerController{
+				log:     ctxLogger,
+				logsSvc: tt.mockSvc,
+				counterMetric: prometheus.NewCounter(prometheus.CounterOpts{
+					Name: "test_omnilogger_verify",
+					Help: "test counter",
+				}),
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/logs/verify"+tt.query, nil)
+			req = req.WithContext(context.WithValue(req.Context(), middleware.RequestIDKey, "rid-verify"))
+			rr := httptest.NewRecorder()
+
+			sc.handleVerify(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Fatalf("expected status %d, got %d, body: %s", tt.expectedCode, rr.Code, rr.Body.String())
+			}
+			if tt.mockSvc.VerifyCalled != tt.expectCalled {
+				t.Fatalf("expected Verify called %v, got %v", tt.expectCalled, tt.mockSvc.VerifyCalled)
+			}
+
+			if rr.Code == http.StatusOK {
+				var resp logs.VerifyResult
+				if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+					t.Fatalf("invalid JSON response: %v", err)
+				}
+				if resp.BrokenLink == nil || resp.BrokenLink.ID != "b" {
+					t.Fatalf("unexpected result %+v", resp)
+				}
+				if tt.mockSvc.VerifyFilter.TenantID != 1 || tt.mockSvc.VerifyFilter.From.IsZero() {
+					t.Fatalf("unexpected filter %+v", tt.mockSvc.VerifyFilter)
+				}
+			}
+		})
+	}
+}
+
+func TestOmniLoggerController_ExportStreaming(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestOmniLoggerController_ExportStreaming", "debug", logger.TextFormat)
+	content := []byte("{\"id\":\"1\"}\n")
+	manifest := &export.Manifest{Version: export.ManifestVersion, File: logs.ExportFileNDJSON, SHA256: export.Digest(content)}
+
+	tests := []struct {
+		name           string
+		query          string
+		acceptEncoding string
+		expectType     string
+		expectFile     string
+		expectGzip     bool
+		expectCompress bool
+	}{
+		{name: "Identity", query: "?format=ndjson", expectType: logs.ContentTypeNDJSON, expectFile: logs.ExportFileNDJSON},
+		{name: "Accepts gzip", query: "?format=ndjson", acceptEncoding: "br, gzip;q=0.8", expectType: logs.ContentTypeNDJSON, expectFile: logs.ExportFileNDJSON, expectGzip: true},
+		{name: "Refuses gzip", query: "?format=ndjson", acceptEncoding: "gzip;q=0", expectType: logs.ContentTypeNDJSON, expectFile: logs.ExportFileNDJSON},
+		{name: "Xlsx not encoded", query: "?page=1&max=10", acceptEncoding: "gzip", expectType: logs.ContentTypeXLSX, expectFile: logs.ExportFileXLSX},
+		{name: "Compressed file not encoded", query: "?format=json&compress=gzip", acceptEncoding: "gzip", expectType: logs.ContentTypeGzip, expectFile: logs.ExportFileJSON + logs.ExportExtGzip, expectCompress: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := &logssvcmock.IService{ExportContent: content}
+			sc := &OmniLoggerController{
+				log:     ctxLogger,
+				logsSvc: mockSvc,
+				counterMetric: prometheus.NewCounter(prometheus.CounterOpts{
+					Name: "test_omnilogger_export_streaming",
+					Help: "test counter",
+				}),
+			}
+
+			// The service returns the file once its content is written, with the manifest
+			mockSvc.ExportRes = &logs.ExportFile{Manifest: manifest}
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/logs/export"+tt.query, nil)
+			req = req.WithContext(context.WithValue(req.Context(), middleware.RequestIDKey, "rid-export"))
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rr := httptest.NewRecorder()
+
+			sc.handleExport(rr, req)
+			res := rr.Result()
+
+			if res.StatusCode != http.StatusOK {
+				t.Fatalf("expected status 200, got %d, body: %s", res.StatusCode, rr.Body.String())
+			}
+			if mockSvc.ExportOpts.Compress != tt.expectCompress {
+				t.Fatalf("expected compress %v, got %v", tt.expectCompress, mockSvc.ExportOpts.Compress)
+			}
+			if res.Header.Get("Content-Type") != tt.expectType {
+				t.Fatalf("unexpected Content-Type %q", res.Header.Get("Content-Type"))
+			}
+			if res.Header.Get("Content-Disposition") != "attachment; filename="+tt.expectFile {
+				t.Fatalf("unexpected Content-Disposition %q", res.Header.Get("Content-Disposition"))
+			}
+			if res.Trailer.Get(ExportDigestHeader) != manifest.SHA256 || res.Trailer.Get(ExportManifestHeader) == "" {
+				t.Fatalf("expected the manifest trailers, got %v", res.Trailer)
+			}
+
+			body := rr.Body.Bytes()
+			if tt.expectGzip {
+				if res.Header.Get("Content-Encoding") != "gzip" {
+					t.Fatalf("expected gzip Content-Encoding, got %q", res.Header.Get("Content-Encoding"))
+				}
+				gz, err := gzip.NewReader(bytes.NewReader(body))
+				if err != nil {
+					t.Fatalf("invalid gzip body: %v", err)
+				}
+				if body, err = io.ReadAll(gz); err != nil {
+					t.Fatalf("invalid gzip body: %v", err)
+				}
+			} else if res.Header.Get("Content-Encoding") != "" {
+				t.Fatalf("did not expect Content-Encoding, got %q", res.Header.Get("Content-Encoding"))
+			}
+			if !bytes.Equal(body, content) {
+				t.Fatalf("expected export bytes %q, got %q", content, body)
+			}
+		})
+	}
+}
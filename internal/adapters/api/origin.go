@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jmontesinos91/omnilogger/internal/services/api_key"
+	"github.com/jmontesinos91/omnilogger/internal/services/logs"
+	"github.com/jmontesinos91/osecurity/sts"
+)
+
+type forwardedChainKey struct{}
+
+// CaptureOriginMiddleware keeps the forwarded chain and the address of the peer before middleware.RealIP
+// rewrites the remote address, it must be registered before middleware.RealIP
+func CaptureOriginMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var chain []string
+		for _, value := range r.Header.Values("X-Forwarded-For") {
+			for _, hop := range strings.Split(value, ",") {
+				if hop = strings.TrimSpace(hop); hop != "" {
+					chain = append(chain, hop)
+				}
+			}
+		}
+		if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" && len(chain) == 0 {
+			chain = append(chain, realIP)
+		}
+
+		// The chain is only meaningful when the request went through a proxy
+		if len(chain) > 0 {
+			chain = append(chain, hostOnly(r.RemoteAddr))
+			ctx := context.WithValue(r.Context(), forwardedChainKey{}, strings.Join(chain, ", "))
+			r = r.WithContext(ctx)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// originFromRequest builds the origin observed by the server, the remote address has already been
+// resolved by middleware.RealIP and the subject comes from the authenticated api key or user
+func originFromRequest(r *http.Request) *logs.Origin {
+	origin := &logs.Origin{
+		IP: hostOnly(r.RemoteAddr),
+	}
+
+	if chain, ok := r.Context().Value(forwardedChainKey{}).(string); ok {
+		origin.ForwardedFor = chain
+	}
+
+	if principal, ok := api_key.PrincipalFromContext(r.Context()); ok {
+		origin.Subject = "api_key:" + principal.KeyID
+	} else if claims, ok := r.Context().Value(&sts.Claim).(sts.Claims); ok {
+		origin.Subject = "user:" + strconv.Itoa(claims.UserID)
+	}
+
+	if claims, ok := r.Context().Value(&sts.Claim).(sts.Claims); ok {
+		origin.TenantID = claims.Tenants
+	}
+
+	return origin
+}
+
+func hostOnly(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	return host
+}
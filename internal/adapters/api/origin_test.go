@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jmontesinos91/omnilogger/internal/services/api_key"
+	"github.com/jmontesinos91/omnilogger/internal/services/logs"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOriginFromRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		ctx      func(context.Context) context.Context
+		expected *logs.Origin
+	}{
+		{
+			name:     "Direct anonymous request",
+			expected: &logs.Origin{IP: "192.0.2.1"},
+		},
+		{
+			name:    "Behind proxies with user",
+			headers: map[string]string{"X-Forwarded-For": "203.0.113.7, 198.51.100.2"},
+			ctx: func(ctx context.Context) context.Context {
+				return context.WithValue(ctx, &sts.Claim, sts.Claims{UserID: 42, Tenants: []int{1, 2}})
+			},
+			expected: &logs.Origin{
+				IP:           "203.0.113.7",
+				ForwardedFor: "203.0.113.7, 198.51.100.2, 192.0.2.1",
+				Subject:      "user:42",
+				TenantID:     []int{1, 2},
+			},
+		},
+		{
+			name:    "X-Real-IP with api key",
+			headers: map[string]string{"X-Real-IP": "203.0.113.9"},
+			ctx: func(ctx context.Context) context.Context {
+				ctx = api_key.StorePrincipalInContext(ctx, &api_key.Principal{KeyID: "key-1", TenantID: 7})
+				return context.WithValue(ctx, &sts.Claim, sts.Claims{Tenants: []int{7}})
+			},
+			expected: &logs.Origin{
+				IP:           "203.0.113.9",
+				ForwardedFor: "203.0.113.9, 192.0.2.1",
+				Subject:      "api_key:key-1",
+				TenantID:     []int{7},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var origin *logs.Origin
+			handler := CaptureOriginMiddleware(middleware.RealIP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.ctx != nil {
+					r = r.WithContext(tt.ctx(r.Context()))
+				}
+				origin = originFromRequest(r)
+			})))
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/logs", nil)
+			req.RemoteAddr = "192.0.2.1:4321"
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, tt.expected, origin)
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package api
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jmontesinos91/ologs/logger"
+	tracekey "github.com/jmontesinos91/ologs/logger/v2"
+	"github.com/jmontesinos91/omnilogger/internal/adapters/otlp"
+	"github.com/jmontesinos91/omnilogger/internal/services/api_key"
+	"github.com/jmontesinos91/omnilogger/internal/services/logs"
+	"github.com/jmontesinos91/omnilogger/internal/services/ratelimit"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/jmontesinos91/terrors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+)
+
+// maxOTLPBodySize upper limit for an uncompressed export request
+const maxOTLPBodySize = 8 << 20
+
+// OTLPController receives logs exported by OpenTelemetry SDKs and collectors over OTLP/HTTP
+type OTLPController struct {
+	log           *logger.ContextLogger
+	logsSvc       logs.IService
+	rateLimitSvc  ratelimit.IService
+	stsClient     sts.ISTSClient
+	counterMetric prometheus.Counter
+	recordsMetric *prometheus.CounterVec
+}
+
+// NewOTLPController Constructor
+func NewOTLPController(server *HTTPServer, ss logs.IService, sts sts.ISTSClient, aks api_key.IService, rls ratelimit.IService) *OTLPController {
+	oc := &OTLPController{
+		log:          server.Logger,
+		logsSvc:      ss,
+		rateLimitSvc: rls,
+		stsClient:    sts,
+		counterMetric: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "otlp_logs_reqs_total",
+			Help: "The total number of requests to the OTLP logs endpoint",
+		}),
+		recordsMetric: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_logs_records_total",
+			Help: "The total number of OTLP log records received, partitioned by result",
+		}, []string{"result"}),
+	}
+
+	server.Router.Group(func(r chi.Router) {
+		r.Use(IngestionAuthMiddleware(server.Logger, sts, aks, api_key.ScopeLogsWrite))
+		r.Post("/v1/otlp/logs", oc.handleExport)
+	})
+
+	return oc
+}
+
+func (oc *OTLPController) handleExport(w http.ResponseWriter, r *http.Request) {
+	// Increment metric
+	oc.counterMetric.Inc()
+
+	requestID := r.Context().Value(middleware.RequestIDKey).(string)
+
+	mediaType, err := otlp.MediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		terr := terrors.BadRequest(terrors.ErrBadRequest, "Unsupported content type", map[string]string{})
+		RenderError(r.Context(), w, terr)
+		return
+	}
+
+	body, err := readOTLPBody(w, r)
+	if err != nil {
+		oc.log.WithContext(
+			logrus.ErrorLevel,
+			"handleExport",
+			"Error while reading OTLP request body: %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+			},
+			err)
+		terr := terrors.BadRequest(terrors.ErrBadRequest, "Malformed body", map[string]string{})
+		RenderError(r.Context(), w, terr)
+		return
+	}
+
+	data, err := otlp.Decode(mediaType, body)
+	if err != nil {
+		oc.log.WithContext(
+			logrus.ErrorLevel,
+			"handleExport",
+			"Error while decoding OTLP request: %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+			},
+			err)
+		terr := terrors.BadRequest(terrors.ErrBadRequest, "Malformed body", map[string]string{})
+		RenderError(r.Context(), w, terr)
+		return
+	}
+
+	payloads, errs := otlp.ToPayloads(data)
+
+	origin := originFromRequest(r)
+	for _, payload := range payloads {
+		payload.Origin = origin
+	}
+
+	// Records sent with an api key always belong to the api key tenant
+	if principal, ok := api_key.PrincipalFromContext(r.Context()); ok {
+		for _, payload := range payloads {
+			payload.TenantCat = principal.TenantCat()
+		}
+	}
+
+	// The whole request is throttled when any producer in it is over its limit, OTLP exporters retry on 429
+	if decision := oc.allow(r, payloads); !decision.Allowed {
+		oc.log.WithContext(
+			logrus.WarnLevel,
+			"handleExport",
+			"Rate limit exceeded by "+decision.Dimension,
+			logger.Context{
+				tracekey.TrackingID: requestID,
+			},
+			nil)
+		RenderTooManyRequests(r.Context(), w, decision.RetryAfterSeconds())
+		return
+	}
+
+	var rejected int64
+	var errorMessage string
+	if len(errs) > 0 {
+		rejected = int64(len(errs))
+		errorMessage = fmt.Sprintf("failed to map log record: %v", errs[0])
+	}
+
+	for _, payload := range payloads {
+		if _, err := oc.logsSvc.Create(r.Context(), payload); err != nil {
+			rejected++
+			errorMessage = "failed to store log record"
+		}
+	}
+
+	oc.recordsMetric.WithLabelValues("accepted").Add(float64(int64(len(payloads)+len(errs)) - rejected))
+	oc.recordsMetric.WithLabelValues("rejected").Add(float64(rejected))
+
+	res, err := otlp.EncodeResponse(mediaType, rejected, errorMessage)
+	if err != nil {
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	w.Header().Set(middleware.RequestIDHeader, middleware.GetReqID(r.Context()))
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(res)
+}
+
+// allow checks the rate limit once per producer of the request
+func (oc *OTLPController) allow(r *http.Request, payloads []*logs.Payload) ratelimit.Decision {
+	counts := make(map[ratelimit.Key]int)
+	var keys []ratelimit.Key
+	for _, payload := range payloads {
+		key := ratelimit.ToKey(r.Context(), payload)
+		if _, ok := counts[key]; !ok {
+			keys = append(keys, key)
+		}
+		counts[key]++
+	}
+
+	for _, key := range keys {
+		if decision := oc.rateLimitSvc.Allow(ratelimit.SourceOTLP, key, counts[key]); !decision.Allowed {
+			return decision
+		}
+	}
+
+	return ratelimit.Decision{Allowed: true}
+}
+
+func readOTLPBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	var reader io.Reader = http.MaxBytesReader(w, r.Body, maxOTLPBodySize)
+
+	switch r.Header.Get("Content-Encoding") {
+	case "", "identity":
+	case "gzip":
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close() //nolint:errcheck
+
+		// Limit the decompressed size as well
+		reader = io.LimitReader(gz, maxOTLPBodySize+1)
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", r.Header.Get("Content-Encoding"))
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxOTLPBodySize {
+		return nil, fmt.Errorf("request body larger than %d bytes", maxOTLPBodySize)
+	}
+
+	return body, nil
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/omnilogger/internal/services/logs/logssvcmock"
+	"github.com/jmontesinos91/omnilogger/internal/services/ratelimit"
+	"github.com/jmontesinos91/omnilogger/internal/services/ratelimit/ratelimitsvcmock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+const otlpJSONBody = `{
+	"resourceLogs": [{
+		"resource": {"attributes": [{"key": "service.name", "value": {"stringValue": "billing"}}]},
+		"scopeLogs": [{"logRecords": [{"severityNumber": 9, "body": {"stringValue": "hello"}}]}]
+	}]
+}`
+
+func gzipString(t *testing.T, s string) string {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestOTLPController_HandleExport(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestOTLPController", "debug", logger.TextFormat)
+
+	tests := []struct {
+		name            string
+		contentType     string
+		contentEncoding string
+		body            string
+		mockSvc         *logssvcmock.IService
+		rateLimitSvc    *ratelimitsvcmock.IService
+		expectedCode    int
+		expectedBody    string
+		expectCreate    bool
+	}{
+		{
+			name:         "JSON_Success",
+			contentType:  "application/json",
+			body:         otlpJSONBody,
+			mockSvc:      &logssvcmock.IService{},
+			expectedCode: http.StatusOK,
+			expectedBody: `{}`,
+			expectCreate: true,
+		},
+		{
+			name:            "GzipJSON_Success",
+			contentType:     "application/json",
+			contentEncoding: "gzip",
+			body:            gzipString(t, otlpJSONBody),
+			mockSvc:         &logssvcmock.IService{},
+			expectedCode:    http.StatusOK,
+			expectedBody:    `{}`,
+			expectCreate:    true,
+		},
+		{
+			name:         "JSON_ServiceError_PartialSuccess",
+			contentType:  "application/json",
+			body:         otlpJSONBody,
+			mockSvc:      &logssvcmock.IService{CreateErr: errors.New("db down")},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"partialSuccess":{"errorMessage":"failed to store log record","rejectedLogRecords":"1"}}`,
+			expectCreate: true,
+		},
+		{
+			name:        "RateLimited",
+			contentType: "application/json",
+			body:        otlpJSONBody,
+			mockSvc:     &logssvcmock.IService{},
+			rateLimitSvc: &ratelimitsvcmock.IService{
+				AllowRes: &ratelimit.Decision{Allowed: false, RetryAfter: time.Second, Dimension: ratelimit.DimensionTenant},
+			},
+			expectedCode: http.StatusTooManyRequests,
+		},
+		{
+			name:         "UnsupportedContentType",
+			contentType:  "text/plain",
+			body:         "hello",
+			mockSvc:      &logssvcmock.IService{},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "MalformedBody",
+			contentType:  "application/x-protobuf",
+			body:         "\xff\xff",
+			mockSvc:      &logssvcmock.IService{},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:            "UnsupportedEncoding",
+			contentType:     "application/json",
+			contentEncoding: "br",
+			body:            otlpJSONBody,
+			mockSvc:         &logssvcmock.IService{},
+			expectedCode:    http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter := prometheus.NewCounter(prometheus.CounterOpts{
+				Name: "test_otlp_" + tt.name,
+				Help: "test counter",
+			})
+
+			if tt.rateLimitSvc == nil {
+				tt.rateLimitSvc = &ratelimitsvcmock.IService{}
+			}
+
+			oc := &OTLPController{
+				log:           ctxLogger,
+				logsSvc:       tt.mockSvc,
+				rateLimitSvc:  tt.rateLimitSvc,
+				counterMetric: counter,
+				recordsMetric: prometheus.NewCounterVec(prometheus.CounterOpts{
+					Name: "test_otlp_records_" + tt.name,
+					Help: "test counter",
+				}, []string{"result"}),
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/otlp/logs", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.contentEncoding != "" {
+				req.Header.Set("Content-Encoding", tt.contentEncoding)
+			}
+			req = req.WithContext(context.WithValue(req.Context(), middleware.RequestIDKey, "rid-otlp"))
+
+			rr := httptest.NewRecorder()
+			oc.handleExport(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Fatalf("expected status %d, got %d, body: %s", tt.expectedCode, rr.Code, rr.Body.String())
+			}
+			if tt.expectedBody != "" && strings.TrimSpace(rr.Body.String()) != tt.expectedBody {
+				t.Fatalf("expected body %s, got %s", tt.expectedBody, rr.Body.String())
+			}
+			if tt.mockSvc.CreateCalled != tt.expectCreate {
+				t.Fatalf("expected Create called %v, got %v", tt.expectCreate, tt.mockSvc.CreateCalled)
+			}
+			if got := testutil.ToFloat64(counter); got != 1 {
+				t.Fatalf("expected counter 1, got %v", got)
+			}
+		})
+	}
+}
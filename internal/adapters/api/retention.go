@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/omnilogger/internal/services/retention"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// RetentionController retention admin controller
+type RetentionController struct {
+	log           *logger.ContextLogger
+	retentionSvc  retention.IService
+	stsClient     sts.ISTSClient
+	counterMetric prometheus.Counter
+}
+
+// NewRetentionController Constructor
+func NewRetentionController(server *HTTPServer, rs retention.IService, sts sts.ISTSClient) *RetentionController {
+	rc := &RetentionController{
+		log:          server.Logger,
+		retentionSvc: rs,
+		stsClient:    sts,
+		counterMetric: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "retention_reqs_total",
+			Help: "The total number of requests to retention endpoints",
+		}),
+	}
+
+	server.Router.Group(func(r chi.Router) {
+		r.Use(JwtVerifyMiddleware(server.Logger, sts))
+		r.Get("/v1/admin/retention", rc.handleStatus)
+	})
+
+	return rc
+}
+
+func (rc *RetentionController) handleStatus(w http.ResponseWriter, r *http.Request) {
+	// Increment metric
+	rc.counterMetric.Inc()
+
+	RenderJSON(r.Context(), w, http.StatusOK, rc.retentionSvc.Status())
+}
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"github.com/jmontesinos91/omnilogger/config"
 	"net/http"
 	"strconv"
@@ -21,6 +23,7 @@ type HTTPServer struct {
 	sc        config.ServerConfigurations
 	Router    *chi.Mux
 	stsClient sts.ISTSClient
+	server    *http.Server
 }
 
 // NewHTTPServer Initializes a new http server
@@ -33,9 +36,10 @@ func NewHTTPServer(logger *logger.ContextLogger, serverConf config.ServerConfigu
 
 	// A good base middleware stack
 	router.Use(middleware.RequestID)
+	router.Use(CaptureOriginMiddleware)
 	router.Use(middleware.RealIP)
 	router.Use(middleware.Recoverer)
-	router.Use(middleware.AllowContentType("application/json"))
+	router.Use(middleware.AllowContentType("application/json", "application/x-protobuf"))
 
 	// Set a timeout value on the request models (ctx), that will signal
 	// through ctx.Done() that the request has timed out and further
@@ -49,16 +53,21 @@ func NewHTTPServer(logger *logger.ContextLogger, serverConf config.ServerConfigu
 		sc:        serverConf,
 		Router:    router,
 		stsClient: client,
+		server:    &http.Server{Addr: ":" + strconv.Itoa(serverConf.Port), Handler: router},
 	}
 }
 
 // Start Fires the http server
 func (r *HTTPServer) Start() {
-	listeningAddr := ":" + strconv.Itoa(r.sc.Port)
-	r.Logger.Log(logrus.InfoLevel, "Start", "Server listening on port "+listeningAddr+"")
+	r.Logger.Log(logrus.InfoLevel, "Start", "Server listening on port "+r.server.Addr+"")
 
-	err := http.ListenAndServe(listeningAddr, r.Router)
-	if err != nil {
+	err := r.server.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		r.Logger.Error(logrus.FatalLevel, "Start", "Failed to start http server. ", err)
 	}
 }
+
+// Shutdown stops accepting requests and waits for the ones in progress, it gives up once ctx is done
+func (r *HTTPServer) Shutdown(ctx context.Context) error {
+	return r.server.Shutdown(ctx)
+}
@@ -26,11 +26,14 @@ func RenderJSON(ctx context.Context, w http.ResponseWriter, httpStatusCode int,
 	_, _ = w.Write(js)
 }
 
-// RenderFile Render A helper function to render a File response
+// RenderFile Render A helper function to render a File response, the content type defaults to
+// application/octet-stream when the handler did not set one
 func RenderFile(ctx context.Context, w http.ResponseWriter, httpStatusCode int, payload []byte) {
 	// Headers
 	w.Header().Set(middleware.RequestIDHeader, middleware.GetReqID(ctx))
-	w.Header().Set("Content-Type", "application/octet-stream")
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/octet-stream")
+	}
 
 	w.WriteHeader(httpStatusCode)
 	_, _ = w.Write(payload)
@@ -74,3 +77,15 @@ func RenderError(ctx context.Context, w http.ResponseWriter, err error) {
 
 	RenderJSON(ctx, w, httpStatusCode, payload)
 }
+
+// RenderTooManyRequests Renders a rate limit rejection, clients should retry after the given seconds
+func RenderTooManyRequests(ctx context.Context, w http.ResponseWriter, retryAfter string) {
+	w.Header().Set("Retry-After", retryAfter)
+
+	payload := map[string]string{
+		"code":    "too_many_requests",
+		"message": "Rate limit exceeded",
+	}
+
+	RenderJSON(ctx, w, http.StatusTooManyRequests, payload)
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/uptrace/bun"
+)
+
+// appendOnlyTriggers triggers keeping the logs table append-only and the event trigger guarding its partitions
+// against drops, they must fire in every session replication role so they have to be 'A' (enabled always)
+var appendOnlyTriggers = []string{
+	"logs_append_only_trg",
+	"logs_append_only_truncate_trg",
+	"logs_drop_guard_trg",
+	"logs_legal_hold_trg",
+	"logs_legal_hold_truncate_trg",
+}
+
+const triggerEnabledAlways = "A"
+
+type logsTrigger struct {
+	Name    string `bun:"tgname"`
+	Enabled string `bun:"tgenabled"`
+}
+
+// CheckAppendOnly validates the logs table is protected against updates, deletes, truncates and partition drops
+func CheckAppendOnly(ctx context.Context, db bun.IDB) error {
+	var triggers []logsTrigger
+	err := db.NewSelect().
+		ColumnExpr("tgname, tgenabled::text AS tgenabled").
+		TableExpr("pg_trigger").
+		Where("tgrelid = to_regclass('public.logs')").
+		Where("NOT tgisinternal").
+		Scan(ctx, &triggers)
+	if err != nil {
+		return fmt.Errorf("failed to read the logs table triggers -> %v", err)
+	}
+
+	var eventTriggers []logsTrigger
+	err = db.NewSelect().
+		ColumnExpr("evtname AS tgname, evtenabled::text AS tgenabled").
+		TableExpr("pg_event_trigger").
+		Where("evtname = ?", "logs_drop_guard_trg").
+		Scan(ctx, &eventTriggers)
+	if err != nil {
+		return fmt.Errorf("failed to read the logs event triggers -> %v", err)
+	}
+	triggers = append(triggers, eventTriggers...)
+
+	if missing := missingTriggers(triggers); len(missing) > 0 {
+		return fmt.Errorf("logs table is not append-only, triggers missing or not enabled always: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// missingTriggers returns the append-only triggers not found or not enabled always
+func missingTriggers(triggers []logsTrigger) []string {
+	enabled := make(map[string]bool, len(triggers))
+	for _, trigger := range triggers {
+		enabled[trigger.Name] = trigger.Enabled == triggerEnabledAlways
+	}
+
+	var missing []string
+	for _, name := range appendOnlyTriggers {
+		if !enabled[name] {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+
+	return missing
+}
@@ -0,0 +1,48 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMissingTriggers(t *testing.T) {
+	cases := []struct {
+		name     string
+		triggers []logsTrigger
+		expected []string
+	}{
+		{
+			name: "Protected",
+			triggers: []logsTrigger{
+				{Name: "logs_append_only_trg", Enabled: "A"},
+				{Name: "logs_append_only_truncate_trg", Enabled: "A"},
+				{Name: "logs_drop_guard_trg", Enabled: "A"},
+				{Name: "logs_legal_hold_trg", Enabled: "A"},
+				{Name: "logs_legal_hold_truncate_trg", Enabled: "A"},
+				{Name: "other_trg", Enabled: "O"},
+			},
+		},
+		{
+			name: "Disabled or origin only",
+			triggers: []logsTrigger{
+				{Name: "logs_append_only_trg", Enabled: "D"},
+				{Name: "logs_append_only_truncate_trg", Enabled: "A"},
+				{Name: "logs_drop_guard_trg", Enabled: "A"},
+				{Name: "logs_legal_hold_trg", Enabled: "O"},
+				{Name: "logs_legal_hold_truncate_trg", Enabled: "A"},
+			},
+			expected: []string{"logs_append_only_trg", "logs_legal_hold_trg"},
+		},
+		{
+			name:     "Migration not applied",
+			expected: []string{"logs_append_only_trg", "logs_append_only_truncate_trg", "logs_drop_guard_trg", "logs_legal_hold_trg", "logs_legal_hold_truncate_trg"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, missingTriggers(tc.triggers))
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"fmt"
 	"github.com/jmontesinos91/omnilogger/config"
 
@@ -13,8 +14,9 @@ import (
 	"go.elastic.co/apm/module/apmsql/v2"
 )
 
-// NewDatabaseConnection Initializes a connection pool to the database
-func NewDatabaseConnection(logger *logger.ContextLogger, config config.DatabaseConfigurations) *bun.DB {
+// NewDatabaseConnection Initializes a connection pool to the database, in production it refuses to boot
+// when the logs table is not append-only
+func NewDatabaseConnection(logger *logger.ContextLogger, config config.DatabaseConfigurations, production bool) *bun.DB {
 
 	apmsql.Register("postgres", &stdlib.Driver{})
 	sqlDb, err := apmsql.Open("postgres", config.Dsn)
@@ -32,6 +34,14 @@ func NewDatabaseConnection(logger *logger.ContextLogger, config config.DatabaseC
 	db := bun.NewDB(sqlDb, pgdialect.New())
 	db.AddQueryHook(bundebug.NewQueryHook())
 
+	if err := CheckAppendOnly(context.Background(), db); err != nil {
+		if production {
+			logger.Error(logrus.FatalLevel, "DatabaseConnection", "Logs table protection check failed ->", err)
+		} else {
+			logger.Error(logrus.WarnLevel, "DatabaseConnection", "Logs table protection check failed ->", err)
+		}
+	}
+
 	logger.Log(logrus.InfoLevel, "Start", fmt.Sprintf("Database connected successfully. Connections opened: %d", db.Stats().OpenConnections))
 
 	return db
@@ -0,0 +1,134 @@
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jmontesinos91/ologs/logger"
+)
+
+// LocalStore stores the objects as files under a root directory
+type LocalStore struct {
+	log  *logger.ContextLogger
+	root string
+}
+
+// NewLocalStore creates a new instance of LocalStore, the root directory is created when missing
+func NewLocalStore(l *logger.ContextLogger, root string) (*LocalStore, error) {
+	if root == "" {
+		return nil, errors.New("objectstore: the local store path is required")
+	}
+
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("objectstore: failed to create %s -> %v", root, err)
+	}
+
+	return &LocalStore{log: l, root: root}, nil
+}
+
+// Put writes the object to a temporary file renamed once complete, readers never see partial objects
+func (s *LocalStore) Put(_ context.Context, key string, r io.Reader, _ int64, _ string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(name), 0o750); err != nil {
+		return fmt.Errorf("objectstore: failed to create the directory of %s -> %v", key, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("objectstore: failed to write %s -> %v", key, err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close() //nolint:errcheck
+		return fmt.Errorf("objectstore: failed to write %s -> %v", key, err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("objectstore: failed to write %s -> %v", key, err)
+	}
+
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return fmt.Errorf("objectstore: failed to write %s -> %v", key, err)
+	}
+
+	return nil
+}
+
+// Get opens the object
+func (s *LocalStore) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	name, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("objectstore: failed to read %s -> %v", key, err)
+	}
+
+	return file, nil
+}
+
+// Stat returns the object metadata
+func (s *LocalStore) Stat(_ context.Context, key string) (*ObjectInfo, error) {
+	name, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("objectstore: failed to read %s -> %v", key, err)
+	}
+
+	return &ObjectInfo{Key: key, Size: info.Size(), ModifiedAt: info.ModTime()}, nil
+}
+
+// Delete removes the object and the directories it leaves empty, deleting a missing object is not an error
+func (s *LocalStore) Delete(_ context.Context, key string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("objectstore: failed to delete %s -> %v", key, err)
+	}
+
+	// Removing a directory that is not empty fails, which stops at the first one still in use
+	for dir := filepath.Dir(name); dir != filepath.Clean(s.root); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+
+	return nil
+}
+
+// path file of the key under the root, keys escaping the root are rejected
+func (s *LocalStore) path(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if key == "" || cleaned == "/" || strings.Contains(key, "..") {
+		return "", fmt.Errorf("objectstore: invalid key %q", key)
+	}
+
+	return filepath.Join(s.root, filepath.FromSlash(cleaned)), nil
+}
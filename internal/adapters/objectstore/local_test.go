@@ -0,0 +1,50 @@
+package objectstore
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStore(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestLocalStore", "debug", logger.TextFormat)
+	store, err := NewLocalStore(ctxLogger, t.TempDir())
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	err = store.Put(ctx, "logs/tenant=1/2024/01/02/file.ndjson.gz", strings.NewReader("content"), 7, "application/gzip")
+	assert.NoError(t, err)
+
+	info, err := store.Stat(ctx, "logs/tenant=1/2024/01/02/file.ndjson.gz")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), info.Size)
+
+	reader, err := store.Get(ctx, "logs/tenant=1/2024/01/02/file.ndjson.gz")
+	assert.NoError(t, err)
+	content, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.NoError(t, reader.Close())
+	assert.Equal(t, "content", string(content))
+
+	_, err = store.Get(ctx, "logs/missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = store.Stat(ctx, "logs/missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	err = store.Delete(ctx, "logs/tenant=1/2024/01/02/file.ndjson.gz")
+	assert.NoError(t, err)
+
+	_, err = store.Stat(ctx, "logs/tenant=1/2024/01/02/file.ndjson.gz")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	err = store.Delete(ctx, "logs/missing")
+	assert.NoError(t, err)
+
+	err = store.Put(ctx, "../outside", strings.NewReader("content"), 7, "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,99 @@
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/omnilogger/config"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/sirupsen/logrus"
+)
+
+// S3Store stores the objects in a bucket of an S3-compatible endpoint
+type S3Store struct {
+	log    *logger.ContextLogger
+	client *minio.Client
+	bucket string
+}
+
+// NewS3Store creates a new instance of S3Store, the bucket is created when missing
+func NewS3Store(l *logger.ContextLogger, c config.ArchiveStoreConfigurations) (*S3Store, error) {
+	if c.Endpoint == "" || c.Bucket == "" {
+		return nil, errors.New("objectstore: the s3 store endpoint and bucket are required")
+	}
+
+	client, err := minio.New(c.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(c.AccessKey, c.SecretKey, ""),
+		Secure: c.UseSSL,
+		Region: c.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("objectstore: failed to create the s3 client -> %v", err)
+	}
+
+	ctx := context.Background()
+	exists, err := client.BucketExists(ctx, c.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("objectstore: failed to check the bucket %s -> %v", c.Bucket, err)
+	}
+
+	if !exists {
+		if err := client.MakeBucket(ctx, c.Bucket, minio.MakeBucketOptions{Region: c.Region}); err != nil {
+			return nil, fmt.Errorf("objectstore: failed to create the bucket %s -> %v", c.Bucket, err)
+		}
+		l.Log(logrus.InfoLevel, "NewS3Store", "Bucket "+c.Bucket+" created")
+	}
+
+	return &S3Store{log: l, client: client, bucket: c.Bucket}, nil
+}
+
+// Put uploads the object
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{ContentType: contentType})
+	if err != nil {
+		return fmt.Errorf("objectstore: failed to upload %s -> %v", key, err)
+	}
+
+	return nil
+}
+
+// Get downloads the object
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	// GetObject does not fail for missing objects until the first read, stat first to report them
+	if _, err := s.Stat(ctx, key); err != nil {
+		return nil, err
+	}
+
+	object, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("objectstore: failed to download %s -> %v", key, err)
+	}
+
+	return object, nil
+}
+
+// Stat returns the object metadata
+func (s *S3Store) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	info, err := s.client.StatObject(ctx, s.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("objectstore: failed to read %s -> %v", key, err)
+	}
+
+	return &ObjectInfo{Key: key, Size: info.Size, ModifiedAt: info.LastModified}, nil
+}
+
+// Delete removes the object, deleting a missing object is not an error
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	if err := s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("objectstore: failed to delete %s -> %v", key, err)
+	}
+
+	return nil
+}
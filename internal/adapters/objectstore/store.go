@@ -0,0 +1,48 @@
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/omnilogger/config"
+)
+
+// Store types
+const (
+	TypeLocal = "local"
+	TypeS3    = "s3"
+)
+
+// ErrNotFound the object does not exist in the store
+var ErrNotFound = errors.New("objectstore: object not found")
+
+// ObjectInfo stored object metadata
+type ObjectInfo struct {
+	Key        string
+	Size       int64
+	ModifiedAt time.Time
+}
+
+// IStore object store holding the archive and export files, keys are slash separated paths
+type IStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// NewStore creates the store of the configured type
+func NewStore(l *logger.ContextLogger, c config.ArchiveStoreConfigurations) (IStore, error) {
+	switch c.Type {
+	case "", TypeLocal:
+		return NewLocalStore(l, c.Path)
+	case TypeS3:
+		return NewS3Store(l, c)
+	default:
+		return nil, fmt.Errorf("invalid store type %q, use %s or %s", c.Type, TypeLocal, TypeS3)
+	}
+}
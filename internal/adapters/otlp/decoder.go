@@ -0,0 +1,135 @@
+package otlp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"mime"
+	"strconv"
+
+	logsv1 "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// Supported content types as described by the OTLP/HTTP specification
+const (
+	ContentTypeProtobuf = "application/x-protobuf"
+	ContentTypeJSON     = "application/json"
+)
+
+// ErrUnsupportedContentType the request is neither protobuf nor json
+var ErrUnsupportedContentType = errors.New("otlp: unsupported content type")
+
+// MediaType returns the OTLP media type of a Content-Type header
+func MediaType(contentType string) (string, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", ErrUnsupportedContentType
+	}
+
+	if mediaType != ContentTypeProtobuf && mediaType != ContentTypeJSON {
+		return "", ErrUnsupportedContentType
+	}
+
+	return mediaType, nil
+}
+
+// Decode decodes an export request body, LogsData shares the wire format of ExportLogsServiceRequest
+func Decode(mediaType string, body []byte) (*logsv1.LogsData, error) {
+	data := &logsv1.LogsData{}
+
+	switch mediaType {
+	case ContentTypeProtobuf:
+		if err := proto.Unmarshal(body, data); err != nil {
+			return nil, err
+		}
+	case ContentTypeJSON:
+		body, err := hexIDsToBase64(body)
+		if err != nil {
+			return nil, err
+		}
+		if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(body, data); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrUnsupportedContentType
+	}
+
+	return data, nil
+}
+
+// EncodeResponse encodes an ExportLogsServiceResponse, partial success is only set when records were rejected
+func EncodeResponse(mediaType string, rejected int64, errorMessage string) ([]byte, error) {
+	if mediaType == ContentTypeJSON {
+		res := map[string]interface{}{}
+		if rejected > 0 {
+			res["partialSuccess"] = map[string]interface{}{
+				// int64 values are encoded as strings in OTLP/JSON
+				"rejectedLogRecords": strconv.FormatInt(rejected, 10),
+				"errorMessage":       errorMessage,
+			}
+		}
+		return json.Marshal(res)
+	}
+
+	if rejected == 0 {
+		return []byte{}, nil
+	}
+
+	// ExportLogsPartialSuccess { int64 rejected_log_records = 1; string error_message = 2; }
+	var partial []byte
+	partial = protowire.AppendTag(partial, 1, protowire.VarintType)
+	partial = protowire.AppendVarint(partial, uint64(rejected))
+	partial = protowire.AppendTag(partial, 2, protowire.BytesType)
+	partial = protowire.AppendString(partial, errorMessage)
+
+	// ExportLogsServiceResponse { ExportLogsPartialSuccess partial_success = 1; }
+	var res []byte
+	res = protowire.AppendTag(res, 1, protowire.BytesType)
+	res = protowire.AppendBytes(res, partial)
+
+	return res, nil
+}
+
+// hexIDsToBase64 OTLP/JSON encodes trace and span ids as hex strings instead of the
+// base64 expected by protojson, ids are re-encoded before unmarshalling
+func hexIDsToBase64(body []byte) ([]byte, error) {
+	// Numbers are kept as they are, fixed64 timestamps do not fit in a float64
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var doc map[string]interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	resourceLogs, _ := doc["resourceLogs"].([]interface{})
+	for _, rl := range resourceLogs {
+		rlMap, _ := rl.(map[string]interface{})
+		scopeLogs, _ := rlMap["scopeLogs"].([]interface{})
+		for _, sl := range scopeLogs {
+			slMap, _ := sl.(map[string]interface{})
+			records, _ := slMap["logRecords"].([]interface{})
+			for _, record := range records {
+				recordMap, _ := record.(map[string]interface{})
+				for _, key := range []string{"traceId", "spanId"} {
+					value, ok := recordMap[key].(string)
+					if !ok || value == "" {
+						continue
+					}
+					raw, err := hex.DecodeString(value)
+					if err != nil {
+						return nil, err
+					}
+					recordMap[key] = base64.StdEncoding.EncodeToString(raw)
+				}
+			}
+		}
+	}
+
+	return json.Marshal(doc)
+}
@@ -0,0 +1,134 @@
+package otlp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	commonv1 "go.opentelemetry.io/proto/otlp/common/v1"
+	logsv1 "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestMediaType(t *testing.T) {
+	cases := []struct {
+		contentType string
+		expected    string
+		err         bool
+	}{
+		{contentType: "application/x-protobuf", expected: ContentTypeProtobuf},
+		{contentType: "application/json; charset=utf-8", expected: ContentTypeJSON},
+		{contentType: "text/plain", err: true},
+		{contentType: "", err: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.contentType, func(t *testing.T) {
+			mediaType, err := MediaType(tc.contentType)
+			if tc.err {
+				assert.ErrorIs(t, err, ErrUnsupportedContentType)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, mediaType)
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	expected := &logsv1.LogsData{
+		ResourceLogs: []*logsv1.ResourceLogs{
+			{
+				ScopeLogs: []*logsv1.ScopeLogs{
+					{
+						LogRecords: []*logsv1.LogRecord{
+							{
+								TimeUnixNano:   1700000000000000001,
+								SeverityNumber: logsv1.SeverityNumber_SEVERITY_NUMBER_INFO,
+								Body:           &commonv1.AnyValue{Value: &commonv1.AnyValue_StringValue{StringValue: "hello"}},
+								TraceId:        []byte{0x5b, 0x8e, 0xff, 0xf7, 0x98, 0x03, 0x81, 0x03, 0xd2, 0x69, 0xb6, 0x33, 0x81, 0x3f, 0xc6, 0x0c},
+								SpanId:         []byte{0xee, 0xe1, 0x9b, 0x7e, 0xc3, 0xc1, 0xb1, 0x74},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	protoBody, err := proto.Marshal(expected)
+	assert.NoError(t, err)
+
+	jsonBody := []byte(`{
+		"resourceLogs": [{
+			"scopeLogs": [{
+				"logRecords": [{
+					"timeUnixNano": "1700000000000000001",
+					"severityNumber": 9,
+					"body": {"stringValue": "hello"},
+					"traceId": "5b8efff798038103d269b633813fc60c",
+					"spanId": "eee19b7ec3c1b174"
+				}]
+			}]
+		}]
+	}`)
+
+	cases := []struct {
+		name      string
+		mediaType string
+		body      []byte
+		err       bool
+	}{
+		{name: "Protobuf", mediaType: ContentTypeProtobuf, body: protoBody},
+		{name: "JSON with hex ids", mediaType: ContentTypeJSON, body: jsonBody},
+		{name: "Malformed JSON", mediaType: ContentTypeJSON, body: []byte("{"), err: true},
+		{name: "Invalid hex trace id", mediaType: ContentTypeJSON, body: []byte(`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"traceId":"zz"}]}]}]}`), err: true},
+		{name: "Malformed protobuf", mediaType: ContentTypeProtobuf, body: []byte{0xff, 0xff}, err: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := Decode(tc.mediaType, tc.body)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, proto.Equal(expected, data), "decoded %v", data)
+		})
+	}
+}
+
+func TestEncodeResponse(t *testing.T) {
+	res, err := EncodeResponse(ContentTypeJSON, 0, "")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{}`, string(res))
+
+	res, err = EncodeResponse(ContentTypeJSON, 2, "bad record")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"partialSuccess":{"rejectedLogRecords":"2","errorMessage":"bad record"}}`, string(res))
+
+	res, err = EncodeResponse(ContentTypeProtobuf, 0, "")
+	assert.NoError(t, err)
+	assert.Empty(t, res)
+
+	res, err = EncodeResponse(ContentTypeProtobuf, 3, "bad")
+	assert.NoError(t, err)
+
+	// partial_success = 1 (bytes) -> rejected_log_records = 1 (varint), error_message = 2 (bytes)
+	num, typ, n := protowire.ConsumeTag(res)
+	assert.Equal(t, protowire.Number(1), num)
+	assert.Equal(t, protowire.BytesType, typ)
+	partial, _ := protowire.ConsumeBytes(res[n:])
+
+	num, _, n = protowire.ConsumeTag(partial)
+	assert.Equal(t, protowire.Number(1), num)
+	rejected, m := protowire.ConsumeVarint(partial[n:])
+	assert.Equal(t, uint64(3), rejected)
+
+	partial = partial[n+m:]
+	num, _, n = protowire.ConsumeTag(partial)
+	assert.Equal(t, protowire.Number(2), num)
+	message, _ := protowire.ConsumeString(partial[n:])
+	assert.Equal(t, "bad", message)
+}
@@ -0,0 +1,264 @@
+package otlp
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/jmontesinos91/omnilogger/domains/level"
+	"github.com/jmontesinos91/omnilogger/internal/services/logs"
+	"github.com/jmontesinos91/omnilogger/internal/utils/text"
+	commonv1 "go.opentelemetry.io/proto/otlp/common/v1"
+	logsv1 "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+// Attribute keys read from resource and log record attributes, record attributes take precedence
+const (
+	AttrServiceName = "service.name"
+	AttrHostName    = "host.name"
+	AttrClientAddr  = "client.address"
+	AttrURLPath     = "url.path"
+	AttrTenantID    = "tenant.id"
+	AttrTenantName  = "tenant.name"
+	AttrUserID      = "user.id"
+	AttrEnduserID   = "enduser.id"
+	AttrAction      = "omnilogger.action"
+	AttrResource    = "omnilogger.resource"
+	AttrMessage     = "omnilogger.message"
+	AttrTarget      = "omnilogger.target"
+	AttrOldData     = "omnilogger.old_data"
+)
+
+// Column sizes of public.logs, values longer than these are truncated
+const (
+	maxIPAddressLength   = 20
+	maxClientHostLength  = 100
+	maxProviderLength    = 20
+	maxDescriptionLength = 255
+	maxPathLength        = 255
+	maxResourceLength    = 50
+	maxActionLength      = 50
+)
+
+const (
+	// DefaultResource resource stored when the record does not set omnilogger.resource
+	DefaultResource = "OTLP"
+	// DefaultAction action stored when the record has neither omnilogger.action nor an event name
+	DefaultAction = "LOG"
+)
+
+// Envelope is the json document stored in the data column for OTLP entries
+type Envelope struct {
+	Body               interface{}            `json:"body,omitempty"`
+	Attributes         map[string]interface{} `json:"attributes,omitempty"`
+	ResourceAttributes map[string]interface{} `json:"resource_attributes,omitempty"`
+	Scope              string                 `json:"scope,omitempty"`
+	SeverityText       string                 `json:"severity_text,omitempty"`
+	SeverityNumber     int32                  `json:"severity_number,omitempty"`
+	Time               *time.Time             `json:"time,omitempty"`
+	TraceID            string                 `json:"trace_id,omitempty"`
+	SpanID             string                 `json:"span_id,omitempty"`
+}
+
+// ToPayloads maps every log record of an export request into a logs payload,
+// records that cannot be mapped are skipped and their errors returned
+func ToPayloads(data *logsv1.LogsData) ([]*logs.Payload, []error) {
+	var payloads []*logs.Payload
+	var errs []error
+
+	for _, rl := range data.GetResourceLogs() {
+		resourceAttrs := ToMap(rl.GetResource().GetAttributes())
+
+		for _, sl := range rl.GetScopeLogs() {
+			for _, record := range sl.GetLogRecords() {
+				payload, err := ToPayload(resourceAttrs, sl.GetScope().GetName(), record)
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				payloads = append(payloads, payload)
+			}
+		}
+	}
+
+	return payloads, errs
+}
+
+// ToPayload maps a single log record into a logs payload
+func ToPayload(resourceAttrs map[string]interface{}, scope string, record *logsv1.LogRecord) (*logs.Payload, error) {
+	attrs := ToMap(record.GetAttributes())
+	lookup := func(keys ...string) string {
+		for _, key := range keys {
+			if v, ok := attrs[key]; ok {
+				return stringify(v)
+			}
+		}
+		for _, key := range keys {
+			if v, ok := resourceAttrs[key]; ok {
+				return stringify(v)
+			}
+		}
+		return ""
+	}
+
+	body := anyValue(record.GetBody())
+
+	envelope := Envelope{
+		Body:               body,
+		Attributes:         attrs,
+		ResourceAttributes: resourceAttrs,
+		Scope:              scope,
+		SeverityText:       record.GetSeverityText(),
+		SeverityNumber:     int32(record.GetSeverityNumber()),
+		Time:               recordTime(record),
+	}
+	if len(record.GetTraceId()) > 0 {
+		envelope.TraceID = hex.EncodeToString(record.GetTraceId())
+	}
+	if len(record.GetSpanId()) > 0 {
+		envelope.SpanID = hex.EncodeToString(record.GetSpanId())
+	}
+
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	tenantCat, err := toTenantCat(lookup(AttrTenantID), lookup(AttrTenantName))
+	if err != nil {
+		return nil, err
+	}
+
+	action := lookup(AttrAction)
+	if action == "" {
+		action = record.GetEventName()
+	}
+	if action == "" {
+		action = DefaultAction
+	}
+
+	resource := lookup(AttrResource)
+	if resource == "" {
+		resource = DefaultResource
+	}
+
+	// Catalog message id is optional, non numeric values are ignored
+	message, _ := strconv.Atoi(lookup(AttrMessage))
+
+	oldData := lookup(AttrOldData)
+	if oldData == "" || !json.Valid([]byte(oldData)) {
+		oldData = "{}"
+	}
+
+	return &logs.Payload{
+		IpAddress:   text.Truncate(lookup(AttrClientAddr), maxIPAddressLength),
+		ClientHost:  text.Truncate(lookup(AttrHostName), maxClientHostLength),
+		Provider:    text.Truncate(lookup(AttrServiceName), maxProviderLength),
+		Level:       level.FromOTLPSeverity(int32(record.GetSeverityNumber())),
+		Message:     message,
+		Description: text.Truncate(stringify(body), maxDescriptionLength),
+		Path:        text.Truncate(lookup(AttrURLPath), maxPathLength),
+		Resource:    text.Truncate(resource, maxResourceLength),
+		Action:      text.Truncate(action, maxActionLength),
+		Data:        string(data),
+		OldData:     oldData,
+		TenantCat:   tenantCat,
+		UserID:      lookup(AttrUserID, AttrEnduserID),
+		Target:      lookup(AttrTarget),
+		OccurredAt:  envelope.Time,
+	}, nil
+}
+
+// ToMap converts OTLP key values into a plain map
+func ToMap(kvs []*commonv1.KeyValue) map[string]interface{} {
+	if len(kvs) == 0 {
+		return nil
+	}
+
+	res := make(map[string]interface{}, len(kvs))
+	for _, kv := range kvs {
+		res[kv.GetKey()] = anyValue(kv.GetValue())
+	}
+
+	return res
+}
+
+func anyValue(v *commonv1.AnyValue) interface{} {
+	if v == nil {
+		return nil
+	}
+
+	switch value := v.GetValue().(type) {
+	case *commonv1.AnyValue_StringValue:
+		return value.StringValue
+	case *commonv1.AnyValue_BoolValue:
+		return value.BoolValue
+	case *commonv1.AnyValue_IntValue:
+		return value.IntValue
+	case *commonv1.AnyValue_DoubleValue:
+		return value.DoubleValue
+	case *commonv1.AnyValue_BytesValue:
+		return base64.StdEncoding.EncodeToString(value.BytesValue)
+	case *commonv1.AnyValue_ArrayValue:
+		items := make([]interface{}, 0, len(value.ArrayValue.GetValues()))
+		for _, item := range value.ArrayValue.GetValues() {
+			items = append(items, anyValue(item))
+		}
+		return items
+	case *commonv1.AnyValue_KvlistValue:
+		return ToMap(value.KvlistValue.GetValues())
+	default:
+		return nil
+	}
+}
+
+func stringify(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case int64:
+		return strconv.FormatInt(value, 10)
+	default:
+		b, err := json.Marshal(value)
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	}
+}
+
+func recordTime(record *logsv1.LogRecord) *time.Time {
+	nanos := record.GetTimeUnixNano()
+	if nanos == 0 {
+		nanos = record.GetObservedTimeUnixNano()
+	}
+	if nanos == 0 {
+		return nil
+	}
+
+	t := time.Unix(0, int64(nanos)).UTC()
+	return &t
+}
+
+// toTenantCat builds the tenant catalog json expected by logs.Payload
+func toTenantCat(tenantID string, tenantName string) (string, error) {
+	if tenantID == "" {
+		return "", nil
+	}
+
+	id, err := strconv.Atoi(tenantID)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := json.Marshal([]logs.Item{{ID: id, Name: tenantName}})
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
@@ -0,0 +1,113 @@
+package otlp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jmontesinos91/omnilogger/domains/level"
+	"github.com/stretchr/testify/assert"
+	commonv1 "go.opentelemetry.io/proto/otlp/common/v1"
+	logsv1 "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcev1 "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+func strAttr(key, value string) *commonv1.KeyValue {
+	return &commonv1.KeyValue{Key: key, Value: &commonv1.AnyValue{Value: &commonv1.AnyValue_StringValue{StringValue: value}}}
+}
+
+func intAttr(key string, value int64) *commonv1.KeyValue {
+	return &commonv1.KeyValue{Key: key, Value: &commonv1.AnyValue{Value: &commonv1.AnyValue_IntValue{IntValue: value}}}
+}
+
+func TestToPayloads(t *testing.T) {
+	data := &logsv1.LogsData{
+		ResourceLogs: []*logsv1.ResourceLogs{
+			{
+				Resource: &resourcev1.Resource{
+					Attributes: []*commonv1.KeyValue{
+						strAttr(AttrServiceName, "billing"),
+						strAttr(AttrHostName, "billing-7f9c"),
+						intAttr(AttrTenantID, 7),
+						strAttr(AttrTenantName, "Acme"),
+						strAttr(AttrUserID, "service-account"),
+					},
+				},
+				ScopeLogs: []*logsv1.ScopeLogs{
+					{
+						Scope: &commonv1.InstrumentationScope{Name: "audit"},
+						LogRecords: []*logsv1.LogRecord{
+							{
+								TimeUnixNano:   1700000000000000000,
+								SeverityNumber: logsv1.SeverityNumber_SEVERITY_NUMBER_WARN,
+								Body:           &commonv1.AnyValue{Value: &commonv1.AnyValue_StringValue{StringValue: "invoice updated"}},
+								Attributes: []*commonv1.KeyValue{
+									strAttr(AttrAction, "UPDATE"),
+									strAttr(AttrResource, "INVOICE"),
+									intAttr(AttrMessage, 1005),
+									strAttr(AttrUserID, "42"),
+									strAttr(AttrClientAddr, "10.1.2.3"),
+								},
+								TraceId: []byte{0x5b, 0x8e, 0xff, 0xf7, 0x98, 0x03, 0x81, 0x03, 0xd2, 0x69, 0xb6, 0x33, 0x81, 0x3f, 0xc6, 0x0c},
+							},
+							{
+								Attributes: []*commonv1.KeyValue{strAttr(AttrTenantID, "not-a-number")},
+							},
+							{
+								SeverityNumber: logsv1.SeverityNumber_SEVERITY_NUMBER_FATAL,
+								EventName:      "crash",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	payloads, errs := ToPayloads(data)
+
+	assert.Len(t, errs, 1)
+	if !assert.Len(t, payloads, 2) {
+		return
+	}
+
+	first := payloads[0]
+	assert.Equal(t, "billing", first.Provider)
+	assert.Equal(t, "billing-7f9c", first.ClientHost)
+	assert.Equal(t, "10.1.2.3", first.IpAddress)
+	assert.Equal(t, level.Warning, first.Level)
+	assert.Equal(t, 1005, first.Message)
+	assert.Equal(t, "invoice updated", first.Description)
+	assert.Equal(t, "UPDATE", first.Action)
+	assert.Equal(t, "INVOICE", first.Resource)
+	assert.Equal(t, "42", first.UserID)
+	assert.Equal(t, `[{"id":7,"name":"Acme"}]`, first.TenantCat)
+	assert.Equal(t, "{}", first.OldData)
+	assert.Equal(t, time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC), *first.OccurredAt)
+	assert.JSONEq(t, `{
+		"body": "invoice updated",
+		"attributes": {
+			"omnilogger.action": "UPDATE",
+			"omnilogger.resource": "INVOICE",
+			"omnilogger.message": 1005,
+			"user.id": "42",
+			"client.address": "10.1.2.3"
+		},
+		"resource_attributes": {
+			"service.name": "billing",
+			"host.name": "billing-7f9c",
+			"tenant.id": 7,
+			"tenant.name": "Acme",
+			"user.id": "service-account"
+		},
+		"scope": "audit",
+		"severity_number": 13,
+		"time": "2023-11-14T22:13:20Z",
+		"trace_id": "5b8efff798038103d269b633813fc60c"
+	}`, first.Data)
+
+	second := payloads[1]
+	assert.Equal(t, level.Critical, second.Level)
+	assert.Equal(t, "crash", second.Action)
+	assert.Equal(t, DefaultResource, second.Resource)
+	assert.Equal(t, "service-account", second.UserID)
+}
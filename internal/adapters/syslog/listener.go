@@ -0,0 +1,289 @@
+package syslog
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/jmontesinos91/ologs/logger"
+	tracekey "github.com/jmontesinos91/ologs/logger/v2"
+	"github.com/jmontesinos91/omnilogger/config"
+	"github.com/jmontesinos91/omnilogger/internal/services/logs"
+	"github.com/sirupsen/logrus"
+)
+
+// Supported networks
+const (
+	NetworkUDP = "udp"
+	NetworkTCP = "tcp"
+	NetworkTLS = "tls"
+)
+
+// defaultMaxMessageSize RFC 5424 receivers must accept at least 2048 octets, most senders go up to 8192
+const defaultMaxMessageSize = 8192
+
+// ErrMessageTooLarge the received frame is bigger than the configured max message size
+var ErrMessageTooLarge = errors.New("syslog: message too large")
+
+// Listener receives syslog messages over UDP, TCP and TLS and stores them as logs
+type Listener struct {
+	log     *logger.ContextLogger
+	config  config.SyslogConfigurations
+	logsSvc logs.IService
+	serving sync.WaitGroup
+}
+
+// NewListener generates a Listener instance
+func NewListener(l *logger.ContextLogger, c config.SyslogConfigurations, ls logs.IService) *Listener {
+	if c.MaxMessageSize <= 0 {
+		c.MaxMessageSize = defaultMaxMessageSize
+	}
+
+	return &Listener{
+		log:     l,
+		config:  c,
+		logsSvc: ls,
+	}
+}
+
+// Start opens every configured listener in background, it stops listening and closes the open connections once ctx
+// is done
+func (s *Listener) Start(ctx context.Context) {
+	if !s.config.Enabled {
+		s.log.Log(logrus.WarnLevel, "Start", "Syslog listener not enabled, ignoring request to start listener")
+		return
+	}
+
+	if s.config.UDPAddress != "" {
+		conn, err := net.ListenPacket(NetworkUDP, s.config.UDPAddress)
+		if err != nil {
+			s.log.Error(logrus.FatalLevel, "Start", "Failed to start syslog udp listener. ", err)
+		}
+		go closeOnDone(ctx, conn)
+		s.serving.Add(1)
+		go s.serveUDP(ctx, conn)
+		s.log.Log(logrus.InfoLevel, "Start", "Syslog listening on udp "+s.config.UDPAddress)
+	}
+
+	if s.config.TCPAddress != "" {
+		ln, err := net.Listen(NetworkTCP, s.config.TCPAddress)
+		if err != nil {
+			s.log.Error(logrus.FatalLevel, "Start", "Failed to start syslog tcp listener. ", err)
+		}
+		go closeOnDone(ctx, ln)
+		s.serving.Add(1)
+		go s.serveStream(ctx, NetworkTCP, ln)
+		s.log.Log(logrus.InfoLevel, "Start", "Syslog listening on tcp "+s.config.TCPAddress)
+	}
+
+	if s.config.TLSAddress != "" {
+		cert, err := tls.LoadX509KeyPair(s.config.CertFile, s.config.KeyFile)
+		if err != nil {
+			s.log.Error(logrus.FatalLevel, "Start", "Failed to load syslog tls certificate. ", err)
+		}
+		ln, err := tls.Listen(NetworkTCP, s.config.TLSAddress, &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		})
+		if err != nil {
+			s.log.Error(logrus.FatalLevel, "Start", "Failed to start syslog tls listener. ", err)
+		}
+		go closeOnDone(ctx, ln)
+		s.serving.Add(1)
+		go s.serveStream(ctx, NetworkTLS, ln)
+		s.log.Log(logrus.InfoLevel, "Start", "Syslog listening on tls "+s.config.TLSAddress)
+	}
+}
+
+// Wait waits for the listeners and their connections to close once the ctx given to Start is done, the messages
+// already received are stored first. It gives up once ctx is done
+func (s *Listener) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.serving.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Listener) serveUDP(ctx context.Context, conn net.PacketConn) {
+	defer s.serving.Done()
+
+	buf := make([]byte, s.config.MaxMessageSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			s.log.Error(logrus.ErrorLevel, "serveUDP", "Error reading syslog datagram", err)
+			continue
+		}
+
+		s.handle(ctx, NetworkUDP, buf[:n], addr)
+	}
+}
+
+func (s *Listener) serveStream(ctx context.Context, network string, ln net.Listener) {
+	defer s.serving.Done()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			s.log.Error(logrus.ErrorLevel, "serveStream", "Error accepting syslog connection", err)
+			continue
+		}
+
+		s.serving.Add(1)
+		go s.serveConn(ctx, network, conn)
+	}
+}
+
+func (s *Listener) serveConn(ctx context.Context, network string, conn net.Conn) {
+	defer s.serving.Done()
+	defer conn.Close() //nolint:errcheck
+
+	// Closing the connection unblocks the read in progress
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	reader := bufio.NewReaderSize(conn, s.config.MaxMessageSize)
+	for {
+		frame, err := ReadFrame(reader, s.config.MaxMessageSize)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+				s.log.Error(logrus.ErrorLevel, "serveConn", "Error reading syslog frame, closing connection", err)
+			}
+			return
+		}
+		if len(frame) == 0 {
+			continue
+		}
+
+		s.handle(ctx, network, frame, conn.RemoteAddr())
+	}
+}
+
+// ReadFrame reads one message from a stream, it supports both octet counting and
+// non-transparent (LF delimited) framing as described in RFC 6587
+func ReadFrame(reader *bufio.Reader, maxSize int) ([]byte, error) {
+	first, err := reader.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+
+	// Octet counting: MSG-LEN SP SYSLOG-MSG
+	if first[0] >= '1' && first[0] <= '9' {
+		length, err := reader.ReadString(' ')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSuffix(length, " "))
+		if err != nil {
+			return nil, fmt.Errorf("syslog: invalid frame length: %w", err)
+		}
+		if size > maxSize {
+			return nil, ErrMessageTooLarge
+		}
+
+		frame := make([]byte, size)
+		if _, err := io.ReadFull(reader, frame); err != nil {
+			return nil, err
+		}
+		return frame, nil
+	}
+
+	// Non-transparent framing: SYSLOG-MSG LF
+	frame, err := reader.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		return nil, ErrMessageTooLarge
+	}
+	if err != nil && !(errors.Is(err, io.EOF) && len(frame) > 0) {
+		return nil, err
+	}
+
+	return []byte(strings.TrimRight(string(frame), "\r\n")), nil
+}
+
+func (s *Listener) handle(ctx context.Context, network string, raw []byte, addr net.Addr) {
+	requestID := uuid.NewString()
+
+	msg, err := Parse(raw)
+	if err != nil {
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			"handle",
+			"Error parsing syslog message",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+			},
+			err)
+		return
+	}
+
+	payload, err := ToPayload(msg, network, remoteIP(addr), s.config.TenantCat)
+	if err != nil {
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			"handle",
+			"Error mapping syslog message to payload",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+			},
+			err)
+		return
+	}
+
+	// The peer address is the only origin the server can observe for syslog messages
+	payload.Origin = &logs.Origin{IP: remoteIP(addr), Subject: "syslog:" + network}
+
+	// The service expects a request ID as any other HTTP request. A message received before the listener stopped is
+	// still stored
+	ctx = context.WithValue(context.WithoutCancel(ctx), middleware.RequestIDKey, requestID)
+	if _, err := s.logsSvc.Create(ctx, payload); err != nil {
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			"handle",
+			"Error storing syslog message",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+			},
+			err)
+	}
+}
+
+func remoteIP(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+
+	return host
+}
+
+func closeOnDone(ctx context.Context, c io.Closer) {
+	<-ctx.Done()
+	_ = c.Close()
+}
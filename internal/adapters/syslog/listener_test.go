@@ -0,0 +1,122 @@
+package syslog
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/omnilogger/config"
+	"github.com/jmontesinos91/omnilogger/internal/services/logs/logssvcmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadFrame(t *testing.T) {
+	cases := []struct {
+		name     string
+		stream   string
+		maxSize  int
+		expected []string
+		err      error
+	}{
+		{
+			name:     "Octet counting framing",
+			stream:   "11 <14>1 - - -5 hello",
+			maxSize:  100,
+			expected: []string{"<14>1 - - -", "hello"},
+			err:      io.EOF,
+		},
+		{
+			name:     "Non transparent framing",
+			stream:   "<14>first\r\n<14>second\n<14>third",
+			maxSize:  100,
+			expected: []string{"<14>first", "<14>second", "<14>third"},
+			err:      io.EOF,
+		},
+		{
+			name:    "Octet counting frame too large",
+			stream:  "500 <14>1 - - -",
+			maxSize: 100,
+			err:     ErrMessageTooLarge,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reader := bufio.NewReaderSize(strings.NewReader(tc.stream), 16)
+
+			var frames []string
+			var err error
+			for {
+				var frame []byte
+				frame, err = ReadFrame(reader, tc.maxSize)
+				if err != nil {
+					break
+				}
+				frames = append(frames, string(frame))
+			}
+
+			assert.True(t, errors.Is(err, tc.err), "unexpected error %v", err)
+			assert.Equal(t, tc.expected, frames)
+		})
+	}
+}
+
+func TestListener_Handle(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestListener", "debug", logger.TextFormat)
+	addr := &net.UDPAddr{IP: net.ParseIP("10.0.0.7"), Port: 514}
+
+	cases := []struct {
+		name          string
+		raw           string
+		svc           *logssvcmock.IService
+		expectCreated bool
+	}{
+		{
+			name:          "Stores valid message",
+			raw:           `<11>1 2003-10-11T22:14:15.003Z router01 firewall - DROP [meta@1 rule="42"] dropped packet`,
+			svc:           &logssvcmock.IService{},
+			expectCreated: true,
+		},
+		{
+			name:          "Ignores unparseable message",
+			raw:           "not a syslog message",
+			svc:           &logssvcmock.IService{},
+			expectCreated: false,
+		},
+		{
+			name:          "Service error does not panic",
+			raw:           "<14>app: hello",
+			svc:           &logssvcmock.IService{CreateErr: errors.New("db down")},
+			expectCreated: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			listener := NewListener(ctxLogger, config.SyslogConfigurations{TenantCat: `[{"id":1,"name":"Tenant"}]`}, tc.svc)
+			listener.handle(context.Background(), NetworkUDP, []byte(tc.raw), addr)
+
+			assert.Equal(t, tc.expectCreated, tc.svc.CreateCalled)
+		})
+	}
+}
+
+func TestListener_Wait(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestListener", "debug", logger.TextFormat)
+	listener := NewListener(ctxLogger, config.SyslogConfigurations{Enabled: true, UDPAddress: "127.0.0.1:0", TCPAddress: "127.0.0.1:0"}, &logssvcmock.IService{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	listener.Start(ctx)
+
+	// The listeners close once ctx is done
+	cancel()
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), time.Second)
+	defer waitCancel()
+	assert.NoError(t, listener.Wait(waitCtx))
+}
@@ -0,0 +1,69 @@
+package syslog
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/jmontesinos91/omnilogger/domains/level"
+	"github.com/jmontesinos91/omnilogger/internal/services/logs"
+	"github.com/jmontesinos91/omnilogger/internal/utils/text"
+)
+
+// Column sizes of public.logs, values longer than these are truncated
+const (
+	maxClientHostLength  = 100
+	maxProviderLength    = 20
+	maxDescriptionLength = 255
+	maxActionLength      = 50
+)
+
+const (
+	// Resource resource name stored for every syslog entry
+	Resource = "SYSLOG"
+	// DefaultAction action stored when the message does not carry a MSGID
+	DefaultAction = "LOG"
+)
+
+// ToPayload maps a parsed syslog message into a logs payload
+func ToPayload(msg *Message, network string, remoteIP string, tenantCat string) (*logs.Payload, error) {
+	envelope := Envelope{
+		Facility:       msg.Facility,
+		Severity:       msg.Severity,
+		Timestamp:      msg.Timestamp,
+		ProcID:         msg.ProcID,
+		MsgID:          msg.MsgID,
+		StructuredData: msg.StructuredData,
+		Message:        msg.Message,
+		Network:        network,
+	}
+
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	clientHost := msg.Hostname
+	if clientHost == "" {
+		clientHost = remoteIP
+	}
+
+	action := DefaultAction
+	if msg.MsgID != "" {
+		action = strings.ToUpper(msg.MsgID)
+	}
+
+	return &logs.Payload{
+		IpAddress:   remoteIP,
+		ClientHost:  text.Truncate(clientHost, maxClientHostLength),
+		Provider:    text.Truncate(msg.AppName, maxProviderLength),
+		Level:       level.FromSyslogSeverity(msg.Severity),
+		OccurredAt:  msg.Timestamp,
+		Description: text.Truncate(msg.Message, maxDescriptionLength),
+		Path:        network,
+		Resource:    Resource,
+		Action:      text.Truncate(action, maxActionLength),
+		Data:        string(data),
+		OldData:     "{}",
+		TenantCat:   tenantCat,
+	}, nil
+}
@@ -0,0 +1,35 @@
+package syslog
+
+import (
+	"testing"
+
+	"github.com/jmontesinos91/omnilogger/domains/level"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToPayload(t *testing.T) {
+	msg, err := Parse([]byte(`<11>1 2003-10-11T22:14:15.003Z router01 firewall - drop [meta@1 rule="42"] dropped packet`))
+	assert.NoError(t, err)
+
+	payload, err := ToPayload(msg, NetworkTCP, "10.0.0.7", `[{"id":1,"name":"Tenant"}]`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.7", payload.IpAddress)
+	assert.Equal(t, "router01", payload.ClientHost)
+	assert.Equal(t, "firewall", payload.Provider)
+	assert.Equal(t, level.Error, payload.Level)
+	assert.Equal(t, "dropped packet", payload.Description)
+	assert.Equal(t, Resource, payload.Resource)
+	assert.Equal(t, "DROP", payload.Action)
+	assert.Equal(t, NetworkTCP, payload.Path)
+	assert.JSONEq(t, `{
+		"facility": 1,
+		"severity": 3,
+		"timestamp": "2003-10-11T22:14:15.003Z",
+		"msg_id": "drop",
+		"structured_data": {"meta@1": {"rule": "42"}},
+		"message": "dropped packet",
+		"network": "tcp"
+	}`, payload.Data)
+	assert.Equal(t, `[{"id":1,"name":"Tenant"}]`, payload.TenantCat)
+}
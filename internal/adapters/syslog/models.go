@@ -0,0 +1,29 @@
+package syslog
+
+import "time"
+
+// Message holds a syslog message parsed either from RFC 5424 or RFC 3164 format
+type Message struct {
+	Facility       int
+	Severity       int
+	Version        int
+	Timestamp      *time.Time
+	Hostname       string
+	AppName        string
+	ProcID         string
+	MsgID          string
+	StructuredData map[string]map[string]string
+	Message        string
+}
+
+// Envelope is the json document stored in the data column for syslog entries
+type Envelope struct {
+	Facility       int                          `json:"facility"`
+	Severity       int                          `json:"severity"`
+	Timestamp      *time.Time                   `json:"timestamp,omitempty"`
+	ProcID         string                       `json:"proc_id,omitempty"`
+	MsgID          string                       `json:"msg_id,omitempty"`
+	StructuredData map[string]map[string]string `json:"structured_data,omitempty"`
+	Message        string                       `json:"message"`
+	Network        string                       `json:"network"`
+}
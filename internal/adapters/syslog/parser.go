@@ -0,0 +1,208 @@
+package syslog
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const nilValue = "-"
+
+// rfc3164Layout timestamp layout used by BSD syslog, the year is not part of the message
+const rfc3164Layout = time.Stamp
+
+var (
+	// ErrInvalidPriority the message does not start with a valid <PRI> part
+	ErrInvalidPriority = errors.New("syslog: invalid priority")
+	// ErrInvalidHeader the message header could not be parsed
+	ErrInvalidHeader = errors.New("syslog: invalid header")
+	// ErrInvalidStructuredData the structured data part is malformed
+	ErrInvalidStructuredData = errors.New("syslog: invalid structured data")
+)
+
+// Parse parses a raw syslog message, it detects whether it is RFC 5424 or RFC 3164
+func Parse(raw []byte) (*Message, error) {
+	line := strings.TrimRight(string(raw), "\r\n\x00")
+
+	priority, rest, err := parsePriority(line)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &Message{
+		Facility: priority / 8,
+		Severity: priority % 8,
+	}
+
+	// RFC 5424 messages have a version right after the priority
+	if len(rest) > 1 && rest[0] >= '1' && rest[0] <= '9' && rest[1] == ' ' {
+		return parseRFC5424(msg, rest)
+	}
+
+	return parseRFC3164(msg, rest), nil
+}
+
+func parsePriority(line string) (int, string, error) {
+	if len(line) < 3 || line[0] != '<' {
+		return 0, "", ErrInvalidPriority
+	}
+
+	end := strings.IndexByte(line, '>')
+	if end < 2 || end > 4 {
+		return 0, "", ErrInvalidPriority
+	}
+
+	priority, err := strconv.Atoi(line[1:end])
+	if err != nil || priority < 0 || priority > 191 {
+		return 0, "", ErrInvalidPriority
+	}
+
+	return priority, line[end+1:], nil
+}
+
+func parseRFC5424(msg *Message, rest string) (*Message, error) {
+	// VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA [MSG]
+	fields := strings.SplitN(rest, " ", 7)
+	if len(fields) < 7 {
+		return nil, ErrInvalidHeader
+	}
+
+	version, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return nil, ErrInvalidHeader
+	}
+	msg.Version = version
+
+	if fields[1] != nilValue {
+		timestamp, err := time.Parse(time.RFC3339Nano, fields[1])
+		if err != nil {
+			return nil, ErrInvalidHeader
+		}
+		timestamp = timestamp.UTC()
+		msg.Timestamp = &timestamp
+	}
+
+	msg.Hostname = nilToEmpty(fields[2])
+	msg.AppName = nilToEmpty(fields[3])
+	msg.ProcID = nilToEmpty(fields[4])
+	msg.MsgID = nilToEmpty(fields[5])
+
+	sd, text, err := parseStructuredData(fields[6])
+	if err != nil {
+		return nil, err
+	}
+	msg.StructuredData = sd
+
+	// Drop the UTF-8 BOM allowed by the RFC in front of the message
+	msg.Message = strings.TrimPrefix(text, "\ufeff")
+
+	return msg, nil
+}
+
+// parseStructuredData parses the SD part and returns the remaining message
+func parseStructuredData(s string) (map[string]map[string]string, string, error) {
+	if strings.HasPrefix(s, nilValue) {
+		return nil, strings.TrimPrefix(strings.TrimPrefix(s, nilValue), " "), nil
+	}
+
+	sd := map[string]map[string]string{}
+	i := 0
+	for i < len(s) && s[i] == '[' {
+		i++
+
+		// SD-ID
+		start := i
+		for i < len(s) && s[i] != ' ' && s[i] != ']' {
+			i++
+		}
+		if i >= len(s) || start == i {
+			return nil, "", ErrInvalidStructuredData
+		}
+		params := map[string]string{}
+		sd[s[start:i]] = params
+
+		// SD-PARAMs
+		for i < len(s) && s[i] == ' ' {
+			i++
+			start = i
+			for i < len(s) && s[i] != '=' {
+				i++
+			}
+			if i+1 >= len(s) || s[i+1] != '"' {
+				return nil, "", ErrInvalidStructuredData
+			}
+			name := s[start:i]
+			i += 2
+
+			var value strings.Builder
+			for i < len(s) && s[i] != '"' {
+				// Only ", \ and ] may be escaped
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(`"\]`, s[i+1]) >= 0 {
+					i++
+				}
+				value.WriteByte(s[i])
+				i++
+			}
+			if i >= len(s) {
+				return nil, "", ErrInvalidStructuredData
+			}
+			params[name] = value.String()
+			i++
+		}
+
+		if i >= len(s) || s[i] != ']' {
+			return nil, "", ErrInvalidStructuredData
+		}
+		i++
+	}
+
+	if len(sd) == 0 {
+		return nil, "", ErrInvalidStructuredData
+	}
+
+	return sd, strings.TrimPrefix(s[i:], " "), nil
+}
+
+func parseRFC3164(msg *Message, rest string) *Message {
+	// TIMESTAMP HOSTNAME TAG[PID]: MSG, every part but the message is optional in the wild
+	if len(rest) >= len(rfc3164Layout) {
+		if timestamp, err := time.Parse(rfc3164Layout, rest[:len(rfc3164Layout)]); err == nil {
+			now := time.Now().UTC()
+			timestamp = timestamp.AddDate(now.Year(), 0, 0)
+
+			// Messages from december received in january belong to the previous year
+			if timestamp.After(now.AddDate(0, 1, 0)) {
+				timestamp = timestamp.AddDate(-1, 0, 0)
+			}
+			msg.Timestamp = &timestamp
+			rest = strings.TrimPrefix(rest[len(rfc3164Layout):], " ")
+
+			if host, remaining, found := strings.Cut(rest, " "); found && !strings.HasSuffix(host, ":") {
+				msg.Hostname = host
+				rest = remaining
+			}
+		}
+	}
+
+	if tag, remaining, found := strings.Cut(rest, ": "); found && !strings.ContainsAny(tag, " ") {
+		if open := strings.IndexByte(tag, '['); open > 0 && strings.HasSuffix(tag, "]") {
+			msg.ProcID = tag[open+1 : len(tag)-1]
+			tag = tag[:open]
+		}
+		msg.AppName = tag
+		rest = remaining
+	}
+
+	msg.Message = rest
+
+	return msg
+}
+
+func nilToEmpty(s string) string {
+	if s == nilValue {
+		return ""
+	}
+
+	return s
+}
@@ -0,0 +1,123 @@
+package syslog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	timestamp := time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC)
+
+	cases := []struct {
+		name     string
+		raw      string
+		err      error
+		expected *Message
+	}{
+		{
+			name: "RFC 5424 without structured data",
+			raw:  "<34>1 2003-10-11T22:14:15.003Z mymachine.example.com su - ID47 - 'su root' failed for lonvick on /dev/pts/8",
+			expected: &Message{
+				Facility:  4,
+				Severity:  2,
+				Version:   1,
+				Timestamp: &timestamp,
+				Hostname:  "mymachine.example.com",
+				AppName:   "su",
+				MsgID:     "ID47",
+				Message:   "'su root' failed for lonvick on /dev/pts/8",
+			},
+		},
+		{
+			name: "RFC 5424 with structured data and escaped values",
+			raw:  `<165>1 2003-10-11T22:14:15.003Z host app 1234 ID47 [exampleSDID@32473 iut="3" eventSource="App\"lication" eventID="1011"][examplePriority@32473 class="high"] An application event`,
+			expected: &Message{
+				Facility:  20,
+				Severity:  5,
+				Version:   1,
+				Timestamp: &timestamp,
+				Hostname:  "host",
+				AppName:   "app",
+				ProcID:    "1234",
+				MsgID:     "ID47",
+				StructuredData: map[string]map[string]string{
+					"exampleSDID@32473":     {"iut": "3", "eventSource": `App"lication`, "eventID": "1011"},
+					"examplePriority@32473": {"class": "high"},
+				},
+				Message: "An application event",
+			},
+		},
+		{
+			name: "RFC 5424 with nil values and no message",
+			raw:  "<14>1 - - - - - -",
+			expected: &Message{
+				Facility: 1,
+				Severity: 6,
+				Version:  1,
+			},
+		},
+		{
+			name: "RFC 3164 without timestamp",
+			raw:  "<13>sshd[4242]: Accepted publickey for root",
+			expected: &Message{
+				Facility: 1,
+				Severity: 5,
+				AppName:  "sshd",
+				ProcID:   "4242",
+				Message:  "Accepted publickey for root",
+			},
+		},
+		{
+			name: "Invalid priority",
+			raw:  "<999>1 - - - - - -",
+			err:  ErrInvalidPriority,
+		},
+		{
+			name: "Missing priority",
+			raw:  "hello world",
+			err:  ErrInvalidPriority,
+		},
+		{
+			name: "RFC 5424 truncated header",
+			raw:  "<34>1 2003-10-11T22:14:15.003Z host",
+			err:  ErrInvalidHeader,
+		},
+		{
+			name: "RFC 5424 unterminated structured data",
+			raw:  `<34>1 - host app - - [id key="value"`,
+			err:  ErrInvalidStructuredData,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			msg, err := Parse([]byte(tc.raw))
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				assert.Nil(t, msg)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, msg)
+		})
+	}
+}
+
+func TestParse_RFC3164WithTimestamp(t *testing.T) {
+	msg, err := Parse([]byte("<34>Oct 11 22:14:15 mymachine su: 'su root' failed for lonvick on /dev/pts/8"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4, msg.Facility)
+	assert.Equal(t, 2, msg.Severity)
+	assert.Equal(t, "mymachine", msg.Hostname)
+	assert.Equal(t, "su", msg.AppName)
+	assert.Equal(t, "'su root' failed for lonvick on /dev/pts/8", msg.Message)
+	if assert.NotNil(t, msg.Timestamp) {
+		assert.Equal(t, time.October, msg.Timestamp.Month())
+		assert.Equal(t, 11, msg.Timestamp.Day())
+		assert.Equal(t, 22, msg.Timestamp.Hour())
+	}
+}
@@ -0,0 +1,66 @@
+package access_log
+
+import (
+	"context"
+
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+// DatabaseRepository struct
+type DatabaseRepository struct {
+	log *logger.ContextLogger
+	db  *bun.DB
+}
+
+// NewDatabaseRepository creates an instance of DatabaseRepository
+func NewDatabaseRepository(l *logger.ContextLogger, conn *bun.DB) *DatabaseRepository {
+	return &DatabaseRepository{
+		log: l,
+		db:  conn,
+	}
+}
+
+// Create Handles the creation of a new access log record on a database
+func (r *DatabaseRepository) Create(ctx context.Context, model *Model) error {
+	_, err := r.db.NewInsert().
+		Model(model).
+		Exec(ctx)
+
+	return err
+}
+
+// Retrieve lists the access logs involving any of the given tenants, newest first
+func (r *DatabaseRepository) Retrieve(ctx context.Context, filter Filter) ([]Model, int, error) {
+	var model []Model
+
+	query := r.db.NewSelect().Model(&model).
+		Where("tenants && ?", pgdialect.Array(filter.TenantID)).
+		Order("created_at DESC").
+		Limit(filter.Size).
+		Offset(filter.From - 1)
+
+	if len(filter.UserID) > 0 {
+		query = query.Where("user_id in (?)", bun.In(filter.UserID))
+	}
+
+	if len(filter.Action) > 0 {
+		query = query.Where("action in (?)", bun.In(filter.Action))
+	}
+
+	if !filter.StartAt.IsZero() {
+		query = query.Where("created_at >= ?", filter.StartAt)
+	}
+
+	if !filter.EndAt.IsZero() {
+		query = query.Where("created_at <= ?", filter.EndAt)
+	}
+
+	count, err := query.ScanAndCount(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return model, count, nil
+}
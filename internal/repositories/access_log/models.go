@@ -0,0 +1,36 @@
+package access_log
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Model Database model for access logs, one record per read or export of audit logs
+type Model struct {
+	bun.BaseModel `bun:"table:access_logs"`
+
+	ID        string          `bun:"id,pk"`
+	RequestID string          `bun:"request_id,nullzero"`
+	UserID    int             `bun:"user_id"`
+	UserName  string          `bun:"user_name"`
+	Role      string          `bun:"role"`
+	Tenants   []int           `bun:"tenants,array"`
+	Action    string          `bun:"action"`
+	LogID     string          `bun:"log_id,nullzero"`
+	Filter    json.RawMessage `bun:"filter,type:jsonb"`
+	RowCount  int             `bun:"row_count"`
+	Outcome   string          `bun:"outcome"`
+	CreatedAt *time.Time      `bun:"created_at"`
+}
+
+type Filter struct {
+	TenantID []int
+	UserID   []int
+	Action   []string
+	StartAt  time.Time
+	EndAt    time.Time
+	From     int
+	Size     int
+}
@@ -0,0 +1,11 @@
+package access_log
+
+import (
+	"context"
+)
+
+// IRepository interface
+type IRepository interface {
+	Create(ctx context.Context, model *Model) error
+	Retrieve(ctx context.Context, filter Filter) ([]Model, int, error)
+}
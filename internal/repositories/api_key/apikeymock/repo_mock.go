@@ -0,0 +1,183 @@
+// Code generated by mockery v2.50.2. DO NOT EDIT.
+
+package apikeymock
+
+import (
+	context "context"
+
+	api_key "github.com/jmontesinos91/omnilogger/internal/repositories/api_key"
+
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// IRepository is an autogenerated mock type for the IRepository type
+type IRepository struct {
+	mock.Mock
+}
+
+// Create provides a mock function with given fields: ctx, model
+func (_m *IRepository) Create(ctx context.Context, model *api_key.Model) error {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *api_key.Model) error); ok {
+		r0 = rf(ctx, model)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FindByHash provides a mock function with given fields: ctx, hash
+func (_m *IRepository) FindByHash(ctx context.Context, hash string) (*api_key.Model, error) {
+	ret := _m.Called(ctx, hash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindByHash")
+	}
+
+	var r0 *api_key.Model
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*api_key.Model, error)); ok {
+		return rf(ctx, hash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *api_key.Model); ok {
+		r0 = rf(ctx, hash)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*api_key.Model)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, hash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindByID provides a mock function with given fields: ctx, ID
+func (_m *IRepository) FindByID(ctx context.Context, ID string) (*api_key.Model, error) {
+	ret := _m.Called(ctx, ID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindByID")
+	}
+
+	var r0 *api_key.Model
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*api_key.Model, error)); ok {
+		return rf(ctx, ID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *api_key.Model); ok {
+		r0 = rf(ctx, ID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*api_key.Model)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, ID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Retrieve provides a mock function with given fields: ctx, filter
+func (_m *IRepository) Retrieve(ctx context.Context, filter api_key.Filter) ([]api_key.Model, int, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Retrieve")
+	}
+
+	var r0 []api_key.Model
+	var r1 int
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, api_key.Filter) ([]api_key.Model, int, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, api_key.Filter) []api_key.Model); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]api_key.Model)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, api_key.Filter) int); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, api_key.Filter) error); ok {
+		r2 = rf(ctx, filter)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// Revoke provides a mock function with given fields: ctx, ID, revokedAt
+func (_m *IRepository) Revoke(ctx context.Context, ID string, revokedAt time.Time) error {
+	ret := _m.Called(ctx, ID, revokedAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Revoke")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) error); ok {
+		r0 = rf(ctx, ID, revokedAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// TouchLastUsed provides a mock function with given fields: ctx, ID, usedAt
+func (_m *IRepository) TouchLastUsed(ctx context.Context, ID string, usedAt time.Time) error {
+	ret := _m.Called(ctx, ID, usedAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for TouchLastUsed")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) error); ok {
+		r0 = rf(ctx, ID, usedAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewIRepository creates a new instance of IRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewIRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *IRepository {
+	mock := &IRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
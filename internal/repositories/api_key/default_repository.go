@@ -0,0 +1,120 @@
+package api_key
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/terrors"
+	"github.com/uptrace/bun"
+)
+
+// DatabaseRepository struct
+type DatabaseRepository struct {
+	log *logger.ContextLogger
+	db  *bun.DB
+}
+
+// NewDatabaseRepository creates an instance of DatabaseRepository
+func NewDatabaseRepository(l *logger.ContextLogger, conn *bun.DB) *DatabaseRepository {
+	return &DatabaseRepository{
+		log: l,
+		db:  conn,
+	}
+}
+
+// FindByID finds an api key by its ID
+func (r *DatabaseRepository) FindByID(ctx context.Context, ID string) (*Model, error) {
+	var apiKey Model
+	query := r.db.NewSelect().
+		Model(&apiKey).
+		Where("id = ?", ID)
+
+	if err := query.Scan(ctx); err != nil {
+		if err.Error() == sql.ErrNoRows.Error() {
+			return nil, terrors.New(terrors.ErrNotFound, "Api key not found", map[string]string{})
+		}
+		return nil, fmt.Errorf("api_key_repository: Error while searching for api key -> %v", err)
+	}
+
+	return &apiKey, nil
+}
+
+// FindByHash finds a non revoked api key by the hash of its secret
+func (r *DatabaseRepository) FindByHash(ctx context.Context, hash string) (*Model, error) {
+	var apiKey Model
+	query := r.db.NewSelect().
+		Model(&apiKey).
+		Where("key_hash = ?", hash).
+		Where("revoked_at IS NULL")
+
+	if err := query.Scan(ctx); err != nil {
+		if err.Error() == sql.ErrNoRows.Error() {
+			return nil, terrors.New(terrors.ErrNotFound, "Api key not found", map[string]string{})
+		}
+		return nil, fmt.Errorf("api_key_repository: Error while searching for api key -> %v", err)
+	}
+
+	return &apiKey, nil
+}
+
+// Create Handles the creation of a new api key record on a database
+func (r *DatabaseRepository) Create(ctx context.Context, model *Model) error {
+	_, err := r.db.NewInsert().
+		Model(model).
+		Exec(ctx)
+
+	// Handling error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Retrieve lists api keys of the given tenants
+func (r *DatabaseRepository) Retrieve(ctx context.Context, filter Filter) ([]Model, int, error) {
+	var model []Model
+
+	query := r.db.NewSelect().Model(&model).
+		Where("tenant_id in (?)", bun.In(filter.TenantID)).
+		Order("created_at DESC").
+		Limit(filter.Size).
+		Offset(filter.From - 1)
+
+	if !filter.IncludeRevoked {
+		query = query.Where("revoked_at IS NULL")
+	}
+
+	count, err := query.ScanAndCount(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return model, count, nil
+}
+
+// Revoke marks an api key as revoked, revoked keys are kept for auditing purposes
+func (r *DatabaseRepository) Revoke(ctx context.Context, ID string, revokedAt time.Time) error {
+	_, err := r.db.NewUpdate().
+		Model((*Model)(nil)).
+		Set("revoked_at = ?", revokedAt).
+		Where("id = ?", ID).
+		Where("revoked_at IS NULL").
+		Exec(ctx)
+
+	return err
+}
+
+// TouchLastUsed updates the last time the api key was used
+func (r *DatabaseRepository) TouchLastUsed(ctx context.Context, ID string, usedAt time.Time) error {
+	_, err := r.db.NewUpdate().
+		Model((*Model)(nil)).
+		Set("last_used_at = ?", usedAt).
+		Where("id = ?", ID).
+		Exec(ctx)
+
+	return err
+}
@@ -0,0 +1,31 @@
+package api_key
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Model Database model for api keys, only the sha256 hash of the key is stored
+type Model struct {
+	bun.BaseModel `bun:"table:api_keys"`
+
+	ID         string     `bun:"id,pk"`
+	Name       string     `bun:"name"`
+	TenantID   int        `bun:"tenant_id"`
+	TenantName string     `bun:"tenant_name"`
+	Prefix     string     `bun:"prefix"`
+	KeyHash    string     `bun:"key_hash"`
+	Scopes     []string   `bun:"scopes,type:jsonb"`
+	CreatedBy  string     `bun:"created_by"`
+	CreatedAt  *time.Time `bun:"created_at"`
+	LastUsedAt *time.Time `bun:"last_used_at"`
+	RevokedAt  *time.Time `bun:"revoked_at"`
+}
+
+type Filter struct {
+	TenantID       []int
+	IncludeRevoked bool
+	From           int
+	Size           int
+}
@@ -0,0 +1,16 @@
+package api_key
+
+import (
+	"context"
+	"time"
+)
+
+// IRepository interface
+type IRepository interface {
+	FindByID(ctx context.Context, ID string) (*Model, error)
+	FindByHash(ctx context.Context, hash string) (*Model, error)
+	Create(ctx context.Context, model *Model) error
+	Retrieve(ctx context.Context, filter Filter) ([]Model, int, error)
+	Revoke(ctx context.Context, ID string, revokedAt time.Time) error
+	TouchLastUsed(ctx context.Context, ID string, usedAt time.Time) error
+}
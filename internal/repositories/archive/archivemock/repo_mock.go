@@ -0,0 +1,291 @@
+// Code generated by mockery v2.50.2. DO NOT EDIT.
+
+package archivemock
+
+import (
+	context "context"
+
+	archive "github.com/jmontesinos91/omnilogger/internal/repositories/archive"
+
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// IRepository is an autogenerated mock type for the IRepository type
+type IRepository struct {
+	mock.Mock
+}
+
+// Create provides a mock function with given fields: ctx, model
+func (_m *IRepository) Create(ctx context.Context, model *archive.Model) error {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *archive.Model) error); ok {
+		r0 = rf(ctx, model)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateRestore provides a mock function with given fields: ctx, model
+func (_m *IRepository) CreateRestore(ctx context.Context, model *archive.RestoreModel) error {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateRestore")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *archive.RestoreModel) error); ok {
+		r0 = rf(ctx, model)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ExpiredRestores provides a mock function with given fields: ctx, now
+func (_m *IRepository) ExpiredRestores(ctx context.Context, now time.Time) ([]archive.RestoreModel, error) {
+	ret := _m.Called(ctx, now)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ExpiredRestores")
+	}
+
+	var r0 []archive.RestoreModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) ([]archive.RestoreModel, error)); ok {
+		return rf(ctx, now)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) []archive.RestoreModel); ok {
+		r0 = rf(ctx, now)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]archive.RestoreModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, now)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FailStaleRestores provides a mock function with given fields: ctx, createdBefore, message
+func (_m *IRepository) FailStaleRestores(ctx context.Context, createdBefore time.Time, message string) ([]archive.RestoreModel, error) {
+	ret := _m.Called(ctx, createdBefore, message)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FailStaleRestores")
+	}
+
+	var r0 []archive.RestoreModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, string) ([]archive.RestoreModel, error)); ok {
+		return rf(ctx, createdBefore, message)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, string) []archive.RestoreModel); ok {
+		r0 = rf(ctx, createdBefore, message)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]archive.RestoreModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, string) error); ok {
+		r1 = rf(ctx, createdBefore, message)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindActiveRestore provides a mock function with given fields: ctx, archiveID
+func (_m *IRepository) FindActiveRestore(ctx context.Context, archiveID string) (*archive.RestoreModel, error) {
+	ret := _m.Called(ctx, archiveID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindActiveRestore")
+	}
+
+	var r0 *archive.RestoreModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*archive.RestoreModel, error)); ok {
+		return rf(ctx, archiveID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *archive.RestoreModel); ok {
+		r0 = rf(ctx, archiveID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*archive.RestoreModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, archiveID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindByID provides a mock function with given fields: ctx, ID
+func (_m *IRepository) FindByID(ctx context.Context, ID string) (*archive.Model, error) {
+	ret := _m.Called(ctx, ID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindByID")
+	}
+
+	var r0 *archive.Model
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*archive.Model, error)); ok {
+		return rf(ctx, ID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *archive.Model); ok {
+		r0 = rf(ctx, ID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*archive.Model)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, ID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindRestore provides a mock function with given fields: ctx, ID
+func (_m *IRepository) FindRestore(ctx context.Context, ID string) (*archive.RestoreModel, error) {
+	ret := _m.Called(ctx, ID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindRestore")
+	}
+
+	var r0 *archive.RestoreModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*archive.RestoreModel, error)); ok {
+		return rf(ctx, ID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *archive.RestoreModel); ok {
+		r0 = rf(ctx, ID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*archive.RestoreModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, ID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MarkPurged provides a mock function with given fields: ctx, ID, rows, purgedAt
+func (_m *IRepository) MarkPurged(ctx context.Context, ID string, rows int, purgedAt time.Time) error {
+	ret := _m.Called(ctx, ID, rows, purgedAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkPurged")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, time.Time) error); ok {
+		r0 = rf(ctx, ID, rows, purgedAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Retrieve provides a mock function with given fields: ctx, filter
+func (_m *IRepository) Retrieve(ctx context.Context, filter archive.Filter) ([]archive.Model, int, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Retrieve")
+	}
+
+	var r0 []archive.Model
+	var r1 int
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, archive.Filter) ([]archive.Model, int, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, archive.Filter) []archive.Model); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]archive.Model)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, archive.Filter) int); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, archive.Filter) error); ok {
+		r2 = rf(ctx, filter)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// UpdateRestore provides a mock function with given fields: ctx, model
+func (_m *IRepository) UpdateRestore(ctx context.Context, model *archive.RestoreModel) error {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateRestore")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *archive.RestoreModel) error); ok {
+		r0 = rf(ctx, model)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewIRepository creates a new instance of IRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewIRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *IRepository {
+	mock := &IRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,183 @@
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/terrors"
+	"github.com/uptrace/bun"
+)
+
+// DatabaseRepository struct
+type DatabaseRepository struct {
+	log *logger.ContextLogger
+	db  *bun.DB
+}
+
+// NewDatabaseRepository creates an instance of DatabaseRepository
+func NewDatabaseRepository(l *logger.ContextLogger, conn *bun.DB) *DatabaseRepository {
+	return &DatabaseRepository{
+		log: l,
+		db:  conn,
+	}
+}
+
+// FindByID finds an archive by its id
+func (r *DatabaseRepository) FindByID(ctx context.Context, ID string) (*Model, error) {
+	var model Model
+	query := r.db.NewSelect().
+		Model(&model).
+		Where("id = ?", ID)
+
+	if err := query.Scan(ctx); err != nil {
+		if err.Error() == sql.ErrNoRows.Error() {
+			return nil, terrors.New(terrors.ErrNotFound, "Archive not found", map[string]string{})
+		}
+		return nil, fmt.Errorf("archive_repository: Error while searching for archive -> %v", err)
+	}
+
+	return &model, nil
+}
+
+// Create Handles the creation of a new archive record on a database
+func (r *DatabaseRepository) Create(ctx context.Context, model *Model) error {
+	_, err := r.db.NewInsert().
+		Model(model).
+		Exec(ctx)
+
+	return err
+}
+
+// MarkPurged records how many logs of the archive were deleted from the logs table, the count is accumulated
+func (r *DatabaseRepository) MarkPurged(ctx context.Context, ID string, rows int, purgedAt time.Time) error {
+	_, err := r.db.NewUpdate().
+		Model((*Model)(nil)).
+		Set("purged_rows = purged_rows + ?", rows).
+		Set("purged_at = ?", purgedAt).
+		Where("id = ?", ID).
+		Exec(ctx)
+
+	return err
+}
+
+// Retrieve lists the archives of the tenants, newest day first
+func (r *DatabaseRepository) Retrieve(ctx context.Context, filter Filter) ([]Model, int, error) {
+	var model []Model
+
+	query := r.db.NewSelect().Model(&model).
+		Where("tenant_id in (?)", bun.In(filter.TenantID)).
+		Order("day DESC", "tenant_id ASC").
+		Limit(filter.Size).
+		Offset(filter.From - 1)
+
+	if !filter.StartAt.IsZero() {
+		query = query.Where("day >= ?::DATE", filter.StartAt)
+	}
+
+	if !filter.EndAt.IsZero() {
+		query = query.Where("day <= ?::DATE", filter.EndAt)
+	}
+
+	count, err := query.ScanAndCount(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return model, count, nil
+}
+
+// FindRestore finds a restore by its id
+func (r *DatabaseRepository) FindRestore(ctx context.Context, ID string) (*RestoreModel, error) {
+	var model RestoreModel
+	query := r.db.NewSelect().
+		Model(&model).
+		Where("id = ?", ID)
+
+	if err := query.Scan(ctx); err != nil {
+		if err.Error() == sql.ErrNoRows.Error() {
+			return nil, terrors.New(terrors.ErrNotFound, "Restore not found", map[string]string{})
+		}
+		return nil, fmt.Errorf("archive_repository: Error while searching for restore -> %v", err)
+	}
+
+	return &model, nil
+}
+
+// FindActiveRestore finds the restore of the archive in progress or ready, nil when there is none
+func (r *DatabaseRepository) FindActiveRestore(ctx context.Context, archiveID string) (*RestoreModel, error) {
+	var model RestoreModel
+	err := r.db.NewSelect().
+		Model(&model).
+		Where("archive_id = ?", archiveID).
+		Where("status IN (?)", bun.In([]string{RestoreRestoring, RestoreReady})).
+		Where("expired_at IS NULL").
+		Limit(1).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("archive_repository: Error while searching for active restore -> %v", err)
+	}
+
+	return &model, nil
+}
+
+// CreateRestore Handles the creation of a new restore record on a database
+func (r *DatabaseRepository) CreateRestore(ctx context.Context, model *RestoreModel) error {
+	_, err := r.db.NewInsert().
+		Model(model).
+		Exec(ctx)
+
+	return err
+}
+
+// UpdateRestore updates the progress of a restore
+func (r *DatabaseRepository) UpdateRestore(ctx context.Context, model *RestoreModel) error {
+	_, err := r.db.NewUpdate().
+		Model(model).
+		Column("status", "row_count", "error", "ready_at", "expired_at").
+		WherePK().
+		Exec(ctx)
+
+	return err
+}
+
+// ExpiredRestores lists the restores past their expiry whose logs have not been deleted yet
+func (r *DatabaseRepository) ExpiredRestores(ctx context.Context, now time.Time) ([]RestoreModel, error) {
+	var model []RestoreModel
+	err := r.db.NewSelect().
+		Model(&model).
+		Where("expired_at IS NULL").
+		Where("expires_at <= ?::TIMESTAMP", now).
+		Order("expires_at ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("archive_repository: Error while searching for expired restores -> %v", err)
+	}
+
+	return model, nil
+}
+
+// FailStaleRestores fails the restores still restoring since before createdBefore, their worker is gone. It returns
+// the restores failed so their logs can be deleted
+func (r *DatabaseRepository) FailStaleRestores(ctx context.Context, createdBefore time.Time, message string) ([]RestoreModel, error) {
+	var model []RestoreModel
+	_, err := r.db.NewUpdate().
+		Model(&model).
+		Set("status = ?", RestoreFailed).
+		Set("error = ?", message).
+		Where("status = ?", RestoreRestoring).
+		Where("created_at < ?::TIMESTAMP", createdBefore).
+		Returning("*").
+		Exec(ctx, &model)
+	if err != nil {
+		return nil, fmt.Errorf("archive_repository: Error while failing stale restores -> %v", err)
+	}
+
+	return model, nil
+}
@@ -0,0 +1,63 @@
+package archive
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// NoTenant tenant of the archives holding the logs without owning tenant
+const NoTenant = 0
+
+// Model Database model for the archive files, one per tenant and day
+type Model struct {
+	bun.BaseModel `bun:"table:log_archives"`
+
+	ID          string     `bun:"id,pk"`
+	TenantID    int        `bun:"tenant_id"`
+	Day         time.Time  `bun:"day,type:date"`
+	Format      string     `bun:"format"`
+	ObjectKey   string     `bun:"object_key"`
+	ManifestKey string     `bun:"manifest_key"`
+	RowCount    int        `bun:"row_count"`
+	SizeBytes   int64      `bun:"size_bytes"`
+	SHA256      string     `bun:"sha256"`
+	PurgedRows  int        `bun:"purged_rows"`
+	CreatedAt   *time.Time `bun:"created_at"`
+	PurgedAt    *time.Time `bun:"purged_at"`
+}
+
+// Restore statuses
+const (
+	RestoreRestoring = "restoring"
+	RestoreReady     = "ready"
+	RestoreFailed    = "failed"
+	RestoreExpired   = "expired"
+)
+
+// RestoreModel Database model for the restores of archive files, their logs are searchable until ExpiresAt
+type RestoreModel struct {
+	bun.BaseModel `bun:"table:log_restores"`
+
+	ID          string     `bun:"id,pk"`
+	ArchiveID   string     `bun:"archive_id"`
+	TenantID    int        `bun:"tenant_id"`
+	Day         time.Time  `bun:"day,type:date"`
+	Status      string     `bun:"status"`
+	RowCount    int        `bun:"row_count"`
+	Error       string     `bun:"error,nullzero"`
+	RequestedBy string     `bun:"requested_by"`
+	CreatedAt   *time.Time `bun:"created_at"`
+	ReadyAt     *time.Time `bun:"ready_at"`
+	ExpiresAt   time.Time  `bun:"expires_at"`
+	ExpiredAt   *time.Time `bun:"expired_at"`
+}
+
+// Filter archives of the tenants created for the days between StartAt and EndAt, zero days leave the range open
+type Filter struct {
+	TenantID []int
+	StartAt  time.Time
+	EndAt    time.Time
+	From     int
+	Size     int
+}
@@ -0,0 +1,20 @@
+package archive
+
+import (
+	"context"
+	"time"
+)
+
+// IRepository interface
+type IRepository interface {
+	FindByID(ctx context.Context, ID string) (*Model, error)
+	Create(ctx context.Context, model *Model) error
+	Retrieve(ctx context.Context, filter Filter) ([]Model, int, error)
+	MarkPurged(ctx context.Context, ID string, rows int, purgedAt time.Time) error
+	FindRestore(ctx context.Context, ID string) (*RestoreModel, error)
+	FindActiveRestore(ctx context.Context, archiveID string) (*RestoreModel, error)
+	CreateRestore(ctx context.Context, model *RestoreModel) error
+	UpdateRestore(ctx context.Context, model *RestoreModel) error
+	ExpiredRestores(ctx context.Context, now time.Time) ([]RestoreModel, error)
+	FailStaleRestores(ctx context.Context, createdBefore time.Time, message string) ([]RestoreModel, error)
+}
@@ -0,0 +1,160 @@
+package export_job
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/terrors"
+	"github.com/uptrace/bun"
+)
+
+// DatabaseRepository struct
+type DatabaseRepository struct {
+	log *logger.ContextLogger
+	db  *bun.DB
+}
+
+// NewDatabaseRepository creates an instance of DatabaseRepository
+func NewDatabaseRepository(l *logger.ContextLogger, conn *bun.DB) *DatabaseRepository {
+	return &DatabaseRepository{
+		log: l,
+		db:  conn,
+	}
+}
+
+// FindByID finds an export job by its id
+func (r *DatabaseRepository) FindByID(ctx context.Context, ID string) (*Model, error) {
+	var model Model
+	query := r.db.NewSelect().
+		Model(&model).
+		Where("id = ?", ID)
+
+	if err := query.Scan(ctx); err != nil {
+		if err.Error() == sql.ErrNoRows.Error() {
+			return nil, terrors.New(terrors.ErrNotFound, "Export job not found", map[string]string{})
+		}
+		return nil, fmt.Errorf("export_job_repository: Error while searching for export job -> %v", err)
+	}
+
+	return &model, nil
+}
+
+// Create Handles the creation of a new export job record on a database
+func (r *DatabaseRepository) Create(ctx context.Context, model *Model) error {
+	_, err := r.db.NewInsert().
+		Model(model).
+		Exec(ctx)
+
+	return err
+}
+
+// MarkRunning moves a queued export job to running, it returns false when the job is no longer queued
+func (r *DatabaseRepository) MarkRunning(ctx context.Context, ID string, startedAt time.Time) (bool, error) {
+	res, err := r.db.NewUpdate().
+		Model((*Model)(nil)).
+		Set("status = ?", StatusRunning).
+		Set("started_at = ?", startedAt).
+		Where("id = ?", ID).
+		Where("status = ?", StatusQueued).
+		Exec(ctx)
+	if err != nil {
+		return false, fmt.Errorf("export_job_repository: Error while starting export job -> %v", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("export_job_repository: Error while starting export job -> %v", err)
+	}
+
+	return affected > 0, nil
+}
+
+// Update updates the status and the outcome of an export job
+func (r *DatabaseRepository) Update(ctx context.Context, model *Model) error {
+	_, err := r.db.NewUpdate().
+		Model(model).
+		Column("status", "row_count", "size_bytes", "object_key", "sha256", "manifest", "error", "started_at", "finished_at", "expired_at").
+		WherePK().
+		Exec(ctx)
+
+	return err
+}
+
+// UpdateProgress records the number of logs written so far by a running export job
+func (r *DatabaseRepository) UpdateProgress(ctx context.Context, ID string, rows int) error {
+	_, err := r.db.NewUpdate().
+		Model((*Model)(nil)).
+		Set("row_count = ?", rows).
+		Where("id = ?", ID).
+		Where("status = ?", StatusRunning).
+		Exec(ctx)
+
+	return err
+}
+
+// FailQueued fails the export jobs queued since before createdBefore, the queue of the workers is not persisted so
+// they are lost with the process that queued them. It returns the number of jobs failed
+func (r *DatabaseRepository) FailQueued(ctx context.Context, createdBefore time.Time, message string, finishedAt time.Time) (int, error) {
+	res, err := r.db.NewUpdate().
+		Model((*Model)(nil)).
+		Set("status = ?", StatusFailed).
+		Set("error = ?", message).
+		Set("finished_at = ?", finishedAt).
+		Where("status = ?", StatusQueued).
+		Where("created_at < ?::TIMESTAMP", createdBefore).
+		Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("export_job_repository: Error while failing queued export jobs -> %v", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("export_job_repository: Error while failing queued export jobs -> %v", err)
+	}
+
+	return int(affected), nil
+}
+
+// FailStale fails the export jobs still running since before startedBefore, their worker is gone. It returns the
+// number of jobs failed
+func (r *DatabaseRepository) FailStale(ctx context.Context, startedBefore time.Time, message string, finishedAt time.Time) (int, error) {
+	res, err := r.db.NewUpdate().
+		Model((*Model)(nil)).
+		Set("status = ?", StatusFailed).
+		Set("error = ?", message).
+		Set("finished_at = ?", finishedAt).
+		Where("status = ?", StatusRunning).
+		Where("started_at < ?::TIMESTAMP", startedBefore).
+		Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("export_job_repository: Error while failing stale export jobs -> %v", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("export_job_repository: Error while failing stale export jobs -> %v", err)
+	}
+
+	return int(affected), nil
+}
+
+// Expired lists the export jobs past their expiry whose files have not been deleted yet, running jobs are left to
+// finish or to be failed first
+func (r *DatabaseRepository) Expired(ctx context.Context, now time.Time) ([]Model, error) {
+	var model []Model
+	err := r.db.NewSelect().
+		Model(&model).
+		Where("expired_at IS NULL").
+		Where("status <> ?", StatusRunning).
+		Where("expires_at <= ?::TIMESTAMP", now).
+		Order("expires_at ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("export_job_repository: Error while searching for expired export jobs -> %v", err)
+	}
+
+	return model, nil
+}
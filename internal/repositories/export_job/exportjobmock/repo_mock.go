@@ -0,0 +1,229 @@
+// Code generated by mockery v2.50.2. DO NOT EDIT.
+
+package exportjobmock
+
+import (
+	context "context"
+
+	export_job "github.com/jmontesinos91/omnilogger/internal/repositories/export_job"
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// IRepository is an autogenerated mock type for the IRepository type
+type IRepository struct {
+	mock.Mock
+}
+
+// Create provides a mock function with given fields: ctx, model
+func (_m *IRepository) Create(ctx context.Context, model *export_job.Model) error {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *export_job.Model) error); ok {
+		r0 = rf(ctx, model)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Expired provides a mock function with given fields: ctx, now
+func (_m *IRepository) Expired(ctx context.Context, now time.Time) ([]export_job.Model, error) {
+	ret := _m.Called(ctx, now)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Expired")
+	}
+
+	var r0 []export_job.Model
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) ([]export_job.Model, error)); ok {
+		return rf(ctx, now)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) []export_job.Model); ok {
+		r0 = rf(ctx, now)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]export_job.Model)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, now)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FailQueued provides a mock function with given fields: ctx, createdBefore, message, finishedAt
+func (_m *IRepository) FailQueued(ctx context.Context, createdBefore time.Time, message string, finishedAt time.Time) (int, error) {
+	ret := _m.Called(ctx, createdBefore, message, finishedAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FailQueued")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, string, time.Time) (int, error)); ok {
+		return rf(ctx, createdBefore, message, finishedAt)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, string, time.Time) int); ok {
+		r0 = rf(ctx, createdBefore, message, finishedAt)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, string, time.Time) error); ok {
+		r1 = rf(ctx, createdBefore, message, finishedAt)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FailStale provides a mock function with given fields: ctx, startedBefore, message, finishedAt
+func (_m *IRepository) FailStale(ctx context.Context, startedBefore time.Time, message string, finishedAt time.Time) (int, error) {
+	ret := _m.Called(ctx, startedBefore, message, finishedAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FailStale")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, string, time.Time) (int, error)); ok {
+		return rf(ctx, startedBefore, message, finishedAt)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, string, time.Time) int); ok {
+		r0 = rf(ctx, startedBefore, message, finishedAt)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, string, time.Time) error); ok {
+		r1 = rf(ctx, startedBefore, message, finishedAt)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindByID provides a mock function with given fields: ctx, ID
+func (_m *IRepository) FindByID(ctx context.Context, ID string) (*export_job.Model, error) {
+	ret := _m.Called(ctx, ID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindByID")
+	}
+
+	var r0 *export_job.Model
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*export_job.Model, error)); ok {
+		return rf(ctx, ID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *export_job.Model); ok {
+		r0 = rf(ctx, ID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*export_job.Model)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, ID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MarkRunning provides a mock function with given fields: ctx, ID, startedAt
+func (_m *IRepository) MarkRunning(ctx context.Context, ID string, startedAt time.Time) (bool, error) {
+	ret := _m.Called(ctx, ID, startedAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkRunning")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) (bool, error)); ok {
+		return rf(ctx, ID, startedAt)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) bool); ok {
+		r0 = rf(ctx, ID, startedAt)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, time.Time) error); ok {
+		r1 = rf(ctx, ID, startedAt)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Update provides a mock function with given fields: ctx, model
+func (_m *IRepository) Update(ctx context.Context, model *export_job.Model) error {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Update")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *export_job.Model) error); ok {
+		r0 = rf(ctx, model)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateProgress provides a mock function with given fields: ctx, ID, rows
+func (_m *IRepository) UpdateProgress(ctx context.Context, ID string, rows int) error {
+	ret := _m.Called(ctx, ID, rows)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateProgress")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) error); ok {
+		r0 = rf(ctx, ID, rows)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewIRepository creates a new instance of IRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewIRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *IRepository {
+	mock := &IRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,43 @@
+package export_job
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Job statuses
+const (
+	StatusQueued  = "queued"
+	StatusRunning = "running"
+	StatusReady   = "ready"
+	StatusFailed  = "failed"
+	StatusExpired = "expired"
+)
+
+// Model Database model for the asynchronous exports, their files can be downloaded until ExpiresAt
+type Model struct {
+	bun.BaseModel `bun:"table:export_jobs"`
+
+	ID          string          `bun:"id,pk"`
+	Status      string          `bun:"status"`
+	Format      string          `bun:"format"`
+	FileName    string          `bun:"file_name"`
+	ContentType string          `bun:"content_type"`
+	Filter      json.RawMessage `bun:"filter,type:jsonb"`
+	RequestedBy string          `bun:"requested_by"`
+	UserID      int             `bun:"user_id"`
+	TenantIDs   []int           `bun:"tenant_ids,array"`
+	RowCount    int             `bun:"row_count"`
+	SizeBytes   int64           `bun:"size_bytes"`
+	ObjectKey   string          `bun:"object_key,nullzero"`
+	SHA256      string          `bun:"sha256,nullzero"`
+	Manifest    string          `bun:"manifest,nullzero"`
+	Error       string          `bun:"error,nullzero"`
+	CreatedAt   *time.Time      `bun:"created_at"`
+	StartedAt   *time.Time      `bun:"started_at"`
+	FinishedAt  *time.Time      `bun:"finished_at"`
+	ExpiresAt   time.Time       `bun:"expires_at"`
+	ExpiredAt   *time.Time      `bun:"expired_at"`
+}
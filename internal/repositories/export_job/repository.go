@@ -0,0 +1,18 @@
+package export_job
+
+import (
+	"context"
+	"time"
+)
+
+// IRepository interface
+type IRepository interface {
+	FindByID(ctx context.Context, ID string) (*Model, error)
+	Create(ctx context.Context, model *Model) error
+	MarkRunning(ctx context.Context, ID string, startedAt time.Time) (bool, error)
+	Update(ctx context.Context, model *Model) error
+	UpdateProgress(ctx context.Context, ID string, rows int) error
+	FailQueued(ctx context.Context, createdBefore time.Time, message string, finishedAt time.Time) (int, error)
+	FailStale(ctx context.Context, startedBefore time.Time, message string, finishedAt time.Time) (int, error)
+	Expired(ctx context.Context, now time.Time) ([]Model, error)
+}
@@ -0,0 +1,116 @@
+package legal_hold
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/terrors"
+	"github.com/uptrace/bun"
+)
+
+// DatabaseRepository struct
+type DatabaseRepository struct {
+	log *logger.ContextLogger
+	db  *bun.DB
+}
+
+// NewDatabaseRepository creates an instance of DatabaseRepository
+func NewDatabaseRepository(l *logger.ContextLogger, conn *bun.DB) *DatabaseRepository {
+	return &DatabaseRepository{
+		log: l,
+		db:  conn,
+	}
+}
+
+// FindByID finds a legal hold by its ID
+func (r *DatabaseRepository) FindByID(ctx context.Context, ID string) (*Model, error) {
+	var hold Model
+	query := r.db.NewSelect().
+		Model(&hold).
+		Where("id = ?", ID)
+
+	if err := query.Scan(ctx); err != nil {
+		if err.Error() == sql.ErrNoRows.Error() {
+			return nil, terrors.New(terrors.ErrNotFound, "Legal hold not found", map[string]string{})
+		}
+		return nil, fmt.Errorf("legal_hold_repository: Error while searching for legal hold -> %v", err)
+	}
+
+	return &hold, nil
+}
+
+// FindByLog finds the active legal holds covering a log, the scope is matched by the log_legal_holds
+// database function so the holds reported are the same ones the deletion triggers enforce
+func (r *DatabaseRepository) FindByLog(ctx context.Context, logID string) ([]Model, error) {
+	var holds []Model
+	err := r.db.NewSelect().
+		Model(&holds).
+		ModelTableExpr("logs AS l, LATERAL log_legal_holds(l.tenant_id, l.user_id, l.resource, l.created_at) AS model").
+		Where("l.id = ?", logID).
+		Order("model.created_at ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("legal_hold_repository: Error while searching for log legal holds -> %v", err)
+	}
+
+	return holds, nil
+}
+
+// Create Handles the creation of a new legal hold record on a database
+func (r *DatabaseRepository) Create(ctx context.Context, model *Model) error {
+	_, err := r.db.NewInsert().
+		Model(model).
+		Exec(ctx)
+
+	return err
+}
+
+// Retrieve lists legal holds of the given tenants
+func (r *DatabaseRepository) Retrieve(ctx context.Context, filter Filter) ([]Model, int, error) {
+	var model []Model
+
+	query := r.db.NewSelect().Model(&model).
+		Where("tenant_id in (?)", bun.In(filter.TenantID)).
+		Order("created_at DESC").
+		Limit(filter.Size).
+		Offset(filter.From - 1)
+
+	if !filter.IncludeReleased {
+		query = query.Where("released_at IS NULL")
+	}
+
+	count, err := query.ScanAndCount(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return model, count, nil
+}
+
+// Update updates the scope and reason of an active legal hold
+func (r *DatabaseRepository) Update(ctx context.Context, model *Model) error {
+	_, err := r.db.NewUpdate().
+		Model(model).
+		Column("tenant_id", "user_id", "resource", "start_at", "end_at", "reason", "updated_at").
+		WherePK().
+		Where("released_at IS NULL").
+		Exec(ctx)
+
+	return err
+}
+
+// Release marks a legal hold as released, released holds are kept for auditing purposes
+func (r *DatabaseRepository) Release(ctx context.Context, ID string, releasedBy string, releasedAt time.Time) error {
+	_, err := r.db.NewUpdate().
+		Model((*Model)(nil)).
+		Set("released_by = ?", releasedBy).
+		Set("released_at = ?", releasedAt).
+		Where("id = ?", ID).
+		Where("released_at IS NULL").
+		Exec(ctx)
+
+	return err
+}
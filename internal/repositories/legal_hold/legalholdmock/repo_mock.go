@@ -0,0 +1,182 @@
+// Code generated by mockery v2.50.2. DO NOT EDIT.
+
+package legalholdmock
+
+import (
+	context "context"
+
+	legal_hold "github.com/jmontesinos91/omnilogger/internal/repositories/legal_hold"
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// IRepository is an autogenerated mock type for the IRepository type
+type IRepository struct {
+	mock.Mock
+}
+
+// Create provides a mock function with given fields: ctx, model
+func (_m *IRepository) Create(ctx context.Context, model *legal_hold.Model) error {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *legal_hold.Model) error); ok {
+		r0 = rf(ctx, model)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FindByID provides a mock function with given fields: ctx, ID
+func (_m *IRepository) FindByID(ctx context.Context, ID string) (*legal_hold.Model, error) {
+	ret := _m.Called(ctx, ID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindByID")
+	}
+
+	var r0 *legal_hold.Model
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*legal_hold.Model, error)); ok {
+		return rf(ctx, ID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *legal_hold.Model); ok {
+		r0 = rf(ctx, ID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*legal_hold.Model)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, ID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindByLog provides a mock function with given fields: ctx, logID
+func (_m *IRepository) FindByLog(ctx context.Context, logID string) ([]legal_hold.Model, error) {
+	ret := _m.Called(ctx, logID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindByLog")
+	}
+
+	var r0 []legal_hold.Model
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]legal_hold.Model, error)); ok {
+		return rf(ctx, logID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []legal_hold.Model); ok {
+		r0 = rf(ctx, logID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]legal_hold.Model)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, logID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Release provides a mock function with given fields: ctx, ID, releasedBy, releasedAt
+func (_m *IRepository) Release(ctx context.Context, ID string, releasedBy string, releasedAt time.Time) error {
+	ret := _m.Called(ctx, ID, releasedBy, releasedAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Release")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) error); ok {
+		r0 = rf(ctx, ID, releasedBy, releasedAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Retrieve provides a mock function with given fields: ctx, filter
+func (_m *IRepository) Retrieve(ctx context.Context, filter legal_hold.Filter) ([]legal_hold.Model, int, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Retrieve")
+	}
+
+	var r0 []legal_hold.Model
+	var r1 int
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, legal_hold.Filter) ([]legal_hold.Model, int, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, legal_hold.Filter) []legal_hold.Model); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]legal_hold.Model)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, legal_hold.Filter) int); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, legal_hold.Filter) error); ok {
+		r2 = rf(ctx, filter)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// Update provides a mock function with given fields: ctx, model
+func (_m *IRepository) Update(ctx context.Context, model *legal_hold.Model) error {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Update")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *legal_hold.Model) error); ok {
+		r0 = rf(ctx, model)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewIRepository creates a new instance of IRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewIRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *IRepository {
+	mock := &IRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
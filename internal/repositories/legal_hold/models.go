@@ -0,0 +1,32 @@
+package legal_hold
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Model Database model for legal holds, an empty user, resource or time bound covers every log of the tenant
+type Model struct {
+	bun.BaseModel `bun:"table:legal_holds"`
+
+	ID         string     `bun:"id,pk"`
+	TenantID   int        `bun:"tenant_id"`
+	UserID     string     `bun:"user_id,nullzero"`
+	Resource   string     `bun:"resource,nullzero"`
+	StartAt    *time.Time `bun:"start_at"`
+	EndAt      *time.Time `bun:"end_at"`
+	Reason     string     `bun:"reason"`
+	CreatedBy  string     `bun:"created_by"`
+	CreatedAt  *time.Time `bun:"created_at"`
+	UpdatedAt  *time.Time `bun:"updated_at"`
+	ReleasedBy string     `bun:"released_by,nullzero"`
+	ReleasedAt *time.Time `bun:"released_at"`
+}
+
+type Filter struct {
+	TenantID        []int
+	IncludeReleased bool
+	From            int
+	Size            int
+}
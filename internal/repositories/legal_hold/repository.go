@@ -0,0 +1,16 @@
+package legal_hold
+
+import (
+	"context"
+	"time"
+)
+
+// IRepository interface
+type IRepository interface {
+	FindByID(ctx context.Context, ID string) (*Model, error)
+	FindByLog(ctx context.Context, logID string) ([]Model, error)
+	Create(ctx context.Context, model *Model) error
+	Retrieve(ctx context.Context, filter Filter) ([]Model, int, error)
+	Update(ctx context.Context, model *Model) error
+	Release(ctx context.Context, ID string, releasedBy string, releasedAt time.Time) error
+}
@@ -0,0 +1,113 @@
+package logs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// archiveTenantsExpr tenants the log is archived under, every tenant of its tenant_id or 0 for logs without tenant,
+// a log shared by several tenants is written to the archive file of each of them
+const archiveTenantsExpr = "CASE WHEN jsonb_typeof(?TableAlias.tenant_id) = 'array' AND jsonb_array_length(?TableAlias.tenant_id) > 0" +
+	" THEN ?TableAlias.tenant_id ELSE '[0]'::jsonb END"
+
+// Conditions matching the logs whose day has been archived for every tenant they are archived under, or not yet
+// for some of them
+const (
+	unarchivedCondition = "EXISTS (SELECT 1 FROM jsonb_array_elements_text(" + archiveTenantsExpr + ") AS archive_tenant(id)" +
+		" WHERE NOT EXISTS (SELECT 1 FROM log_archives AS a WHERE a.tenant_id = archive_tenant.id::int AND a.day = ?TableAlias.created_at::date))"
+	archivedCondition = "NOT " + unarchivedCondition
+)
+
+// ArchiveDay logs of a tenant created on a day, the unit written to an archive file
+type ArchiveDay struct {
+	TenantID int       `bun:"tenant_id"`
+	Day      time.Time `bun:"day"`
+	Count    int       `bun:"count"`
+}
+
+// ArchivableDays tenant days with logs created before the given time not archived yet, oldest first, at most limit of them
+func (r *DatabaseRepository) ArchivableDays(ctx context.Context, before time.Time, limit int) ([]ArchiveDay, error) {
+	var days []ArchiveDay
+	err := r.db.NewSelect().
+		Model((*Model)(nil)).
+		Join("CROSS JOIN LATERAL jsonb_array_elements_text("+archiveTenantsExpr+") AS archive_tenant(id)").
+		ColumnExpr("archive_tenant.id::int AS tenant_id").
+		ColumnExpr("model.created_at::date AS day").
+		ColumnExpr("count(*) AS count").
+		Where("model.created_at < ?::TIMESTAMP", before).
+		Where("NOT EXISTS (SELECT 1 FROM log_archives AS a WHERE a.tenant_id = archive_tenant.id::int AND a.day = model.created_at::date)").
+		GroupExpr("1, 2").
+		OrderExpr("2 ASC, 1 ASC").
+		Limit(limit).
+		Scan(ctx, &days)
+	if err != nil {
+		return nil, fmt.Errorf("logs_repository: Error while searching for archivable days -> %v", err)
+	}
+
+	return days, nil
+}
+
+// EachDayLog calls fn with every log of the tenant day in creation order, the logs are streamed from the database
+func (r *DatabaseRepository) EachDayLog(ctx context.Context, tenantID int, day time.Time, fn func(Model) error) error {
+	rows, err := dayQuery(r.db.NewSelect().Model((*Model)(nil)), tenantID, day).
+		OrderExpr("created_at ASC, id ASC").
+		Rows(ctx)
+	if err != nil {
+		return fmt.Errorf("logs_repository: Error while reading the logs of the day -> %v", err)
+	}
+	defer rows.Close() //nolint:errcheck
+
+	for rows.Next() {
+		var model Model
+		if err := r.db.ScanRow(ctx, rows, &model); err != nil {
+			return fmt.Errorf("logs_repository: Error while reading the logs of the day -> %v", err)
+		}
+		if err := fn(model); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// PurgeArchivedDay deletes up to limit logs of an archived tenant day on the maintenance path, held logs and logs not
+// archived for every tenant yet are skipped and the purged chain ranges are recorded, it returns the number of logs deleted
+func (r *DatabaseRepository) PurgeArchivedDay(ctx context.Context, tenantID int, day time.Time, limit int) (int, error) {
+	var deleted int
+	err := r.maintenanceTx(ctx, func(ctx context.Context, tx bun.Tx) error {
+		// Logs shared with tenants whose day is not archived yet are kept until it is
+		batch := dayQuery(tx.NewSelect().Model((*Model)(nil)).Column("id"), tenantID, day).
+			Where(archivedCondition).
+			Where(notHeldCondition).
+			Limit(limit)
+
+		// The day bounds are repeated on the delete so it only scans the partition of the day
+		return purgeChainQuery(tx, tx.NewDelete().
+			Model((*Model)(nil)).
+			Where("created_at >= ?::TIMESTAMP", day).
+			Where("created_at < ?::TIMESTAMP", day.AddDate(0, 0, 1)).
+			Where("id IN (?)", batch)).
+			Scan(ctx, &deleted)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("logs_repository: Error while purging archived logs -> %v", err)
+	}
+
+	return deleted, nil
+}
+
+// dayQuery restricts the query to the logs archived under the tenant created on the day
+func dayQuery(query *bun.SelectQuery, tenantID int, day time.Time) *bun.SelectQuery {
+	if tenantID == 0 {
+		query = query.Where(archiveTenantsExpr + " = '[0]'::jsonb")
+	} else {
+		query = query.Where("?TableAlias.tenant_id @> ?::jsonb", fmt.Sprintf("[%d]", tenantID))
+	}
+
+	return query.
+		Where("created_at >= ?::TIMESTAMP", day).
+		Where("created_at < ?::TIMESTAMP", day.AddDate(0, 0, 1))
+}
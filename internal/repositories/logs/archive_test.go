@@ -0,0 +1,34 @@
+package logs
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+func TestDayQuery(t *testing.T) {
+	db := bun.NewDB(&sql.DB{}, pgdialect.New())
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name     string
+		tenantID int
+		expected string
+	}{
+		{name: "Tenant", tenantID: 5, expected: `"model".tenant_id @> '[5]'::jsonb`},
+		{name: "Logs without tenant", tenantID: 0, expected: `ELSE '[0]'::jsonb END = '[0]'::jsonb`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			query := dayQuery(db.NewSelect().Model((*Model)(nil)).Column("id"), tc.tenantID, day).String()
+
+			assert.Contains(t, query, tc.expected)
+			assert.Contains(t, query, `created_at >= '2024-03-01 00:00:00+00:00'::TIMESTAMP`)
+		})
+	}
+}
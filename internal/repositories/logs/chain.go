@@ -0,0 +1,218 @@
+package logs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// canonicalLog content covered by the hash of a log, fields are written in this order. jsonb columns are
+// normalized and times are written in UTC with the microsecond precision of the database so the hash
+// computed at insert time matches the one computed from the stored row
+type canonicalLog struct {
+	ID                 string          `json:"id"`
+	IpAddress          string          `json:"ip_address"`
+	ClientHost         string          `json:"client_host"`
+	Provider           string          `json:"provider"`
+	Level              int             `json:"level"`
+	Message            int             `json:"message"`
+	Description        string          `json:"description"`
+	Path               string          `json:"path"`
+	Resource           string          `json:"resource"`
+	Action             string          `json:"action"`
+	Data               string          `json:"data"`
+	OldData            string          `json:"old_data"`
+	TenantCat          json.RawMessage `json:"tenant_cat"`
+	TenantID           json.RawMessage `json:"tenant_id"`
+	UserID             string          `json:"user_id"`
+	Target             string          `json:"target"`
+	CreatedAt          string          `json:"created_at"`
+	OccurredAt         string          `json:"occurred_at"`
+	ClockSkew          bool            `json:"clock_skew"`
+	Labels             json.RawMessage `json:"labels"`
+	OriginIP           string          `json:"origin_ip"`
+	OriginForwardedFor string          `json:"origin_forwarded_for"`
+	OriginSubject      string          `json:"origin_subject"`
+	OriginTenantID     json.RawMessage `json:"origin_tenant_id"`
+	ChainTenantID      int             `json:"chain_tenant_id"`
+	ChainSeq           int64           `json:"chain_seq"`
+	PrevHash           string          `json:"prev_hash"`
+}
+
+// ComputeHash hex encoded sha256 of the canonical content of the log, including its link to the previous log
+func ComputeHash(model *Model) (string, error) {
+	// Logs without labels are stored with an empty object
+	labels, err := json.Marshal(model.Labels)
+	if model.Labels == nil {
+		labels, err = []byte("{}"), nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	canonical := canonicalLog{
+		ID:                 model.ID,
+		IpAddress:          model.IpAddress,
+		ClientHost:         model.ClientHost,
+		Provider:           model.Provider,
+		Level:              model.Level,
+		Message:            model.Message,
+		Description:        model.Description,
+		Path:               model.Path,
+		Resource:           model.Resource,
+		Action:             model.Action,
+		Data:               model.Data,
+		OldData:            model.OldData,
+		TenantCat:          canonicalJSON(model.TenantCat),
+		TenantID:           canonicalJSON(model.TenantID),
+		UserID:             model.UserID,
+		Target:             model.Target,
+		CreatedAt:          canonicalTime(model.CreatedAt),
+		OccurredAt:         canonicalTime(model.OccurredAt),
+		ClockSkew:          model.ClockSkew,
+		Labels:             canonicalJSON(string(labels)),
+		OriginIP:           model.OriginIP,
+		OriginForwardedFor: model.OriginForwardedFor,
+		OriginSubject:      model.OriginSubject,
+		OriginTenantID:     canonicalJSON(model.OriginTenantID),
+		ChainTenantID:      model.ChainTenantID,
+		ChainSeq:           model.ChainSeq,
+		PrevHash:           model.PrevHash,
+	}
+
+	content, err := json.Marshal(canonical)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(content)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ChainTenant tenant whose chain the log belongs to, the lowest of its tenants or 0 for logs without tenant
+func ChainTenant(model *Model) int {
+	var tenants []int
+	if err := json.Unmarshal([]byte(model.TenantID), &tenants); err != nil || len(tenants) == 0 {
+		return 0
+	}
+
+	lowest := tenants[0]
+	for _, id := range tenants[1:] {
+		if id < lowest {
+			lowest = id
+		}
+	}
+
+	return lowest
+}
+
+// TruncateTimes drops the precision the database does not store, it must be called before hashing a new log
+func TruncateTimes(model *Model) {
+	if model.CreatedAt != nil {
+		createdAt := model.CreatedAt.UTC().Truncate(time.Microsecond)
+		model.CreatedAt = &createdAt
+	}
+
+	if model.OccurredAt != nil {
+		occurredAt := model.OccurredAt.UTC().Truncate(time.Microsecond)
+		model.OccurredAt = &occurredAt
+	}
+}
+
+// canonicalJSON compacts a jsonb value the way it is read back, keys sorted and without spaces
+func canonicalJSON(raw string) json.RawMessage {
+	if strings.TrimSpace(raw) == "" {
+		return json.RawMessage("null")
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		// Not json, hash the raw text
+		quoted, _ := json.Marshal(raw)
+		return quoted
+	}
+
+	normalized, err := json.Marshal(jsonbNumbers(value))
+	if err != nil {
+		quoted, _ := json.Marshal(raw)
+		return quoted
+	}
+
+	return normalized
+}
+
+// jsonbNumbers rewrites the numbers of a decoded json value the way jsonb stores them
+func jsonbNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		return json.Number(jsonbNumber(string(v)))
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = jsonbNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = jsonbNumbers(item)
+		}
+	}
+
+	return value
+}
+
+// jsonbNumber writes a json number as a numeric is printed: without exponent, keeping the fraction digits written
+// by the client shifted by the exponent, and without sign for zero. 1e2 is 100, 1.50e1 is 15.0 and -0 is 0
+func jsonbNumber(number string) string {
+	mantissa, exponent := number, 0
+	if idx := strings.IndexAny(number, "eE"); idx >= 0 {
+		exp, err := strconv.Atoi(number[idx+1:])
+		if err != nil {
+			return number
+		}
+		mantissa, exponent = number[:idx], exp
+	}
+
+	negative := strings.HasPrefix(mantissa, "-")
+	mantissa = strings.TrimPrefix(mantissa, "-")
+	integer, fraction, _ := strings.Cut(mantissa, ".")
+
+	// The point moves by the exponent over the digits, zeros are added past either end
+	digits := integer + fraction
+	point := len(integer) + exponent
+	if point <= 0 {
+		digits = strings.Repeat("0", 1-point) + digits
+		point = 1
+	}
+	if point > len(digits) {
+		digits += strings.Repeat("0", point-len(digits))
+	}
+
+	integer = strings.TrimLeft(digits[:point], "0")
+	if integer == "" {
+		integer = "0"
+	}
+	result := integer
+	if fraction = digits[point:]; fraction != "" {
+		result += "." + fraction
+	}
+
+	if negative && strings.Trim(digits, "0") != "" {
+		result = "-" + result
+	}
+
+	return result
+}
+
+func canonicalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+
+	return t.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano)
+}
@@ -0,0 +1,62 @@
+package logs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/uptrace/bun"
+)
+
+// purgeChainSQL runs the purge and records the chain entries it removes as ranges of consecutive entries per tenant,
+// in the same statement so no entry leaves the chain unrecorded. Logs created before the hash chain have no entry
+// to record. It returns the number of logs purged
+const purgeChainSQL = `WITH purged AS (?),
+islands AS (
+    SELECT chain_tenant_id, chain_seq, prev_hash, hash,
+        chain_seq - row_number() OVER (PARTITION BY chain_tenant_id ORDER BY chain_seq) AS island
+    FROM purged
+    WHERE chain_seq IS NOT NULL
+),
+recorded AS (
+    INSERT INTO log_chain_purges (tenant_id, from_seq, to_seq, prev_hash, hash, purged_at)
+    SELECT chain_tenant_id, min(chain_seq), max(chain_seq),
+        coalesce((array_agg(prev_hash ORDER BY chain_seq ASC))[1], ''),
+        coalesce((array_agg(hash ORDER BY chain_seq DESC))[1], ''),
+        now() AT TIME ZONE 'UTC'
+    FROM islands
+    GROUP BY chain_tenant_id, island
+)
+SELECT count(*) FROM purged`
+
+// ChainPurges purged ranges of the tenant chain overlapping the entries from fromSeq to toSeq, in chain order
+func (r *DatabaseRepository) ChainPurges(ctx context.Context, tenantID int, fromSeq, toSeq int64) ([]ChainPurge, error) {
+	var purges []ChainPurge
+
+	err := r.db.NewSelect().
+		Model(&purges).
+		Where("tenant_id = ?", tenantID).
+		Where("to_seq >= ?", fromSeq).
+		Where("from_seq <= ?", toSeq).
+		OrderExpr("from_seq ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("logs_repository: Error while reading the purged chain ranges -> %v", err)
+	}
+
+	return purges, nil
+}
+
+// chainColumns columns of the logs read to record their chain entries
+const chainColumns = "chain_tenant_id, chain_seq, prev_hash, hash"
+
+// purgeChainQuery deletes the logs of the delete query recording the chain ranges it removes, callers run it on the
+// maintenance path
+func purgeChainQuery(db bun.IDB, purged *bun.DeleteQuery) *bun.RawQuery {
+	return db.NewRaw(purgeChainSQL, purged.Returning(chainColumns))
+}
+
+// recordChainQuery records the chain ranges of the logs of the select query, for logs removed without a delete
+// such as the ones of a retired partition
+func recordChainQuery(db bun.IDB, removed *bun.SelectQuery) *bun.RawQuery {
+	return db.NewRaw(purgeChainSQL, removed.ColumnExpr(chainColumns))
+}
@@ -0,0 +1,138 @@
+package logs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeHash(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 10, 0, 0, 123456789, time.UTC)
+	base := func() *Model {
+		at := createdAt
+		return &Model{
+			ID:        "c1a0d4a6-0000-4000-8000-000000000001",
+			Provider:  "billing",
+			Level:     1,
+			Message:   2,
+			Data:      `{"amount": 10}`,
+			OldData:   `{}`,
+			TenantCat: `[{"id": 1, "name": "Acme"}]`,
+			TenantID:  "[1]",
+			CreatedAt: &at,
+			Labels:    map[string]string{"env": "prod"},
+			ChainSeq:  2,
+			PrevHash:  "abc",
+		}
+	}
+
+	hash, err := ComputeHash(base())
+	assert.NoError(t, err)
+	assert.Len(t, hash, 64)
+
+	again, err := ComputeHash(base())
+	assert.NoError(t, err)
+	assert.Equal(t, hash, again)
+
+	t.Run("Stored row hashes the same", func(t *testing.T) {
+		// jsonb columns come back reformatted and timestamps with microsecond precision
+		stored := base()
+		stored.TenantCat = `[{"name":"Acme","id":1}]`
+		stored.TenantID = "[1]"
+		storedAt := createdAt.Truncate(time.Microsecond)
+		stored.CreatedAt = &storedAt
+
+		storedHash, err := ComputeHash(stored)
+		assert.NoError(t, err)
+		assert.Equal(t, hash, storedHash)
+	})
+
+	t.Run("Stored numbers hash the same", func(t *testing.T) {
+		// jsonb prints numbers without exponent nor negative zero
+		written := base()
+		written.TenantCat = `[{"id": 1e0, "name": "Acme", "rank": -0, "score": 1.50e1}]`
+		stored := base()
+		stored.TenantCat = `[{"id": 1, "name": "Acme", "rank": 0, "score": 15.0}]`
+
+		writtenHash, err := ComputeHash(written)
+		assert.NoError(t, err)
+		storedHash, err := ComputeHash(stored)
+		assert.NoError(t, err)
+		assert.Equal(t, storedHash, writtenHash)
+	})
+
+	t.Run("Nil labels hash as empty labels", func(t *testing.T) {
+		withNil := base()
+		withNil.Labels = nil
+		withEmpty := base()
+		withEmpty.Labels = map[string]string{}
+
+		nilHash, _ := ComputeHash(withNil)
+		emptyHash, _ := ComputeHash(withEmpty)
+		assert.Equal(t, emptyHash, nilHash)
+	})
+
+	changes := map[string]func(m *Model){
+		"data":      func(m *Model) { m.Data = `{"amount": 11}` },
+		"level":     func(m *Model) { m.Level = 3 },
+		"labels":    func(m *Model) { m.Labels["env"] = "dev" },
+		"prev hash": func(m *Model) { m.PrevHash = "abd" },
+		"sequence":  func(m *Model) { m.ChainSeq = 3 },
+		"created":   func(m *Model) { at := createdAt.Add(time.Second); m.CreatedAt = &at },
+	}
+	for name, change := range changes {
+		t.Run("Changed "+name, func(t *testing.T) {
+			model := base()
+			change(model)
+			changed, err := ComputeHash(model)
+			assert.NoError(t, err)
+			assert.NotEqual(t, hash, changed)
+		})
+	}
+}
+
+func TestJSONBNumber(t *testing.T) {
+	cases := map[string]string{
+		"100":      "100",
+		"1e2":      "100",
+		"1E+2":     "100",
+		"1.5e1":    "15",
+		"1.50e1":   "15.0",
+		"1.0":      "1.0",
+		"1e-2":     "0.01",
+		"12.5e-3":  "0.0125",
+		"-0":       "0",
+		"-0.0":     "0.0",
+		"-0e5":     "0",
+		"-1.25":    "-1.25",
+		"0.5":      "0.5",
+		"123e-1":   "12.3",
+		"25e-2":    "0.25",
+		"-2.5E+3":  "-2500",
+		"1.2345e2": "123.45",
+	}
+
+	for number, expected := range cases {
+		t.Run(number, func(t *testing.T) {
+			assert.Equal(t, expected, jsonbNumber(number))
+		})
+	}
+}
+
+func TestChainTenant(t *testing.T) {
+	assert.Equal(t, 2, ChainTenant(&Model{TenantID: "[5, 2, 9]"}))
+	assert.Equal(t, 7, ChainTenant(&Model{TenantID: "[7]"}))
+	assert.Equal(t, 0, ChainTenant(&Model{TenantID: ""}))
+	assert.Equal(t, 0, ChainTenant(&Model{TenantID: "[]"}))
+}
+
+func TestTruncateTimes(t *testing.T) {
+	at := time.Date(2024, 5, 1, 10, 0, 0, 123456789, time.FixedZone("CST", -6*3600))
+	model := &Model{CreatedAt: &at, OccurredAt: &at}
+
+	TruncateTimes(model)
+
+	assert.Equal(t, time.Date(2024, 5, 1, 16, 0, 0, 123456000, time.UTC), *model.CreatedAt)
+	assert.Equal(t, time.UTC, model.OccurredAt.Location())
+}
@@ -3,13 +3,21 @@ package logs
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"maps"
+	"slices"
+	"time"
+
 	"github.com/jmontesinos91/ologs/logger"
 	"github.com/jmontesinos91/osecurity/sts"
 	"github.com/jmontesinos91/terrors"
 	"github.com/uptrace/bun"
 )
 
+// maintenanceSetting transaction setting checked by the append-only triggers of the logs table
+const maintenanceSetting = "omnilogger.maintenance"
+
 // DatabaseRepository struct
 type DatabaseRepository struct {
 	log *logger.ContextLogger
@@ -43,17 +51,57 @@ func (r *DatabaseRepository) FindByID(ctx context.Context, ID *string, filter Fi
 	return &payout, nil
 }
 
-// Create Handles the creation of a new log record on a database
+// Create Handles the creation of a new log record on a database, the log is appended to the hash chain
+// of its tenant while the chain head is locked so concurrent inserts never link to the same entry
 func (r *DatabaseRepository) Create(ctx context.Context, model *Model) error {
-	_, err := r.db.NewInsert().
-		Model(model).
-		Exec(ctx)
+	TruncateTimes(model)
+	model.ChainTenantID = ChainTenant(model)
+
+	return r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		head := &ChainHead{TenantID: model.ChainTenantID, UpdatedAt: time.Now().UTC()}
+
+		_, err := tx.NewInsert().
+			Model(head).
+			On("CONFLICT (tenant_id) DO NOTHING").
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = tx.NewSelect().
+			Model(head).
+			WherePK().
+			For("UPDATE").
+			Scan(ctx)
+		if err != nil {
+			return err
+		}
+
+		model.ChainSeq = head.Seq + 1
+		model.PrevHash = head.Hash
+		model.Hash, err = ComputeHash(model)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.NewInsert().
+			Model(model).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		head.Seq = model.ChainSeq
+		head.Hash = model.Hash
+		head.UpdatedAt = time.Now().UTC()
+
+		_, err = tx.NewUpdate().
+			Model(head).
+			WherePK().
+			Exec(ctx)
 
-	// Handling error
-	if err != nil {
 		return err
-	}
-	return nil
+	})
 }
 
 func (r *DatabaseRepository) Retrieve(ctx context.Context, filter Filter) ([]Model, int, error) {
@@ -65,79 +113,123 @@ func (r *DatabaseRepository) Retrieve(ctx context.Context, filter Filter) ([]Mod
 		Relation("LogMessage", func(q *bun.SelectQuery) *bun.SelectQuery {
 			return q.Where("model.lang = ?", filter.Lang)
 		}).
-		Order("created_at DESC").
+		Order(sortColumn(filter) + " DESC").
 		Limit(filter.Size).
 		Offset(filter.From - 1)
+	query = source(query, filter)
 
-	if len(filter.Message) > 0 {
-		query = query.Where("message in (?)", bun.In(filter.Message))
+	query, ok := applyFilter(query, filter, userTenantsID)
+	if !ok {
+		return model, 0, nil
 	}
 
-	if len(filter.Level) > 0 {
-		query = query.Where("level in (?)", bun.In(filter.Level))
+	if err := query.Scan(ctx); err != nil {
+		return nil, 0, err
 	}
 
-	if len(filter.Provider) > 0 {
-		query = query.Where("provider in (?)", bun.In(filter.Provider))
+	count, _ := query.Count(ctx)
+
+	return model, count, nil
+}
+
+// ChainBounds first and last sequence of the tenant chain entries created between from and to, zero times leave
+// the range open and a zero last sequence means there are no entries
+func (r *DatabaseRepository) ChainBounds(ctx context.Context, tenantID int, from, to time.Time) (int64, int64, error) {
+	var bounds struct {
+		First sql.NullInt64 `bun:"first"`
+		Last  sql.NullInt64 `bun:"last"`
 	}
 
-	if len(filter.Action) > 0 {
-		query = query.Where("action in (?)", bun.In(filter.Action))
+	query := r.db.NewSelect().
+		Model((*Model)(nil)).
+		ColumnExpr("min(chain_seq) AS first, max(chain_seq) AS last").
+		Where("chain_tenant_id = ?", tenantID).
+		Where("chain_seq IS NOT NULL")
+
+	if !from.IsZero() {
+		query = query.Where("created_at >= ?", from)
 	}
 
-	if filter.Path != "" {
-		query = query.Where("path like LOWER(?)", "%"+filter.Path+"%")
+	if !to.IsZero() {
+		query = query.Where("created_at <= ?", to)
 	}
 
-	if filter.Resource != "" {
-		query = query.Where("resource like UPPER(?)", "%"+filter.Resource+"%")
+	if err := query.Scan(ctx, &bounds); err != nil {
+		return 0, 0, err
 	}
 
-	if len(filter.TenantID) > 0 {
-		allowedTenantsIds := filterAllowedTenants(userTenantsID, filter.TenantID)
+	return bounds.First.Int64, bounds.Last.Int64, nil
+}
 
-		if len(allowedTenantsIds) == 0 {
-			return model, 0, nil
-		}
+// ChainEntries entries of the tenant chain after afterSeq up to lastSeq in chain order, at most limit of them
+func (r *DatabaseRepository) ChainEntries(ctx context.Context, tenantID int, afterSeq, lastSeq int64, limit int) ([]Model, error) {
+	var model []Model
 
-		conditions := buildQueryTenants(allowedTenantsIds, "OR")
-		query = query.Where(conditions)
+	err := r.db.NewSelect().
+		Model(&model).
+		Where("chain_tenant_id = ?", tenantID).
+		Where("chain_seq > ?", afterSeq).
+		Where("chain_seq <= ?", lastSeq).
+		OrderExpr("chain_seq ASC").
+		Limit(limit).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(filter.UserID) > 0 {
-		query = query.Where("user_id in (?)", bun.In(filter.UserID))
+	return model, nil
+}
+
+// LabelFacets counts the logs matching the filter per label value, at most size values are returned per label key
+func (r *DatabaseRepository) LabelFacets(ctx context.Context, filter Filter, keys []string, size int) ([]LabelFacet, error) {
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+	userTenantsID := claims.Tenants
+
+	facets := []LabelFacet{}
+
+	counts := r.db.NewSelect().Model((*Model)(nil)).
+		ColumnExpr("lbl.key AS key, lbl.value AS value, count(*) AS count").
+		ColumnExpr("row_number() OVER (PARTITION BY lbl.key ORDER BY count(*) DESC, lbl.value) AS rank").
+		Join("CROSS JOIN LATERAL jsonb_each_text(model.labels) AS lbl").
+		GroupExpr("lbl.key, lbl.value")
+
+	counts, ok := applyFilter(counts, filter, userTenantsID)
+	if !ok {
+		return facets, nil
 	}
 
-	if !filter.StartAt.IsZero() && !filter.EndAt.IsZero() {
-		query = query.Where("created_at::TIMESTAMP BETWEEN TIMESTAMP ? AND TIMESTAMP ?", filter.StartAt, filter.EndAt)
+	if len(keys) > 0 {
+		counts = counts.Where("lbl.key IN (?)", bun.In(keys))
 	}
 
-	conditions := buildQueryTenants(userTenantsID, "OR")
-	query = query.Where(conditions)
+	query := r.db.NewSelect().
+		TableExpr("(?) AS facets", counts).
+		ColumnExpr("key, value, count").
+		Where("rank <= ?", size).
+		OrderExpr("key ASC, count DESC, value ASC")
 
-	if err := query.Scan(ctx); err != nil {
-		return nil, 0, err
+	if err := query.Scan(ctx, &facets); err != nil {
+		return nil, err
 	}
 
-	count, _ := query.Count(ctx)
-
-	return model, count, nil
+	return facets, nil
 }
 
-func (r *DatabaseRepository) Export(ctx context.Context, filter Filter) ([]Model, error) {
-	claims := ctx.Value(&sts.Claim).(sts.Claims)
-	userTenantsID := claims.Tenants
-
-	var model []Model
+// maintenanceTx runs fn on the maintenance path, the only path allowed to update or delete logs, it requires
+// the connected role to be granted omnilogger_maintenance and is meant for retention jobs only. Held logs are
+// still protected by the legal hold triggers.
+func (r *DatabaseRepository) maintenanceTx(ctx context.Context, fn func(ctx context.Context, tx bun.Tx) error) error {
+	return r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.ExecContext(ctx, "SELECT set_config(?, 'on', true)", maintenanceSetting); err != nil {
+			return fmt.Errorf("logs_repository: Error while enabling the maintenance path -> %v", err)
+		}
 
-	query := r.db.NewSelect().Model(&model).
-		Relation("LogMessage", func(q *bun.SelectQuery) *bun.SelectQuery {
-			return q.Where("model.lang = ?", filter.Lang)
-		}).
-		Order("created_at DESC").
-		Limit(filter.Size).
-		Offset(filter.From - 1)
+		return fn(ctx, tx)
+	})
+}
 
+// applyFilter adds the filter conditions to the query, it returns false when none of the requested tenants is allowed
+func applyFilter(query *bun.SelectQuery, filter Filter, userTenantsID []int) (*bun.SelectQuery, bool) {
 	if len(filter.Message) > 0 {
 		query = query.Where("message in (?)", bun.In(filter.Message))
 	}
@@ -166,7 +258,7 @@ func (r *DatabaseRepository) Export(ctx context.Context, filter Filter) ([]Model
 		allowedTenantsIds := filterAllowedTenants(userTenantsID, filter.TenantID)
 
 		if len(allowedTenantsIds) == 0 {
-			return model, nil
+			return query, false
 		}
 
 		conditions := buildQueryTenants(allowedTenantsIds, "OR")
@@ -178,17 +270,62 @@ func (r *DatabaseRepository) Export(ctx context.Context, filter Filter) ([]Model
 	}
 
 	if !filter.StartAt.IsZero() && !filter.EndAt.IsZero() {
-		query = query.Where("created_at::TIMESTAMP BETWEEN TIMESTAMP ? AND TIMESTAMP ?", filter.StartAt, filter.EndAt)
+		// The column is compared as is against constant bounds so ranges on created_at prune the partitions
+		query = query.Where("? BETWEEN ?::TIMESTAMP AND ?::TIMESTAMP", bun.Ident(dateColumn(filter)), filter.StartAt, filter.EndAt)
+	}
+
+	if filter.ClockSkew != nil {
+		query = query.Where("clock_skew = ?", *filter.ClockSkew)
 	}
 
+	query = applyLabels(query, filter.Labels)
+
 	conditions := buildQueryTenants(userTenantsID, "OR")
 	query = query.Where(conditions)
 
-	if err := query.Scan(ctx); err != nil {
-		return nil, err
+	return query, true
+}
+
+// applyLabels adds a containment condition per label key so the GIN index on labels is used
+func applyLabels(query *bun.SelectQuery, labels map[string][]string) *bun.SelectQuery {
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		values := labels[key]
+		if len(values) == 0 {
+			continue
+		}
+
+		query = query.WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			for _, value := range values {
+				q = q.WhereOr("labels @> ?::jsonb", labelContainment(key, value))
+			}
+			return q
+		})
 	}
 
-	return model, nil
+	return query
+}
+
+func labelContainment(key, value string) string {
+	containment, _ := json.Marshal(map[string]string{key: value})
+	return string(containment)
+}
+
+// dateColumn column used by the date range filter, created_at unless occurred_at is requested
+func dateColumn(filter Filter) string {
+	if filter.DateField == "occurred_at" {
+		return "occurred_at"
+	}
+
+	return "created_at"
+}
+
+// sortColumn column used to sort, created_at unless occurred_at is requested
+func sortColumn(filter Filter) string {
+	if filter.SortBy == "occurred_at" {
+		return "occurred_at"
+	}
+
+	return "created_at"
 }
 
 func buildQueryTenants(tenants []int, operator string) string {
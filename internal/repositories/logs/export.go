@@ -0,0 +1,137 @@
+package logs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmontesinos91/omnilogger/internal/repositories/log_message"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/uptrace/bun"
+)
+
+// EachExportLog calls fn with every log matching the filter in the order of the search, the logs are streamed from
+// a database cursor so the number of logs exported does not change the memory used. The messages of the filter
+// language are attached to the logs the way the LogMessage relation does
+func (r *DatabaseRepository) EachExportLog(ctx context.Context, filter Filter, fn func(Model) error) error {
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	query, ok := exportQuery(r.db, filter, claims.Tenants)
+	if !ok {
+		return nil
+	}
+
+	// The has-many relation can not be loaded on a cursor, the messages of a language are a bounded catalog
+	var messages []*log_message.Model
+	if err := r.db.NewSelect().Model(&messages).Where("lang = ?", filter.Lang).Scan(ctx); err != nil {
+		return fmt.Errorf("logs_repository: Error while reading the log messages -> %v", err)
+	}
+	messagesByID := make(map[int][]*log_message.Model, len(messages))
+	for _, message := range messages {
+		messagesByID[message.ID] = append(messagesByID[message.ID], message)
+	}
+
+	rows, err := query.Rows(ctx)
+	if err != nil {
+		return fmt.Errorf("logs_repository: Error while exporting the logs -> %v", err)
+	}
+	defer rows.Close() //nolint:errcheck
+
+	for rows.Next() {
+		var model Model
+		if err := r.db.ScanRow(ctx, rows, &model); err != nil {
+			return fmt.Errorf("logs_repository: Error while exporting the logs -> %v", err)
+		}
+		model.LogMessage = messagesByID[model.Message]
+
+		if err := fn(model); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// ExportLabelKeys sorted label keys used by any of the logs matching the filter, the columns of the exports are
+// known before the first log is written
+func (r *DatabaseRepository) ExportLabelKeys(ctx context.Context, filter Filter) ([]string, error) {
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	keys := []string{}
+
+	query, ok := labelKeysQuery(r.db, filter, claims.Tenants)
+	if !ok {
+		return keys, nil
+	}
+
+	if err := query.Scan(ctx, &keys); err != nil {
+		return nil, fmt.Errorf("logs_repository: Error while reading the label keys -> %v", err)
+	}
+
+	return keys, nil
+}
+
+// ExportDataKeys sorted keys of the json objects of the data and old data of the logs matching the filter, the
+// expanded exports have a before and an after column per key
+func (r *DatabaseRepository) ExportDataKeys(ctx context.Context, filter Filter) ([]string, error) {
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	keys := []string{}
+
+	query, ok := dataKeysQuery(r.db, filter, claims.Tenants)
+	if !ok {
+		return keys, nil
+	}
+
+	if err := query.Scan(ctx, &keys); err != nil {
+		return nil, fmt.Errorf("logs_repository: Error while reading the data keys -> %v", err)
+	}
+
+	return keys, nil
+}
+
+// exportQuery builds the search of the exported logs, it returns false when none of the requested tenants is
+// allowed. A zero size exports every log
+func exportQuery(db bun.IDB, filter Filter, userTenantsID []int) (*bun.SelectQuery, bool) {
+	query := db.NewSelect().Model((*Model)(nil)).
+		Order(sortColumn(filter) + " DESC")
+	if filter.Size > 0 {
+		query = query.Limit(filter.Size).Offset(filter.From - 1)
+	}
+	query = source(query, filter)
+
+	return applyFilter(query, filter, userTenantsID)
+}
+
+// labelKeysQuery builds the distinct label keys of the logs the export query reads
+func labelKeysQuery(db bun.IDB, filter Filter, userTenantsID []int) (*bun.SelectQuery, bool) {
+	exported, ok := exportQuery(db, filter, userTenantsID)
+	if !ok {
+		return nil, false
+	}
+
+	query := db.NewSelect().
+		TableExpr("(?) AS exported", exported.ColumnExpr("labels")).
+		ColumnExpr("DISTINCT jsonb_object_keys(labels) AS key").
+		Where("jsonb_typeof(labels) = 'object'").
+		OrderExpr("key ASC")
+
+	return query, true
+}
+
+// dataKeysQuery builds the distinct keys of the data and old data of the logs the export query reads, values that
+// are not json objects have no keys
+func dataKeysQuery(db bun.IDB, filter Filter, userTenantsID []int) (*bun.SelectQuery, bool) {
+	exported, ok := exportQuery(db, filter, userTenantsID)
+	if !ok {
+		return nil, false
+	}
+
+	query := db.NewSelect().
+		TableExpr("(?) AS exported", exported.ColumnExpr("data, old_data")).
+		TableExpr("LATERAL (VALUES (data), (old_data)) AS payload(value)").
+		ColumnExpr("DISTINCT json_object_keys(payload.value) AS key").
+		Where("json_typeof(payload.value) = 'object'").
+		OrderExpr("key ASC")
+
+	return query, true
+}
@@ -0,0 +1,79 @@
+package logs
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+func TestExportQuery(t *testing.T) {
+	db := bun.NewDB(&sql.DB{}, pgdialect.New())
+
+	cases := []struct {
+		name       string
+		filter     Filter
+		expected   []string
+		unexpected []string
+		denied     bool
+	}{
+		{
+			name:       "Every log",
+			filter:     Filter{Level: []string{"3"}, From: 1},
+			expected:   []string{`FROM "logs" AS "model"`, `(level in ('3'))`, `ORDER BY "created_at" DESC`},
+			unexpected: []string{"LIMIT", "OFFSET"},
+		},
+		{
+			name:     "Page",
+			filter:   Filter{Size: 100, From: 201},
+			expected: []string{`LIMIT 100 OFFSET 200`},
+		},
+		{
+			name:     "Archived logs",
+			filter:   Filter{Archived: true, From: 1},
+			expected: []string{`FROM restored_logs AS model`},
+		},
+		{
+			name:   "Tenant not allowed",
+			filter: Filter{TenantID: []int{3}, From: 1},
+			denied: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			query, ok := exportQuery(db, tc.filter, []int{1, 2})
+			keysQuery, keysOk := labelKeysQuery(db, tc.filter, []int{1, 2})
+			dataQuery, dataOk := dataKeysQuery(db, tc.filter, []int{1, 2})
+			assert.Equal(t, !tc.denied, ok)
+			assert.Equal(t, !tc.denied, keysOk)
+			assert.Equal(t, !tc.denied, dataOk)
+			if tc.denied {
+				return
+			}
+
+			sql := query.String()
+			for _, part := range tc.expected {
+				assert.Contains(t, sql, part)
+			}
+			for _, part := range tc.unexpected {
+				assert.NotContains(t, sql, part)
+			}
+
+			keysSQL := keysQuery.String()
+			assert.Contains(t, keysSQL, `SELECT DISTINCT jsonb_object_keys(labels) AS key FROM (SELECT labels FROM`)
+			for _, part := range tc.expected {
+				assert.Contains(t, keysSQL, part)
+			}
+
+			dataSQL := dataQuery.String()
+			assert.Contains(t, dataSQL, `SELECT DISTINCT json_object_keys(payload.value) AS key FROM (SELECT data, old_data FROM`)
+			assert.Contains(t, dataSQL, `LATERAL (VALUES (data), (old_data)) AS payload(value)`)
+			for _, part := range tc.expected {
+				assert.Contains(t, dataSQL, part)
+			}
+		})
+	}
+}
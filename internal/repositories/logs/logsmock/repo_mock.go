@@ -4,9 +4,11 @@ package logsmock
 
 import (
 	context "context"
-	"github.com/jmontesinos91/omnilogger/internal/repositories/logs"
 
+	logs "github.com/jmontesinos91/omnilogger/internal/repositories/logs"
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // IRepository is an autogenerated mock type for the IRepository type
@@ -14,6 +16,159 @@ type IRepository struct {
 	mock.Mock
 }
 
+// ArchivableDays provides a mock function with given fields: ctx, before, limit
+func (_m *IRepository) ArchivableDays(ctx context.Context, before time.Time, limit int) ([]logs.ArchiveDay, error) {
+	ret := _m.Called(ctx, before, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ArchivableDays")
+	}
+
+	var r0 []logs.ArchiveDay
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) ([]logs.ArchiveDay, error)); ok {
+		return rf(ctx, before, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) []logs.ArchiveDay); ok {
+		r0 = rf(ctx, before, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]logs.ArchiveDay)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, int) error); ok {
+		r1 = rf(ctx, before, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ChainBounds provides a mock function with given fields: ctx, tenantID, from, to
+func (_m *IRepository) ChainBounds(ctx context.Context, tenantID int, from time.Time, to time.Time) (int64, int64, error) {
+	ret := _m.Called(ctx, tenantID, from, to)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ChainBounds")
+	}
+
+	var r0 int64
+	var r1 int64
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Time, time.Time) (int64, int64, error)); ok {
+		return rf(ctx, tenantID, from, to)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Time, time.Time) int64); ok {
+		r0 = rf(ctx, tenantID, from, to)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, time.Time, time.Time) int64); ok {
+		r1 = rf(ctx, tenantID, from, to)
+	} else {
+		r1 = ret.Get(1).(int64)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, int, time.Time, time.Time) error); ok {
+		r2 = rf(ctx, tenantID, from, to)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ChainEntries provides a mock function with given fields: ctx, tenantID, afterSeq, lastSeq, limit
+func (_m *IRepository) ChainEntries(ctx context.Context, tenantID int, afterSeq int64, lastSeq int64, limit int) ([]logs.Model, error) {
+	ret := _m.Called(ctx, tenantID, afterSeq, lastSeq, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ChainEntries")
+	}
+
+	var r0 []logs.Model
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int64, int64, int) ([]logs.Model, error)); ok {
+		return rf(ctx, tenantID, afterSeq, lastSeq, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int64, int64, int) []logs.Model); ok {
+		r0 = rf(ctx, tenantID, afterSeq, lastSeq, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]logs.Model)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int64, int64, int) error); ok {
+		r1 = rf(ctx, tenantID, afterSeq, lastSeq, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ChainPurges provides a mock function with given fields: ctx, tenantID, fromSeq, toSeq
+func (_m *IRepository) ChainPurges(ctx context.Context, tenantID int, fromSeq int64, toSeq int64) ([]logs.ChainPurge, error) {
+	ret := _m.Called(ctx, tenantID, fromSeq, toSeq)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ChainPurges")
+	}
+
+	var r0 []logs.ChainPurge
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int64, int64) ([]logs.ChainPurge, error)); ok {
+		return rf(ctx, tenantID, fromSeq, toSeq)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int64, int64) []logs.ChainPurge); ok {
+		r0 = rf(ctx, tenantID, fromSeq, toSeq)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]logs.ChainPurge)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int64, int64) error); ok {
+		r1 = rf(ctx, tenantID, fromSeq, toSeq)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CountExpired provides a mock function with given fields: ctx, rules, now, archivedOnly
+func (_m *IRepository) CountExpired(ctx context.Context, rules []logs.RetentionRule, now time.Time, archivedOnly bool) (logs.RetentionCount, error) {
+	ret := _m.Called(ctx, rules, now, archivedOnly)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountExpired")
+	}
+
+	var r0 logs.RetentionCount
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []logs.RetentionRule, time.Time, bool) (logs.RetentionCount, error)); ok {
+		return rf(ctx, rules, now, archivedOnly)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []logs.RetentionRule, time.Time, bool) logs.RetentionCount); ok {
+		r0 = rf(ctx, rules, now, archivedOnly)
+	} else {
+		r0 = ret.Get(0).(logs.RetentionCount)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []logs.RetentionRule, time.Time, bool) error); ok {
+		r1 = rf(ctx, rules, now, archivedOnly)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Create provides a mock function with given fields: ctx, model
 func (_m *IRepository) Create(ctx context.Context, model *logs.Model) error {
 	ret := _m.Called(ctx, model)
@@ -32,6 +187,120 @@ func (_m *IRepository) Create(ctx context.Context, model *logs.Model) error {
 	return r0
 }
 
+// CreatePartition provides a mock function with given fields: ctx, partition
+func (_m *IRepository) CreatePartition(ctx context.Context, partition logs.Partition) error {
+	ret := _m.Called(ctx, partition)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreatePartition")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, logs.Partition) error); ok {
+		r0 = rf(ctx, partition)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// EachDayLog provides a mock function with given fields: ctx, tenantID, day, fn
+func (_m *IRepository) EachDayLog(ctx context.Context, tenantID int, day time.Time, fn func(logs.Model) error) error {
+	ret := _m.Called(ctx, tenantID, day, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EachDayLog")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Time, func(logs.Model) error) error); ok {
+		r0 = rf(ctx, tenantID, day, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// EachExportLog provides a mock function with given fields: ctx, filter, fn
+func (_m *IRepository) EachExportLog(ctx context.Context, filter logs.Filter, fn func(logs.Model) error) error {
+	ret := _m.Called(ctx, filter, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EachExportLog")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, logs.Filter, func(logs.Model) error) error); ok {
+		r0 = rf(ctx, filter, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ExportDataKeys provides a mock function with given fields: ctx, filter
+func (_m *IRepository) ExportDataKeys(ctx context.Context, filter logs.Filter) ([]string, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ExportDataKeys")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, logs.Filter) ([]string, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, logs.Filter) []string); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, logs.Filter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ExportLabelKeys provides a mock function with given fields: ctx, filter
+func (_m *IRepository) ExportLabelKeys(ctx context.Context, filter logs.Filter) ([]string, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ExportLabelKeys")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, logs.Filter) ([]string, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, logs.Filter) []string); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, logs.Filter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // FindByID provides a mock function with given fields: ctx, ID, filter
 func (_m *IRepository) FindByID(ctx context.Context, ID *string, filter logs.Filter) (*logs.Model, error) {
 	ret := _m.Called(ctx, ID, filter)
@@ -62,6 +331,196 @@ func (_m *IRepository) FindByID(ctx context.Context, ID *string, filter logs.Fil
 	return r0, r1
 }
 
+// InsertRestored provides a mock function with given fields: ctx, restoreID, models
+func (_m *IRepository) InsertRestored(ctx context.Context, restoreID string, models []logs.Model) error {
+	ret := _m.Called(ctx, restoreID, models)
+
+	if len(ret) == 0 {
+		panic("no return value specified for InsertRestored")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []logs.Model) error); ok {
+		r0 = rf(ctx, restoreID, models)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LabelFacets provides a mock function with given fields: ctx, filter, keys, size
+func (_m *IRepository) LabelFacets(ctx context.Context, filter logs.Filter, keys []string, size int) ([]logs.LabelFacet, error) {
+	ret := _m.Called(ctx, filter, keys, size)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LabelFacets")
+	}
+
+	var r0 []logs.LabelFacet
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, logs.Filter, []string, int) ([]logs.LabelFacet, error)); ok {
+		return rf(ctx, filter, keys, size)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, logs.Filter, []string, int) []logs.LabelFacet); ok {
+		r0 = rf(ctx, filter, keys, size)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]logs.LabelFacet)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, logs.Filter, []string, int) error); ok {
+		r1 = rf(ctx, filter, keys, size)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Partitions provides a mock function with given fields: ctx
+func (_m *IRepository) Partitions(ctx context.Context) ([]logs.Partition, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Partitions")
+	}
+
+	var r0 []logs.Partition
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]logs.Partition, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []logs.Partition); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]logs.Partition)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PurgeArchivedDay provides a mock function with given fields: ctx, tenantID, day, limit
+func (_m *IRepository) PurgeArchivedDay(ctx context.Context, tenantID int, day time.Time, limit int) (int, error) {
+	ret := _m.Called(ctx, tenantID, day, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PurgeArchivedDay")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Time, int) (int, error)); ok {
+		return rf(ctx, tenantID, day, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Time, int) int); ok {
+		r0 = rf(ctx, tenantID, day, limit)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, time.Time, int) error); ok {
+		r1 = rf(ctx, tenantID, day, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PurgeExpired provides a mock function with given fields: ctx, rules, now, limit, archivedOnly
+func (_m *IRepository) PurgeExpired(ctx context.Context, rules []logs.RetentionRule, now time.Time, limit int, archivedOnly bool) (int, error) {
+	ret := _m.Called(ctx, rules, now, limit, archivedOnly)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PurgeExpired")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []logs.RetentionRule, time.Time, int, bool) (int, error)); ok {
+		return rf(ctx, rules, now, limit, archivedOnly)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []logs.RetentionRule, time.Time, int, bool) int); ok {
+		r0 = rf(ctx, rules, now, limit, archivedOnly)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []logs.RetentionRule, time.Time, int, bool) error); ok {
+		r1 = rf(ctx, rules, now, limit, archivedOnly)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PurgeRestored provides a mock function with given fields: ctx, restoreID
+func (_m *IRepository) PurgeRestored(ctx context.Context, restoreID string) (int, error) {
+	ret := _m.Called(ctx, restoreID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PurgeRestored")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (int, error)); ok {
+		return rf(ctx, restoreID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) int); ok {
+		r0 = rf(ctx, restoreID)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, restoreID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RetirePartition provides a mock function with given fields: ctx, name, drop, archivedOnly
+func (_m *IRepository) RetirePartition(ctx context.Context, name string, drop bool, archivedOnly bool) (bool, error) {
+	ret := _m.Called(ctx, name, drop, archivedOnly)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RetirePartition")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool, bool) (bool, error)); ok {
+		return rf(ctx, name, drop, archivedOnly)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool, bool) bool); ok {
+		r0 = rf(ctx, name, drop, archivedOnly)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, bool, bool) error); ok {
+		r1 = rf(ctx, name, drop, archivedOnly)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Retrieve provides a mock function with given fields: ctx, filter
 func (_m *IRepository) Retrieve(ctx context.Context, filter logs.Filter) ([]logs.Model, int, error) {
 	ret := _m.Called(ctx, filter)
@@ -99,28 +558,86 @@ func (_m *IRepository) Retrieve(ctx context.Context, filter logs.Filter) ([]logs
 	return r0, r1, r2
 }
 
-// Export provides a mock function with given fields: ctx, filter
-func (_m *IRepository) Export(ctx context.Context, filter logs.Filter) ([]logs.Model, error) {
+// Rollup provides a mock function with given fields: ctx, granularity, from, to
+func (_m *IRepository) Rollup(ctx context.Context, granularity string, from time.Time, to time.Time) (int, error) {
+	ret := _m.Called(ctx, granularity, from, to)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Rollup")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time, time.Time) (int, error)); ok {
+		return rf(ctx, granularity, from, to)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time, time.Time) int); ok {
+		r0 = rf(ctx, granularity, from, to)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, time.Time, time.Time) error); ok {
+		r1 = rf(ctx, granularity, from, to)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RollupWatermarks provides a mock function with given fields: ctx
+func (_m *IRepository) RollupWatermarks(ctx context.Context) (map[string]time.Time, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RollupWatermarks")
+	}
+
+	var r0 map[string]time.Time
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (map[string]time.Time, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) map[string]time.Time); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]time.Time)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Stats provides a mock function with given fields: ctx, filter
+func (_m *IRepository) Stats(ctx context.Context, filter logs.StatsFilter) ([]logs.StatsPoint, error) {
 	ret := _m.Called(ctx, filter)
 
 	if len(ret) == 0 {
-		panic("no return value specified for Export")
+		panic("no return value specified for Stats")
 	}
 
-	var r0 []logs.Model
+	var r0 []logs.StatsPoint
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, logs.Filter) ([]logs.Model, error)); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, logs.StatsFilter) ([]logs.StatsPoint, error)); ok {
 		return rf(ctx, filter)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, logs.Filter) []logs.Model); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, logs.StatsFilter) []logs.StatsPoint); ok {
 		r0 = rf(ctx, filter)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]logs.Model)
+			r0 = ret.Get(0).([]logs.StatsPoint)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, logs.Filter) error); ok {
+	if rf, ok := ret.Get(1).(func(context.Context, logs.StatsFilter) error); ok {
 		r1 = rf(ctx, filter)
 	} else {
 		r1 = ret.Error(1)
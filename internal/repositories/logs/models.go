@@ -28,7 +28,45 @@ type Model struct {
 	UserID      string               `bun:"user_id"`
 	Target      string               `bun:"target"`
 	CreatedAt   *time.Time           `bun:"created_at"`
+	OccurredAt  *time.Time           `bun:"occurred_at"`
+	ClockSkew   bool                 `bun:"clock_skew"`
+	Labels      map[string]string    `bun:"labels,type:jsonb"`
 	LogMessage  []*log_message.Model `bun:"rel:has-many,join:message=id"`
+
+	// Origin observed by the server, empty for logs that did not come through the http api
+	OriginIP           string `bun:"origin_ip,nullzero"`
+	OriginForwardedFor string `bun:"origin_forwarded_for,nullzero"`
+	OriginSubject      string `bun:"origin_subject,nullzero"`
+	OriginTenantID     string `bun:"origin_tenant_id,nullzero"`
+
+	// Hash chain of the tenant, set by the repository when the log is inserted
+	ChainTenantID int    `bun:"chain_tenant_id"`
+	ChainSeq      int64  `bun:"chain_seq"`
+	PrevHash      string `bun:"prev_hash"`
+	Hash          string `bun:"hash"`
+}
+
+// ChainHead last link of the hash chain of a tenant
+type ChainHead struct {
+	bun.BaseModel `bun:"table:log_chain_heads"`
+
+	TenantID  int       `bun:"tenant_id,pk"`
+	Seq       int64     `bun:"seq"`
+	Hash      string    `bun:"hash"`
+	UpdatedAt time.Time `bun:"updated_at"`
+}
+
+// ChainPurge range of consecutive chain entries of a tenant deleted by retention, PrevHash is the link of the
+// first purged entry and Hash the hash of the last one
+type ChainPurge struct {
+	bun.BaseModel `bun:"table:log_chain_purges"`
+
+	TenantID int       `bun:"tenant_id,pk"`
+	FromSeq  int64     `bun:"from_seq,pk"`
+	ToSeq    int64     `bun:"to_seq"`
+	PrevHash string    `bun:"prev_hash"`
+	Hash     string    `bun:"hash"`
+	PurgedAt time.Time `bun:"purged_at"`
 }
 
 type Filter struct {
@@ -46,4 +84,38 @@ type Filter struct {
 	EndAt    time.Time
 	From     int
 	Size     int
+
+	// DateField column used by StartAt and EndAt, SortBy column used to sort, both created_at or occurred_at
+	DateField string
+	SortBy    string
+	ClockSkew *bool
+
+	// Labels values accepted per label key, a log matches when it has any of the values of every key
+	Labels map[string][]string
+
+	// Archived searches the logs of the restored archive files instead of the live logs
+	Archived bool
+}
+
+// LabelFacet number of logs carrying a label value
+type LabelFacet struct {
+	Key   string `bun:"key"`
+	Value string `bun:"value"`
+	Count int    `bun:"count"`
+}
+
+// RetentionRule days the logs matching every non empty field are kept, rules are evaluated in order and the
+// first match wins, Days <= 0 keeps the matching logs forever. TenantID matches the tenant owning the hash
+// chain of the log
+type RetentionRule struct {
+	TenantID *int
+	Level    *int
+	Resource string
+	Days     int
+}
+
+// RetentionCount logs past their retention, held ones are kept until their legal hold is released
+type RetentionCount struct {
+	Expired int `bun:"expired"`
+	Held    int `bun:"held"`
 }
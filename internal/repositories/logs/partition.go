@@ -0,0 +1,142 @@
+package logs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// partitionBoundLayout layout of the created_at bounds reported by pg_get_expr
+const partitionBoundLayout = "2006-01-02 15:04:05"
+
+var partitionBoundRegex = regexp.MustCompile(`^FOR VALUES FROM \('([^']+)'\) TO \('([^']+)'\)$`)
+
+// Partition partition of the logs table holding the logs created from From (inclusive) to To (exclusive),
+// the default partition holds the logs outside every other partition and has no bounds
+type Partition struct {
+	Name    string
+	From    time.Time
+	To      time.Time
+	Default bool
+}
+
+type partitionRow struct {
+	Name  string `bun:"name"`
+	Bound string `bun:"bound"`
+}
+
+// Partitions lists the partitions attached to the logs table
+func (r *DatabaseRepository) Partitions(ctx context.Context) ([]Partition, error) {
+	var rows []partitionRow
+	err := r.db.NewSelect().
+		ColumnExpr("c.relname AS name, pg_get_expr(c.relpartbound, c.oid) AS bound").
+		TableExpr("pg_inherits AS i").
+		Join("JOIN pg_class AS c ON c.oid = i.inhrelid").
+		Where("i.inhparent = to_regclass('public.logs')").
+		OrderExpr("c.relname ASC").
+		Scan(ctx, &rows)
+	if err != nil {
+		return nil, fmt.Errorf("logs_repository: Error while listing partitions -> %v", err)
+	}
+
+	partitions := make([]Partition, 0, len(rows))
+	for _, row := range rows {
+		partition, err := parsePartition(row)
+		if err != nil {
+			return nil, err
+		}
+		partitions = append(partitions, partition)
+	}
+
+	return partitions, nil
+}
+
+// CreatePartition attaches a new partition to the logs table, nothing is done when it already exists
+func (r *DatabaseRepository) CreatePartition(ctx context.Context, partition Partition) error {
+	_, err := r.db.ExecContext(ctx, "SELECT logs_create_partition(?, ?::TIMESTAMP, ?::TIMESTAMP)",
+		partition.Name, partition.From.UTC(), partition.To.UTC())
+	if err != nil {
+		return fmt.Errorf("logs_repository: Error while creating partition %s -> %v", partition.Name, err)
+	}
+
+	return nil
+}
+
+// errPartitionKept rolls back the retirement of a partition the database kept
+var errPartitionKept = errors.New("logs_repository: partition kept")
+
+// RetirePartition detaches the partition from the logs table on the maintenance path and drops it when drop is set,
+// the chain ranges of its logs are recorded as purged. Partitions holding logs under legal hold, or logs whose tenant
+// day has not been archived when archivedOnly is set, are kept and false is returned
+func (r *DatabaseRepository) RetirePartition(ctx context.Context, name string, drop bool, archivedOnly bool) (bool, error) {
+	err := r.maintenanceTx(ctx, func(ctx context.Context, tx bun.Tx) error {
+		if archivedOnly {
+			unarchived, err := partitionQuery(tx, name).
+				Where(unarchivedCondition).
+				Exists(ctx)
+			if err != nil {
+				return err
+			}
+			if unarchived {
+				return errPartitionKept
+			}
+		}
+
+		var removed int
+		if err := recordChainQuery(tx, partitionQuery(tx, name)).Scan(ctx, &removed); err != nil {
+			return err
+		}
+
+		var retired bool
+		if err := tx.QueryRowContext(ctx, "SELECT logs_retire_partition(?, ?)", name, drop).Scan(&retired); err != nil {
+			return err
+		}
+		if !retired {
+			return errPartitionKept
+		}
+
+		return nil
+	})
+	if errors.Is(err, errPartitionKept) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("logs_repository: Error while retiring partition %s -> %v", name, err)
+	}
+
+	return true, nil
+}
+
+// partitionQuery reads the logs of a single partition, aliased as the logs model
+func partitionQuery(db bun.IDB, name string) *bun.SelectQuery {
+	return db.NewSelect().
+		Model((*Model)(nil)).
+		ModelTableExpr("? AS model", bun.Ident(name))
+}
+
+func parsePartition(row partitionRow) (Partition, error) {
+	if row.Bound == "DEFAULT" {
+		return Partition{Name: row.Name, Default: true}, nil
+	}
+
+	matches := partitionBoundRegex.FindStringSubmatch(row.Bound)
+	if matches == nil {
+		return Partition{}, fmt.Errorf("logs_repository: Unexpected bound of partition %s: %s", row.Name, row.Bound)
+	}
+
+	from, err := time.Parse(partitionBoundLayout, matches[1])
+	if err != nil {
+		return Partition{}, fmt.Errorf("logs_repository: Invalid lower bound of partition %s -> %v", row.Name, err)
+	}
+
+	to, err := time.Parse(partitionBoundLayout, matches[2])
+	if err != nil {
+		return Partition{}, fmt.Errorf("logs_repository: Invalid upper bound of partition %s -> %v", row.Name, err)
+	}
+
+	return Partition{Name: row.Name, From: from, To: to}, nil
+}
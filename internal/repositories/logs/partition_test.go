@@ -0,0 +1,97 @@
+package logs
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+func TestParsePartition(t *testing.T) {
+	cases := []struct {
+		name     string
+		row      partitionRow
+		expected Partition
+		err      bool
+	}{
+		{
+			name: "Range",
+			row:  partitionRow{Name: "logs_p202401", Bound: "FOR VALUES FROM ('2024-01-01 00:00:00') TO ('2024-02-01 00:00:00')"},
+			expected: Partition{
+				Name: "logs_p202401",
+				From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				To:   time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:     "Default",
+			row:      partitionRow{Name: "logs_default", Bound: "DEFAULT"},
+			expected: Partition{Name: "logs_default", Default: true},
+		},
+		{
+			name: "Unbounded",
+			row:  partitionRow{Name: "logs_old", Bound: "FOR VALUES FROM (MINVALUE) TO ('2024-01-01 00:00:00')"},
+			err:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			partition, err := parsePartition(tc.row)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, partition)
+		})
+	}
+}
+
+func TestApplyFilter_DateRangeKeepsPruning(t *testing.T) {
+	db := bun.NewDB(&sql.DB{}, pgdialect.New())
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name     string
+		filter   Filter
+		expected string
+	}{
+		{
+			name:     "Created at",
+			filter:   Filter{StartAt: start, EndAt: end},
+			expected: `"created_at" BETWEEN '2024-01-01 00:00:00+00:00'::TIMESTAMP AND '2024-01-31 00:00:00+00:00'::TIMESTAMP`,
+		},
+		{
+			name:     "Occurred at",
+			filter:   Filter{StartAt: start, EndAt: end, DateField: "occurred_at"},
+			expected: `"occurred_at" BETWEEN '2024-01-01 00:00:00+00:00'::TIMESTAMP AND '2024-01-31 00:00:00+00:00'::TIMESTAMP`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			query, ok := applyFilter(db.NewSelect().Model((*Model)(nil)), tc.filter, []int{1})
+
+			assert.True(t, ok)
+			assert.Contains(t, query.String(), tc.expected)
+		})
+	}
+}
+
+func TestPartitionQuery(t *testing.T) {
+	db := bun.NewDB(&sql.DB{}, pgdialect.New())
+
+	unarchived := partitionQuery(db, "logs_p202403").Where(unarchivedCondition).String()
+	assert.Contains(t, unarchived, `FROM "logs_p202403" AS model WHERE (EXISTS (SELECT 1 FROM jsonb_array_elements_text(`)
+	assert.Contains(t, unarchived, `a.day = "model".created_at::date`)
+
+	recorded := recordChainQuery(db, partitionQuery(db, "logs_p202403")).String()
+	assert.Contains(t, recorded, `WITH purged AS (SELECT chain_tenant_id, chain_seq, prev_hash, hash FROM "logs_p202403" AS model)`)
+	assert.Contains(t, recorded, "INSERT INTO log_chain_purges")
+}
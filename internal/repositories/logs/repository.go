@@ -2,6 +2,7 @@ package logs
 
 import (
 	"context"
+	"time"
 )
 
 // IRepository interface
@@ -9,5 +10,24 @@ type IRepository interface {
 	FindByID(ctx context.Context, ID *string, filter Filter) (*Model, error)
 	Create(ctx context.Context, model *Model) error
 	Retrieve(ctx context.Context, filter Filter) ([]Model, int, error)
-	Export(ctx context.Context, filter Filter) ([]Model, error)
+	EachExportLog(ctx context.Context, filter Filter, fn func(Model) error) error
+	ExportLabelKeys(ctx context.Context, filter Filter) ([]string, error)
+	ExportDataKeys(ctx context.Context, filter Filter) ([]string, error)
+	LabelFacets(ctx context.Context, filter Filter, keys []string, size int) ([]LabelFacet, error)
+	ChainBounds(ctx context.Context, tenantID int, from, to time.Time) (int64, int64, error)
+	ChainEntries(ctx context.Context, tenantID int, afterSeq, lastSeq int64, limit int) ([]Model, error)
+	ChainPurges(ctx context.Context, tenantID int, fromSeq, toSeq int64) ([]ChainPurge, error)
+	CountExpired(ctx context.Context, rules []RetentionRule, now time.Time, archivedOnly bool) (RetentionCount, error)
+	PurgeExpired(ctx context.Context, rules []RetentionRule, now time.Time, limit int, archivedOnly bool) (int, error)
+	Partitions(ctx context.Context) ([]Partition, error)
+	CreatePartition(ctx context.Context, partition Partition) error
+	RetirePartition(ctx context.Context, name string, drop bool, archivedOnly bool) (bool, error)
+	ArchivableDays(ctx context.Context, before time.Time, limit int) ([]ArchiveDay, error)
+	EachDayLog(ctx context.Context, tenantID int, day time.Time, fn func(Model) error) error
+	PurgeArchivedDay(ctx context.Context, tenantID int, day time.Time, limit int) (int, error)
+	InsertRestored(ctx context.Context, restoreID string, models []Model) error
+	PurgeRestored(ctx context.Context, restoreID string) (int, error)
+	RollupWatermarks(ctx context.Context) (map[string]time.Time, error)
+	Rollup(ctx context.Context, granularity string, from, to time.Time) (int, error)
+	Stats(ctx context.Context, filter StatsFilter) ([]StatsPoint, error)
 }
@@ -0,0 +1,79 @@
+package logs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/uptrace/bun"
+)
+
+// restoredTable table holding the logs of the restored archive files
+const restoredTable = "restored_logs"
+
+// readyRestoresCondition matches the restored logs of the restores ready and not expired
+const readyRestoresCondition = "restore_id IN (SELECT id FROM log_restores WHERE status = 'ready' AND expired_at IS NULL AND expires_at > now() AT TIME ZONE 'UTC')"
+
+// RestoredModel log loaded from an archive file, read-only until its restore expires. It extends the logs model
+// so queries name the restored_logs table explicitly
+type RestoredModel struct {
+	Model `bun:",extend"`
+
+	RestoreID string `bun:"restore_id"`
+}
+
+// InsertRestored loads logs of an archive file under the restore
+func (r *DatabaseRepository) InsertRestored(ctx context.Context, restoreID string, models []Model) error {
+	if len(models) == 0 {
+		return nil
+	}
+
+	restored := make([]RestoredModel, len(models))
+	for i, model := range models {
+		restored[i] = RestoredModel{Model: model, RestoreID: restoreID}
+	}
+
+	_, err := r.db.NewInsert().
+		Model(&restored).
+		ModelTableExpr(restoredTable).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("logs_repository: Error while inserting restored logs -> %v", err)
+	}
+
+	return nil
+}
+
+// PurgeRestored deletes the logs of the restore on the maintenance path, it returns the number of logs deleted
+func (r *DatabaseRepository) PurgeRestored(ctx context.Context, restoreID string) (int, error) {
+	var deleted int
+	err := r.maintenanceTx(ctx, func(ctx context.Context, tx bun.Tx) error {
+		res, err := tx.NewDelete().
+			Model((*RestoredModel)(nil)).
+			ModelTableExpr(restoredTable+" AS model").
+			Where("restore_id = ?", restoreID).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		affected, err := res.RowsAffected()
+		deleted = int(affected)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("logs_repository: Error while purging restored logs -> %v", err)
+	}
+
+	return deleted, nil
+}
+
+// source reads the restored logs of the ready restores instead of the logs when the filter asks for archived logs
+func source(query *bun.SelectQuery, filter Filter) *bun.SelectQuery {
+	if !filter.Archived {
+		return query
+	}
+
+	return query.
+		ModelTableExpr(restoredTable + " AS model").
+		Where(readyRestoresCondition)
+}
@@ -0,0 +1,22 @@
+package logs
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+func TestSource(t *testing.T) {
+	db := bun.NewDB(&sql.DB{}, pgdialect.New())
+
+	live := source(db.NewSelect().Model((*Model)(nil)), Filter{}).String()
+	assert.Contains(t, live, `FROM "logs" AS "model"`)
+	assert.NotContains(t, live, "restore_id")
+
+	archived := source(db.NewSelect().Model((*Model)(nil)), Filter{Archived: true}).String()
+	assert.Contains(t, archived, "FROM restored_logs AS model")
+	assert.Contains(t, archived, readyRestoresCondition)
+}
@@ -0,0 +1,140 @@
+package logs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Conditions matching the logs covered by an active legal hold, deletion paths must exclude them
+const (
+	heldCondition    = "log_is_held(tenant_id, user_id, resource, created_at)"
+	notHeldCondition = "NOT " + heldCondition
+)
+
+// CountExpired counts the logs past their retention and how many of them are held, archivedOnly only counts
+// the logs whose tenant day has been archived
+func (r *DatabaseRepository) CountExpired(ctx context.Context, rules []RetentionRule, now time.Time, archivedOnly bool) (RetentionCount, error) {
+	var count RetentionCount
+	if len(rules) == 0 {
+		return count, nil
+	}
+
+	expr, args := expiredCondition(rules, now)
+	query := r.db.NewSelect().
+		Model((*Model)(nil)).
+		ColumnExpr("count(*) AS expired").
+		ColumnExpr("count(*) FILTER (WHERE "+heldCondition+") AS held").
+		Where(expr, args...)
+	if archivedOnly {
+		query = query.Where(archivedCondition)
+	}
+
+	err := query.Scan(ctx, &count)
+	if err != nil {
+		return count, fmt.Errorf("logs_repository: Error while counting expired logs -> %v", err)
+	}
+
+	return count, nil
+}
+
+// PurgeExpired deletes up to limit logs past their retention on the maintenance path, held logs are skipped and
+// archivedOnly skips the logs whose tenant day has not been archived. The purged chain ranges are recorded so the
+// chains stay verifiable, it returns the number of logs deleted
+func (r *DatabaseRepository) PurgeExpired(ctx context.Context, rules []RetentionRule, now time.Time, limit int, archivedOnly bool) (int, error) {
+	if len(rules) == 0 {
+		return 0, nil
+	}
+
+	var deleted int
+	err := r.maintenanceTx(ctx, func(ctx context.Context, tx bun.Tx) error {
+		expr, args := expiredCondition(rules, now)
+		candidates := tx.NewSelect().
+			Model((*Model)(nil)).
+			Column("id", "tenant_id", "user_id", "resource", "created_at").
+			Where(expr, args...)
+		if archivedOnly {
+			candidates = candidates.Where(archivedCondition)
+		}
+
+		// OFFSET 0 keeps the candidates from being flattened into the outer query, the held check only runs on
+		// the expired logs and the scan stops once limit of them are not held
+		batch := tx.NewSelect().
+			TableExpr("(? OFFSET 0) AS candidate", candidates).
+			Column("id").
+			Where(notHeldCondition).
+			Limit(limit)
+
+		return purgeChainQuery(tx, tx.NewDelete().
+			Model((*Model)(nil)).
+			Where("id IN (?)", batch)).
+			Scan(ctx, &deleted)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("logs_repository: Error while purging expired logs -> %v", err)
+	}
+
+	return deleted, nil
+}
+
+// expiredCondition matches the logs created before now minus the days of the first matching rule, logs whose first
+// matching rule keeps them forever never match. Every rule is a range on created_at the planner can use an index or
+// prune partitions with, restricted to the logs no earlier rule matches
+func expiredCondition(rules []RetentionRule, now time.Time) (string, []interface{}) {
+	var branches []string
+	var args []interface{}
+
+	var earlier []string
+	var earlierArgs []interface{}
+	for _, rule := range rules {
+		conditions, conditionArgs := ruleConditions(rule)
+
+		if rule.Days > 0 {
+			branch := append([]string{"created_at < ?::TIMESTAMP"}, conditions...)
+			args = append(args, now.AddDate(0, 0, -rule.Days))
+			args = append(args, conditionArgs...)
+			for _, condition := range earlier {
+				branch = append(branch, "("+condition+") IS NOT TRUE")
+			}
+			args = append(args, earlierArgs...)
+
+			branches = append(branches, "("+strings.Join(branch, " AND ")+")")
+		}
+
+		// Rules after a rule matching every log are never reached
+		if len(conditions) == 0 {
+			break
+		}
+		earlier = append(earlier, strings.Join(conditions, " AND "))
+		earlierArgs = append(earlierArgs, conditionArgs...)
+	}
+
+	if len(branches) == 0 {
+		return "FALSE", nil
+	}
+
+	return "(" + strings.Join(branches, " OR ") + ")", args
+}
+
+// ruleConditions conditions on the log of the non empty fields of the rule
+func ruleConditions(rule RetentionRule) ([]string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if rule.TenantID != nil {
+		conditions = append(conditions, "chain_tenant_id = ?")
+		args = append(args, *rule.TenantID)
+	}
+	if rule.Level != nil {
+		conditions = append(conditions, "level = ?")
+		args = append(args, *rule.Level)
+	}
+	if rule.Resource != "" {
+		conditions = append(conditions, "UPPER(resource) = UPPER(?)")
+		args = append(args, rule.Resource)
+	}
+
+	return conditions, args
+}
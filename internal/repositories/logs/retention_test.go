@@ -0,0 +1,56 @@
+package logs
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+func TestExpiredCondition(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	tenant := 5
+	debug := 7
+
+	expr, args := expiredCondition([]RetentionRule{
+		{TenantID: &tenant, Resource: "AUTH", Days: -1},
+		{TenantID: &tenant, Level: &debug, Days: 7},
+		{TenantID: &tenant, Days: 90},
+		{Days: 30},
+	}, now)
+
+	assert.Equal(t, "("+
+		"(created_at < ?::TIMESTAMP AND chain_tenant_id = ? AND level = ?"+
+		" AND (chain_tenant_id = ? AND UPPER(resource) = UPPER(?)) IS NOT TRUE)"+
+		" OR (created_at < ?::TIMESTAMP AND chain_tenant_id = ?"+
+		" AND (chain_tenant_id = ? AND UPPER(resource) = UPPER(?)) IS NOT TRUE"+
+		" AND (chain_tenant_id = ? AND level = ?) IS NOT TRUE)"+
+		" OR (created_at < ?::TIMESTAMP"+
+		" AND (chain_tenant_id = ? AND UPPER(resource) = UPPER(?)) IS NOT TRUE"+
+		" AND (chain_tenant_id = ? AND level = ?) IS NOT TRUE"+
+		" AND (chain_tenant_id = ?) IS NOT TRUE)"+
+		")", expr)
+	assert.Equal(t, []interface{}{
+		now.AddDate(0, 0, -7), 5, 7, 5, "AUTH",
+		now.AddDate(0, 0, -90), 5, 5, "AUTH", 5, 7,
+		now.AddDate(0, 0, -30), 5, "AUTH", 5, 7, 5,
+	}, args)
+
+	expr, args = expiredCondition([]RetentionRule{{Days: -1}, {TenantID: &tenant, Days: 7}}, now)
+	assert.Equal(t, "FALSE", expr)
+	assert.Nil(t, args)
+}
+
+func TestPurgeChainQuery(t *testing.T) {
+	db := bun.NewDB(&sql.DB{}, pgdialect.New())
+
+	query := purgeChainQuery(db, db.NewDelete().Model((*Model)(nil)).Where("id IN (?)", bun.In([]string{"a"}))).String()
+
+	assert.Contains(t, query, `WITH purged AS (DELETE FROM "logs" AS "model" WHERE (id IN ('a')) RETURNING chain_tenant_id, chain_seq, prev_hash, hash)`)
+	assert.Contains(t, query, "INSERT INTO log_chain_purges")
+	assert.Contains(t, query, "GROUP BY chain_tenant_id, island")
+	assert.Contains(t, query, "SELECT count(*) FROM purged")
+}
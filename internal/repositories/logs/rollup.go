@@ -0,0 +1,281 @@
+package logs
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/uptrace/bun"
+)
+
+// Granularities of the stats, minute stats are always counted on the logs
+const (
+	GranularityMinute = "minute"
+	GranularityHour   = "hour"
+	GranularityDay    = "day"
+)
+
+// rollupColumns dimensions of the rollups, the logs are counted per distinct value of all of them
+const rollupColumns = "tenant_id, level, provider, action, resource, message"
+
+// statsGroups columns the stats can be grouped by, logs without a value are grouped under an empty key. The tenant
+// group reads the tenants expanded by tenantGroupQuery
+var statsGroups = map[string]string{
+	"":         "''",
+	"tenant":   "stats_tenant.id",
+	"level":    "coalesce(level::text, '')",
+	"provider": "coalesce(provider, '')",
+	"action":   "coalesce(action, '')",
+	"resource": "coalesce(resource, '')",
+	"message":  "coalesce(message::text, '')",
+}
+
+// RollupModel number of logs of a bucket sharing the same dimensions
+type RollupModel struct {
+	bun.BaseModel `bun:"table:log_rollups"`
+
+	Granularity string    `bun:"granularity"`
+	Bucket      time.Time `bun:"bucket"`
+	TenantID    string    `bun:"tenant_id,type:jsonb"`
+	Level       int       `bun:"level"`
+	Provider    string    `bun:"provider"`
+	Action      string    `bun:"action"`
+	Resource    string    `bun:"resource"`
+	Message     int       `bun:"message"`
+	Count       int64     `bun:"count"`
+}
+
+// RollupWatermark the rollups of the granularity are complete for the buckets before RolledUntil
+type RollupWatermark struct {
+	bun.BaseModel `bun:"table:log_rollup_watermarks"`
+
+	Granularity string    `bun:"granularity,pk"`
+	RolledUntil time.Time `bun:"rolled_until"`
+	UpdatedAt   time.Time `bun:"updated_at"`
+}
+
+// StatsFilter counts the logs matching the filter per bucket of the granularity and value of the group, StartAt
+// is inclusive and EndAt exclusive. Rollup reads the rollups instead of the logs, only the filters on their
+// dimensions are applied then
+type StatsFilter struct {
+	Filter
+	Granularity string
+	GroupBy     string
+	Rollup      bool
+}
+
+// StatsPoint number of logs of a bucket with the value of the group
+type StatsPoint struct {
+	Bucket time.Time `bun:"bucket"`
+	Key    string    `bun:"key"`
+	Count  int64     `bun:"count"`
+}
+
+// RollupWatermarks returns how far the rollups of every granularity are complete
+func (r *DatabaseRepository) RollupWatermarks(ctx context.Context) (map[string]time.Time, error) {
+	var watermarks []RollupWatermark
+	if err := r.db.NewSelect().Model(&watermarks).Scan(ctx); err != nil {
+		return nil, fmt.Errorf("logs_repository: Error while reading the rollup watermarks -> %v", err)
+	}
+
+	res := make(map[string]time.Time, len(watermarks))
+	for _, watermark := range watermarks {
+		res[watermark.Granularity] = watermark.RolledUntil
+	}
+
+	return res, nil
+}
+
+// Rollup rebuilds the rollups of the granularity for the buckets from from (inclusive) to to (exclusive) and moves
+// its watermark to to. Hourly rollups count the logs, daily rollups add up the hourly ones. It returns the number of
+// rollup rows written
+func (r *DatabaseRepository) Rollup(ctx context.Context, granularity string, from, to time.Time) (int, error) {
+	var source *bun.SelectQuery
+	switch granularity {
+	case GranularityHour:
+		source = r.db.NewSelect().
+			Model((*Model)(nil)).
+			ColumnExpr("'hour', date_trunc('hour', created_at), "+rollupColumns+", count(*)").
+			Where("created_at >= ?::TIMESTAMP AND created_at < ?::TIMESTAMP", from, to).
+			GroupExpr("2, " + rollupColumns)
+	case GranularityDay:
+		source = r.db.NewSelect().
+			Model((*RollupModel)(nil)).
+			ColumnExpr("'day', date_trunc('day', bucket), "+rollupColumns+", sum(count)").
+			Where("granularity = ?", GranularityHour).
+			Where("bucket >= ?::TIMESTAMP AND bucket < ?::TIMESTAMP", from, to).
+			GroupExpr("2, " + rollupColumns)
+	default:
+		return 0, fmt.Errorf("logs_repository: Rollups of granularity %q are not supported", granularity)
+	}
+
+	var written int
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		_, err := tx.NewDelete().
+			Model((*RollupModel)(nil)).
+			Where("granularity = ?", granularity).
+			Where("bucket >= ?::TIMESTAMP AND bucket < ?::TIMESTAMP", from, to).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.NewRaw("INSERT INTO log_rollups (granularity, bucket, "+rollupColumns+", count) ?", source).Exec(ctx)
+		if err != nil {
+			return err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		written = int(affected)
+
+		watermark := &RollupWatermark{Granularity: granularity, RolledUntil: to, UpdatedAt: time.Now().UTC()}
+		_, err = tx.NewInsert().
+			Model(watermark).
+			On("CONFLICT (granularity) DO UPDATE").
+			Set("rolled_until = EXCLUDED.rolled_until").
+			Set("updated_at = EXCLUDED.updated_at").
+			Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("logs_repository: Error while rolling up the %s buckets -> %v", granularity, err)
+	}
+
+	return written, nil
+}
+
+// Stats counts the logs per bucket and group value, from the logs or from the rollups
+func (r *DatabaseRepository) Stats(ctx context.Context, filter StatsFilter) ([]StatsPoint, error) {
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	query, ok, err := statsQuery(r.db, filter, claims.Tenants)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return []StatsPoint{}, nil
+	}
+
+	var points []StatsPoint
+	if err := query.Scan(ctx, &points); err != nil {
+		return nil, fmt.Errorf("logs_repository: Error while counting the logs -> %v", err)
+	}
+
+	return points, nil
+}
+
+// statsQuery builds the count of the stats, it returns false when none of the requested tenants is allowed
+func statsQuery(db bun.IDB, filter StatsFilter, userTenantsID []int) (*bun.SelectQuery, bool, error) {
+	group, ok := statsGroups[filter.GroupBy]
+	if !ok {
+		return nil, false, fmt.Errorf("logs_repository: Stats can not be grouped by %q", filter.GroupBy)
+	}
+
+	if filter.Rollup {
+		if filter.Granularity != GranularityHour && filter.Granularity != GranularityDay {
+			return nil, false, fmt.Errorf("logs_repository: Rollups of granularity %q are not supported", filter.Granularity)
+		}
+
+		query := db.NewSelect().
+			Model((*RollupModel)(nil)).
+			ColumnExpr("bucket").
+			ColumnExpr(group+" AS key").
+			ColumnExpr("sum(count)::bigint AS count").
+			Where("granularity = ?", filter.Granularity).
+			Where("bucket >= ?::TIMESTAMP AND bucket < ?::TIMESTAMP", filter.StartAt, filter.EndAt).
+			GroupExpr("1, 2").
+			OrderExpr("1 ASC, 2 ASC")
+
+		query, ok := applyRollupFilter(query, filter.Filter, userTenantsID)
+		if ok && filter.GroupBy == "tenant" {
+			query = tenantGroupQuery(query, filter.Filter, userTenantsID)
+		}
+		return query, ok, nil
+	}
+
+	switch filter.Granularity {
+	case GranularityMinute, GranularityHour, GranularityDay:
+	default:
+		return nil, false, fmt.Errorf("logs_repository: Stats of granularity %q are not supported", filter.Granularity)
+	}
+
+	// The bounds are half open so adjacent ranges never count a log twice
+	bounds := filter.Filter
+	bounds.StartAt, bounds.EndAt = time.Time{}, time.Time{}
+
+	query := db.NewSelect().
+		Model((*Model)(nil)).
+		ColumnExpr("date_trunc(?, created_at) AS bucket", filter.Granularity).
+		ColumnExpr(group+" AS key").
+		ColumnExpr("count(*) AS count").
+		Where("created_at >= ?::TIMESTAMP AND created_at < ?::TIMESTAMP", filter.StartAt, filter.EndAt).
+		GroupExpr("1, 2").
+		OrderExpr("1 ASC, 2 ASC")
+
+	query, ok = applyFilter(query, bounds, userTenantsID)
+	if ok && filter.GroupBy == "tenant" {
+		query = tenantGroupQuery(query, filter.Filter, userTenantsID)
+	}
+	return query, ok, nil
+}
+
+// tenantGroupQuery expands the tenants of every log, or rollup, so it is counted under each of its tenants. Only the
+// tenants of the user are kept, the requested ones among them when the filter has tenants
+func tenantGroupQuery(query *bun.SelectQuery, filter Filter, userTenantsID []int) *bun.SelectQuery {
+	tenantsID := userTenantsID
+	if len(filter.TenantID) > 0 {
+		tenantsID = filterAllowedTenants(userTenantsID, filter.TenantID)
+	}
+
+	tenants := make([]string, len(tenantsID))
+	for i, id := range tenantsID {
+		tenants[i] = strconv.Itoa(id)
+	}
+
+	return query.
+		Join("CROSS JOIN LATERAL jsonb_array_elements_text(CASE WHEN jsonb_typeof(?TableAlias.tenant_id) = 'array'"+
+			" THEN ?TableAlias.tenant_id ELSE '[]'::jsonb END) AS stats_tenant(id)").
+		Where("stats_tenant.id IN (?)", bun.In(tenants))
+}
+
+// applyRollupFilter adds the conditions of the filter on the dimensions of the rollups, they match the logs the
+// same way applyFilter does
+func applyRollupFilter(query *bun.SelectQuery, filter Filter, userTenantsID []int) (*bun.SelectQuery, bool) {
+	if len(filter.Message) > 0 {
+		query = query.Where("message in (?)", bun.In(filter.Message))
+	}
+
+	if len(filter.Level) > 0 {
+		query = query.Where("level in (?)", bun.In(filter.Level))
+	}
+
+	if len(filter.Provider) > 0 {
+		query = query.Where("provider in (?)", bun.In(filter.Provider))
+	}
+
+	if len(filter.Action) > 0 {
+		query = query.Where("action in (?)", bun.In(filter.Action))
+	}
+
+	if filter.Resource != "" {
+		query = query.Where("resource like UPPER(?)", "%"+filter.Resource+"%")
+	}
+
+	if len(filter.TenantID) > 0 {
+		allowedTenantsIds := filterAllowedTenants(userTenantsID, filter.TenantID)
+
+		if len(allowedTenantsIds) == 0 {
+			return query, false
+		}
+
+		query = query.Where(buildQueryTenants(allowedTenantsIds, "OR"))
+	}
+
+	query = query.Where(buildQueryTenants(userTenantsID, "OR"))
+
+	return query, true
+}
@@ -0,0 +1,87 @@
+package logs
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+func TestStatsQuery(t *testing.T) {
+	db := bun.NewDB(&sql.DB{}, pgdialect.New())
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 1)
+
+	cases := []struct {
+		name     string
+		filter   StatsFilter
+		expected []string
+		denied   bool
+		err      bool
+	}{
+		{
+			name:   "Logs",
+			filter: StatsFilter{Filter: Filter{StartAt: start, EndAt: end, Level: []string{"3"}, Path: "users"}, Granularity: GranularityMinute, GroupBy: "level"},
+			expected: []string{
+				`date_trunc('minute', created_at) AS bucket, coalesce(level::text, '') AS key, count(*) AS count FROM "logs"`,
+				`created_at >= '2024-01-01 00:00:00+00:00'::TIMESTAMP AND created_at < '2024-01-02 00:00:00+00:00'::TIMESTAMP`,
+				`(path like LOWER('%users%'))`,
+				`((tenant_id @> '[1]' OR tenant_id @> '[2]'))`,
+			},
+		},
+		{
+			name:   "Rollups",
+			filter: StatsFilter{Filter: Filter{StartAt: start, EndAt: end, TenantID: []int{2}}, Granularity: GranularityDay, GroupBy: "tenant", Rollup: true},
+			expected: []string{
+				`stats_tenant.id AS key, sum(count)::bigint AS count FROM "log_rollups"`,
+				`CROSS JOIN LATERAL jsonb_array_elements_text(CASE WHEN jsonb_typeof("rollup_model".tenant_id) = 'array'`,
+				`(granularity = 'day')`,
+				`((tenant_id @> '[2]'))`,
+				`((tenant_id @> '[1]' OR tenant_id @> '[2]'))`,
+				`(stats_tenant.id IN ('2'))`,
+			},
+		},
+		{
+			name:   "Logs per tenant",
+			filter: StatsFilter{Filter: Filter{StartAt: start, EndAt: end}, Granularity: GranularityHour, GroupBy: "tenant"},
+			expected: []string{
+				`stats_tenant.id AS key, count(*) AS count FROM "logs" AS "model" CROSS JOIN LATERAL jsonb_array_elements_text(`,
+				`(stats_tenant.id IN ('1', '2'))`,
+			},
+		},
+		{
+			name:   "Tenant not allowed",
+			filter: StatsFilter{Filter: Filter{StartAt: start, EndAt: end, TenantID: []int{3}}, Granularity: GranularityDay, Rollup: true},
+			denied: true,
+		},
+		{
+			name:   "Minute rollups",
+			filter: StatsFilter{Filter: Filter{StartAt: start, EndAt: end}, Granularity: GranularityMinute, Rollup: true},
+			err:    true,
+		},
+		{
+			name:   "Invalid group",
+			filter: StatsFilter{Filter: Filter{StartAt: start, EndAt: end}, Granularity: GranularityHour, GroupBy: "path"},
+			err:    true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			query, ok, err := statsQuery(db, tc.filter, []int{1, 2})
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, !tc.denied, ok)
+			for _, expected := range tc.expected {
+				assert.Contains(t, query.String(), expected)
+			}
+		})
+	}
+}
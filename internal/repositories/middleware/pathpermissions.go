@@ -12,8 +12,12 @@ import (
 type Paths string
 
 const (
-	full   Paths = "/v1/logs/{id},/v1/logs,/v1/log_messages"
-	export Paths = "/v1/logs/export"
+	full     Paths = "/v1/logs/{id},/v1/logs,/v1/log_messages,/v1/otlp/logs,/v1/logs/labels/facets,/v1/logs/verify,/v1/logs/stats,/v1/logs/dashboard"
+	export   Paths = "/v1/logs/export,/v1/logs/exports,/v1/logs/exports/{id},/v1/logs/exports/{id}/download,/v1/logs/exports/{id}/manifest"
+	apiKeys  Paths = "/v1/api_keys,/v1/api_keys/{id}"
+	holds    Paths = "/v1/legal_holds,/v1/legal_holds/{id}"
+	archives Paths = "/v1/archives,/v1/archives/{id}/restore,/v1/archives/restores/{id}"
+	admin    Paths = "/v1/admin/retention,/v1/access_logs"
 )
 
 // ValidatePermission validates requested sources based on user permissions
@@ -31,6 +35,23 @@ func ValidatePermission(permission sts.Permission, path string, method string, l
 			logger.Log(logrus.DebugLevel, "ValidatePermission", "Full: "+action)
 			return true
 		}
+		if strings.Contains(string(apiKeys), path) && (method == http.MethodGet || method == http.MethodOptions || method == http.MethodPost || method == http.MethodDelete) {
+			logger.Log(logrus.DebugLevel, "ValidatePermission", "Full: "+action)
+			return true
+		}
+		if strings.Contains(string(holds), path) && (method == http.MethodGet || method == http.MethodOptions || method == http.MethodPost || method == http.MethodPut || method == http.MethodDelete) {
+			logger.Log(logrus.DebugLevel, "ValidatePermission", "Full: "+action)
+			return true
+		}
+		if strings.Contains(string(archives), path) && (method == http.MethodGet || method == http.MethodOptions || method == http.MethodPost) {
+			logger.Log(logrus.DebugLevel, "ValidatePermission", "Full: "+action)
+			return true
+		}
+	case "admin":
+		if strings.Contains(string(admin), path) && (method == http.MethodGet || method == http.MethodOptions) {
+			logger.Log(logrus.DebugLevel, "ValidatePermission", "Admin: "+action)
+			return true
+		}
 	default:
 		logger.Log(logrus.DebugLevel, "ValidatePermission", "Default Action: "+action)
 	}
@@ -0,0 +1,32 @@
+package accesslogsvcmock
+
+import (
+	"context"
+
+	"github.com/jmontesinos91/omnilogger/internal/services/access_log"
+)
+
+type IService struct {
+	// Record
+	Entries []access_log.Entry
+
+	// Retrieve
+	RetrieveErr    error
+	RetrieveRes    *access_log.PaginatedRes
+	RetrieveCalled bool
+}
+
+func (m *IService) Record(ctx context.Context, entry access_log.Entry) {
+	m.Entries = append(m.Entries, entry)
+}
+
+func (m *IService) Retrieve(ctx context.Context, filter access_log.Filter) (*access_log.PaginatedRes, error) {
+	m.RetrieveCalled = true
+	if m.RetrieveErr != nil {
+		return nil, m.RetrieveErr
+	}
+	if m.RetrieveRes != nil {
+		return m.RetrieveRes, nil
+	}
+	return &access_log.PaginatedRes{}, nil
+}
@@ -0,0 +1,97 @@
+package access_log
+
+import (
+	"context"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jmontesinos91/ologs/logger"
+	tracekey "github.com/jmontesinos91/ologs/logger/v2"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/access_log"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/jmontesinos91/terrors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/samber/lo"
+	lop "github.com/samber/lo/parallel"
+	"github.com/sirupsen/logrus"
+)
+
+var recordFailuresMetric = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "access_log_record_failures_total",
+	Help: "The total number of reads and exports of logs that could not be recorded on the access logs, partitioned by action",
+}, []string{"action"})
+
+// DefaultService struct
+type DefaultService struct {
+	log            *logger.ContextLogger
+	accessLogsRepo access_log.IRepository
+}
+
+// NewDefaultService creates a new instance of DefaultService access log
+func NewDefaultService(l *logger.ContextLogger, r access_log.IRepository) *DefaultService {
+	return &DefaultService{
+		log:            l,
+		accessLogsRepo: r,
+	}
+}
+
+// Record stores who read or exported logs, a failure is reported and counted but never fails the read
+func (s *DefaultService) Record(ctx context.Context, entry Entry) {
+	requestID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	claims, _ := ctx.Value(&sts.Claim).(sts.Claims)
+
+	model := ToModel(entry, requestID, claims)
+
+	if err := s.accessLogsRepo.Create(ctx, model); err != nil {
+		recordFailuresMetric.WithLabelValues(entry.Action).Inc()
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			"Record",
+			"Error while recording access log: %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+				tracekey.UserID:     claims.UserID,
+			},
+			err)
+	}
+}
+
+// Retrieve lists the access logs of the tenants the user has access to
+func (s *DefaultService) Retrieve(ctx context.Context, filter Filter) (*PaginatedRes, error) {
+	requestID := ctx.Value(middleware.RequestIDKey).(string)
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	if len(filter.TenantID) > 0 {
+		filter.TenantID = lo.Intersect(claims.Tenants, filter.TenantID)
+	} else {
+		filter.TenantID = claims.Tenants
+	}
+
+	if len(filter.TenantID) == 0 {
+		return &PaginatedRes{Data: []Response{}, Size: filter.Size, Page: filter.Page}, nil
+	}
+
+	res, total, err := s.accessLogsRepo.Retrieve(ctx, ToRepoFilter(filter))
+	if err != nil {
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			"Retrieve",
+			"Error while retrieve access logs: %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+			},
+			err)
+		return nil, terrors.New(terrors.ErrInternalService, "Internal error service", map[string]string{})
+	}
+
+	items := lop.Map(res, func(p access_log.Model, _ int) Response {
+		return *ToResponse(&p)
+	})
+
+	return &PaginatedRes{
+		Data:  items,
+		Size:  filter.Size,
+		Total: total,
+		Page:  filter.Page,
+	}, nil
+}
@@ -0,0 +1,170 @@
+package access_log
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/omnilogger/domains/pagination"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/access_log"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/access_log/accesslogmock"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/jmontesinos91/terrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func testContext() context.Context {
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "test-request-id")
+	return context.WithValue(ctx, &sts.Claim, sts.Claims{UserID: 42, User: "auditor", Role: "admin", Tenants: []int{1, 2}})
+}
+
+func TestRecord(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestRecord", "debug", logger.TextFormat)
+
+	cases := []struct {
+		name     string
+		entry    Entry
+		repoErr  error
+		expected func(*testing.T, *access_log.Model)
+	}{
+		{
+			name:  "Successful read",
+			entry: Entry{Action: ActionRetrieve, Tenants: []int{1, 3}, Filter: map[string][]int{"TenantID": {1, 3}}, RowCount: 10},
+			expected: func(t *testing.T, m *access_log.Model) {
+				assert.Equal(t, "test-request-id", m.RequestID)
+				assert.Equal(t, 42, m.UserID)
+				assert.Equal(t, "auditor", m.UserName)
+				assert.Equal(t, []int{1}, m.Tenants)
+				assert.Equal(t, ActionRetrieve, m.Action)
+				assert.JSONEq(t, `{"TenantID":[1,3]}`, string(m.Filter))
+				assert.Equal(t, 10, m.RowCount)
+				assert.Equal(t, OutcomeSuccess, m.Outcome)
+			},
+		},
+		{
+			name:  "Failed read",
+			entry: Entry{Action: ActionGet, LogID: "log-1", Err: errors.New("not found")},
+			expected: func(t *testing.T, m *access_log.Model) {
+				assert.Equal(t, "log-1", m.LogID)
+				assert.Equal(t, []int{1, 2}, m.Tenants)
+				assert.JSONEq(t, `{}`, string(m.Filter))
+				assert.Equal(t, OutcomeFailure, m.Outcome)
+			},
+		},
+		{
+			name:  "Tenants of another user",
+			entry: Entry{Action: ActionExport, Tenants: []int{3}},
+			expected: func(t *testing.T, m *access_log.Model) {
+				assert.Equal(t, []int{}, m.Tenants)
+			},
+		},
+		{
+			name:    "Repository error does not panic",
+			entry:   Entry{Action: ActionExport, RowCount: 3},
+			repoErr: errors.New("db down"),
+			expected: func(t *testing.T, m *access_log.Model) {
+				assert.Equal(t, ActionExport, m.Action)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var recorded *access_log.Model
+			repoMock := &accesslogmock.IRepository{}
+			repoMock.On("Create", mock.Anything, mock.Anything).
+				Run(func(args mock.Arguments) { recorded = args.Get(1).(*access_log.Model) }).
+				Return(tc.repoErr)
+
+			NewDefaultService(ctxLogger, repoMock).Record(testContext(), tc.entry)
+
+			assert.NotNil(t, recorded)
+			tc.expected(t, recorded)
+		})
+	}
+}
+
+func TestRecord_WithoutClaims(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestRecord_WithoutClaims", "debug", logger.TextFormat)
+	repoMock := &accesslogmock.IRepository{}
+	repoMock.On("Create", mock.Anything, mock.MatchedBy(func(m *access_log.Model) bool {
+		return m.UserID == 0 && len(m.Tenants) == 0 && m.RequestID == ""
+	})).Return(nil)
+
+	NewDefaultService(ctxLogger, repoMock).Record(context.Background(), Entry{Action: ActionRetrieve})
+
+	repoMock.AssertExpectations(t)
+}
+
+func TestRetrieve(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestRetrieve", "debug", logger.TextFormat)
+	page := pagination.Filter{Page: 1, Size: 10}
+	raw := json.RawMessage(`{}`)
+
+	cases := []struct {
+		name        string
+		filter      Filter
+		repoFunc    func() *accesslogmock.IRepository
+		expectedLen int
+		errCode     string
+	}{
+		{
+			name:   "Scoped to the user tenants",
+			filter: Filter{Filter: page},
+			repoFunc: func() *accesslogmock.IRepository {
+				repoMock := &accesslogmock.IRepository{}
+				repoMock.On("Retrieve", mock.Anything, mock.MatchedBy(func(f access_log.Filter) bool {
+					return assert.ObjectsAreEqual([]int{1, 2}, f.TenantID) && f.From == 1
+				})).Return([]access_log.Model{{ID: "1", Filter: raw}, {ID: "2", Filter: raw}}, 2, nil)
+				return repoMock
+			},
+			expectedLen: 2,
+		},
+		{
+			name:   "Requested tenants intersected",
+			filter: Filter{TenantID: []int{2, 9}, Filter: page},
+			repoFunc: func() *accesslogmock.IRepository {
+				repoMock := &accesslogmock.IRepository{}
+				repoMock.On("Retrieve", mock.Anything, mock.MatchedBy(func(f access_log.Filter) bool {
+					return assert.ObjectsAreEqual([]int{2}, f.TenantID)
+				})).Return([]access_log.Model{{ID: "1", Filter: raw}}, 1, nil)
+				return repoMock
+			},
+			expectedLen: 1,
+		},
+		{
+			name:   "No tenant allowed",
+			filter: Filter{TenantID: []int{9}, Filter: page},
+			repoFunc: func() *accesslogmock.IRepository {
+				return &accesslogmock.IRepository{}
+			},
+		},
+		{
+			name:   "Repository error",
+			filter: Filter{Filter: page},
+			repoFunc: func() *accesslogmock.IRepository {
+				repoMock := &accesslogmock.IRepository{}
+				repoMock.On("Retrieve", mock.Anything, mock.Anything).Return(nil, 0, errors.New("db down"))
+				return repoMock
+			},
+			errCode: terrors.ErrInternalService,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := NewDefaultService(ctxLogger, tc.repoFunc()).Retrieve(testContext(), tc.filter)
+			if tc.errCode != "" {
+				assert.True(t, terrors.Is(err, tc.errCode), "unexpected error %v", err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Len(t, res.Data, tc.expectedLen)
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package access_log
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmontesinos91/omnilogger/domains/pagination"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/access_log"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/jmontesinos91/terrors"
+	"github.com/samber/lo"
+)
+
+// dateFormat format of the start_at and end_at params, the same used by the logs filters
+const dateFormat = "2006-01-02T15:04:05"
+
+func ToModel(entry Entry, requestID string, claims sts.Claims) *access_log.Model {
+	date := time.Now().UTC()
+
+	filter, err := json.Marshal(entry.Filter)
+	if err != nil || entry.Filter == nil {
+		filter = []byte("{}")
+	}
+
+	outcome := OutcomeSuccess
+	if entry.Err != nil {
+		outcome = OutcomeFailure
+	}
+
+	// The tenants actually read, the requested ones the user has access to
+	tenants := claims.Tenants
+	if len(entry.Tenants) > 0 {
+		tenants = lo.Intersect(claims.Tenants, entry.Tenants)
+	}
+	if tenants == nil {
+		tenants = []int{}
+	}
+
+	return &access_log.Model{
+		ID:        uuid.NewString(),
+		RequestID: requestID,
+		UserID:    claims.UserID,
+		UserName:  claims.User,
+		Role:      claims.Role,
+		Tenants:   tenants,
+		Action:    entry.Action,
+		LogID:     entry.LogID,
+		Filter:    filter,
+		RowCount:  entry.RowCount,
+		Outcome:   outcome,
+		CreatedAt: &date,
+	}
+}
+
+func ToResponse(model *access_log.Model) *Response {
+	return &Response{
+		ID:        model.ID,
+		RequestID: model.RequestID,
+		UserID:    model.UserID,
+		UserName:  model.UserName,
+		Role:      model.Role,
+		Tenants:   model.Tenants,
+		Action:    model.Action,
+		LogID:     model.LogID,
+		Filter:    model.Filter,
+		RowCount:  model.RowCount,
+		Outcome:   model.Outcome,
+		CreatedAt: model.CreatedAt,
+	}
+}
+
+func ToRepoFilter(filter Filter) access_log.Filter {
+	from := ((filter.Page * filter.Size) - filter.Size) + 1
+
+	return access_log.Filter{
+		TenantID: filter.TenantID,
+		UserID:   filter.UserID,
+		Action:   filter.Action,
+		StartAt:  filter.StartAt,
+		EndAt:    filter.EndAt,
+		From:     from,
+		Size:     filter.Size,
+	}
+}
+
+func ToParseFilterRequest(r *http.Request) (Filter, error) {
+	query := r.URL.Query()
+
+	tenantIds, err := toIntArr(query["tenant_id[]"])
+	if err != nil {
+		return Filter{}, terrors.New(terrors.ErrBadRequest, "Invalid tenant_id param", map[string]string{})
+	}
+
+	userIds, err := toIntArr(query["user_id[]"])
+	if err != nil {
+		return Filter{}, terrors.New(terrors.ErrBadRequest, "Invalid user_id param", map[string]string{})
+	}
+
+	var startAt, endAt time.Time
+	if query.Get("start_at") != "" {
+		startAt, err = time.Parse(dateFormat, query.Get("start_at"))
+		if err != nil {
+			return Filter{}, terrors.New(terrors.ErrBadRequest, "Invalid start_at param", map[string]string{})
+		}
+	}
+
+	if query.Get("end_at") != "" {
+		endAt, err = time.Parse(dateFormat, query.Get("end_at"))
+		if err != nil {
+			return Filter{}, terrors.New(terrors.ErrBadRequest, "Invalid end_at param", map[string]string{})
+		}
+	}
+
+	page := pagination.Filter{
+		Size: pagination.DefaultSizeValue,
+		Page: 1,
+	}
+
+	if query.Get("max") != "" {
+		size, err := strconv.Atoi(query.Get("max"))
+		if err != nil {
+			return Filter{}, terrors.New(terrors.ErrBadRequest, "Invalid max param", map[string]string{})
+		}
+		page.Size = size
+	}
+
+	if query.Get("page") != "" {
+		pageNumber, err := strconv.Atoi(query.Get("page"))
+		if err != nil {
+			return Filter{}, terrors.New(terrors.ErrBadRequest, "Invalid page param", map[string]string{})
+		}
+		page.Page = pageNumber
+	}
+
+	if err := page.SanitizePageFilter(); err != nil {
+		return Filter{}, err
+	}
+	if page.Page < 1 {
+		page.Page = 1
+	}
+
+	return Filter{
+		TenantID: tenantIds,
+		UserID:   userIds,
+		Action:   query["action[]"],
+		StartAt:  startAt,
+		EndAt:    endAt,
+		Filter:   page,
+	}, nil
+}
+
+func toIntArr(values []string) ([]int, error) {
+	var res []int
+	for _, value := range values {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, id)
+	}
+
+	return res, nil
+}
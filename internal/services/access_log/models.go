@@ -0,0 +1,64 @@
+package access_log
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/jmontesinos91/omnilogger/domains/pagination"
+)
+
+// Actions recorded on the access logs
+const (
+	ActionRetrieve = "logs.retrieve"
+	ActionGet      = "logs.get"
+	ActionExport   = "logs.export"
+)
+
+// Outcomes of the recorded calls
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Entry read or export of audit logs to record, the user and request id are taken from the context. Tenants are
+// the tenants requested by the read, none reads every tenant of the user
+type Entry struct {
+	Action   string
+	LogID    string
+	Tenants  []int
+	Filter   interface{}
+	RowCount int
+	Err      error
+}
+
+// Response Holds the information of an access log
+type Response struct {
+	ID        string          `json:"id"`
+	RequestID string          `json:"requestId"`
+	UserID    int             `json:"userId"`
+	UserName  string          `json:"userName"`
+	Role      string          `json:"role"`
+	Tenants   []int           `json:"tenants"`
+	Action    string          `json:"action"`
+	LogID     string          `json:"logId,omitempty"`
+	Filter    json.RawMessage `json:"filter"`
+	RowCount  int             `json:"rowCount"`
+	Outcome   string          `json:"outcome"`
+	CreatedAt *time.Time      `json:"createdAt,omitempty"`
+}
+
+type Filter struct {
+	TenantID []int
+	UserID   []int
+	Action   []string
+	StartAt  time.Time
+	EndAt    time.Time
+	pagination.Filter
+}
+
+type PaginatedRes struct {
+	Data  []Response `json:"data"`
+	Size  int        `json:"max"`
+	Total int        `json:"total"`
+	Page  int        `json:"currentPage"`
+}
@@ -0,0 +1,11 @@
+package access_log
+
+import (
+	"context"
+)
+
+// IService Manage access log interfaces
+type IService interface {
+	Record(ctx context.Context, entry Entry)
+	Retrieve(ctx context.Context, filter Filter) (*PaginatedRes, error)
+}
@@ -0,0 +1,65 @@
+package apikeysvcmock
+
+import (
+	"context"
+
+	"github.com/jmontesinos91/omnilogger/internal/services/api_key"
+)
+
+type IService struct {
+	// Create
+	CreateErr    error
+	CreateCalled bool
+
+	// Retrieve
+	RetrieveErr    error
+	RetrieveRes    *api_key.PaginatedRes
+	RetrieveCalled bool
+
+	// Revoke
+	RevokeErr    error
+	RevokeCalled bool
+
+	// Authenticate
+	AuthenticateErr    error
+	AuthenticateRes    *api_key.Principal
+	AuthenticateCalled bool
+}
+
+func (m *IService) Create(ctx context.Context, payload *api_key.Payload) (*api_key.CreatedResponse, error) {
+	m.CreateCalled = true
+	if m.CreateErr != nil {
+		return nil, m.CreateErr
+	}
+	return &api_key.CreatedResponse{
+		Response: api_key.Response{ID: "1", Name: payload.Name, TenantID: payload.TenantID, Scopes: payload.Scopes},
+		Key:      api_key.KeyPrefix + "00000000_secret",
+	}, nil
+}
+
+func (m *IService) Retrieve(ctx context.Context, filter api_key.Filter) (*api_key.PaginatedRes, error) {
+	m.RetrieveCalled = true
+	if m.RetrieveErr != nil {
+		return nil, m.RetrieveErr
+	}
+	if m.RetrieveRes != nil {
+		return m.RetrieveRes, nil
+	}
+	return &api_key.PaginatedRes{}, nil
+}
+
+func (m *IService) Revoke(ctx context.Context, id string) error {
+	m.RevokeCalled = true
+	return m.RevokeErr
+}
+
+func (m *IService) Authenticate(ctx context.Context, key string) (*api_key.Principal, error) {
+	m.AuthenticateCalled = true
+	if m.AuthenticateErr != nil {
+		return nil, m.AuthenticateErr
+	}
+	if m.AuthenticateRes != nil {
+		return m.AuthenticateRes, nil
+	}
+	return &api_key.Principal{KeyID: "1", TenantID: 1, Scopes: []string{api_key.ScopeLogsWrite}}, nil
+}
@@ -0,0 +1,186 @@
+package api_key
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-playground/validator/v10"
+	"github.com/jmontesinos91/ologs/logger"
+	tracekey "github.com/jmontesinos91/ologs/logger/v2"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/api_key"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/jmontesinos91/terrors"
+	"github.com/samber/lo"
+	lop "github.com/samber/lo/parallel"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultService struct
+type DefaultService struct {
+	log         *logger.ContextLogger
+	validate    *validator.Validate
+	apiKeysRepo api_key.IRepository
+}
+
+// NewDefaultService creates a new instance of DefaultService api key
+func NewDefaultService(l *logger.ContextLogger, v *validator.Validate, r api_key.IRepository) *DefaultService {
+	return &DefaultService{
+		log:         l,
+		validate:    v,
+		apiKeysRepo: r,
+	}
+}
+
+// Create generates a new api key for one of the tenants of the user
+func (s *DefaultService) Create(ctx context.Context, payload *Payload) (*CreatedResponse, error) {
+	requestID := ctx.Value(middleware.RequestIDKey).(string)
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	if err := payload.SanitizeAndValidate(s.validate); err != nil {
+		return nil, terrors.New(terrors.ErrBadRequest, err.Error(), map[string]string{})
+	}
+
+	if !lo.Contains(claims.Tenants, payload.TenantID) {
+		return nil, terrors.Unauthorized(terrors.ErrUnauthorized, "Tenant not allowed", map[string]string{})
+	}
+
+	key, prefix, err := GenerateKey()
+	if err != nil {
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			"Create",
+			"Error while generating api key: %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+			},
+			err)
+		return nil, terrors.New(terrors.ErrInternalService, "Internal error service", map[string]string{})
+	}
+
+	model := ToModel(payload, prefix, HashKey(key), strconv.Itoa(claims.UserID))
+
+	err = s.apiKeysRepo.Create(ctx, model)
+	if err != nil {
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			"Create",
+			"Error while persisting api key: %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+				tracekey.UserID:     claims.UserID,
+			},
+			err)
+		return nil, terrors.New(terrors.ErrInternalService, "Internal error service", map[string]string{})
+	}
+
+	return &CreatedResponse{
+		Response: *ToResponse(model),
+		Key:      key,
+	}, nil
+}
+
+// Retrieve lists the api keys of the tenants the user has access to
+func (s *DefaultService) Retrieve(ctx context.Context, filter Filter) (*PaginatedRes, error) {
+	requestID := ctx.Value(middleware.RequestIDKey).(string)
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	if len(filter.TenantID) > 0 {
+		filter.TenantID = lo.Intersect(claims.Tenants, filter.TenantID)
+	} else {
+		filter.TenantID = claims.Tenants
+	}
+
+	if len(filter.TenantID) == 0 {
+		return &PaginatedRes{Data: []Response{}, Size: filter.Size, Page: filter.Page}, nil
+	}
+
+	res, total, err := s.apiKeysRepo.Retrieve(ctx, ToRepoFilter(filter))
+	if err != nil {
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			"Retrieve",
+			"Error while retrieve api keys: %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+			},
+			err)
+		return nil, terrors.New(terrors.ErrInternalService, "Internal error service", map[string]string{})
+	}
+
+	items := lop.Map(res, func(p api_key.Model, _ int) Response {
+		return *ToResponse(&p)
+	})
+
+	return &PaginatedRes{
+		Data:  items,
+		Size:  filter.Size,
+		Total: total,
+		Page:  filter.Page,
+	}, nil
+}
+
+// Revoke revokes an api key, further requests using it are rejected
+func (s *DefaultService) Revoke(ctx context.Context, id string) error {
+	requestID := ctx.Value(middleware.RequestIDKey).(string)
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	if id == "" {
+		return terrors.New(terrors.ErrBadRequest, "Missing id param", map[string]string{})
+	}
+
+	model, err := s.apiKeysRepo.FindByID(ctx, id)
+	if err != nil {
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			"Revoke",
+			"Error while retrieve api key: %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+			},
+			err)
+		return terrors.New(terrors.ErrNotFound, "Api key not found", map[string]string{})
+	}
+
+	// Keys of other tenants are reported as not found to avoid leaking their existence
+	if !lo.Contains(claims.Tenants, model.TenantID) {
+		return terrors.New(terrors.ErrNotFound, "Api key not found", map[string]string{})
+	}
+
+	err = s.apiKeysRepo.Revoke(ctx, id, time.Now().UTC())
+	if err != nil {
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			"Revoke",
+			"Error while revoking api key: %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+				tracekey.UserID:     claims.UserID,
+			},
+			err)
+		return terrors.New(terrors.ErrInternalService, "Internal error service", map[string]string{})
+	}
+
+	return nil
+}
+
+// Authenticate validates an api key and returns the principal it represents
+func (s *DefaultService) Authenticate(ctx context.Context, key string) (*Principal, error) {
+	if len(key) <= len(KeyPrefix) || key[:len(KeyPrefix)] != KeyPrefix {
+		return nil, terrors.Unauthorized(terrors.ErrUnauthorized, "Invalid credentials", map[string]string{})
+	}
+
+	model, err := s.apiKeysRepo.FindByHash(ctx, HashKey(key))
+	if err != nil {
+		s.log.Error(logrus.ErrorLevel, "Authenticate", "Api key lookup failure", err)
+		return nil, terrors.Unauthorized(terrors.ErrUnauthorized, "Invalid credentials", map[string]string{})
+	}
+
+	// Usage tracking must not block ingestion
+	if err := s.apiKeysRepo.TouchLastUsed(ctx, model.ID, time.Now().UTC()); err != nil {
+		s.log.Error(logrus.WarnLevel, "Authenticate", "Failed to update api key last usage", err)
+	}
+
+	return ToPrincipal(model), nil
+}
@@ -0,0 +1,228 @@
+package api_key
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-playground/validator/v10"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/api_key"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/api_key/apikeymock"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/jmontesinos91/terrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func testContext() context.Context {
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "test-request-id")
+	return context.WithValue(ctx, &sts.Claim, sts.Claims{UserID: 42, Tenants: []int{1, 2}})
+}
+
+func TestCreate(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestCreate", "debug", logger.TextFormat)
+
+	cases := []struct {
+		name     string
+		payload  *Payload
+		repoFunc func() *apikeymock.IRepository
+		errCode  string
+	}{
+		{
+			name:    "Happy path",
+			payload: &Payload{Name: "billing", TenantID: 1, Scopes: []string{ScopeLogsWrite}},
+			repoFunc: func() *apikeymock.IRepository {
+				repoMock := &apikeymock.IRepository{}
+				repoMock.On("Create", mock.Anything, mock.Anything).Return(nil)
+				return repoMock
+			},
+		},
+		{
+			name:    "Invalid scope",
+			payload: &Payload{Name: "billing", TenantID: 1, Scopes: []string{"logs:admin"}},
+			repoFunc: func() *apikeymock.IRepository {
+				return &apikeymock.IRepository{}
+			},
+			errCode: terrors.ErrBadRequest,
+		},
+		{
+			name:    "Tenant not allowed",
+			payload: &Payload{Name: "billing", TenantID: 9, Scopes: []string{ScopeLogsWrite}},
+			repoFunc: func() *apikeymock.IRepository {
+				return &apikeymock.IRepository{}
+			},
+			errCode: terrors.ErrUnauthorized,
+		},
+		{
+			name:    "Repository error",
+			payload: &Payload{Name: "billing", TenantID: 2, Scopes: []string{ScopeLogsWrite}},
+			repoFunc: func() *apikeymock.IRepository {
+				repoMock := &apikeymock.IRepository{}
+				repoMock.On("Create", mock.Anything, mock.Anything).Return(errors.New("db down"))
+				return repoMock
+			},
+			errCode: terrors.ErrInternalService,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repoMock := tc.repoFunc()
+			svc := NewDefaultService(ctxLogger, validator.New(), repoMock)
+
+			res, err := svc.Create(testContext(), tc.payload)
+			if tc.errCode != "" {
+				assert.Nil(t, res)
+				assert.True(t, terrors.Is(err, tc.errCode), "unexpected error %v", err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.payload.TenantID, res.TenantID)
+			assert.Equal(t, "42", res.CreatedBy)
+			assert.Equal(t, res.Prefix, res.Key[len(KeyPrefix):len(KeyPrefix)+len(res.Prefix)])
+			repoMock.AssertCalled(t, "Create", mock.Anything, mock.MatchedBy(func(m *api_key.Model) bool {
+				return m.KeyHash == HashKey(res.Key)
+			}))
+		})
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestRevoke", "debug", logger.TextFormat)
+
+	cases := []struct {
+		name     string
+		id       string
+		repoFunc func() *apikeymock.IRepository
+		errCode  string
+	}{
+		{
+			name: "Happy path",
+			id:   "key-1",
+			repoFunc: func() *apikeymock.IRepository {
+				repoMock := &apikeymock.IRepository{}
+				repoMock.On("FindByID", mock.Anything, "key-1").Return(&api_key.Model{ID: "key-1", TenantID: 1}, nil)
+				repoMock.On("Revoke", mock.Anything, "key-1", mock.Anything).Return(nil)
+				return repoMock
+			},
+		},
+		{
+			name: "Empty id",
+			repoFunc: func() *apikeymock.IRepository {
+				return &apikeymock.IRepository{}
+			},
+			errCode: terrors.ErrBadRequest,
+		},
+		{
+			name: "Not found",
+			id:   "key-1",
+			repoFunc: func() *apikeymock.IRepository {
+				repoMock := &apikeymock.IRepository{}
+				repoMock.On("FindByID", mock.Anything, "key-1").Return(nil, errors.New("no rows"))
+				return repoMock
+			},
+			errCode: terrors.ErrNotFound,
+		},
+		{
+			name: "Key of another tenant",
+			id:   "key-1",
+			repoFunc: func() *apikeymock.IRepository {
+				repoMock := &apikeymock.IRepository{}
+				repoMock.On("FindByID", mock.Anything, "key-1").Return(&api_key.Model{ID: "key-1", TenantID: 9}, nil)
+				return repoMock
+			},
+			errCode: terrors.ErrNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repoMock := tc.repoFunc()
+			svc := NewDefaultService(ctxLogger, validator.New(), repoMock)
+
+			err := svc.Revoke(testContext(), tc.id)
+			if tc.errCode != "" {
+				assert.True(t, terrors.Is(err, tc.errCode), "unexpected error %v", err)
+				repoMock.AssertNotCalled(t, "Revoke", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+
+			assert.NoError(t, err)
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestAuthenticate", "debug", logger.TextFormat)
+	key := KeyPrefix + "0a1b2c3d_secret"
+
+	cases := []struct {
+		name     string
+		key      string
+		repoFunc func() *apikeymock.IRepository
+		err      bool
+	}{
+		{
+			name: "Happy path",
+			key:  key,
+			repoFunc: func() *apikeymock.IRepository {
+				repoMock := &apikeymock.IRepository{}
+				repoMock.On("FindByHash", mock.Anything, HashKey(key)).
+					Return(&api_key.Model{ID: "key-1", Name: "billing", TenantID: 7, Scopes: []string{ScopeLogsWrite}}, nil)
+				repoMock.On("TouchLastUsed", mock.Anything, "key-1", mock.Anything).Return(nil)
+				return repoMock
+			},
+		},
+		{
+			name: "Usage tracking failure does not reject the key",
+			key:  key,
+			repoFunc: func() *apikeymock.IRepository {
+				repoMock := &apikeymock.IRepository{}
+				repoMock.On("FindByHash", mock.Anything, HashKey(key)).
+					Return(&api_key.Model{ID: "key-1", Name: "billing", TenantID: 7, Scopes: []string{ScopeLogsWrite}}, nil)
+				repoMock.On("TouchLastUsed", mock.Anything, "key-1", mock.Anything).Return(errors.New("db down"))
+				return repoMock
+			},
+		},
+		{
+			name: "Missing prefix",
+			key:  "0a1b2c3d_secret",
+			repoFunc: func() *apikeymock.IRepository {
+				return &apikeymock.IRepository{}
+			},
+			err: true,
+		},
+		{
+			name: "Unknown or revoked key",
+			key:  key,
+			repoFunc: func() *apikeymock.IRepository {
+				repoMock := &apikeymock.IRepository{}
+				repoMock.On("FindByHash", mock.Anything, HashKey(key)).Return(nil, errors.New("no rows"))
+				return repoMock
+			},
+			err: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := NewDefaultService(ctxLogger, validator.New(), tc.repoFunc())
+
+			principal, err := svc.Authenticate(context.Background(), tc.key)
+			if tc.err {
+				assert.Nil(t, principal)
+				assert.True(t, terrors.Is(err, terrors.ErrUnauthorized), "unexpected error %v", err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, "key-1", principal.KeyID)
+			assert.Equal(t, 7, principal.TenantID)
+			assert.True(t, principal.HasScope(ScopeLogsWrite))
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package api_key
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmontesinos91/omnilogger/domains/pagination"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/api_key"
+	"github.com/jmontesinos91/omnilogger/internal/services/logs"
+)
+
+// KeyPrefix every api key starts with this prefix so leaked keys are easy to spot
+const KeyPrefix = "olk_"
+
+type principalKey struct{}
+
+// GenerateKey generates a new random api key, it returns the key and its lookup prefix
+func GenerateKey() (string, string, error) {
+	prefix := make([]byte, 4)
+	if _, err := rand.Read(prefix); err != nil {
+		return "", "", err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+
+	lookup := hex.EncodeToString(prefix)
+
+	return KeyPrefix + lookup + "_" + base64.RawURLEncoding.EncodeToString(secret), lookup, nil
+}
+
+// HashKey returns the hex encoded sha256 of an api key
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func ToModel(payload *Payload, prefix string, hash string, createdBy string) *api_key.Model {
+	date := time.Now().UTC()
+
+	return &api_key.Model{
+		ID:         uuid.NewString(),
+		Name:       payload.Name,
+		TenantID:   payload.TenantID,
+		TenantName: payload.TenantName,
+		Prefix:     prefix,
+		KeyHash:    hash,
+		Scopes:     payload.Scopes,
+		CreatedBy:  createdBy,
+		CreatedAt:  &date,
+	}
+}
+
+func ToResponse(model *api_key.Model) *Response {
+	return &Response{
+		ID:         model.ID,
+		Name:       model.Name,
+		TenantID:   model.TenantID,
+		TenantName: model.TenantName,
+		Prefix:     model.Prefix,
+		Scopes:     model.Scopes,
+		CreatedBy:  model.CreatedBy,
+		CreatedAt:  model.CreatedAt,
+		LastUsedAt: model.LastUsedAt,
+		RevokedAt:  model.RevokedAt,
+	}
+}
+
+func ToPrincipal(model *api_key.Model) *Principal {
+	return &Principal{
+		KeyID:      model.ID,
+		Name:       model.Name,
+		TenantID:   model.TenantID,
+		TenantName: model.TenantName,
+		Scopes:     model.Scopes,
+	}
+}
+
+func ToRepoFilter(filter Filter) api_key.Filter {
+	from := ((filter.Page * filter.Size) - filter.Size) + 1
+
+	return api_key.Filter{
+		TenantID:       filter.TenantID,
+		IncludeRevoked: filter.IncludeRevoked,
+		From:           from,
+		Size:           filter.Size,
+	}
+}
+
+func ToParseFilterRequest(r *http.Request) (Filter, error) {
+	query := r.URL.Query()
+
+	var tenantIds []int
+	for _, str := range query["tenant_id[]"] {
+		id, err := strconv.Atoi(str)
+		if err != nil {
+			return Filter{}, err
+		}
+		tenantIds = append(tenantIds, id)
+	}
+
+	page := pagination.Filter{
+		Size: pagination.DefaultSizeValue,
+		Page: 1,
+	}
+
+	if query.Get("max") != "" {
+		size, err := strconv.Atoi(query.Get("max"))
+		if err != nil {
+			return Filter{}, err
+		}
+		page.Size = size
+	}
+
+	if query.Get("page") != "" {
+		pageNumber, err := strconv.Atoi(query.Get("page"))
+		if err != nil {
+			return Filter{}, err
+		}
+		page.Page = pageNumber
+	}
+
+	if err := page.SanitizePageFilter(); err != nil {
+		return Filter{}, err
+	}
+	if page.Page < 1 {
+		page.Page = 1
+	}
+
+	return Filter{
+		TenantID:       tenantIds,
+		IncludeRevoked: strings.EqualFold(query.Get("include_revoked"), "true"),
+		Filter:         page,
+	}, nil
+}
+
+// HasScope validates whether the api key was granted the given scope
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// TenantCat returns the tenant catalog json stamped on logs created with this api key
+func (p *Principal) TenantCat() string {
+	b, _ := json.Marshal([]logs.Item{{ID: p.TenantID, Name: p.TenantName}})
+	return string(b)
+}
+
+// StorePrincipalInContext propagates the authenticated api key through the context
+func StorePrincipalInContext(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the api key that authenticated the request, if any
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
@@ -0,0 +1,90 @@
+package api_key
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateKey(t *testing.T) {
+	key, prefix, err := GenerateKey()
+
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, KeyPrefix+prefix+"_"))
+	assert.Len(t, prefix, 8)
+
+	other, _, err := GenerateKey()
+	assert.NoError(t, err)
+	assert.NotEqual(t, key, other)
+}
+
+func TestHashKey(t *testing.T) {
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", HashKey("hello"))
+	assert.NotEqual(t, HashKey("olk_a"), HashKey("olk_b"))
+}
+
+func TestPrincipal(t *testing.T) {
+	principal := &Principal{KeyID: "1", Name: "billing", TenantID: 7, TenantName: "Acme", Scopes: []string{ScopeLogsWrite}}
+
+	assert.True(t, principal.HasScope(ScopeLogsWrite))
+	assert.False(t, principal.HasScope("logs:read"))
+	assert.Equal(t, `[{"id":7,"name":"Acme"}]`, principal.TenantCat())
+
+	_, ok := PrincipalFromContext(context.Background())
+	assert.False(t, ok)
+
+	res, ok := PrincipalFromContext(StorePrincipalInContext(context.Background(), principal))
+	assert.True(t, ok)
+	assert.Equal(t, principal, res)
+}
+
+func TestToParseFilterRequest(t *testing.T) {
+	cases := []struct {
+		name    string
+		query   string
+		tenants []int
+		revoked bool
+		size    int
+		page    int
+		err     bool
+	}{
+		{
+			name:  "Defaults",
+			query: "",
+			size:  10,
+			page:  1,
+		},
+		{
+			name:    "All params",
+			query:   "?tenant_id[]=1&tenant_id[]=2&max=5&page=3&include_revoked=true",
+			tenants: []int{1, 2},
+			revoked: true,
+			size:    5,
+			page:    3,
+		},
+		{
+			name:  "Invalid tenant",
+			query: "?tenant_id[]=abc",
+			err:   true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/v1/api_keys"+tc.query, nil)
+			filter, err := ToParseFilterRequest(req)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.tenants, filter.TenantID)
+			assert.Equal(t, tc.revoked, filter.IncludeRevoked)
+			assert.Equal(t, tc.size, filter.Size)
+			assert.Equal(t, tc.page, filter.Page)
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package api_key
+
+import (
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jmontesinos91/omnilogger/domains/pagination"
+)
+
+// Scopes that can be granted to an api key
+const (
+	// ScopeLogsWrite allows to ingest logs
+	ScopeLogsWrite = "logs:write"
+)
+
+// Payload payload to create an api key
+type Payload struct {
+	Name       string   `json:"name" validate:"required,max=100"`
+	TenantID   int      `json:"tenant_id" validate:"required"`
+	TenantName string   `json:"tenant_name" validate:"max=100"`
+	Scopes     []string `json:"scopes" validate:"required,min=1,dive,oneof=logs:write"`
+}
+
+// Response Holds the public information of an api key, the secret is never returned
+type Response struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	TenantID   int        `json:"tenantId"`
+	TenantName string     `json:"tenantName"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  string     `json:"createdBy"`
+	CreatedAt  *time.Time `json:"createdAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+}
+
+// CreatedResponse Holds a newly created api key, it is the only time the key is returned
+type CreatedResponse struct {
+	Response
+	Key string `json:"key"`
+}
+
+type Filter struct {
+	TenantID       []int
+	IncludeRevoked bool
+	pagination.Filter
+}
+
+type PaginatedRes struct {
+	Data  []Response `json:"data"`
+	Size  int        `json:"max"`
+	Total int        `json:"total"`
+	Page  int        `json:"currentPage"`
+}
+
+// Principal holds the api key that authenticated a request
+type Principal struct {
+	KeyID      string
+	Name       string
+	TenantID   int
+	TenantName string
+	Scopes     []string
+}
+
+func (r *Payload) SanitizeAndValidate(validate *validator.Validate) error {
+	return validate.Struct(r)
+}
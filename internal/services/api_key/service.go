@@ -0,0 +1,13 @@
+package api_key
+
+import (
+	"context"
+)
+
+// IService Manage api key interfaces
+type IService interface {
+	Create(ctx context.Context, payload *Payload) (*CreatedResponse, error)
+	Retrieve(ctx context.Context, filter Filter) (*PaginatedRes, error)
+	Revoke(ctx context.Context, id string) error
+	Authenticate(ctx context.Context, key string) (*Principal, error)
+}
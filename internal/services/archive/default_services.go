@@ -0,0 +1,683 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/jmontesinos91/ologs/logger"
+	tracekey "github.com/jmontesinos91/ologs/logger/v2"
+	"github.com/jmontesinos91/omnilogger/config"
+	"github.com/jmontesinos91/omnilogger/internal/adapters/objectstore"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/archive"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/logs"
+	"github.com/jmontesinos91/omnilogger/internal/utils/export"
+	"github.com/jmontesinos91/omnilogger/internal/utils/text"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/jmontesinos91/terrors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/samber/lo"
+	lop "github.com/samber/lo/parallel"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrAlreadyRunning a run was requested while another one is in progress
+var ErrAlreadyRunning = errors.New("archive: already running")
+
+var (
+	filesMetric = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "archive_files_total",
+		Help: "The total number of archive files written and verified",
+	})
+	rowsMetric = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "archive_rows_total",
+		Help: "The total number of logs written to archive files",
+	})
+	purgedMetric = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "archive_purged_rows_total",
+		Help: "The total number of archived logs deleted from the database",
+	})
+	failuresMetric = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "archive_failures_total",
+		Help: "The total number of tenant days that failed to be archived",
+	})
+	restoresMetric = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "archive_restores_total",
+		Help: "The total number of archive files restored, partitioned by status ready, failed or expired",
+	}, []string{"status"})
+	lastRunMetric = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "archive_last_run_timestamp_seconds",
+		Help: "The time the last archive run finished",
+	})
+)
+
+// DefaultService writes the old logs to archive files, one per tenant and day
+type DefaultService struct {
+	log         *logger.ContextLogger
+	logsRepo    logs.IRepository
+	archiveRepo archive.IRepository
+	store       objectstore.IStore
+	signer      *export.Signer
+	config      config.ArchiveConfigurations
+	restoreTTL  time.Duration
+	running     atomic.Bool
+	restores    sync.WaitGroup
+	slots       chan struct{}
+	now         func() time.Time
+}
+
+// NewDefaultService creates a new instance of DefaultService archive, it fails when the format is not valid
+func NewDefaultService(l *logger.ContextLogger, lr logs.IRepository, ar archive.IRepository, st objectstore.IStore, sg *export.Signer, c config.ArchiveConfigurations) (*DefaultService, error) {
+	if c.Format == "" {
+		c.Format = FormatNDJSON
+	}
+	if c.Format != FormatNDJSON && c.Format != FormatParquet {
+		return nil, fmt.Errorf("invalid archive format %q, use %s or %s", c.Format, FormatNDJSON, FormatParquet)
+	}
+
+	if c.MaxDaysPerRun <= 0 {
+		c.MaxDaysPerRun = defaultMaxDaysPerRun
+	}
+
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaultBatchSize
+	}
+
+	restoreTTL := defaultRestoreTTL
+	if c.RestoreTTLInHours > 0 {
+		restoreTTL = time.Duration(c.RestoreTTLInHours) * time.Hour
+	}
+
+	if c.MaxRestores <= 0 {
+		c.MaxRestores = defaultMaxRestores
+	}
+
+	return &DefaultService{
+		log:         l,
+		logsRepo:    lr,
+		archiveRepo: ar,
+		store:       st,
+		signer:      sg,
+		config:      c,
+		restoreTTL:  restoreTTL,
+		slots:       make(chan struct{}, c.MaxRestores),
+		now:         time.Now,
+	}, nil
+}
+
+// Start runs the archive in background every interval, it stops once ctx is done
+func (s *DefaultService) Start(ctx context.Context) {
+	if !s.config.Enabled {
+		s.log.Log(logrus.WarnLevel, "Start", "Archive not enabled, logs are not copied to the archive store")
+		return
+	}
+
+	interval := defaultInterval
+	if s.config.IntervalInMinutes > 0 {
+		interval = time.Duration(s.config.IntervalInMinutes) * time.Minute
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := s.Run(ctx); err != nil && !errors.Is(err, ErrAlreadyRunning) {
+				s.log.Error(logrus.ErrorLevel, "Start", "Archive run failed", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	s.log.Log(logrus.InfoLevel, "Start", "Archive of "+s.config.Format+" files scheduled every "+interval.String())
+}
+
+// Run archives the tenant days older than after-days not archived yet, each file is uploaded with its manifest
+// and read back to verify its checksum before the archive is recorded and, when enabled, its logs are deleted.
+// The logs of the expired restores are deleted as well, and the restores interrupted by a restart are failed
+func (s *DefaultService) Run(ctx context.Context) (*RunResult, error) {
+	if !s.running.CompareAndSwap(false, true) {
+		return nil, ErrAlreadyRunning
+	}
+	defer s.running.Store(false)
+
+	now := s.now().UTC()
+	result := &RunResult{StartedAt: now}
+
+	err := errors.Join(s.archive(ctx, now, result), s.expire(ctx, now, result), s.failStale(ctx, now, result))
+
+	finishedAt := s.now().UTC()
+	result.FinishedAt = &finishedAt
+	if err != nil {
+		result.Error = err.Error()
+	}
+	lastRunMetric.Set(float64(finishedAt.Unix()))
+
+	s.log.Log(logrus.InfoLevel, "Run", result.summary())
+
+	return result, err
+}
+
+func (s *DefaultService) archive(ctx context.Context, now time.Time, result *RunResult) error {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	before := today.AddDate(0, 0, -s.config.AfterDays)
+
+	days, err := s.logsRepo.ArchivableDays(ctx, before, s.config.MaxDaysPerRun)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, day := range days {
+		if ctx.Err() != nil {
+			errs = append(errs, ctx.Err())
+			break
+		}
+
+		if err := s.archiveDay(ctx, day, result); err != nil {
+			result.Failed++
+			failuresMetric.Inc()
+			errs = append(errs, fmt.Errorf("tenant %d day %s -> %w", day.TenantID, day.Day.Format(time.DateOnly), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *DefaultService) archiveDay(ctx context.Context, day logs.ArchiveDay, result *RunResult) error {
+	file, err := os.CreateTemp("", "omnilogger-archive-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name()) //nolint:errcheck
+	defer file.Close()           //nolint:errcheck
+
+	digest := sha256.New()
+	writer, err := newRecordWriter(s.config.Format, io.MultiWriter(file, digest))
+	if err != nil {
+		return err
+	}
+
+	rows := 0
+	err = s.logsRepo.EachDayLog(ctx, day.TenantID, day.Day, func(m logs.Model) error {
+		rows++
+		return writer.Write(ToRecord(m))
+	})
+	if err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	key := objectKey(s.config.Prefix, day.TenantID, day.Day, s.config.Format)
+	manifest, err := s.manifest(key, day, rows, hex.EncodeToString(digest.Sum(nil)))
+	if err != nil {
+		return err
+	}
+
+	if err := s.store.Put(ctx, key, file, size, contentType(s.config.Format)); err != nil {
+		return err
+	}
+	rawManifest, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := s.store.Put(ctx, manifestKey(key), bytes.NewReader(rawManifest), int64(len(rawManifest)), "application/json"); err != nil {
+		return err
+	}
+
+	if err := s.verify(ctx, key, size, manifest.SHA256); err != nil {
+		return err
+	}
+
+	createdAt := s.now().UTC()
+	model := &archive.Model{
+		ID:          uuid.NewString(),
+		TenantID:    day.TenantID,
+		Day:         day.Day,
+		Format:      s.config.Format,
+		ObjectKey:   key,
+		ManifestKey: manifestKey(key),
+		RowCount:    rows,
+		SizeBytes:   size,
+		SHA256:      manifest.SHA256,
+		CreatedAt:   &createdAt,
+	}
+	if err := s.archiveRepo.Create(ctx, model); err != nil {
+		return err
+	}
+	result.Files++
+	result.Rows += rows
+	filesMetric.Inc()
+	rowsMetric.Add(float64(rows))
+
+	if !s.config.DeleteAfterArchive {
+		return nil
+	}
+
+	return s.purge(ctx, model, result)
+}
+
+// manifest signed manifest of the archive file of a tenant day
+func (s *DefaultService) manifest(key string, day logs.ArchiveDay, rows int, digest string) (*export.Manifest, error) {
+	rawScope, err := json.Marshal(scope{TenantID: day.TenantID, Day: day.Day.Format(time.DateOnly)})
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &export.Manifest{
+		Version:   export.ManifestVersion,
+		Service:   "omnilogger",
+		File:      key,
+		Format:    s.config.Format,
+		CreatedAt: s.now().UTC(),
+		Filter:    rawScope,
+		RowCount:  rows,
+		SHA256:    digest,
+	}
+	if err := s.signer.Sign(manifest); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// verify reads the uploaded file back and compares its size and checksum with the local ones
+func (s *DefaultService) verify(ctx context.Context, key string, size int64, digest string) error {
+	info, err := s.store.Stat(ctx, key)
+	if err != nil {
+		return err
+	}
+	if info.Size != size {
+		return fmt.Errorf("uploaded %s has %d bytes instead of %d", key, info.Size, size)
+	}
+
+	reader, err := s.store.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer reader.Close() //nolint:errcheck
+
+	uploaded := sha256.New()
+	if _, err := io.Copy(uploaded, reader); err != nil {
+		return err
+	}
+	if uploadedDigest := hex.EncodeToString(uploaded.Sum(nil)); uploadedDigest != digest {
+		return fmt.Errorf("uploaded %s digest %s does not match the manifest digest %s", key, uploadedDigest, digest)
+	}
+
+	return nil
+}
+
+// purge deletes the archived logs of the tenant day batch by batch, held logs are kept
+func (s *DefaultService) purge(ctx context.Context, model *archive.Model, result *RunResult) error {
+	purged := 0
+	for ctx.Err() == nil {
+		deleted, err := s.logsRepo.PurgeArchivedDay(ctx, model.TenantID, model.Day, s.config.BatchSize)
+		if err != nil {
+			return err
+		}
+		purged += deleted
+
+		if deleted < s.config.BatchSize {
+			break
+		}
+	}
+	result.Purged += purged
+	purgedMetric.Add(float64(purged))
+
+	if err := s.archiveRepo.MarkPurged(ctx, model.ID, purged, s.now().UTC()); err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+// expire deletes the logs of the restores past their expiry, they are no longer searchable
+func (s *DefaultService) expire(ctx context.Context, now time.Time, result *RunResult) error {
+	restores, err := s.archiveRepo.ExpiredRestores(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, restore := range restores {
+		if _, err := s.logsRepo.PurgeRestored(ctx, restore.ID); err != nil {
+			errs = append(errs, fmt.Errorf("restore %s -> %w", restore.ID, err))
+			continue
+		}
+
+		expiredAt := s.now().UTC()
+		restore.Status = archive.RestoreExpired
+		restore.ExpiredAt = &expiredAt
+		if err := s.archiveRepo.UpdateRestore(ctx, &restore); err != nil {
+			errs = append(errs, fmt.Errorf("restore %s -> %w", restore.ID, err))
+			continue
+		}
+		result.Expired++
+		restoresMetric.WithLabelValues(archive.RestoreExpired).Inc()
+	}
+
+	return errors.Join(errs...)
+}
+
+// failStale fails the restores interrupted by a restart and deletes the logs they loaded
+func (s *DefaultService) failStale(ctx context.Context, now time.Time, result *RunResult) error {
+	// Restores time out after restoreTimeout, the ones still restoring later were interrupted by a restart
+	restores, err := s.archiveRepo.FailStaleRestores(ctx, now.Add(-staleRestoreAfter), "Restore interrupted")
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, restore := range restores {
+		result.Stale++
+		restoresMetric.WithLabelValues(archive.RestoreFailed).Inc()
+		if _, err := s.logsRepo.PurgeRestored(ctx, restore.ID); err != nil {
+			errs = append(errs, fmt.Errorf("restore %s -> %w", restore.ID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Wait waits for the restores in progress to finish, it gives up once ctx is done. The restores still running then
+// are failed by the first run after the restart
+func (s *DefaultService) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.restores.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Retrieve lists the archive files of the tenants the user has access to
+func (s *DefaultService) Retrieve(ctx context.Context, filter Filter) (*PaginatedRes, error) {
+	requestID := ctx.Value(middleware.RequestIDKey).(string)
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	if len(filter.TenantID) > 0 {
+		filter.TenantID = lo.Intersect(claims.Tenants, filter.TenantID)
+	} else {
+		filter.TenantID = claims.Tenants
+	}
+
+	if len(filter.TenantID) == 0 {
+		return &PaginatedRes{Data: []Response{}, Size: filter.Size, Page: filter.Page}, nil
+	}
+
+	if !filter.StartAt.IsZero() && !filter.EndAt.IsZero() && filter.EndAt.Before(filter.StartAt) {
+		return nil, terrors.New(terrors.ErrBadRequest, "end_at must not be before start_at", map[string]string{})
+	}
+
+	res, total, err := s.archiveRepo.Retrieve(ctx, ToRepoFilter(filter))
+	if err != nil {
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			"Retrieve",
+			"Error while retrieve archives: %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+			},
+			err)
+		return nil, terrors.New(terrors.ErrInternalService, "Internal error service", map[string]string{})
+	}
+
+	items := lop.Map(res, func(p archive.Model, _ int) Response {
+		return *ToResponse(&p)
+	})
+
+	return &PaginatedRes{
+		Data:  items,
+		Size:  filter.Size,
+		Total: total,
+		Page:  filter.Page,
+	}, nil
+}
+
+// Restore loads the logs of an archive file into the restored logs in background, they are searchable with
+// archived=true once the restore is ready and until it expires. The active restore of the archive is returned
+// when there is one, at most max-restores archive files are loaded at the same time
+func (s *DefaultService) Restore(ctx context.Context, archiveID string) (*RestoreResponse, error) {
+	requestID := ctx.Value(middleware.RequestIDKey).(string)
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	if s.store == nil {
+		return nil, terrors.New(terrors.ErrBadRequest, "Archive store not configured", map[string]string{})
+	}
+
+	if archiveID == "" {
+		return nil, terrors.New(terrors.ErrBadRequest, "Missing id param", map[string]string{})
+	}
+
+	model, err := s.archiveRepo.FindByID(ctx, archiveID)
+	if err != nil {
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			"Restore",
+			"Error while retrieve archive: %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+			},
+			err)
+		return nil, terrors.New(terrors.ErrNotFound, "Archive not found", map[string]string{})
+	}
+
+	// Archives of other tenants are reported as not found to avoid leaking their existence
+	if !lo.Contains(claims.Tenants, model.TenantID) {
+		return nil, terrors.New(terrors.ErrNotFound, "Archive not found", map[string]string{})
+	}
+
+	active, err := s.archiveRepo.FindActiveRestore(ctx, model.ID)
+	if err != nil {
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			"Restore",
+			"Error while retrieve active restore: %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+			},
+			err)
+		return nil, terrors.New(terrors.ErrInternalService, "Internal error service", map[string]string{})
+	}
+	if active != nil {
+		return ToRestoreResponse(active), nil
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		return nil, terrors.New(terrors.ErrRateLimited, "Too many restores running, try again later", map[string]string{})
+	}
+
+	createdAt := s.now().UTC()
+	restore := &archive.RestoreModel{
+		ID:          uuid.NewString(),
+		ArchiveID:   model.ID,
+		TenantID:    model.TenantID,
+		Day:         model.Day,
+		Status:      archive.RestoreRestoring,
+		RequestedBy: strconv.Itoa(claims.UserID),
+		CreatedAt:   &createdAt,
+		ExpiresAt:   createdAt.Add(s.restoreTTL),
+	}
+	if err := s.archiveRepo.CreateRestore(ctx, restore); err != nil {
+		<-s.slots
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			"Restore",
+			"Error while persisting restore: %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+				tracekey.UserID:     claims.UserID,
+			},
+			err)
+		return nil, terrors.New(terrors.ErrInternalService, "Internal error service", map[string]string{})
+	}
+
+	response := ToRestoreResponse(restore)
+
+	// The restore outlives the request, only its values are kept
+	s.restores.Add(1)
+	go func() {
+		defer s.restores.Done()
+		defer func() { <-s.slots }()
+		s.restore(context.WithoutCancel(ctx), model, restore)
+	}()
+
+	return response, nil
+}
+
+// GetRestore gets the progress of a restore of one of the tenants of the user
+func (s *DefaultService) GetRestore(ctx context.Context, id string) (*RestoreResponse, error) {
+	requestID := ctx.Value(middleware.RequestIDKey).(string)
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	if id == "" {
+		return nil, terrors.New(terrors.ErrBadRequest, "Missing id param", map[string]string{})
+	}
+
+	model, err := s.archiveRepo.FindRestore(ctx, id)
+	if err != nil {
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			"GetRestore",
+			"Error while retrieve restore: %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+			},
+			err)
+		return nil, terrors.New(terrors.ErrNotFound, "Restore not found", map[string]string{})
+	}
+
+	if !lo.Contains(claims.Tenants, model.TenantID) {
+		return nil, terrors.New(terrors.ErrNotFound, "Restore not found", map[string]string{})
+	}
+
+	return ToRestoreResponse(model), nil
+}
+
+// restore downloads the archive file, checks it against the recorded checksum and loads its logs in batches,
+// the logs already loaded are deleted when it fails
+func (s *DefaultService) restore(ctx context.Context, model *archive.Model, restore *archive.RestoreModel) {
+	requestID, _ := ctx.Value(middleware.RequestIDKey).(string)
+
+	loadCtx, cancel := context.WithTimeout(ctx, restoreTimeout)
+	defer cancel()
+
+	rows, err := s.load(loadCtx, model, restore.ID)
+	if err == nil {
+		readyAt := s.now().UTC()
+		restore.Status = archive.RestoreReady
+		restore.RowCount = rows
+		restore.ReadyAt = &readyAt
+	} else {
+		if _, purgeErr := s.logsRepo.PurgeRestored(ctx, restore.ID); purgeErr != nil {
+			err = errors.Join(err, purgeErr)
+		}
+		restore.Status = archive.RestoreFailed
+		restore.Error = text.Truncate(err.Error(), maxRestoreErrorLength)
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			"Restore",
+			"Error while restoring archive "+model.ID+": %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+			},
+			err)
+	}
+	restoresMetric.WithLabelValues(restore.Status).Inc()
+
+	if err := s.archiveRepo.UpdateRestore(ctx, restore); err != nil {
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			"Restore",
+			"Error while updating restore: %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+			},
+			err)
+	}
+}
+
+func (s *DefaultService) load(ctx context.Context, model *archive.Model, restoreID string) (int, error) {
+	file, err := os.CreateTemp("", "omnilogger-restore-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(file.Name()) //nolint:errcheck
+	defer file.Close()           //nolint:errcheck
+
+	reader, err := s.store.Get(ctx, model.ObjectKey)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close() //nolint:errcheck
+
+	digest := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, digest), reader)
+	if err != nil {
+		return 0, err
+	}
+	if fileDigest := hex.EncodeToString(digest.Sum(nil)); fileDigest != model.SHA256 {
+		return 0, fmt.Errorf("archive %s digest %s does not match the recorded digest %s", model.ObjectKey, fileDigest, model.SHA256)
+	}
+
+	rows := 0
+	batch := make([]logs.Model, 0, restoreBatchSize)
+	err = readRecords(model.Format, file, size, func(record Record) error {
+		batch = append(batch, ToModel(record))
+		if len(batch) < restoreBatchSize {
+			return nil
+		}
+
+		rows += len(batch)
+		err := s.logsRepo.InsertRestored(ctx, restoreID, batch)
+		batch = batch[:0]
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	rows += len(batch)
+	if err := s.logsRepo.InsertRestored(ctx, restoreID, batch); err != nil {
+		return 0, err
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,386 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/omnilogger/config"
+	"github.com/jmontesinos91/omnilogger/internal/adapters/objectstore"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/archive"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/archive/archivemock"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/logs"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/logs/logsmock"
+	"github.com/jmontesinos91/omnilogger/internal/utils/export"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/jmontesinos91/terrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// corruptedStore returns other content than the uploaded one
+type corruptedStore struct {
+	objectstore.IStore
+}
+
+func (s corruptedStore) Get(_ context.Context, _ string) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader("corrupted")), nil
+}
+
+func TestRun(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestRun", "debug", logger.TextFormat)
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	createdAt := day.Add(time.Hour)
+	archiveDay := logs.ArchiveDay{TenantID: 5, Day: day, Count: 2}
+	eachLog := func(args mock.Arguments) {
+		fn := args.Get(3).(func(logs.Model) error)
+		_ = fn(logs.Model{ID: "1", CreatedAt: &createdAt, ChainTenantID: 5})
+		_ = fn(logs.Model{ID: "2", CreatedAt: &createdAt, ChainTenantID: 5})
+	}
+
+	cases := []struct {
+		name        string
+		config      config.ArchiveConfigurations
+		corrupted   bool
+		repoFunc    func() *logsmock.IRepository
+		archiveFunc func() *archivemock.IRepository
+		expected    RunResult
+		err         bool
+	}{
+		{
+			name:   "Archive and purge",
+			config: config.ArchiveConfigurations{AfterDays: 30, Prefix: "logs", DeleteAfterArchive: true, BatchSize: 2},
+			repoFunc: func() *logsmock.IRepository {
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("ArchivableDays", mock.Anything, time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC), defaultMaxDaysPerRun).Return([]logs.ArchiveDay{archiveDay}, nil)
+				repoMock.On("EachDayLog", mock.Anything, 5, day, mock.Anything).Run(eachLog).Return(nil)
+				repoMock.On("PurgeArchivedDay", mock.Anything, 5, day, 2).Return(2, nil).Once()
+				repoMock.On("PurgeArchivedDay", mock.Anything, 5, day, 2).Return(0, nil).Once()
+				return repoMock
+			},
+			archiveFunc: func() *archivemock.IRepository {
+				archiveMock := &archivemock.IRepository{}
+				archiveMock.On("Create", mock.Anything, mock.MatchedBy(func(m *archive.Model) bool {
+					return m.TenantID == 5 && m.RowCount == 2 && m.ObjectKey == "logs/tenant=5/2024/01/02/logs-5-20240102.ndjson.gz"
+				})).Return(nil)
+				archiveMock.On("MarkPurged", mock.Anything, mock.Anything, 2, mock.Anything).Return(nil)
+				return archiveMock
+			},
+			expected: RunResult{Files: 1, Rows: 2, Purged: 2},
+		},
+		{
+			name:   "Archive only",
+			config: config.ArchiveConfigurations{AfterDays: 30, Format: FormatParquet},
+			repoFunc: func() *logsmock.IRepository {
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("ArchivableDays", mock.Anything, mock.Anything, mock.Anything).Return([]logs.ArchiveDay{archiveDay}, nil)
+				repoMock.On("EachDayLog", mock.Anything, 5, day, mock.Anything).Run(eachLog).Return(nil)
+				return repoMock
+			},
+			archiveFunc: func() *archivemock.IRepository {
+				archiveMock := &archivemock.IRepository{}
+				archiveMock.On("Create", mock.Anything, mock.Anything).Return(nil)
+				return archiveMock
+			},
+			expected: RunResult{Files: 1, Rows: 2},
+		},
+		{
+			name:      "Upload not verified keeps the logs",
+			config:    config.ArchiveConfigurations{AfterDays: 30, DeleteAfterArchive: true},
+			corrupted: true,
+			repoFunc: func() *logsmock.IRepository {
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("ArchivableDays", mock.Anything, mock.Anything, mock.Anything).Return([]logs.ArchiveDay{archiveDay}, nil)
+				repoMock.On("EachDayLog", mock.Anything, 5, day, mock.Anything).Run(eachLog).Return(nil)
+				return repoMock
+			},
+			archiveFunc: func() *archivemock.IRepository {
+				return &archivemock.IRepository{}
+			},
+			expected: RunResult{Failed: 1},
+			err:      true,
+		},
+		{
+			name:   "Expire restores",
+			config: config.ArchiveConfigurations{AfterDays: 30},
+			repoFunc: func() *logsmock.IRepository {
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("ArchivableDays", mock.Anything, mock.Anything, mock.Anything).Return([]logs.ArchiveDay{}, nil)
+				repoMock.On("PurgeRestored", mock.Anything, "r1").Return(2, nil)
+				return repoMock
+			},
+			archiveFunc: func() *archivemock.IRepository {
+				archiveMock := &archivemock.IRepository{}
+				archiveMock.On("ExpiredRestores", mock.Anything, mock.Anything).Return([]archive.RestoreModel{{ID: "r1", Status: archive.RestoreReady}}, nil)
+				archiveMock.On("UpdateRestore", mock.Anything, mock.MatchedBy(func(m *archive.RestoreModel) bool {
+					return m.ID == "r1" && m.Status == archive.RestoreExpired && m.ExpiredAt != nil
+				})).Return(nil)
+				return archiveMock
+			},
+			expected: RunResult{Expired: 1},
+		},
+		{
+			name:   "Fail stale restores",
+			config: config.ArchiveConfigurations{AfterDays: 30},
+			repoFunc: func() *logsmock.IRepository {
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("ArchivableDays", mock.Anything, mock.Anything, mock.Anything).Return([]logs.ArchiveDay{}, nil)
+				repoMock.On("PurgeRestored", mock.Anything, "r2").Return(1, nil)
+				return repoMock
+			},
+			archiveFunc: func() *archivemock.IRepository {
+				archiveMock := &archivemock.IRepository{}
+				archiveMock.On("FailStaleRestores", mock.Anything, time.Date(2024, 6, 1, 10, 50, 0, 0, time.UTC), "Restore interrupted").
+					Return([]archive.RestoreModel{{ID: "r2", Status: archive.RestoreFailed}}, nil)
+				return archiveMock
+			},
+			expected: RunResult{Stale: 1},
+		},
+		{
+			name:   "Archivable days error",
+			config: config.ArchiveConfigurations{AfterDays: 30},
+			repoFunc: func() *logsmock.IRepository {
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("ArchivableDays", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("db down"))
+				return repoMock
+			},
+			archiveFunc: func() *archivemock.IRepository {
+				return &archivemock.IRepository{}
+			},
+			err: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repoMock := tc.repoFunc()
+			archiveMock := tc.archiveFunc()
+			archiveMock.On("ExpiredRestores", mock.Anything, mock.Anything).Return([]archive.RestoreModel{}, nil).Maybe()
+			archiveMock.On("FailStaleRestores", mock.Anything, mock.Anything, mock.Anything).Return([]archive.RestoreModel{}, nil).Maybe()
+			var store objectstore.IStore
+			store, err := objectstore.NewLocalStore(ctxLogger, t.TempDir())
+			assert.NoError(t, err)
+			if tc.corrupted {
+				store = corruptedStore{IStore: store}
+			}
+
+			svc, err := NewDefaultService(ctxLogger, repoMock, archiveMock, store, &export.Signer{}, tc.config)
+			assert.NoError(t, err)
+			startedAt := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+			svc.now = func() time.Time { return startedAt }
+
+			res, err := svc.Run(context.Background())
+			if tc.err {
+				assert.Error(t, err)
+				assert.Equal(t, err.Error(), res.Error)
+				res.Error = ""
+			} else {
+				assert.NoError(t, err)
+			}
+
+			tc.expected.StartedAt = startedAt
+			tc.expected.FinishedAt = &startedAt
+			assert.Equal(t, &tc.expected, res)
+			repoMock.AssertExpectations(t)
+			archiveMock.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRun_ManifestStored(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestRun", "debug", logger.TextFormat)
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	repoMock := &logsmock.IRepository{}
+	repoMock.On("ArchivableDays", mock.Anything, mock.Anything, mock.Anything).Return([]logs.ArchiveDay{{TenantID: 5, Day: day}}, nil)
+	repoMock.On("EachDayLog", mock.Anything, 5, day, mock.Anything).Return(nil)
+	archiveMock := &archivemock.IRepository{}
+	archiveMock.On("Create", mock.Anything, mock.Anything).Return(nil)
+	archiveMock.On("ExpiredRestores", mock.Anything, mock.Anything).Return([]archive.RestoreModel{}, nil)
+	archiveMock.On("FailStaleRestores", mock.Anything, mock.Anything, mock.Anything).Return([]archive.RestoreModel{}, nil)
+	store, err := objectstore.NewLocalStore(ctxLogger, t.TempDir())
+	assert.NoError(t, err)
+	privateKey, publicKey, err := export.GenerateKey()
+	assert.NoError(t, err)
+	signer, err := export.NewSigner(privateKey, "test")
+	assert.NoError(t, err)
+
+	svc, err := NewDefaultService(ctxLogger, repoMock, archiveMock, store, signer, config.ArchiveConfigurations{})
+	assert.NoError(t, err)
+	_, err = svc.Run(context.Background())
+	assert.NoError(t, err)
+
+	key := objectKey("", 5, day, FormatNDJSON)
+	content := readObject(t, store, key)
+	manifest, err := export.ParseManifest(readObject(t, store, manifestKey(key)))
+	assert.NoError(t, err)
+	parsedKey, err := export.ParsePublicKey(publicKey)
+	assert.NoError(t, err)
+	assert.NoError(t, export.Verify(manifest, content, parsedKey))
+	assert.JSONEq(t, `{"tenantId":5,"day":"2024-01-02"}`, string(manifest.Filter))
+}
+
+func testContext() context.Context {
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "test-request-id")
+	return context.WithValue(ctx, &sts.Claim, sts.Claims{UserID: 42, Tenants: []int{1, 5}})
+}
+
+func TestRestore(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestRestore", "debug", logger.TextFormat)
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	key := objectKey("", 5, day, FormatNDJSON)
+
+	var buf bytes.Buffer
+	writer, err := newRecordWriter(FormatNDJSON, &buf)
+	assert.NoError(t, err)
+	for _, record := range testRecords() {
+		assert.NoError(t, writer.Write(record))
+	}
+	assert.NoError(t, writer.Close())
+	digest := sha256.Sum256(buf.Bytes())
+	model := &archive.Model{ID: "a1", TenantID: 5, Day: day, Format: FormatNDJSON, ObjectKey: key, SHA256: hex.EncodeToString(digest[:])}
+
+	cases := []struct {
+		name        string
+		noStore     bool
+		busy        bool
+		repoFunc    func() *logsmock.IRepository
+		archiveFunc func() *archivemock.IRepository
+		expected    string
+		errCode     string
+	}{
+		{
+			name: "Ready",
+			repoFunc: func() *logsmock.IRepository {
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("InsertRestored", mock.Anything, mock.Anything, mock.MatchedBy(func(m []logs.Model) bool {
+					return len(m) == 2 && m[1].Hash == "b"
+				})).Return(nil)
+				return repoMock
+			},
+			archiveFunc: func() *archivemock.IRepository {
+				archiveMock := &archivemock.IRepository{}
+				archiveMock.On("FindByID", mock.Anything, "a1").Return(model, nil)
+				archiveMock.On("FindActiveRestore", mock.Anything, "a1").Return(nil, nil)
+				archiveMock.On("CreateRestore", mock.Anything, mock.MatchedBy(func(m *archive.RestoreModel) bool {
+					return m.TenantID == 5 && m.RequestedBy == "42" && m.ExpiresAt.Equal(m.CreatedAt.Add(defaultRestoreTTL))
+				})).Return(nil)
+				archiveMock.On("UpdateRestore", mock.Anything, mock.MatchedBy(func(m *archive.RestoreModel) bool {
+					return m.Status == archive.RestoreReady && m.RowCount == 2 && m.ReadyAt != nil
+				})).Return(nil)
+				return archiveMock
+			},
+			expected: archive.RestoreRestoring,
+		},
+		{
+			name: "Digest mismatch fails",
+			repoFunc: func() *logsmock.IRepository {
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("PurgeRestored", mock.Anything, mock.Anything).Return(0, nil)
+				return repoMock
+			},
+			archiveFunc: func() *archivemock.IRepository {
+				archiveMock := &archivemock.IRepository{}
+				corrupted := *model
+				corrupted.SHA256 = "other"
+				archiveMock.On("FindByID", mock.Anything, "a1").Return(&corrupted, nil)
+				archiveMock.On("FindActiveRestore", mock.Anything, "a1").Return(nil, nil)
+				archiveMock.On("CreateRestore", mock.Anything, mock.Anything).Return(nil)
+				archiveMock.On("UpdateRestore", mock.Anything, mock.MatchedBy(func(m *archive.RestoreModel) bool {
+					return m.Status == archive.RestoreFailed && strings.Contains(m.Error, "does not match")
+				})).Return(nil)
+				return archiveMock
+			},
+			expected: archive.RestoreRestoring,
+		},
+		{
+			name:     "Active restore",
+			repoFunc: func() *logsmock.IRepository { return &logsmock.IRepository{} },
+			archiveFunc: func() *archivemock.IRepository {
+				archiveMock := &archivemock.IRepository{}
+				archiveMock.On("FindByID", mock.Anything, "a1").Return(model, nil)
+				archiveMock.On("FindActiveRestore", mock.Anything, "a1").Return(&archive.RestoreModel{ID: "r1", Status: archive.RestoreReady}, nil)
+				return archiveMock
+			},
+			expected: archive.RestoreReady,
+		},
+		{
+			name:     "Tenant not allowed",
+			repoFunc: func() *logsmock.IRepository { return &logsmock.IRepository{} },
+			archiveFunc: func() *archivemock.IRepository {
+				archiveMock := &archivemock.IRepository{}
+				archiveMock.On("FindByID", mock.Anything, "a1").Return(&archive.Model{ID: "a1", TenantID: 9}, nil)
+				return archiveMock
+			},
+			errCode: terrors.ErrNotFound,
+		},
+		{
+			name:     "Too many restores",
+			busy:     true,
+			repoFunc: func() *logsmock.IRepository { return &logsmock.IRepository{} },
+			archiveFunc: func() *archivemock.IRepository {
+				archiveMock := &archivemock.IRepository{}
+				archiveMock.On("FindByID", mock.Anything, "a1").Return(model, nil)
+				archiveMock.On("FindActiveRestore", mock.Anything, "a1").Return(nil, nil)
+				return archiveMock
+			},
+			errCode: terrors.ErrRateLimited,
+		},
+		{
+			name:        "No store",
+			noStore:     true,
+			repoFunc:    func() *logsmock.IRepository { return &logsmock.IRepository{} },
+			archiveFunc: func() *archivemock.IRepository { return &archivemock.IRepository{} },
+			errCode:     terrors.ErrBadRequest,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repoMock := tc.repoFunc()
+			archiveMock := tc.archiveFunc()
+			var store objectstore.IStore
+			if !tc.noStore {
+				localStore, err := objectstore.NewLocalStore(ctxLogger, t.TempDir())
+				assert.NoError(t, err)
+				assert.NoError(t, localStore.Put(context.Background(), key, bytes.NewReader(buf.Bytes()), int64(buf.Len()), contentType(FormatNDJSON)))
+				store = localStore
+			}
+
+			svc, err := NewDefaultService(ctxLogger, repoMock, archiveMock, store, &export.Signer{}, config.ArchiveConfigurations{})
+			assert.NoError(t, err)
+			for tc.busy && len(svc.slots) < cap(svc.slots) {
+				svc.slots <- struct{}{}
+			}
+
+			res, err := svc.Restore(testContext(), "a1")
+			assert.NoError(t, svc.Wait(context.Background()))
+			if tc.errCode != "" {
+				assert.True(t, terrors.Is(err, tc.errCode), "unexpected error %v", err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, res.Status)
+			repoMock.AssertExpectations(t)
+			archiveMock.AssertExpectations(t)
+		})
+	}
+}
+
+func readObject(t *testing.T, store objectstore.IStore, key string) []byte {
+	reader, err := store.Get(context.Background(), key)
+	assert.NoError(t, err)
+	defer reader.Close() //nolint:errcheck
+
+	content, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	return content
+}
@@ -0,0 +1,266 @@
+package archive
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/jmontesinos91/omnilogger/internal/repositories/logs"
+	"github.com/parquet-go/parquet-go"
+)
+
+// Archive file formats
+const (
+	FormatNDJSON  = "ndjson"
+	FormatParquet = "parquet"
+)
+
+// parquetBatchSize rows buffered before they are written to the parquet file
+const parquetBatchSize = 1000
+
+// Record archived log, the columns of the logs table
+type Record struct {
+	ID                 string            `json:"id" parquet:"id"`
+	IpAddress          string            `json:"ip_address" parquet:"ip_address"`
+	ClientHost         string            `json:"client_host" parquet:"client_host"`
+	Provider           string            `json:"provider" parquet:"provider"`
+	Level              int               `json:"level" parquet:"level"`
+	Message            int               `json:"message" parquet:"message"`
+	Description        string            `json:"description" parquet:"description"`
+	Path               string            `json:"path" parquet:"path"`
+	Resource           string            `json:"resource" parquet:"resource"`
+	Action             string            `json:"action" parquet:"action"`
+	Data               string            `json:"data" parquet:"data"`
+	OldData            string            `json:"old_data" parquet:"old_data"`
+	TenantCat          string            `json:"tenant_cat" parquet:"tenant_cat"`
+	TenantID           string            `json:"tenant_id" parquet:"tenant_id"`
+	UserID             string            `json:"user_id" parquet:"user_id"`
+	Target             string            `json:"target" parquet:"target"`
+	CreatedAt          *time.Time        `json:"created_at" parquet:"created_at,optional"`
+	OccurredAt         *time.Time        `json:"occurred_at" parquet:"occurred_at,optional"`
+	ClockSkew          bool              `json:"clock_skew" parquet:"clock_skew"`
+	Labels             map[string]string `json:"labels,omitempty" parquet:"labels"`
+	OriginIP           string            `json:"origin_ip,omitempty" parquet:"origin_ip"`
+	OriginForwardedFor string            `json:"origin_forwarded_for,omitempty" parquet:"origin_forwarded_for"`
+	OriginSubject      string            `json:"origin_subject,omitempty" parquet:"origin_subject"`
+	OriginTenantID     string            `json:"origin_tenant_id,omitempty" parquet:"origin_tenant_id"`
+	ChainTenantID      int               `json:"chain_tenant_id" parquet:"chain_tenant_id"`
+	ChainSeq           int64             `json:"chain_seq" parquet:"chain_seq"`
+	PrevHash           string            `json:"prev_hash" parquet:"prev_hash"`
+	Hash               string            `json:"hash" parquet:"hash"`
+}
+
+// recordWriter encodes records to an archive file, Close flushes the pending records
+type recordWriter interface {
+	Write(record Record) error
+	Close() error
+}
+
+func newRecordWriter(format string, w io.Writer) (recordWriter, error) {
+	switch format {
+	case FormatNDJSON:
+		gz := gzip.NewWriter(w)
+		return &ndjsonWriter{gz: gz, enc: json.NewEncoder(gz)}, nil
+	case FormatParquet:
+		return &parquetWriter{w: parquet.NewGenericWriter[Record](w, parquet.Compression(&parquet.Zstd))}, nil
+	default:
+		return nil, fmt.Errorf("invalid archive format %q, use %s or %s", format, FormatNDJSON, FormatParquet)
+	}
+}
+
+// ndjsonWriter writes one json record per line, gzip compressed
+type ndjsonWriter struct {
+	gz  *gzip.Writer
+	enc *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(record Record) error {
+	return w.enc.Encode(record)
+}
+
+func (w *ndjsonWriter) Close() error {
+	return w.gz.Close()
+}
+
+// parquetWriter writes the records in batches to a zstd compressed parquet file
+type parquetWriter struct {
+	w     *parquet.GenericWriter[Record]
+	batch []Record
+}
+
+func (w *parquetWriter) Write(record Record) error {
+	w.batch = append(w.batch, record)
+	if len(w.batch) < parquetBatchSize {
+		return nil
+	}
+
+	return w.flush()
+}
+
+func (w *parquetWriter) Close() error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+
+	return w.w.Close()
+}
+
+func (w *parquetWriter) flush() error {
+	if len(w.batch) == 0 {
+		return nil
+	}
+
+	_, err := w.w.Write(w.batch)
+	w.batch = w.batch[:0]
+	return err
+}
+
+// readRecords decodes the records of an archive file of the format and calls fn for each one
+func readRecords(format string, r io.ReaderAt, size int64, fn func(record Record) error) error {
+	section := io.NewSectionReader(r, 0, size)
+
+	switch format {
+	case FormatNDJSON:
+		gz, err := gzip.NewReader(section)
+		if err != nil {
+			return err
+		}
+		defer gz.Close() //nolint:errcheck
+
+		dec := json.NewDecoder(gz)
+		for {
+			var record Record
+			if err := dec.Decode(&record); err != nil {
+				if errors.Is(err, io.EOF) {
+					return nil
+				}
+				return err
+			}
+			if err := fn(record); err != nil {
+				return err
+			}
+		}
+	case FormatParquet:
+		reader := parquet.NewGenericReader[Record](section)
+		defer reader.Close() //nolint:errcheck
+
+		batch := make([]Record, parquetBatchSize)
+		for {
+			n, err := reader.Read(batch)
+			for _, record := range batch[:n] {
+				if err := fn(record); err != nil {
+					return err
+				}
+			}
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("invalid archive format %q, use %s or %s", format, FormatNDJSON, FormatParquet)
+	}
+}
+
+// fileExtension extension of the archive files of the format
+func fileExtension(format string) string {
+	if format == FormatParquet {
+		return ".parquet"
+	}
+
+	return ".ndjson.gz"
+}
+
+// contentType content type of the archive files of the format
+func contentType(format string) string {
+	if format == FormatParquet {
+		return "application/vnd.apache.parquet"
+	}
+
+	return "application/gzip"
+}
+
+// objectKey key of the archive file of a tenant day, prefix/tenant=<id>/<yyyy>/<mm>/<dd>/logs-<id>-<yyyymmdd>.<ext>
+func objectKey(prefix string, tenantID int, day time.Time, format string) string {
+	name := fmt.Sprintf("logs-%d-%s%s", tenantID, day.Format("20060102"), fileExtension(format))
+	return path.Join(prefix, fmt.Sprintf("tenant=%d", tenantID), day.Format("2006/01/02"), name)
+}
+
+// manifestKey key of the manifest of an archive file
+func manifestKey(objectKey string) string {
+	return objectKey + ".manifest.json"
+}
+
+// ToRecord maps a log to its archived record
+func ToRecord(m logs.Model) Record {
+	return Record{
+		ID:                 m.ID,
+		IpAddress:          m.IpAddress,
+		ClientHost:         m.ClientHost,
+		Provider:           m.Provider,
+		Level:              m.Level,
+		Message:            m.Message,
+		Description:        m.Description,
+		Path:               m.Path,
+		Resource:           m.Resource,
+		Action:             m.Action,
+		Data:               m.Data,
+		OldData:            m.OldData,
+		TenantCat:          m.TenantCat,
+		TenantID:           m.TenantID,
+		UserID:             m.UserID,
+		Target:             m.Target,
+		CreatedAt:          m.CreatedAt,
+		OccurredAt:         m.OccurredAt,
+		ClockSkew:          m.ClockSkew,
+		Labels:             m.Labels,
+		OriginIP:           m.OriginIP,
+		OriginForwardedFor: m.OriginForwardedFor,
+		OriginSubject:      m.OriginSubject,
+		OriginTenantID:     m.OriginTenantID,
+		ChainTenantID:      m.ChainTenantID,
+		ChainSeq:           m.ChainSeq,
+		PrevHash:           m.PrevHash,
+		Hash:               m.Hash,
+	}
+}
+
+// ToModel maps an archived record back to its log
+func ToModel(r Record) logs.Model {
+	return logs.Model{
+		ID:                 r.ID,
+		IpAddress:          r.IpAddress,
+		ClientHost:         r.ClientHost,
+		Provider:           r.Provider,
+		Level:              r.Level,
+		Message:            r.Message,
+		Description:        r.Description,
+		Path:               r.Path,
+		Resource:           r.Resource,
+		Action:             r.Action,
+		Data:               r.Data,
+		OldData:            r.OldData,
+		TenantCat:          r.TenantCat,
+		TenantID:           r.TenantID,
+		UserID:             r.UserID,
+		Target:             r.Target,
+		CreatedAt:          r.CreatedAt,
+		OccurredAt:         r.OccurredAt,
+		ClockSkew:          r.ClockSkew,
+		Labels:             r.Labels,
+		OriginIP:           r.OriginIP,
+		OriginForwardedFor: r.OriginForwardedFor,
+		OriginSubject:      r.OriginSubject,
+		OriginTenantID:     r.OriginTenantID,
+		ChainTenantID:      r.ChainTenantID,
+		ChainSeq:           r.ChainSeq,
+		PrevHash:           r.PrevHash,
+		Hash:               r.Hash,
+	}
+}
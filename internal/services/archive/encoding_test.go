@@ -0,0 +1,101 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jmontesinos91/omnilogger/internal/repositories/logs"
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func testRecords() []Record {
+	createdAt := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	return []Record{
+		ToRecord(logs.Model{ID: "1", Level: 6, TenantID: "[1]", CreatedAt: &createdAt, Labels: map[string]string{"env": "prod"}, ChainTenantID: 1, ChainSeq: 1, Hash: "a"}),
+		ToRecord(logs.Model{ID: "2", Level: 3, TenantID: "[1]", CreatedAt: &createdAt, ChainTenantID: 1, ChainSeq: 2, PrevHash: "a", Hash: "b"}),
+	}
+}
+
+func TestRecordWriter_NDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := newRecordWriter(FormatNDJSON, &buf)
+	assert.NoError(t, err)
+	for _, record := range testRecords() {
+		assert.NoError(t, writer.Write(record))
+	}
+	assert.NoError(t, writer.Close())
+
+	gz, err := gzip.NewReader(&buf)
+	assert.NoError(t, err)
+	var records []Record
+	scanner := bufio.NewScanner(gz)
+	for scanner.Scan() {
+		var record Record
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+
+	assert.Equal(t, testRecords(), records)
+}
+
+func TestRecordWriter_Parquet(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := newRecordWriter(FormatParquet, &buf)
+	assert.NoError(t, err)
+	for _, record := range testRecords() {
+		assert.NoError(t, writer.Write(record))
+	}
+	assert.NoError(t, writer.Close())
+
+	records, err := parquet.Read[Record](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, "b", records[1].Hash)
+	assert.Equal(t, map[string]string{"env": "prod"}, records[0].Labels)
+	assert.True(t, testRecords()[0].CreatedAt.Equal(*records[0].CreatedAt))
+}
+
+func TestRecordWriter_InvalidFormat(t *testing.T) {
+	_, err := newRecordWriter("csv", &bytes.Buffer{})
+	assert.Error(t, err)
+}
+
+func TestObjectKey(t *testing.T) {
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, "logs/tenant=5/2024/01/02/logs-5-20240102.ndjson.gz", objectKey("logs", 5, day, FormatNDJSON))
+	assert.Equal(t, "tenant=0/2024/01/02/logs-0-20240102.parquet", objectKey("", 0, day, FormatParquet))
+	assert.Equal(t, "tenant=0/2024/01/02/logs-0-20240102.parquet.manifest.json", manifestKey(objectKey("", 0, day, FormatParquet)))
+}
+
+func TestReadRecords(t *testing.T) {
+	for _, format := range []string{FormatNDJSON, FormatParquet} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			writer, err := newRecordWriter(format, &buf)
+			assert.NoError(t, err)
+			for _, record := range testRecords() {
+				assert.NoError(t, writer.Write(record))
+			}
+			assert.NoError(t, writer.Close())
+
+			var models []logs.Model
+			err = readRecords(format, bytes.NewReader(buf.Bytes()), int64(buf.Len()), func(record Record) error {
+				models = append(models, ToModel(record))
+				return nil
+			})
+
+			assert.NoError(t, err)
+			assert.Len(t, models, 2)
+			assert.Equal(t, "2", models[1].ID)
+			assert.Equal(t, "a", models[1].PrevHash)
+			assert.Equal(t, map[string]string{"env": "prod"}, models[0].Labels)
+			assert.True(t, testRecords()[0].CreatedAt.Equal(*models[0].CreatedAt))
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package archive
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jmontesinos91/omnilogger/domains/pagination"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/archive"
+)
+
+func ToResponse(model *archive.Model) *Response {
+	return &Response{
+		ID:         model.ID,
+		TenantID:   model.TenantID,
+		Day:        model.Day.Format(time.DateOnly),
+		Format:     model.Format,
+		ObjectKey:  model.ObjectKey,
+		RowCount:   model.RowCount,
+		SizeBytes:  model.SizeBytes,
+		SHA256:     model.SHA256,
+		PurgedRows: model.PurgedRows,
+		CreatedAt:  model.CreatedAt,
+		PurgedAt:   model.PurgedAt,
+	}
+}
+
+func ToRestoreResponse(model *archive.RestoreModel) *RestoreResponse {
+	return &RestoreResponse{
+		ID:          model.ID,
+		ArchiveID:   model.ArchiveID,
+		TenantID:    model.TenantID,
+		Day:         model.Day.Format(time.DateOnly),
+		Status:      model.Status,
+		RowCount:    model.RowCount,
+		Error:       model.Error,
+		RequestedBy: model.RequestedBy,
+		CreatedAt:   model.CreatedAt,
+		ReadyAt:     model.ReadyAt,
+		ExpiresAt:   model.ExpiresAt,
+		ExpiredAt:   model.ExpiredAt,
+	}
+}
+
+func ToRepoFilter(filter Filter) archive.Filter {
+	from := ((filter.Page * filter.Size) - filter.Size) + 1
+
+	return archive.Filter{
+		TenantID: filter.TenantID,
+		StartAt:  filter.StartAt,
+		EndAt:    filter.EndAt,
+		From:     from,
+		Size:     filter.Size,
+	}
+}
+
+// ToParseFilterRequest parses the archives filter, start_at and end_at are days formatted as yyyy-mm-dd
+func ToParseFilterRequest(r *http.Request) (Filter, error) {
+	query := r.URL.Query()
+
+	var tenantIds []int
+	for _, str := range query["tenant_id[]"] {
+		id, err := strconv.Atoi(str)
+		if err != nil {
+			return Filter{}, err
+		}
+		tenantIds = append(tenantIds, id)
+	}
+
+	var startAt, endAt time.Time
+	if query.Get("start_at") != "" {
+		day, err := time.Parse(time.DateOnly, query.Get("start_at"))
+		if err != nil {
+			return Filter{}, err
+		}
+		startAt = day
+	}
+
+	if query.Get("end_at") != "" {
+		day, err := time.Parse(time.DateOnly, query.Get("end_at"))
+		if err != nil {
+			return Filter{}, err
+		}
+		endAt = day
+	}
+
+	page := pagination.Filter{
+		Size: pagination.DefaultSizeValue,
+		Page: 1,
+	}
+
+	if query.Get("max") != "" {
+		size, err := strconv.Atoi(query.Get("max"))
+		if err != nil {
+			return Filter{}, err
+		}
+		page.Size = size
+	}
+
+	if query.Get("page") != "" {
+		pageNumber, err := strconv.Atoi(query.Get("page"))
+		if err != nil {
+			return Filter{}, err
+		}
+		page.Page = pageNumber
+	}
+
+	if err := page.SanitizePageFilter(); err != nil {
+		return Filter{}, err
+	}
+	if page.Page < 1 {
+		page.Page = 1
+	}
+
+	return Filter{
+		TenantID: tenantIds,
+		StartAt:  startAt,
+		EndAt:    endAt,
+		Filter:   page,
+	}, nil
+}
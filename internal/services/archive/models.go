@@ -0,0 +1,107 @@
+package archive
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jmontesinos91/omnilogger/domains/pagination"
+)
+
+// defaultInterval time between runs when interval-in-minutes is not configured
+const defaultInterval = time.Hour
+
+// defaultMaxDaysPerRun tenant days archived per run when max-days-per-run is not configured
+const defaultMaxDaysPerRun = 100
+
+// defaultBatchSize number of archived logs deleted per batch when batch-size is not configured
+const defaultBatchSize = 1000
+
+// defaultRestoreTTL time restored logs stay searchable when restore-ttl-in-hours is not configured
+const defaultRestoreTTL = 24 * time.Hour
+
+// defaultMaxRestores archive files loaded at the same time when max-restores is not configured
+const defaultMaxRestores = 2
+
+// restoreTimeout longest a restore can load its archive file
+const restoreTimeout = time.Hour
+
+// staleRestoreAfter restores still restoring this long after they were requested lost their worker, the margin over
+// restoreTimeout leaves the worker time to record the outcome of a restore that timed out
+const staleRestoreAfter = restoreTimeout + 10*time.Minute
+
+// restoreBatchSize restored logs inserted per batch
+const restoreBatchSize = 1000
+
+// maxRestoreErrorLength length of the error column of the restores
+const maxRestoreErrorLength = 500
+
+// RunResult outcome of an archive run, Files counts the tenant days archived, Purged the logs deleted
+// from the database once their archive was verified and Expired the restores whose logs were deleted
+type RunResult struct {
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Files      int        `json:"files"`
+	Rows       int        `json:"rows"`
+	Purged     int        `json:"purged"`
+	Failed     int        `json:"failed"`
+	Expired    int        `json:"expired"`
+	Stale      int        `json:"stale"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// scope tenant day covered by an archive file, recorded as the filter of its manifest
+type scope struct {
+	TenantID int    `json:"tenantId"`
+	Day      string `json:"day"`
+}
+
+func (r *RunResult) summary() string {
+	return fmt.Sprintf("Archive: %d files with %d logs written, %d logs purged, %d tenant days failed, %d restores expired, %d stale restores failed", r.Files, r.Rows, r.Purged, r.Failed, r.Expired, r.Stale)
+}
+
+// Response Holds the information of an archive file
+type Response struct {
+	ID         string     `json:"id"`
+	TenantID   int        `json:"tenantId"`
+	Day        string     `json:"day"`
+	Format     string     `json:"format"`
+	ObjectKey  string     `json:"objectKey"`
+	RowCount   int        `json:"rowCount"`
+	SizeBytes  int64      `json:"sizeBytes"`
+	SHA256     string     `json:"sha256"`
+	PurgedRows int        `json:"purgedRows"`
+	CreatedAt  *time.Time `json:"createdAt,omitempty"`
+	PurgedAt   *time.Time `json:"purgedAt,omitempty"`
+}
+
+// RestoreResponse Holds the progress of the restore of an archive file, once ready its logs are searchable
+// with archived=true until ExpiresAt
+type RestoreResponse struct {
+	ID          string     `json:"id"`
+	ArchiveID   string     `json:"archiveId"`
+	TenantID    int        `json:"tenantId"`
+	Day         string     `json:"day"`
+	Status      string     `json:"status"`
+	RowCount    int        `json:"rowCount"`
+	Error       string     `json:"error,omitempty"`
+	RequestedBy string     `json:"requestedBy"`
+	CreatedAt   *time.Time `json:"createdAt,omitempty"`
+	ReadyAt     *time.Time `json:"readyAt,omitempty"`
+	ExpiresAt   time.Time  `json:"expiresAt"`
+	ExpiredAt   *time.Time `json:"expiredAt,omitempty"`
+}
+
+// Filter archives of the tenants for the days between StartAt and EndAt
+type Filter struct {
+	TenantID []int
+	StartAt  time.Time
+	EndAt    time.Time
+	pagination.Filter
+}
+
+type PaginatedRes struct {
+	Data  []Response `json:"data"`
+	Size  int        `json:"max"`
+	Total int        `json:"total"`
+	Page  int        `json:"currentPage"`
+}
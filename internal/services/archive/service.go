@@ -0,0 +1,13 @@
+package archive
+
+import (
+	"context"
+)
+
+// IService Manage archive interfaces
+type IService interface {
+	Run(ctx context.Context) (*RunResult, error)
+	Retrieve(ctx context.Context, filter Filter) (*PaginatedRes, error)
+	Restore(ctx context.Context, archiveID string) (*RestoreResponse, error)
+	GetRestore(ctx context.Context, id string) (*RestoreResponse, error)
+}
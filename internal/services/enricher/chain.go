@@ -0,0 +1,120 @@
+package enricher
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/omnilogger/config"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/logs"
+	"github.com/jmontesinos91/terrors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	durationMetric = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ingestion_enrichment_duration_seconds",
+		Help:    "How long the enrichment stages took per log, partitioned by stage",
+		Buckets: []float64{.0001, .0005, .001, .005, .01, .05, .1, .5},
+	}, []string{"stage"})
+	failuresMetric = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ingestion_enrichment_failures_total",
+		Help: "The total number of enrichment stage failures, partitioned by stage and failure mode",
+	}, []string{"stage", "failure_mode"})
+)
+
+type stage struct {
+	enricher    IEnricher
+	failClosed  bool
+	failureMode string
+}
+
+// Chain runs the enabled stages in the configured order
+type Chain struct {
+	log    *logger.ContextLogger
+	stages []stage
+}
+
+// NewChain creates a new instance of Chain with the enabled stages of the configuration
+func NewChain(l *logger.ContextLogger, c config.EnrichmentConfigurations, factories map[string]Factory) (*Chain, error) {
+	chain := &Chain{log: l}
+
+	for _, sc := range c.Stages {
+		if !sc.Enabled {
+			continue
+		}
+
+		factory, ok := factories[sc.Name]
+		if !ok {
+			return nil, fmt.Errorf("enricher: unknown stage %q", sc.Name)
+		}
+
+		failureMode := sc.FailureMode
+		switch failureMode {
+		case "":
+			failureMode = FailOpen
+		case FailOpen, FailClosed:
+		default:
+			return nil, fmt.Errorf("enricher: invalid failure mode %q of stage %q", sc.FailureMode, sc.Name)
+		}
+
+		e, err := factory(l, c)
+		if err != nil {
+			return nil, fmt.Errorf("enricher: failed to build stage %q -> %v", sc.Name, err)
+		}
+
+		chain.stages = append(chain.stages, stage{
+			enricher:    e,
+			failClosed:  failureMode == FailClosed,
+			failureMode: failureMode,
+		})
+		l.Log(logrus.InfoLevel, "NewChain", "Enrichment stage "+sc.Name+" enabled, fail "+failureMode)
+	}
+
+	return chain, nil
+}
+
+// Name of the chain
+func (c *Chain) Name() string {
+	return "chain"
+}
+
+// Enrich runs every stage on the model, a failing fail-closed stage stops the chain and rejects the log
+func (c *Chain) Enrich(ctx context.Context, model *logs.Model) error {
+	for _, s := range c.stages {
+		name := s.enricher.Name()
+
+		start := time.Now()
+		err := run(ctx, s.enricher, model)
+		durationMetric.WithLabelValues(name).Observe(time.Since(start).Seconds())
+
+		if err == nil {
+			continue
+		}
+
+		failuresMetric.WithLabelValues(name, s.failureMode).Inc()
+
+		if s.failClosed {
+			c.log.Error(logrus.ErrorLevel, "Enrich", "Enrichment stage "+name+" failed, rejecting log", err)
+			return terrors.InternalService("enrichment_error", "Failed to enrich log on stage "+name, map[string]string{})
+		}
+
+		c.log.Error(logrus.WarnLevel, "Enrich", "Enrichment stage "+name+" failed, keeping log", err)
+	}
+
+	return nil
+}
+
+// run calls the stage turning a panic into an error so a broken stage follows its failure mode
+func run(ctx context.Context, e IEnricher, model *logs.Model) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("enricher: stage %s panicked -> %v", e.Name(), r)
+		}
+	}()
+
+	return e.Enrich(ctx, model)
+}
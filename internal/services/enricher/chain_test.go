@@ -0,0 +1,155 @@
+package enricher
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/omnilogger/config"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/logs"
+	"github.com/jmontesinos91/omnilogger/internal/services/enricher/enrichermock"
+	"github.com/jmontesinos91/terrors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewChain(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestNewChain", "debug", logger.TextFormat)
+
+	cases := []struct {
+		name     string
+		config   config.EnrichmentConfigurations
+		expected []string
+		err      bool
+	}{
+		{
+			name: "Enabled stages in order",
+			config: config.EnrichmentConfigurations{
+				Stages: []config.EnrichmentStageConfigurations{
+					{Name: StageRules, Enabled: true},
+					{Name: StageGeoIP, Enabled: false},
+					{Name: StageNormalize, Enabled: true, FailureMode: FailClosed},
+				},
+			},
+			expected: []string{StageRules, StageNormalize},
+		},
+		{
+			name:   "Unknown stage",
+			config: config.EnrichmentConfigurations{Stages: []config.EnrichmentStageConfigurations{{Name: "translate", Enabled: true}}},
+			err:    true,
+		},
+		{
+			name:   "Invalid failure mode",
+			config: config.EnrichmentConfigurations{Stages: []config.EnrichmentStageConfigurations{{Name: StageNormalize, Enabled: true, FailureMode: "sometimes"}}},
+			err:    true,
+		},
+		{
+			name: "Stage fails to build",
+			config: config.EnrichmentConfigurations{
+				Stages:    []config.EnrichmentStageConfigurations{{Name: StageRedaction, Enabled: true}},
+				Redaction: config.RedactionConfigurations{Patterns: []string{"("}},
+			},
+			err: true,
+		},
+		{
+			name:   "GeoIP without database",
+			config: config.EnrichmentConfigurations{Stages: []config.EnrichmentStageConfigurations{{Name: StageGeoIP, Enabled: true}}},
+			err:    true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			chain, err := NewChain(ctxLogger, tc.config, Factories())
+			if tc.err {
+				assert.Error(t, err)
+				assert.Nil(t, chain)
+				return
+			}
+
+			assert.NoError(t, err)
+			var names []string
+			for _, s := range chain.stages {
+				names = append(names, s.enricher.Name())
+			}
+			assert.Equal(t, tc.expected, names)
+		})
+	}
+}
+
+func TestChain_Enrich(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestChain_Enrich", "debug", logger.TextFormat)
+
+	factoriesOf := func(stages map[string]*enrichermock.IEnricher) map[string]Factory {
+		factories := map[string]Factory{}
+		for name, stage := range stages {
+			stage := stage
+			factories[name] = func(l *logger.ContextLogger, c config.EnrichmentConfigurations) (IEnricher, error) {
+				return stage, nil
+			}
+		}
+		return factories
+	}
+
+	t.Run("Stages run in order", func(t *testing.T) {
+		var order []string
+		stages := map[string]*enrichermock.IEnricher{
+			"first":  {StageName: "first", EnrichFunc: func(m *logs.Model) { order = append(order, "first") }},
+			"second": {StageName: "second", EnrichFunc: func(m *logs.Model) { order = append(order, "second") }},
+		}
+		chain, err := NewChain(ctxLogger, config.EnrichmentConfigurations{Stages: []config.EnrichmentStageConfigurations{
+			{Name: "second", Enabled: true},
+			{Name: "first", Enabled: true},
+		}}, factoriesOf(stages))
+		assert.NoError(t, err)
+
+		assert.NoError(t, chain.Enrich(context.Background(), &logs.Model{}))
+		assert.Equal(t, []string{"second", "first"}, order)
+	})
+
+	t.Run("Fail open keeps going", func(t *testing.T) {
+		stages := map[string]*enrichermock.IEnricher{
+			"broken": {StageName: "broken_open", EnrichErr: errors.New("boom")},
+			"next":   {StageName: "next"},
+		}
+		chain, err := NewChain(ctxLogger, config.EnrichmentConfigurations{Stages: []config.EnrichmentStageConfigurations{
+			{Name: "broken", Enabled: true, FailureMode: FailOpen},
+			{Name: "next", Enabled: true},
+		}}, factoriesOf(stages))
+		assert.NoError(t, err)
+
+		assert.NoError(t, chain.Enrich(context.Background(), &logs.Model{}))
+		assert.Equal(t, 1, stages["next"].Calls)
+		assert.Equal(t, float64(1), testutil.ToFloat64(failuresMetric.WithLabelValues("broken_open", FailOpen)))
+	})
+
+	t.Run("Fail closed rejects the log", func(t *testing.T) {
+		stages := map[string]*enrichermock.IEnricher{
+			"broken": {StageName: "broken_closed", EnrichErr: errors.New("boom")},
+			"next":   {StageName: "next"},
+		}
+		chain, err := NewChain(ctxLogger, config.EnrichmentConfigurations{Stages: []config.EnrichmentStageConfigurations{
+			{Name: "broken", Enabled: true, FailureMode: FailClosed},
+			{Name: "next", Enabled: true},
+		}}, factoriesOf(stages))
+		assert.NoError(t, err)
+
+		err = chain.Enrich(context.Background(), &logs.Model{})
+		assert.True(t, terrors.Is(err, terrors.ErrInternalService))
+		assert.Equal(t, 0, stages["next"].Calls)
+		assert.Equal(t, float64(1), testutil.ToFloat64(failuresMetric.WithLabelValues("broken_closed", FailClosed)))
+	})
+
+	t.Run("Panicking stage follows its failure mode", func(t *testing.T) {
+		stages := map[string]*enrichermock.IEnricher{
+			"panic": {StageName: "panic", EnrichFunc: func(m *logs.Model) { panic("nil map") }},
+		}
+		chain, err := NewChain(ctxLogger, config.EnrichmentConfigurations{Stages: []config.EnrichmentStageConfigurations{
+			{Name: "panic", Enabled: true, FailureMode: FailClosed},
+		}}, factoriesOf(stages))
+		assert.NoError(t, err)
+
+		assert.Error(t, chain.Enrich(context.Background(), &logs.Model{}))
+	})
+}
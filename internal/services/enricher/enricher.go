@@ -0,0 +1,47 @@
+package enricher
+
+import (
+	"context"
+
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/omnilogger/config"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/logs"
+)
+
+// IEnricher a stage of the ingestion pipeline, it completes or rewrites a log before it is stored
+type IEnricher interface {
+	Name() string
+	Enrich(ctx context.Context, model *logs.Model) error
+}
+
+// Factory builds a stage from the enrichment configurations
+type Factory func(l *logger.ContextLogger, c config.EnrichmentConfigurations) (IEnricher, error)
+
+// Factories built-in stages by name, new stages are added to this map and enabled through the stages configuration
+func Factories() map[string]Factory {
+	return map[string]Factory{
+		StageNormalize: func(l *logger.ContextLogger, c config.EnrichmentConfigurations) (IEnricher, error) {
+			return NewNormalizer(), nil
+		},
+		StageTenant: func(l *logger.ContextLogger, c config.EnrichmentConfigurations) (IEnricher, error) {
+			names, err := NewStaticTenantNames(c.TenantNames)
+			if err != nil {
+				return nil, err
+			}
+			return NewTenantResolver(names), nil
+		},
+		StageGeoIP: func(l *logger.ContextLogger, c config.EnrichmentConfigurations) (IEnricher, error) {
+			database, err := LoadGeoIPDatabase(c.GeoIP.DatabaseFile)
+			if err != nil {
+				return nil, err
+			}
+			return NewGeoIP(database), nil
+		},
+		StageRedaction: func(l *logger.ContextLogger, c config.EnrichmentConfigurations) (IEnricher, error) {
+			return NewRedactor(c.Redaction)
+		},
+		StageRules: func(l *logger.ContextLogger, c config.EnrichmentConfigurations) (IEnricher, error) {
+			return NewRuleLabeler(c.Rules), nil
+		},
+	}
+}
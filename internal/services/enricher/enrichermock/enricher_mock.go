@@ -0,0 +1,29 @@
+package enrichermock
+
+import (
+	"context"
+
+	"github.com/jmontesinos91/omnilogger/internal/repositories/logs"
+)
+
+type IEnricher struct {
+	StageName  string
+	EnrichErr  error
+	EnrichFunc func(model *logs.Model)
+	Calls      int
+}
+
+func (m *IEnricher) Name() string {
+	if m.StageName == "" {
+		return "mock"
+	}
+	return m.StageName
+}
+
+func (m *IEnricher) Enrich(ctx context.Context, model *logs.Model) error {
+	m.Calls++
+	if m.EnrichFunc != nil {
+		m.EnrichFunc(model)
+	}
+	return m.EnrichErr
+}
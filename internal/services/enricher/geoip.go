@@ -0,0 +1,200 @@
+package enricher
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/jmontesinos91/omnilogger/internal/repositories/logs"
+)
+
+// GeoLocation location of an ip address
+type GeoLocation struct {
+	Country string
+	City    string
+}
+
+// GeoLocator locates an ip address
+type GeoLocator interface {
+	Lookup(addr netip.Addr) (GeoLocation, bool)
+}
+
+// geoTable networks of an address family keyed by their masked prefix, lengths holds the prefix lengths found in
+// the table from the most to the least specific
+type geoTable struct {
+	networks map[netip.Prefix]GeoLocation
+	lengths  []int
+}
+
+// GeoIPDatabase in memory table of networks, the most specific network containing an address wins. A lookup checks
+// one prefix per distinct prefix length of the address family instead of every network
+type GeoIPDatabase struct {
+	v4 geoTable
+	v6 geoTable
+}
+
+// LoadGeoIPDatabase reads a csv file of network,country,city rows
+func LoadGeoIPDatabase(file string) (*GeoIPDatabase, error) {
+	if file == "" {
+		return nil, errors.New("enricher: geoip database-file is required")
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParseGeoIPDatabase(f)
+}
+
+// ParseGeoIPDatabase parses network,country,city rows, lines starting with # are ignored
+func ParseGeoIPDatabase(r io.Reader) (*GeoIPDatabase, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	database := &GeoIPDatabase{
+		v4: geoTable{networks: map[netip.Prefix]GeoLocation{}},
+		v6: geoTable{networks: map[netip.Prefix]GeoLocation{}},
+	}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(record) < 2 {
+			return nil, fmt.Errorf("enricher: invalid geoip row %v", record)
+		}
+
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(record[0]))
+		if err != nil {
+			return nil, fmt.Errorf("enricher: invalid geoip network %q -> %v", record[0], err)
+		}
+
+		location := GeoLocation{Country: strings.TrimSpace(record[1])}
+		if len(record) > 2 {
+			location.City = strings.TrimSpace(record[2])
+		}
+
+		database.table(prefix.Addr()).add(prefix.Masked(), location)
+	}
+
+	// Most specific networks first
+	database.v4.sort()
+	database.v6.sort()
+
+	return database, nil
+}
+
+// Lookup returns the location of the most specific network containing the address
+func (d *GeoIPDatabase) Lookup(addr netip.Addr) (GeoLocation, bool) {
+	table := d.table(addr)
+	for _, bits := range table.lengths {
+		prefix, err := addr.WithZone("").Prefix(bits)
+		if err != nil {
+			continue
+		}
+		if location, ok := table.networks[prefix]; ok {
+			return location, true
+		}
+	}
+
+	return GeoLocation{}, false
+}
+
+func (d *GeoIPDatabase) table(addr netip.Addr) *geoTable {
+	if addr.Is4() {
+		return &d.v4
+	}
+
+	return &d.v6
+}
+
+// add adds a network, the first row of a network wins
+func (t *geoTable) add(prefix netip.Prefix, location GeoLocation) {
+	if _, ok := t.networks[prefix]; ok {
+		return
+	}
+
+	t.networks[prefix] = location
+	if !slices.Contains(t.lengths, prefix.Bits()) {
+		t.lengths = append(t.lengths, prefix.Bits())
+	}
+}
+
+func (t *geoTable) sort() {
+	sort.Sort(sort.Reverse(sort.IntSlice(t.lengths)))
+}
+
+// GeoIP labels a log with the location of its origin ip, the client reported ip_address is used
+// when the server did not observe an origin
+type GeoIP struct {
+	locator GeoLocator
+}
+
+// NewGeoIP creates a new instance of GeoIP
+func NewGeoIP(locator GeoLocator) *GeoIP {
+	return &GeoIP{locator: locator}
+}
+
+// Name of the stage
+func (g *GeoIP) Name() string {
+	return StageGeoIP
+}
+
+// Enrich sets the geo labels, labels sent by the producer are kept
+func (g *GeoIP) Enrich(_ context.Context, model *logs.Model) error {
+	ip := model.OriginIP
+	if ip == "" {
+		ip = model.IpAddress
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		// Not an ip address, there is nothing to locate
+		return nil
+	}
+	addr = addr.Unmap()
+
+	if addr.IsPrivate() || addr.IsLoopback() || addr.IsUnspecified() || addr.IsLinkLocalUnicast() {
+		return nil
+	}
+
+	location, ok := g.locator.Lookup(addr)
+	if !ok {
+		return nil
+	}
+
+	setLabel(model, LabelGeoCountry, location.Country)
+	setLabel(model, LabelGeoCity, location.City)
+
+	return nil
+}
+
+// setLabel sets a label unless the log already has it
+func setLabel(model *logs.Model, key, value string) {
+	if value == "" {
+		return
+	}
+
+	if model.Labels == nil {
+		model.Labels = map[string]string{}
+	}
+
+	if _, ok := model.Labels[key]; !ok {
+		model.Labels[key] = value
+	}
+}
@@ -0,0 +1,25 @@
+package enricher
+
+// Built-in stage names
+const (
+	StageNormalize = "normalize"
+	StageTenant    = "tenant"
+	StageGeoIP     = "geoip"
+	StageRedaction = "redaction"
+	StageRules     = "rules"
+)
+
+// Failure modes of a stage, open keeps the log as the stage left it and closed rejects the log
+const (
+	FailOpen   = "open"
+	FailClosed = "closed"
+)
+
+// Labels set by the GeoIP stage
+const (
+	LabelGeoCountry = "geo.country"
+	LabelGeoCity    = "geo.city"
+)
+
+// defaultReplacement used by the redaction stage when no replacement is configured
+const defaultReplacement = "[REDACTED]"
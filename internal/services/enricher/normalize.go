@@ -0,0 +1,37 @@
+package enricher
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jmontesinos91/omnilogger/internal/repositories/logs"
+)
+
+// Normalizer trims the text fields and applies the casing the filters expect, resource and action in
+// upper case and path in lower case
+type Normalizer struct{}
+
+// NewNormalizer creates a new instance of Normalizer
+func NewNormalizer() *Normalizer {
+	return &Normalizer{}
+}
+
+// Name of the stage
+func (n *Normalizer) Name() string {
+	return StageNormalize
+}
+
+// Enrich normalizes the model fields
+func (n *Normalizer) Enrich(_ context.Context, model *logs.Model) error {
+	model.IpAddress = strings.TrimSpace(model.IpAddress)
+	model.ClientHost = strings.TrimSpace(model.ClientHost)
+	model.Provider = strings.TrimSpace(model.Provider)
+	model.Description = strings.TrimSpace(model.Description)
+	model.Path = strings.ToLower(strings.TrimSpace(model.Path))
+	model.Resource = strings.ToUpper(strings.TrimSpace(model.Resource))
+	model.Action = strings.ToUpper(strings.TrimSpace(model.Action))
+	model.UserID = strings.TrimSpace(model.UserID)
+	model.Target = strings.TrimSpace(model.Target)
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package enricher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jmontesinos91/omnilogger/config"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/logs"
+)
+
+// Redactor replaces the values of sensitive keys of data and old_data, and the text matching the
+// configured patterns in their string values and in the description
+type Redactor struct {
+	keys        map[string]struct{}
+	patterns    []*regexp.Regexp
+	replacement string
+}
+
+// NewRedactor creates a new instance of Redactor
+func NewRedactor(c config.RedactionConfigurations) (*Redactor, error) {
+	r := &Redactor{
+		keys:        make(map[string]struct{}, len(c.Keys)),
+		replacement: c.Replacement,
+	}
+
+	if r.replacement == "" {
+		r.replacement = defaultReplacement
+	}
+
+	for _, key := range c.Keys {
+		r.keys[strings.ToLower(key)] = struct{}{}
+	}
+
+	for _, pattern := range c.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("enricher: invalid redaction pattern %q -> %v", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+
+	return r, nil
+}
+
+// Name of the stage
+func (r *Redactor) Name() string {
+	return StageRedaction
+}
+
+// Enrich redacts data, old_data and description, data that is not json fails the stage
+func (r *Redactor) Enrich(_ context.Context, model *logs.Model) error {
+	data, err := r.redactJSON(model.Data)
+	if err != nil {
+		return fmt.Errorf("enricher: failed to redact data -> %v", err)
+	}
+
+	oldData, err := r.redactJSON(model.OldData)
+	if err != nil {
+		return fmt.Errorf("enricher: failed to redact old_data -> %v", err)
+	}
+
+	model.Data = data
+	model.OldData = oldData
+	model.Description = r.redactText(model.Description)
+
+	return nil
+}
+
+func (r *Redactor) redactJSON(raw string) (string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return raw, nil
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return "", err
+	}
+
+	value, changed := r.redactValue(value)
+	if !changed {
+		return raw, nil
+	}
+
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(buffer.String(), "\n"), nil
+}
+
+func (r *Redactor) redactValue(value interface{}) (interface{}, bool) {
+	changed := false
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if _, ok := r.keys[strings.ToLower(key)]; ok {
+				v[key] = r.replacement
+				changed = true
+				continue
+			}
+
+			redacted, itemChanged := r.redactValue(item)
+			if itemChanged {
+				v[key] = redacted
+				changed = true
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			redacted, itemChanged := r.redactValue(item)
+			if itemChanged {
+				v[i] = redacted
+				changed = true
+			}
+		}
+	case string:
+		redacted := r.redactText(v)
+		return redacted, redacted != v
+	}
+
+	return value, changed
+}
+
+func (r *Redactor) redactText(text string) string {
+	for _, re := range r.patterns {
+		text = re.ReplaceAllLiteralString(text, r.replacement)
+	}
+
+	return text
+}
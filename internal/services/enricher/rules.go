@@ -0,0 +1,65 @@
+package enricher
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jmontesinos91/omnilogger/config"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/logs"
+)
+
+// RuleLabeler adds the labels of the rules a log matches, labels sent by the producer and labels of
+// earlier rules are kept
+type RuleLabeler struct {
+	rules []config.LabelRuleConfigurations
+}
+
+// NewRuleLabeler creates a new instance of RuleLabeler
+func NewRuleLabeler(rules []config.LabelRuleConfigurations) *RuleLabeler {
+	return &RuleLabeler{rules: rules}
+}
+
+// Name of the stage
+func (r *RuleLabeler) Name() string {
+	return StageRules
+}
+
+// Enrich applies the labels of every matching rule
+func (r *RuleLabeler) Enrich(_ context.Context, model *logs.Model) error {
+	for _, rule := range r.rules {
+		if !matches(rule, model) {
+			continue
+		}
+
+		for key, value := range rule.Labels {
+			setLabel(model, key, value)
+		}
+	}
+
+	return nil
+}
+
+// matches validates every non empty field of the rule, path is a prefix
+func matches(rule config.LabelRuleConfigurations, model *logs.Model) bool {
+	if rule.Provider != "" && !strings.EqualFold(rule.Provider, model.Provider) {
+		return false
+	}
+
+	if rule.Resource != "" && !strings.EqualFold(rule.Resource, model.Resource) {
+		return false
+	}
+
+	if rule.Action != "" && !strings.EqualFold(rule.Action, model.Action) {
+		return false
+	}
+
+	if rule.Path != "" && !strings.HasPrefix(strings.ToLower(model.Path), strings.ToLower(rule.Path)) {
+		return false
+	}
+
+	if rule.Level != 0 && rule.Level != model.Level {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,248 @@
+package enricher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/jmontesinos91/omnilogger/config"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/logs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizer_Enrich(t *testing.T) {
+	model := &logs.Model{
+		Provider: " billing ",
+		Path:     " /V1/Users ",
+		Resource: "user",
+		Action:   " delete",
+		UserID:   "42 ",
+	}
+
+	assert.NoError(t, NewNormalizer().Enrich(context.Background(), model))
+	assert.Equal(t, "billing", model.Provider)
+	assert.Equal(t, "/v1/users", model.Path)
+	assert.Equal(t, "USER", model.Resource)
+	assert.Equal(t, "DELETE", model.Action)
+	assert.Equal(t, "42", model.UserID)
+}
+
+type failingTenantNames struct{}
+
+func (failingTenantNames) TenantName(context.Context, int) (string, bool, error) {
+	return "", false, errors.New("tenant service down")
+}
+
+func TestTenantResolver_Enrich(t *testing.T) {
+	names, err := NewStaticTenantNames(map[string]string{"1": "Acme", "2": "Globex"})
+	assert.NoError(t, err)
+
+	cases := []struct {
+		name      string
+		names     TenantNames
+		tenantCat string
+		expected  string
+		err       bool
+	}{
+		{name: "No tenants", names: names, tenantCat: "", expected: ""},
+		{
+			name:      "Missing names resolved",
+			names:     names,
+			tenantCat: `[{"id":1,"name":""},{"id":2},{"id":3}]`,
+			expected:  `[{"id":1,"name":"Acme"},{"id":2,"name":"Globex"},{"id":3}]`,
+		},
+		{
+			name:      "Known names kept",
+			names:     names,
+			tenantCat: `[{"id":1,"name":"Acme Corp"}]`,
+			expected:  `[{"id":1,"name":"Acme Corp"}]`,
+		},
+		{name: "Invalid catalog", names: names, tenantCat: `{"id":1}`, err: true},
+		{name: "Resolver error", names: failingTenantNames{}, tenantCat: `[{"id":1}]`, err: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			model := &logs.Model{TenantCat: tc.tenantCat}
+			err := NewTenantResolver(tc.names).Enrich(context.Background(), model)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, model.TenantCat)
+		})
+	}
+
+	_, err = NewStaticTenantNames(map[string]string{"acme": "Acme"})
+	assert.Error(t, err)
+}
+
+func TestGeoIP_Enrich(t *testing.T) {
+	database, err := ParseGeoIPDatabase(strings.NewReader(`# network,country,city
+203.0.113.0/24,MX,Monterrey
+203.0.0.0/16,MX
+2001:db8::/32,US,Seattle
+`))
+	assert.NoError(t, err)
+
+	cases := []struct {
+		name     string
+		model    logs.Model
+		expected map[string]string
+	}{
+		{
+			name:     "Most specific network",
+			model:    logs.Model{OriginIP: "203.0.113.10"},
+			expected: map[string]string{LabelGeoCountry: "MX", LabelGeoCity: "Monterrey"},
+		},
+		{
+			name:     "Network without city",
+			model:    logs.Model{OriginIP: "203.0.1.1"},
+			expected: map[string]string{LabelGeoCountry: "MX"},
+		},
+		{
+			name:     "Client reported ip as fallback",
+			model:    logs.Model{IpAddress: "2001:db8::1"},
+			expected: map[string]string{LabelGeoCountry: "US", LabelGeoCity: "Seattle"},
+		},
+		{
+			name:     "Producer labels kept",
+			model:    logs.Model{OriginIP: "203.0.113.10", Labels: map[string]string{LabelGeoCountry: "CA"}},
+			expected: map[string]string{LabelGeoCountry: "CA", LabelGeoCity: "Monterrey"},
+		},
+		{name: "Private address", model: logs.Model{OriginIP: "10.0.0.1"}},
+		{name: "Unknown address", model: logs.Model{OriginIP: "198.51.100.1"}},
+		{name: "Not an address", model: logs.Model{IpAddress: "localhost"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			model := tc.model
+			assert.NoError(t, NewGeoIP(database).Enrich(context.Background(), &model))
+			if tc.expected == nil {
+				assert.Empty(t, model.Labels)
+				return
+			}
+			assert.Equal(t, tc.expected, model.Labels)
+		})
+	}
+
+	_, err = ParseGeoIPDatabase(strings.NewReader("not-a-network,MX\n"))
+	assert.Error(t, err)
+
+	location, ok := database.Lookup(netip.MustParseAddr("203.0.113.200"))
+	assert.True(t, ok)
+	assert.Equal(t, GeoLocation{Country: "MX", City: "Monterrey"}, location)
+}
+
+func TestGeoIPDatabase_Lookup(t *testing.T) {
+	database, err := ParseGeoIPDatabase(strings.NewReader(`0.0.0.0/0,ZZ
+203.0.113.128/25,MX,Guadalajara
+203.0.113.0/24,MX,Monterrey
+203.0.113.0/24,US,Duplicate
+203.0.0.0/16,MX
+2001:db8::/32,US,Seattle
+2001:db8:1::/48,US,Portland
+`))
+	assert.NoError(t, err)
+
+	cases := []struct {
+		addr     string
+		expected GeoLocation
+		found    bool
+	}{
+		{addr: "203.0.113.200", expected: GeoLocation{Country: "MX", City: "Guadalajara"}, found: true},
+		{addr: "203.0.113.10", expected: GeoLocation{Country: "MX", City: "Monterrey"}, found: true},
+		{addr: "203.0.1.1", expected: GeoLocation{Country: "MX"}, found: true},
+		{addr: "198.51.100.1", expected: GeoLocation{Country: "ZZ"}, found: true},
+		{addr: "2001:db8:1::1", expected: GeoLocation{Country: "US", City: "Portland"}, found: true},
+		{addr: "2001:db8:2::1", expected: GeoLocation{Country: "US", City: "Seattle"}, found: true},
+		{addr: "2001:db9::1"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.addr, func(t *testing.T) {
+			location, ok := database.Lookup(netip.MustParseAddr(tc.addr))
+			assert.Equal(t, tc.found, ok)
+			assert.Equal(t, tc.expected, location)
+		})
+	}
+}
+
+func TestRedactor_Enrich(t *testing.T) {
+	redactor, err := NewRedactor(config.RedactionConfigurations{
+		Keys:     []string{"password", "Token"},
+		Patterns: []string{`[\w.+-]+@[\w-]+\.[\w.]+`},
+	})
+	assert.NoError(t, err)
+
+	model := &logs.Model{
+		Data:        `{"user":{"email":"jane@example.com","password":"hunter2"},"tokens":[{"token":"abc"}],"amount":12345678901234567890}`,
+		OldData:     `{}`,
+		Description: "Password reset for jane@example.com",
+	}
+	assert.NoError(t, redactor.Enrich(context.Background(), model))
+
+	var data map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(model.Data), &data))
+	assert.Equal(t, map[string]interface{}{"email": "[REDACTED]", "password": "[REDACTED]"}, data["user"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"token": "[REDACTED]"}}, data["tokens"])
+	assert.Contains(t, model.Data, "12345678901234567890")
+	assert.Equal(t, `{}`, model.OldData)
+	assert.Equal(t, "Password reset for [REDACTED]", model.Description)
+
+	// Data that can not be parsed can not be redacted
+	assert.Error(t, redactor.Enrich(context.Background(), &logs.Model{Data: "password=hunter2"}))
+}
+
+func TestRuleLabeler_Enrich(t *testing.T) {
+	labeler := NewRuleLabeler([]config.LabelRuleConfigurations{
+		{Provider: "billing", Labels: map[string]string{"team": "payments"}},
+		{Resource: "USER", Action: "DELETE", Labels: map[string]string{"audit": "gdpr", "team": "identity"}},
+		{Path: "/v1/admin", Level: 3, Labels: map[string]string{"severity": "high"}},
+	})
+
+	cases := []struct {
+		name     string
+		model    logs.Model
+		expected map[string]string
+	}{
+		{
+			name:     "Single rule",
+			model:    logs.Model{Provider: "Billing"},
+			expected: map[string]string{"team": "payments"},
+		},
+		{
+			name:     "Earlier rules win",
+			model:    logs.Model{Provider: "billing", Resource: "user", Action: "delete"},
+			expected: map[string]string{"team": "payments", "audit": "gdpr"},
+		},
+		{
+			name:     "Producer labels win",
+			model:    logs.Model{Resource: "USER", Action: "DELETE", Labels: map[string]string{"team": "support"}},
+			expected: map[string]string{"team": "support", "audit": "gdpr"},
+		},
+		{
+			name:     "Path prefix and level",
+			model:    logs.Model{Path: "/v1/admin/users", Level: 3},
+			expected: map[string]string{"severity": "high"},
+		},
+		{name: "No match", model: logs.Model{Path: "/v1/admin/users", Level: 1}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			model := tc.model
+			assert.NoError(t, labeler.Enrich(context.Background(), &model))
+			if tc.expected == nil {
+				assert.Empty(t, model.Labels)
+				return
+			}
+			assert.Equal(t, tc.expected, model.Labels)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package enricher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/jmontesinos91/omnilogger/internal/repositories/logs"
+)
+
+// TenantNames resolves the name of a tenant
+type TenantNames interface {
+	TenantName(ctx context.Context, id int) (string, bool, error)
+}
+
+// StaticTenantNames tenant names taken from the configuration
+type StaticTenantNames map[int]string
+
+// NewStaticTenantNames creates a new instance of StaticTenantNames from names keyed by tenant id
+func NewStaticTenantNames(names map[string]string) (StaticTenantNames, error) {
+	res := make(StaticTenantNames, len(names))
+	for key, name := range names {
+		id, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, fmt.Errorf("enricher: invalid tenant id %q -> %v", key, err)
+		}
+		res[id] = name
+	}
+
+	return res, nil
+}
+
+// TenantName returns the configured name of the tenant
+func (s StaticTenantNames) TenantName(_ context.Context, id int) (string, bool, error) {
+	name, ok := s[id]
+	return name, ok, nil
+}
+
+// TenantResolver fills the names missing from the tenant catalog of a log
+type TenantResolver struct {
+	names TenantNames
+}
+
+// NewTenantResolver creates a new instance of TenantResolver
+func NewTenantResolver(names TenantNames) *TenantResolver {
+	return &TenantResolver{names: names}
+}
+
+// Name of the stage
+func (t *TenantResolver) Name() string {
+	return StageTenant
+}
+
+// Enrich resolves the tenants of tenant_cat without a name, the other fields of the catalog are kept
+func (t *TenantResolver) Enrich(ctx context.Context, model *logs.Model) error {
+	if model.TenantCat == "" {
+		return nil
+	}
+
+	var items []map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(model.TenantCat), &items); err != nil {
+		return fmt.Errorf("enricher: invalid tenant_cat -> %v", err)
+	}
+
+	changed := false
+	for _, item := range items {
+		var name string
+		if raw, ok := item["name"]; ok {
+			_ = json.Unmarshal(raw, &name)
+		}
+		if name != "" {
+			continue
+		}
+
+		var id int
+		if err := json.Unmarshal(item["id"], &id); err != nil {
+			continue
+		}
+
+		resolved, ok, err := t.names.TenantName(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		item["name"], _ = json.Marshal(resolved)
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+
+	tenantCat, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	model.TenantCat = string(tenantCat)
+
+	return nil
+}
@@ -0,0 +1,555 @@
+package export_job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/jmontesinos91/ologs/logger"
+	tracekey "github.com/jmontesinos91/ologs/logger/v2"
+	"github.com/jmontesinos91/omnilogger/config"
+	"github.com/jmontesinos91/omnilogger/internal/adapters/objectstore"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/export_job"
+	"github.com/jmontesinos91/omnilogger/internal/services/logs"
+	"github.com/jmontesinos91/omnilogger/internal/utils/export"
+	"github.com/jmontesinos91/omnilogger/internal/utils/text"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/jmontesinos91/terrors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/samber/lo"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrAlreadyRunning a run was requested while another one is in progress
+var ErrAlreadyRunning = errors.New("export_job: already running")
+
+var (
+	jobsMetric = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "export_jobs_total",
+		Help: "The total number of export jobs, partitioned by status queued, ready, failed or expired",
+	}, []string{"status"})
+	queuedMetric = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "export_jobs_queued",
+		Help: "The number of export jobs waiting for a worker",
+	})
+)
+
+// job export job handed to the workers, ctx holds the values of the request that queued it
+type job struct {
+	ctx    context.Context
+	model  *export_job.Model
+	filter logs.Filter
+	opts   logs.ExportOptions
+}
+
+// DefaultService runs the exports queued by the users in background and keeps their files in the store until they
+// expire
+type DefaultService struct {
+	log     *logger.ContextLogger
+	logsSvc logs.IService
+	jobRepo export_job.IRepository
+	store   objectstore.IStore
+	config  config.ExportJobsConfigurations
+	ttl     time.Duration
+	queue   chan job
+	workers sync.WaitGroup
+	running atomic.Bool
+	now     func() time.Time
+}
+
+// NewDefaultService creates a new instance of DefaultService export job
+func NewDefaultService(l *logger.ContextLogger, ls logs.IService, jr export_job.IRepository, st objectstore.IStore, c config.ExportJobsConfigurations) *DefaultService {
+	if c.Workers <= 0 {
+		c.Workers = defaultWorkers
+	}
+
+	if c.QueueSize <= 0 {
+		c.QueueSize = defaultQueueSize
+	}
+
+	if c.Prefix == "" {
+		c.Prefix = defaultPrefix
+	}
+
+	ttl := defaultTTL
+	if c.TTLInHours > 0 {
+		ttl = time.Duration(c.TTLInHours) * time.Hour
+	}
+
+	return &DefaultService{
+		log:     l,
+		logsSvc: ls,
+		jobRepo: jr,
+		store:   st,
+		config:  c,
+		ttl:     ttl,
+		queue:   make(chan job, c.QueueSize),
+		now:     time.Now,
+	}
+}
+
+// Start starts the workers running the queued jobs and expires the jobs in background every interval, it stops
+// once ctx is done
+func (s *DefaultService) Start(ctx context.Context) {
+	if !s.config.Enabled {
+		s.log.Log(logrus.WarnLevel, "Start", "Export jobs not enabled, exports are only streamed")
+		return
+	}
+
+	// The queue only lives in memory, the jobs still queued by the previous process never run
+	now := s.now().UTC()
+	failed, err := s.jobRepo.FailQueued(ctx, now, "Export job interrupted by a restart", now)
+	if err != nil {
+		s.log.Error(logrus.ErrorLevel, "Start", "Failed to fail the export jobs queued before the restart", err)
+	}
+	jobsMetric.WithLabelValues(export_job.StatusFailed).Add(float64(failed))
+
+	s.workers.Add(s.config.Workers)
+	for i := 0; i < s.config.Workers; i++ {
+		go s.work(ctx)
+	}
+
+	interval := defaultInterval
+	if s.config.IntervalInMinutes > 0 {
+		interval = time.Duration(s.config.IntervalInMinutes) * time.Minute
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := s.Run(ctx); err != nil && !errors.Is(err, ErrAlreadyRunning) {
+				s.log.Error(logrus.ErrorLevel, "Start", "Export jobs expiry run failed", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	s.log.Log(logrus.InfoLevel, "Start", fmt.Sprintf("Export jobs run by %d workers, expired every %s", s.config.Workers, interval))
+}
+
+// work runs the queued jobs one at a time, the jobs still queued once ctx is done are failed by the next start
+func (s *DefaultService) work(ctx context.Context) {
+	defer s.workers.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case j := <-s.queue:
+			if ctx.Err() != nil {
+				return
+			}
+			queuedMetric.Dec()
+			s.run(j)
+		}
+	}
+}
+
+// Wait waits for the workers to finish their jobs once the ctx given to Start is done, it gives up once ctx is
+// done. The jobs still running then are failed once stale
+func (s *DefaultService) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Run fails the jobs whose worker is gone and deletes the files of the jobs past their expiry
+func (s *DefaultService) Run(ctx context.Context) (*RunResult, error) {
+	if !s.running.CompareAndSwap(false, true) {
+		return nil, ErrAlreadyRunning
+	}
+	defer s.running.Store(false)
+
+	now := s.now().UTC()
+	result := &RunResult{StartedAt: now}
+
+	err := s.expire(ctx, now, result)
+
+	finishedAt := s.now().UTC()
+	result.FinishedAt = &finishedAt
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	s.log.Log(logrus.InfoLevel, "Run", result.summary())
+
+	return result, err
+}
+
+func (s *DefaultService) expire(ctx context.Context, now time.Time, result *RunResult) error {
+	// Jobs time out after jobTimeout, the ones still running later were interrupted by a restart
+	failed, err := s.jobRepo.FailStale(ctx, now.Add(-staleAfter), "Export job interrupted", now)
+	if err != nil {
+		return err
+	}
+	result.Failed = failed
+	jobsMetric.WithLabelValues(export_job.StatusFailed).Add(float64(failed))
+
+	jobs, err := s.jobRepo.Expired(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, model := range jobs {
+		if model.ObjectKey != "" {
+			if err := s.store.Delete(ctx, model.ObjectKey); err != nil {
+				errs = append(errs, fmt.Errorf("export job %s -> %w", model.ID, err))
+				continue
+			}
+		}
+
+		expiredAt := s.now().UTC()
+		model.Status = export_job.StatusExpired
+		model.ExpiredAt = &expiredAt
+		if err := s.jobRepo.Update(ctx, &model); err != nil {
+			errs = append(errs, fmt.Errorf("export job %s -> %w", model.ID, err))
+			continue
+		}
+		result.Expired++
+		jobsMetric.WithLabelValues(export_job.StatusExpired).Inc()
+	}
+
+	return errors.Join(errs...)
+}
+
+// Create queues an export of the logs matching the filter, the job is only visible to the user requesting it
+func (s *DefaultService) Create(ctx context.Context, filter logs.Filter, opts logs.ExportOptions) (*Response, error) {
+	requestID := ctx.Value(middleware.RequestIDKey).(string)
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	if !s.config.Enabled || s.store == nil {
+		return nil, terrors.New(terrors.ErrBadRequest, "Export jobs not enabled", map[string]string{})
+	}
+
+	tenants := claims.Tenants
+	if len(filter.TenantID) > 0 {
+		tenants = lo.Intersect(claims.Tenants, filter.TenantID)
+	}
+
+	rawFilter, err := json.Marshal(filter)
+	if err != nil {
+		return nil, terrors.New(terrors.ErrBadRequest, "Invalid export filter", map[string]string{})
+	}
+
+	file := logs.ToExportFile(opts)
+	createdAt := s.now().UTC()
+	model := &export_job.Model{
+		ID:          uuid.NewString(),
+		Status:      export_job.StatusQueued,
+		Format:      file.Format,
+		FileName:    file.Name,
+		ContentType: file.ContentType,
+		Filter:      rawFilter,
+		RequestedBy: claims.User,
+		UserID:      claims.UserID,
+		TenantIDs:   tenants,
+		CreatedAt:   &createdAt,
+		ExpiresAt:   createdAt.Add(s.ttl),
+	}
+	if err := s.jobRepo.Create(ctx, model); err != nil {
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			"Create",
+			"Error while persisting export job: %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+				tracekey.UserID:     claims.UserID,
+			},
+			err)
+		return nil, terrors.New(terrors.ErrInternalService, "Internal error service", map[string]string{})
+	}
+
+	// The response is built before the job is handed to a worker which updates the model
+	response := ToResponse(model)
+
+	// The job outlives the request, only its values are kept
+	select {
+	case s.queue <- job{ctx: context.WithoutCancel(ctx), model: model, filter: filter, opts: opts}:
+		queuedMetric.Inc()
+		jobsMetric.WithLabelValues(export_job.StatusQueued).Inc()
+	default:
+		finishedAt := s.now().UTC()
+		model.Status = export_job.StatusFailed
+		model.Error = "Export queue full"
+		model.FinishedAt = &finishedAt
+		s.update(ctx, "Create", model)
+		return nil, terrors.New(terrors.ErrRateLimited, "Too many export jobs queued, try again later", map[string]string{})
+	}
+
+	return response, nil
+}
+
+// Get gets the progress of an export job of the user
+func (s *DefaultService) Get(ctx context.Context, id string) (*Response, error) {
+	model, err := s.find(ctx, "Get", id)
+	if err != nil {
+		return nil, err
+	}
+
+	return ToResponse(model), nil
+}
+
+// Download opens the file of a ready export job of the user
+func (s *DefaultService) Download(ctx context.Context, id string) (*Download, error) {
+	requestID := ctx.Value(middleware.RequestIDKey).(string)
+
+	model, err := s.find(ctx, "Download", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if model.Status == export_job.StatusExpired || !s.now().Before(model.ExpiresAt) {
+		return nil, terrors.New(terrors.ErrNotFound, "Export file expired", map[string]string{})
+	}
+
+	if model.Status != export_job.StatusReady {
+		return nil, terrors.New(terrors.ErrPreconditionFailed, "Export job is "+model.Status, map[string]string{})
+	}
+
+	if s.store == nil {
+		return nil, terrors.New(terrors.ErrBadRequest, "Export jobs not enabled", map[string]string{})
+	}
+
+	content, err := s.store.Get(ctx, model.ObjectKey)
+	if errors.Is(err, objectstore.ErrNotFound) {
+		return nil, terrors.New(terrors.ErrNotFound, "Export file not found", map[string]string{})
+	}
+	if err != nil {
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			"Download",
+			"Error while reading export file: %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+			},
+			err)
+		return nil, terrors.New(terrors.ErrInternalService, "Internal error service", map[string]string{})
+	}
+
+	return &Download{
+		Name:        model.FileName,
+		ContentType: model.ContentType,
+		Size:        model.SizeBytes,
+		SHA256:      model.SHA256,
+		Manifest:    model.Manifest,
+		Content:     content,
+	}, nil
+}
+
+// Manifest gets the manifest of a ready export job of the user, it stays available after the file expired so the
+// copies already downloaded can still be verified
+func (s *DefaultService) Manifest(ctx context.Context, id string) (*export.Manifest, error) {
+	requestID := ctx.Value(middleware.RequestIDKey).(string)
+
+	model, err := s.find(ctx, "Manifest", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if model.Status != export_job.StatusReady && model.Status != export_job.StatusExpired {
+		return nil, terrors.New(terrors.ErrPreconditionFailed, "Export job is "+model.Status, map[string]string{})
+	}
+	if model.Manifest == "" {
+		return nil, terrors.New(terrors.ErrNotFound, "Export manifest not found", map[string]string{})
+	}
+
+	manifest, err := export.ParseManifest([]byte(model.Manifest))
+	if err != nil {
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			"Manifest",
+			"Error while reading export manifest: %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+			},
+			err)
+		return nil, terrors.New(terrors.ErrInternalService, "Internal error service", map[string]string{})
+	}
+
+	return manifest, nil
+}
+
+// find finds an export job of the user, jobs of other users or of tenants the user no longer has access to are
+// reported as not found to avoid leaking their existence
+func (s *DefaultService) find(ctx context.Context, caller, id string) (*export_job.Model, error) {
+	requestID := ctx.Value(middleware.RequestIDKey).(string)
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	if id == "" {
+		return nil, terrors.New(terrors.ErrBadRequest, "Missing id param", map[string]string{})
+	}
+
+	model, err := s.jobRepo.FindByID(ctx, id)
+	if err != nil {
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			caller,
+			"Error while retrieve export job: %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+			},
+			err)
+		return nil, terrors.New(terrors.ErrNotFound, "Export job not found", map[string]string{})
+	}
+
+	if model.UserID != claims.UserID || model.RequestedBy != claims.User || !lo.Every(claims.Tenants, model.TenantIDs) {
+		return nil, terrors.New(terrors.ErrNotFound, "Export job not found", map[string]string{})
+	}
+
+	return model, nil
+}
+
+// run exports the logs of the job to a temporary file then uploads it to the store, jobs no longer queued are
+// skipped
+func (s *DefaultService) run(j job) {
+	requestID, _ := j.ctx.Value(middleware.RequestIDKey).(string)
+	model := j.model
+
+	startedAt := s.now().UTC()
+	started, err := s.jobRepo.MarkRunning(j.ctx, model.ID, startedAt)
+	if err != nil {
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			"Run",
+			"Error while starting export job "+model.ID+": %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+			},
+			err)
+		return
+	}
+	if !started {
+		s.log.Log(logrus.InfoLevel, "Run", "Export job "+model.ID+" is no longer queued, skipped")
+		return
+	}
+	model.Status = export_job.StatusRunning
+	model.StartedAt = &startedAt
+
+	ctx, cancel := context.WithTimeout(j.ctx, jobTimeout)
+	defer cancel()
+
+	err = s.export(ctx, j)
+
+	finishedAt := s.now().UTC()
+	model.FinishedAt = &finishedAt
+	if err == nil {
+		model.Status = export_job.StatusReady
+	} else {
+		model.Status = export_job.StatusFailed
+		model.Error = text.Truncate(err.Error(), maxErrorLength)
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			"Run",
+			"Error while running export job "+model.ID+": %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+			},
+			err)
+	}
+	jobsMetric.WithLabelValues(model.Status).Inc()
+
+	// The outcome is recorded even when the job timed out
+	s.update(j.ctx, "Run", model)
+}
+
+func (s *DefaultService) export(ctx context.Context, j job) error {
+	requestID, _ := ctx.Value(middleware.RequestIDKey).(string)
+
+	file, err := os.CreateTemp("", "omnilogger-export-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name()) //nolint:errcheck
+	defer file.Close()           //nolint:errcheck
+
+	opts := j.opts
+	opts.Progress = func(rows int) {
+		if rows%progressRows != 0 {
+			return
+		}
+		if err := s.jobRepo.UpdateProgress(ctx, j.model.ID, rows); err != nil {
+			s.log.WithContext(
+				logrus.WarnLevel,
+				"Run",
+				"Error while updating export job progress: %v",
+				logger.Context{
+					tracekey.TrackingID: requestID,
+				},
+				err)
+		}
+	}
+
+	exported, err := s.logsSvc.Export(ctx, j.filter, opts, file)
+	if err != nil {
+		return err
+	}
+
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	key := objectKey(s.config.Prefix, j.model.ID, exported.Name)
+	if err := s.store.Put(ctx, key, file, size, exported.ContentType); err != nil {
+		return err
+	}
+	j.model.ObjectKey = key
+	j.model.SizeBytes = size
+
+	if exported.Manifest != nil {
+		manifest, err := exported.Manifest.Encode()
+		if err != nil {
+			return err
+		}
+		j.model.RowCount = exported.Manifest.RowCount
+		j.model.SHA256 = exported.Manifest.SHA256
+		j.model.Manifest = manifest
+	}
+
+	return nil
+}
+
+// update records the status of the job, failures are only logged as the job itself is done
+func (s *DefaultService) update(ctx context.Context, caller string, model *export_job.Model) {
+	if err := s.jobRepo.Update(ctx, model); err != nil {
+		requestID, _ := ctx.Value(middleware.RequestIDKey).(string)
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			caller,
+			"Error while updating export job "+model.ID+": %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+				tracekey.UserID:     model.UserID,
+			},
+			err)
+	}
+}
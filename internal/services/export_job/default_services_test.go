@@ -0,0 +1,362 @@
+package export_job
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/omnilogger/config"
+	"github.com/jmontesinos91/omnilogger/internal/adapters/objectstore"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/export_job"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/export_job/exportjobmock"
+	"github.com/jmontesinos91/omnilogger/internal/services/logs"
+	"github.com/jmontesinos91/omnilogger/internal/services/logs/logssvcmock"
+	"github.com/jmontesinos91/omnilogger/internal/utils/export"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/jmontesinos91/terrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func testContext() context.Context {
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "test-request-id")
+	return context.WithValue(ctx, &sts.Claim, sts.Claims{User: "jane", UserID: 42, Tenants: []int{1, 5}})
+}
+
+func TestCreate(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestCreate", "debug", logger.TextFormat)
+	content := []byte("{\"id\":\"1\"}\n")
+	manifest := &export.Manifest{File: logs.ExportFileNDJSON, RowCount: 1, SHA256: export.Digest(content)}
+
+	cases := []struct {
+		name      string
+		config    config.ExportJobsConfigurations
+		filter    logs.Filter
+		exportErr error
+		repoFunc  func() *exportjobmock.IRepository
+		expected  func(t *testing.T, model *export_job.Model, store objectstore.IStore)
+		errCode   string
+	}{
+		{
+			name:   "Ready",
+			config: config.ExportJobsConfigurations{Enabled: true},
+			filter: logs.Filter{TenantID: []int{5, 9}},
+			repoFunc: func() *exportjobmock.IRepository {
+				repoMock := &exportjobmock.IRepository{}
+				repoMock.On("Create", mock.Anything, mock.Anything).Return(nil)
+				repoMock.On("MarkRunning", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+				repoMock.On("Update", mock.Anything, mock.Anything).Return(nil)
+				return repoMock
+			},
+			expected: func(t *testing.T, model *export_job.Model, store objectstore.IStore) {
+				assert.Equal(t, export_job.StatusReady, model.Status)
+				assert.Equal(t, []int{5}, model.TenantIDs)
+				assert.Equal(t, "jane", model.RequestedBy)
+				assert.Equal(t, 1, model.RowCount)
+				assert.Equal(t, int64(len(content)), model.SizeBytes)
+				assert.Equal(t, manifest.SHA256, model.SHA256)
+				assert.NotEmpty(t, model.Manifest)
+				assert.Equal(t, "exports/"+model.ID+"/"+logs.ExportFileNDJSON, model.ObjectKey)
+
+				reader, err := store.Get(context.Background(), model.ObjectKey)
+				assert.NoError(t, err)
+				defer reader.Close() //nolint:errcheck
+				uploaded, err := io.ReadAll(reader)
+				assert.NoError(t, err)
+				assert.Equal(t, content, uploaded)
+			},
+		},
+		{
+			name:      "Export fails",
+			config:    config.ExportJobsConfigurations{Enabled: true},
+			exportErr: errors.New("db down"),
+			repoFunc: func() *exportjobmock.IRepository {
+				repoMock := &exportjobmock.IRepository{}
+				repoMock.On("Create", mock.Anything, mock.Anything).Return(nil)
+				repoMock.On("MarkRunning", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+				repoMock.On("Update", mock.Anything, mock.Anything).Return(nil)
+				return repoMock
+			},
+			expected: func(t *testing.T, model *export_job.Model, _ objectstore.IStore) {
+				assert.Equal(t, export_job.StatusFailed, model.Status)
+				assert.Equal(t, "db down", model.Error)
+				assert.Empty(t, model.ObjectKey)
+			},
+		},
+		{
+			name:   "No longer queued",
+			config: config.ExportJobsConfigurations{Enabled: true},
+			repoFunc: func() *exportjobmock.IRepository {
+				repoMock := &exportjobmock.IRepository{}
+				repoMock.On("Create", mock.Anything, mock.Anything).Return(nil)
+				repoMock.On("MarkRunning", mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+				return repoMock
+			},
+			expected: func(t *testing.T, model *export_job.Model, _ objectstore.IStore) {
+				assert.Equal(t, export_job.StatusQueued, model.Status)
+			},
+		},
+		{
+			name:     "Not enabled",
+			config:   config.ExportJobsConfigurations{},
+			repoFunc: func() *exportjobmock.IRepository { return &exportjobmock.IRepository{} },
+			errCode:  terrors.ErrBadRequest,
+		},
+		{
+			name:   "Persist error",
+			config: config.ExportJobsConfigurations{Enabled: true},
+			repoFunc: func() *exportjobmock.IRepository {
+				repoMock := &exportjobmock.IRepository{}
+				repoMock.On("Create", mock.Anything, mock.Anything).Return(errors.New("db down"))
+				return repoMock
+			},
+			errCode: terrors.ErrInternalService,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repoMock := tc.repoFunc()
+			logsMock := &logssvcmock.IService{ExportContent: content, ExportRes: &logs.ExportFile{Name: logs.ExportFileNDJSON, ContentType: logs.ContentTypeNDJSON, Manifest: manifest}, ExportErr: tc.exportErr}
+			store, err := objectstore.NewLocalStore(ctxLogger, t.TempDir())
+			assert.NoError(t, err)
+
+			svc := NewDefaultService(ctxLogger, logsMock, repoMock, store, tc.config)
+
+			// The queued job is run in place of a worker
+			res, err := svc.Create(testContext(), tc.filter, logs.ExportOptions{Format: logs.ExportFormatNDJSON})
+			if err == nil {
+				svc.run(<-svc.queue)
+			}
+			if tc.errCode != "" {
+				assert.True(t, terrors.Is(err, tc.errCode), "unexpected error %v", err)
+				repoMock.AssertExpectations(t)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, export_job.StatusQueued, res.Status)
+			model := repoMock.Calls[0].Arguments.Get(1).(*export_job.Model)
+			tc.expected(t, model, store)
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCreate_QueueFull(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestCreate_QueueFull", "debug", logger.TextFormat)
+	repoMock := &exportjobmock.IRepository{}
+	repoMock.On("Create", mock.Anything, mock.Anything).Return(nil)
+	repoMock.On("Update", mock.Anything, mock.MatchedBy(func(m *export_job.Model) bool {
+		return m.Status == export_job.StatusFailed
+	})).Return(nil).Once()
+	store, err := objectstore.NewLocalStore(ctxLogger, t.TempDir())
+	assert.NoError(t, err)
+
+	// No worker takes the jobs, the second one does not fit in the queue
+	svc := NewDefaultService(ctxLogger, &logssvcmock.IService{}, repoMock, store, config.ExportJobsConfigurations{Enabled: true, QueueSize: 1})
+
+	_, err = svc.Create(testContext(), logs.Filter{}, logs.ExportOptions{})
+	assert.NoError(t, err)
+
+	_, err = svc.Create(testContext(), logs.Filter{}, logs.ExportOptions{})
+	assert.True(t, terrors.Is(err, terrors.ErrRateLimited), "unexpected error %v", err)
+	repoMock.AssertExpectations(t)
+}
+
+func TestStart(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestStart", "debug", logger.TextFormat)
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+
+	repoMock := &exportjobmock.IRepository{}
+	repoMock.On("FailQueued", mock.Anything, now, mock.Anything, now).Return(2, nil).Once()
+	repoMock.On("FailStale", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(0, nil).Maybe()
+	repoMock.On("Expired", mock.Anything, mock.Anything).Return([]export_job.Model{}, nil).Maybe()
+
+	svc := NewDefaultService(ctxLogger, &logssvcmock.IService{}, repoMock, nil, config.ExportJobsConfigurations{Enabled: true, IntervalInMinutes: 60})
+	svc.now = func() time.Time { return now }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	svc.Start(ctx)
+	repoMock.AssertCalled(t, "FailQueued", mock.Anything, now, mock.Anything, now)
+
+	// The workers stop once ctx is done
+	cancel()
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), time.Second)
+	defer waitCancel()
+	assert.NoError(t, svc.Wait(waitCtx))
+}
+
+func TestGet(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestGet", "debug", logger.TextFormat)
+
+	cases := []struct {
+		name    string
+		model   *export_job.Model
+		findErr error
+		errCode string
+	}{
+		{
+			name:  "Own job",
+			model: &export_job.Model{ID: "j1", Status: export_job.StatusRunning, RequestedBy: "jane", UserID: 42, TenantIDs: []int{5}, RowCount: 10000},
+		},
+		{
+			name:    "Other user",
+			model:   &export_job.Model{ID: "j1", RequestedBy: "john", UserID: 7, TenantIDs: []int{5}},
+			errCode: terrors.ErrNotFound,
+		},
+		{
+			name:    "Tenant no longer allowed",
+			model:   &export_job.Model{ID: "j1", RequestedBy: "jane", UserID: 42, TenantIDs: []int{5, 9}},
+			errCode: terrors.ErrNotFound,
+		},
+		{
+			name:    "Not found",
+			findErr: terrors.New(terrors.ErrNotFound, "Export job not found", map[string]string{}),
+			errCode: terrors.ErrNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repoMock := &exportjobmock.IRepository{}
+			repoMock.On("FindByID", mock.Anything, "j1").Return(tc.model, tc.findErr)
+
+			svc := NewDefaultService(ctxLogger, &logssvcmock.IService{}, repoMock, nil, config.ExportJobsConfigurations{})
+			res, err := svc.Get(testContext(), "j1")
+			if tc.errCode != "" {
+				assert.True(t, terrors.Is(err, tc.errCode), "unexpected error %v", err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.model.Status, res.Status)
+			assert.Equal(t, tc.model.RowCount, res.RowCount)
+		})
+	}
+}
+
+func TestDownload(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestDownload", "debug", logger.TextFormat)
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+	job := func(status string, expiresAt time.Time) *export_job.Model {
+		return &export_job.Model{ID: "j1", Status: status, RequestedBy: "jane", UserID: 42, TenantIDs: []int{1}, FileName: logs.ExportFileCSV,
+			ContentType: logs.ContentTypeCSV, ObjectKey: "exports/j1/logs.csv", SizeBytes: 7, SHA256: "digest", ExpiresAt: expiresAt}
+	}
+
+	cases := []struct {
+		name    string
+		model   *export_job.Model
+		errCode string
+	}{
+		{name: "Ready", model: job(export_job.StatusReady, now.Add(time.Hour))},
+		{name: "Running", model: job(export_job.StatusRunning, now.Add(time.Hour)), errCode: terrors.ErrPreconditionFailed},
+		{name: "Past expiry", model: job(export_job.StatusReady, now), errCode: terrors.ErrNotFound},
+		{name: "Expired", model: job(export_job.StatusExpired, now.Add(time.Hour)), errCode: terrors.ErrNotFound},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repoMock := &exportjobmock.IRepository{}
+			repoMock.On("FindByID", mock.Anything, "j1").Return(tc.model, nil)
+			store, err := objectstore.NewLocalStore(ctxLogger, t.TempDir())
+			assert.NoError(t, err)
+			assert.NoError(t, store.Put(context.Background(), "exports/j1/logs.csv", strings.NewReader("content"), 7, logs.ContentTypeCSV))
+
+			svc := NewDefaultService(ctxLogger, &logssvcmock.IService{}, repoMock, store, config.ExportJobsConfigurations{Enabled: true})
+			svc.now = func() time.Time { return now }
+
+			res, err := svc.Download(testContext(), "j1")
+			if tc.errCode != "" {
+				assert.True(t, terrors.Is(err, tc.errCode), "unexpected error %v", err)
+				return
+			}
+
+			assert.NoError(t, err)
+			defer res.Content.Close() //nolint:errcheck
+			content, err := io.ReadAll(res.Content)
+			assert.NoError(t, err)
+			assert.Equal(t, "content", string(content))
+			assert.Equal(t, logs.ExportFileCSV, res.Name)
+			assert.Equal(t, "digest", res.SHA256)
+		})
+	}
+}
+
+func TestManifest(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestManifest", "debug", logger.TextFormat)
+	manifest, err := export.NewDigestManifest(logs.ExportFileCSV, logs.ExportFormatCSV, nil, 1, "digest")
+	assert.NoError(t, err)
+	encoded, err := manifest.Encode()
+	assert.NoError(t, err)
+	job := func(status, manifest string) *export_job.Model {
+		return &export_job.Model{ID: "j1", Status: status, RequestedBy: "jane", UserID: 42, TenantIDs: []int{1}, Manifest: manifest}
+	}
+
+	cases := []struct {
+		name    string
+		model   *export_job.Model
+		errCode string
+	}{
+		{name: "Ready", model: job(export_job.StatusReady, encoded)},
+		{name: "Expired", model: job(export_job.StatusExpired, encoded)},
+		{name: "Running", model: job(export_job.StatusRunning, ""), errCode: terrors.ErrPreconditionFailed},
+		{name: "Missing manifest", model: job(export_job.StatusReady, ""), errCode: terrors.ErrNotFound},
+		{name: "Invalid manifest", model: job(export_job.StatusReady, "not a manifest"), errCode: terrors.ErrInternalService},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repoMock := &exportjobmock.IRepository{}
+			repoMock.On("FindByID", mock.Anything, "j1").Return(tc.model, nil)
+
+			svc := NewDefaultService(ctxLogger, &logssvcmock.IService{}, repoMock, nil, config.ExportJobsConfigurations{Enabled: true})
+
+			res, err := svc.Manifest(testContext(), "j1")
+			if tc.errCode != "" {
+				assert.True(t, terrors.Is(err, tc.errCode), "unexpected error %v", err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, "digest", res.SHA256)
+			assert.Equal(t, logs.ExportFileCSV, res.File)
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestRun", "debug", logger.TextFormat)
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+
+	store, err := objectstore.NewLocalStore(ctxLogger, t.TempDir())
+	assert.NoError(t, err)
+	assert.NoError(t, store.Put(context.Background(), "exports/j1/logs.csv", strings.NewReader("content"), 7, logs.ContentTypeCSV))
+
+	repoMock := &exportjobmock.IRepository{}
+	repoMock.On("FailStale", mock.Anything, now.Add(-staleAfter), mock.Anything, now).Return(1, nil)
+	repoMock.On("Expired", mock.Anything, now).Return([]export_job.Model{
+		{ID: "j1", Status: export_job.StatusReady, ObjectKey: "exports/j1/logs.csv"},
+		{ID: "j2", Status: export_job.StatusFailed},
+	}, nil)
+	repoMock.On("Update", mock.Anything, mock.MatchedBy(func(m *export_job.Model) bool {
+		return m.Status == export_job.StatusExpired && m.ExpiredAt != nil
+	})).Return(nil).Twice()
+
+	svc := NewDefaultService(ctxLogger, &logssvcmock.IService{}, repoMock, store, config.ExportJobsConfigurations{Enabled: true})
+	svc.now = func() time.Time { return now }
+
+	res, err := svc.Run(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, res.Failed)
+	assert.Equal(t, 2, res.Expired)
+
+	_, err = store.Stat(context.Background(), "exports/j1/logs.csv")
+	assert.ErrorIs(t, err, objectstore.ErrNotFound)
+	repoMock.AssertExpectations(t)
+}
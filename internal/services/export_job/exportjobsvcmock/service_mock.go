@@ -0,0 +1,91 @@
+package exportjobsvcmock
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/jmontesinos91/omnilogger/internal/services/export_job"
+	"github.com/jmontesinos91/omnilogger/internal/services/logs"
+	"github.com/jmontesinos91/omnilogger/internal/utils/export"
+)
+
+type IService struct {
+	// Create
+	CreateErr    error
+	CreateRes    *export_job.Response
+	CreateCalled bool
+	CreateFilter logs.Filter
+	CreateOpts   logs.ExportOptions
+
+	// Get
+	GetErr    error
+	GetRes    *export_job.Response
+	GetCalled bool
+	GetID     string
+
+	// Download
+	DownloadErr     error
+	DownloadRes     *export_job.Download
+	DownloadContent string
+	DownloadCalled  bool
+	DownloadID      string
+
+	// Manifest
+	ManifestErr    error
+	ManifestRes    *export.Manifest
+	ManifestCalled bool
+	ManifestID     string
+}
+
+func (m *IService) Create(ctx context.Context, filter logs.Filter, opts logs.ExportOptions) (*export_job.Response, error) {
+	m.CreateCalled = true
+	m.CreateFilter = filter
+	m.CreateOpts = opts
+	if m.CreateErr != nil {
+		return nil, m.CreateErr
+	}
+	if m.CreateRes != nil {
+		return m.CreateRes, nil
+	}
+	return &export_job.Response{}, nil
+}
+
+func (m *IService) Get(ctx context.Context, id string) (*export_job.Response, error) {
+	m.GetCalled = true
+	m.GetID = id
+	if m.GetErr != nil {
+		return nil, m.GetErr
+	}
+	if m.GetRes != nil {
+		return m.GetRes, nil
+	}
+	return &export_job.Response{ID: id}, nil
+}
+
+func (m *IService) Download(ctx context.Context, id string) (*export_job.Download, error) {
+	m.DownloadCalled = true
+	m.DownloadID = id
+	if m.DownloadErr != nil {
+		return nil, m.DownloadErr
+	}
+
+	res := export_job.Download{}
+	if m.DownloadRes != nil {
+		res = *m.DownloadRes
+	}
+	res.Content = io.NopCloser(strings.NewReader(m.DownloadContent))
+	return &res, nil
+}
+
+func (m *IService) Manifest(ctx context.Context, id string) (*export.Manifest, error) {
+	m.ManifestCalled = true
+	m.ManifestID = id
+	if m.ManifestErr != nil {
+		return nil, m.ManifestErr
+	}
+	if m.ManifestRes != nil {
+		return m.ManifestRes, nil
+	}
+	return &export.Manifest{}, nil
+}
@@ -0,0 +1,33 @@
+package export_job
+
+import (
+	"path"
+
+	"github.com/jmontesinos91/omnilogger/internal/repositories/export_job"
+)
+
+func ToResponse(model *export_job.Model) *Response {
+	return &Response{
+		ID:          model.ID,
+		Status:      model.Status,
+		Format:      model.Format,
+		FileName:    model.FileName,
+		Filter:      model.Filter,
+		RequestedBy: model.RequestedBy,
+		TenantIDs:   model.TenantIDs,
+		RowCount:    model.RowCount,
+		SizeBytes:   model.SizeBytes,
+		SHA256:      model.SHA256,
+		Error:       model.Error,
+		CreatedAt:   model.CreatedAt,
+		StartedAt:   model.StartedAt,
+		FinishedAt:  model.FinishedAt,
+		ExpiresAt:   model.ExpiresAt,
+		ExpiredAt:   model.ExpiredAt,
+	}
+}
+
+// objectKey key of the file of an export job, prefix/id/name
+func objectKey(prefix, id, name string) string {
+	return path.Join(prefix, id, name)
+}
@@ -0,0 +1,81 @@
+package export_job
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// defaultWorkers export jobs run at the same time when workers is not configured
+const defaultWorkers = 2
+
+// defaultQueueSize export jobs waiting for a worker when queue-size is not configured
+const defaultQueueSize = 100
+
+// defaultTTL time the export files can be downloaded when ttl-in-hours is not configured
+const defaultTTL = 24 * time.Hour
+
+// defaultInterval time between expiry runs when interval-in-minutes is not configured
+const defaultInterval = 15 * time.Minute
+
+// defaultPrefix key prefix of the export files when prefix is not configured
+const defaultPrefix = "exports"
+
+// jobTimeout longest an export job can run
+const jobTimeout = 2 * time.Hour
+
+// staleAfter jobs still running this long after they started lost their worker, the margin over jobTimeout leaves
+// the worker time to record the outcome of a job that timed out
+const staleAfter = jobTimeout + 10*time.Minute
+
+// progressRows logs written between two updates of the row count of a running job
+const progressRows = 10000
+
+// maxErrorLength length of the error column of the export jobs
+const maxErrorLength = 500
+
+// RunResult outcome of an expiry run, Failed counts the jobs that lost their worker and Expired the jobs whose
+// files were deleted
+type RunResult struct {
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Failed     int        `json:"failed"`
+	Expired    int        `json:"expired"`
+	Error      string     `json:"error,omitempty"`
+}
+
+func (r *RunResult) summary() string {
+	return fmt.Sprintf("Export jobs: %d stale jobs failed, %d jobs expired", r.Failed, r.Expired)
+}
+
+// Response Holds the progress of an export job, RowCount counts the logs written so far. Once ready its file can
+// be downloaded until ExpiresAt
+type Response struct {
+	ID          string          `json:"id"`
+	Status      string          `json:"status"`
+	Format      string          `json:"format"`
+	FileName    string          `json:"fileName"`
+	Filter      json.RawMessage `json:"filter"`
+	RequestedBy string          `json:"requestedBy"`
+	TenantIDs   []int           `json:"tenantIds"`
+	RowCount    int             `json:"rowCount"`
+	SizeBytes   int64           `json:"sizeBytes"`
+	SHA256      string          `json:"sha256,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	CreatedAt   *time.Time      `json:"createdAt,omitempty"`
+	StartedAt   *time.Time      `json:"startedAt,omitempty"`
+	FinishedAt  *time.Time      `json:"finishedAt,omitempty"`
+	ExpiresAt   time.Time       `json:"expiresAt"`
+	ExpiredAt   *time.Time      `json:"expiredAt,omitempty"`
+}
+
+// Download file of a ready export job with its encoded manifest, Content must be closed
+type Download struct {
+	Name        string
+	ContentType string
+	Size        int64
+	SHA256      string
+	Manifest    string
+	Content     io.ReadCloser
+}
@@ -0,0 +1,16 @@
+package export_job
+
+import (
+	"context"
+
+	"github.com/jmontesinos91/omnilogger/internal/services/logs"
+	"github.com/jmontesinos91/omnilogger/internal/utils/export"
+)
+
+// IService Manage export job interfaces
+type IService interface {
+	Create(ctx context.Context, filter logs.Filter, opts logs.ExportOptions) (*Response, error)
+	Get(ctx context.Context, id string) (*Response, error)
+	Download(ctx context.Context, id string) (*Download, error)
+	Manifest(ctx context.Context, id string) (*export.Manifest, error)
+}
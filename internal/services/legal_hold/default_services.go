@@ -0,0 +1,241 @@
+package legal_hold
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-playground/validator/v10"
+	"github.com/jmontesinos91/ologs/logger"
+	tracekey "github.com/jmontesinos91/ologs/logger/v2"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/legal_hold"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/jmontesinos91/terrors"
+	"github.com/samber/lo"
+	lop "github.com/samber/lo/parallel"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultService struct
+type DefaultService struct {
+	log       *logger.ContextLogger
+	validate  *validator.Validate
+	holdsRepo legal_hold.IRepository
+}
+
+// NewDefaultService creates a new instance of DefaultService legal hold
+func NewDefaultService(l *logger.ContextLogger, v *validator.Validate, r legal_hold.IRepository) *DefaultService {
+	return &DefaultService{
+		log:       l,
+		validate:  v,
+		holdsRepo: r,
+	}
+}
+
+// Create places a legal hold on the logs of one of the tenants of the user
+func (s *DefaultService) Create(ctx context.Context, payload *Payload) (*Response, error) {
+	requestID := ctx.Value(middleware.RequestIDKey).(string)
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	if err := payload.SanitizeAndValidate(s.validate); err != nil {
+		return nil, err
+	}
+
+	if !lo.Contains(claims.Tenants, payload.TenantID) {
+		return nil, terrors.Unauthorized(terrors.ErrUnauthorized, "Tenant not allowed", map[string]string{})
+	}
+
+	model := ToModel(payload, strconv.Itoa(claims.UserID))
+
+	err := s.holdsRepo.Create(ctx, model)
+	if err != nil {
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			"Create",
+			"Error while persisting legal hold: %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+				tracekey.UserID:     claims.UserID,
+			},
+			err)
+		return nil, terrors.New(terrors.ErrInternalService, "Internal error service", map[string]string{})
+	}
+
+	s.log.WithContext(
+		logrus.InfoLevel,
+		"Create",
+		"Legal hold "+model.ID+" placed on tenant "+strconv.Itoa(model.TenantID),
+		logger.Context{
+			tracekey.TrackingID: requestID,
+			tracekey.UserID:     claims.UserID,
+		},
+		nil)
+
+	return ToResponse(model), nil
+}
+
+// GetByID gets a legal hold of one of the tenants of the user
+func (s *DefaultService) GetByID(ctx context.Context, id string) (*Response, error) {
+	model, err := s.find(ctx, "GetByID", id)
+	if err != nil {
+		return nil, err
+	}
+
+	return ToResponse(model), nil
+}
+
+// Retrieve lists the legal holds of the tenants the user has access to
+func (s *DefaultService) Retrieve(ctx context.Context, filter Filter) (*PaginatedRes, error) {
+	requestID := ctx.Value(middleware.RequestIDKey).(string)
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	if len(filter.TenantID) > 0 {
+		filter.TenantID = lo.Intersect(claims.Tenants, filter.TenantID)
+	} else {
+		filter.TenantID = claims.Tenants
+	}
+
+	if len(filter.TenantID) == 0 {
+		return &PaginatedRes{Data: []Response{}, Size: filter.Size, Page: filter.Page}, nil
+	}
+
+	res, total, err := s.holdsRepo.Retrieve(ctx, ToRepoFilter(filter))
+	if err != nil {
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			"Retrieve",
+			"Error while retrieve legal holds: %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+			},
+			err)
+		return nil, terrors.New(terrors.ErrInternalService, "Internal error service", map[string]string{})
+	}
+
+	items := lop.Map(res, func(p legal_hold.Model, _ int) Response {
+		return *ToResponse(&p)
+	})
+
+	return &PaginatedRes{
+		Data:  items,
+		Size:  filter.Size,
+		Total: total,
+		Page:  filter.Page,
+	}, nil
+}
+
+// Update changes the scope or the reason of an active legal hold, released holds are immutable
+func (s *DefaultService) Update(ctx context.Context, id string, payload *Payload) (*Response, error) {
+	requestID := ctx.Value(middleware.RequestIDKey).(string)
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	if err := payload.SanitizeAndValidate(s.validate); err != nil {
+		return nil, err
+	}
+
+	model, err := s.find(ctx, "Update", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if model.ReleasedAt != nil {
+		return nil, terrors.New(terrors.ErrBadRequest, "Legal hold already released", map[string]string{})
+	}
+
+	// A hold can not be moved to a tenant the user has no access to
+	if !lo.Contains(claims.Tenants, payload.TenantID) {
+		return nil, terrors.Unauthorized(terrors.ErrUnauthorized, "Tenant not allowed", map[string]string{})
+	}
+
+	date := time.Now().UTC()
+	applyPayload(model, payload)
+	model.UpdatedAt = &date
+
+	err = s.holdsRepo.Update(ctx, model)
+	if err != nil {
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			"Update",
+			"Error while updating legal hold: %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+				tracekey.UserID:     claims.UserID,
+			},
+			err)
+		return nil, terrors.New(terrors.ErrInternalService, "Internal error service", map[string]string{})
+	}
+
+	return ToResponse(model), nil
+}
+
+// Release releases a legal hold, the logs it covered can be purged or anonymized again
+func (s *DefaultService) Release(ctx context.Context, id string) error {
+	requestID := ctx.Value(middleware.RequestIDKey).(string)
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	model, err := s.find(ctx, "Release", id)
+	if err != nil {
+		return err
+	}
+
+	if model.ReleasedAt != nil {
+		return nil
+	}
+
+	err = s.holdsRepo.Release(ctx, id, strconv.Itoa(claims.UserID), time.Now().UTC())
+	if err != nil {
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			"Release",
+			"Error while releasing legal hold: %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+				tracekey.UserID:     claims.UserID,
+			},
+			err)
+		return terrors.New(terrors.ErrInternalService, "Internal error service", map[string]string{})
+	}
+
+	s.log.WithContext(
+		logrus.InfoLevel,
+		"Release",
+		"Legal hold "+id+" released",
+		logger.Context{
+			tracekey.TrackingID: requestID,
+			tracekey.UserID:     claims.UserID,
+		},
+		nil)
+
+	return nil
+}
+
+// find finds a legal hold of one of the tenants of the user
+func (s *DefaultService) find(ctx context.Context, caller string, id string) (*legal_hold.Model, error) {
+	requestID := ctx.Value(middleware.RequestIDKey).(string)
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	if id == "" {
+		return nil, terrors.New(terrors.ErrBadRequest, "Missing id param", map[string]string{})
+	}
+
+	model, err := s.holdsRepo.FindByID(ctx, id)
+	if err != nil {
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			caller,
+			"Error while retrieve legal hold: %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+			},
+			err)
+		return nil, terrors.New(terrors.ErrNotFound, "Legal hold not found", map[string]string{})
+	}
+
+	// Holds of other tenants are reported as not found to avoid leaking their existence
+	if !lo.Contains(claims.Tenants, model.TenantID) {
+		return nil, terrors.New(terrors.ErrNotFound, "Legal hold not found", map[string]string{})
+	}
+
+	return model, nil
+}
@@ -0,0 +1,246 @@
+package legal_hold
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-playground/validator/v10"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/legal_hold"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/legal_hold/legalholdmock"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/jmontesinos91/terrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func testContext() context.Context {
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "test-request-id")
+	return context.WithValue(ctx, &sts.Claim, sts.Claims{UserID: 42, Tenants: []int{1, 2}})
+}
+
+func TestCreate(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestCreate", "debug", logger.TextFormat)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+
+	cases := []struct {
+		name     string
+		payload  *Payload
+		repoFunc func() *legalholdmock.IRepository
+		errCode  string
+	}{
+		{
+			name:    "Happy path",
+			payload: &Payload{TenantID: 1, UserID: " 7 ", Resource: "invoice", StartAt: &start, EndAt: &end, Reason: "Case 2024-17"},
+			repoFunc: func() *legalholdmock.IRepository {
+				repoMock := &legalholdmock.IRepository{}
+				repoMock.On("Create", mock.Anything, mock.Anything).Return(nil)
+				return repoMock
+			},
+		},
+		{
+			name:    "Missing reason",
+			payload: &Payload{TenantID: 1},
+			repoFunc: func() *legalholdmock.IRepository {
+				return &legalholdmock.IRepository{}
+			},
+			errCode: terrors.ErrBadRequest,
+		},
+		{
+			name:    "Inverted time range",
+			payload: &Payload{TenantID: 1, StartAt: &end, EndAt: &start, Reason: "Case 2024-17"},
+			repoFunc: func() *legalholdmock.IRepository {
+				return &legalholdmock.IRepository{}
+			},
+			errCode: terrors.ErrBadRequest,
+		},
+		{
+			name:    "Tenant not allowed",
+			payload: &Payload{TenantID: 9, Reason: "Case 2024-17"},
+			repoFunc: func() *legalholdmock.IRepository {
+				return &legalholdmock.IRepository{}
+			},
+			errCode: terrors.ErrUnauthorized,
+		},
+		{
+			name:    "Repository error",
+			payload: &Payload{TenantID: 2, Reason: "Case 2024-17"},
+			repoFunc: func() *legalholdmock.IRepository {
+				repoMock := &legalholdmock.IRepository{}
+				repoMock.On("Create", mock.Anything, mock.Anything).Return(errors.New("db down"))
+				return repoMock
+			},
+			errCode: terrors.ErrInternalService,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repoMock := tc.repoFunc()
+			svc := NewDefaultService(ctxLogger, validator.New(), repoMock)
+
+			res, err := svc.Create(testContext(), tc.payload)
+			if tc.errCode != "" {
+				assert.Nil(t, res)
+				assert.True(t, terrors.Is(err, tc.errCode), "unexpected error %v", err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.True(t, res.Active)
+			assert.Equal(t, "42", res.CreatedBy)
+			repoMock.AssertCalled(t, "Create", mock.Anything, mock.MatchedBy(func(m *legal_hold.Model) bool {
+				return m.TenantID == 1 && m.UserID == "7" && m.Resource == "INVOICE" && m.StartAt.Equal(start) && m.EndAt.Equal(end)
+			}))
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestUpdate", "debug", logger.TextFormat)
+	released := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name     string
+		payload  *Payload
+		repoFunc func() *legalholdmock.IRepository
+		errCode  string
+	}{
+		{
+			name:    "Happy path",
+			payload: &Payload{TenantID: 2, Reason: "Case 2024-17, extended"},
+			repoFunc: func() *legalholdmock.IRepository {
+				repoMock := &legalholdmock.IRepository{}
+				repoMock.On("FindByID", mock.Anything, "hold-1").Return(&legal_hold.Model{ID: "hold-1", TenantID: 1, CreatedBy: "42"}, nil)
+				repoMock.On("Update", mock.Anything, mock.Anything).Return(nil)
+				return repoMock
+			},
+		},
+		{
+			name:    "Released hold",
+			payload: &Payload{TenantID: 1, Reason: "Case 2024-17"},
+			repoFunc: func() *legalholdmock.IRepository {
+				repoMock := &legalholdmock.IRepository{}
+				repoMock.On("FindByID", mock.Anything, "hold-1").Return(&legal_hold.Model{ID: "hold-1", TenantID: 1, ReleasedAt: &released}, nil)
+				return repoMock
+			},
+			errCode: terrors.ErrBadRequest,
+		},
+		{
+			name:    "Hold of another tenant",
+			payload: &Payload{TenantID: 1, Reason: "Case 2024-17"},
+			repoFunc: func() *legalholdmock.IRepository {
+				repoMock := &legalholdmock.IRepository{}
+				repoMock.On("FindByID", mock.Anything, "hold-1").Return(&legal_hold.Model{ID: "hold-1", TenantID: 9}, nil)
+				return repoMock
+			},
+			errCode: terrors.ErrNotFound,
+		},
+		{
+			name:    "Moved to a tenant not allowed",
+			payload: &Payload{TenantID: 9, Reason: "Case 2024-17"},
+			repoFunc: func() *legalholdmock.IRepository {
+				repoMock := &legalholdmock.IRepository{}
+				repoMock.On("FindByID", mock.Anything, "hold-1").Return(&legal_hold.Model{ID: "hold-1", TenantID: 1}, nil)
+				return repoMock
+			},
+			errCode: terrors.ErrUnauthorized,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repoMock := tc.repoFunc()
+			svc := NewDefaultService(ctxLogger, validator.New(), repoMock)
+
+			res, err := svc.Update(testContext(), "hold-1", tc.payload)
+			if tc.errCode != "" {
+				assert.Nil(t, res)
+				assert.True(t, terrors.Is(err, tc.errCode), "unexpected error %v", err)
+				repoMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.payload.Reason, res.Reason)
+			assert.Equal(t, "42", res.CreatedBy)
+			assert.NotNil(t, res.UpdatedAt)
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRelease(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestRelease", "debug", logger.TextFormat)
+	released := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name          string
+		id            string
+		repoFunc      func() *legalholdmock.IRepository
+		errCode       string
+		expectRelease bool
+	}{
+		{
+			name: "Happy path",
+			id:   "hold-1",
+			repoFunc: func() *legalholdmock.IRepository {
+				repoMock := &legalholdmock.IRepository{}
+				repoMock.On("FindByID", mock.Anything, "hold-1").Return(&legal_hold.Model{ID: "hold-1", TenantID: 1}, nil)
+				repoMock.On("Release", mock.Anything, "hold-1", "42", mock.Anything).Return(nil)
+				return repoMock
+			},
+			expectRelease: true,
+		},
+		{
+			name: "Already released",
+			id:   "hold-1",
+			repoFunc: func() *legalholdmock.IRepository {
+				repoMock := &legalholdmock.IRepository{}
+				repoMock.On("FindByID", mock.Anything, "hold-1").Return(&legal_hold.Model{ID: "hold-1", TenantID: 1, ReleasedAt: &released}, nil)
+				return repoMock
+			},
+		},
+		{
+			name: "Empty id",
+			repoFunc: func() *legalholdmock.IRepository {
+				return &legalholdmock.IRepository{}
+			},
+			errCode: terrors.ErrBadRequest,
+		},
+		{
+			name: "Not found",
+			id:   "hold-1",
+			repoFunc: func() *legalholdmock.IRepository {
+				repoMock := &legalholdmock.IRepository{}
+				repoMock.On("FindByID", mock.Anything, "hold-1").Return(nil, errors.New("no rows"))
+				return repoMock
+			},
+			errCode: terrors.ErrNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repoMock := tc.repoFunc()
+			svc := NewDefaultService(ctxLogger, validator.New(), repoMock)
+
+			err := svc.Release(testContext(), tc.id)
+			if tc.errCode != "" {
+				assert.True(t, terrors.Is(err, tc.errCode), "unexpected error %v", err)
+				return
+			}
+
+			assert.NoError(t, err)
+			if tc.expectRelease {
+				repoMock.AssertExpectations(t)
+			} else {
+				repoMock.AssertNotCalled(t, "Release", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
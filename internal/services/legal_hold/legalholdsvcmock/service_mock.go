@@ -0,0 +1,74 @@
+package legalholdsvcmock
+
+import (
+	"context"
+
+	"github.com/jmontesinos91/omnilogger/internal/services/legal_hold"
+)
+
+type IService struct {
+	// Create
+	CreateErr    error
+	CreateCalled bool
+
+	// GetByID
+	GetByIDErr    error
+	GetByIDRes    *legal_hold.Response
+	GetByIDCalled bool
+
+	// Retrieve
+	RetrieveErr    error
+	RetrieveRes    *legal_hold.PaginatedRes
+	RetrieveCalled bool
+
+	// Update
+	UpdateErr    error
+	UpdateCalled bool
+
+	// Release
+	ReleaseErr    error
+	ReleaseCalled bool
+}
+
+func (m *IService) Create(ctx context.Context, payload *legal_hold.Payload) (*legal_hold.Response, error) {
+	m.CreateCalled = true
+	if m.CreateErr != nil {
+		return nil, m.CreateErr
+	}
+	return &legal_hold.Response{ID: "1", TenantID: payload.TenantID, Reason: payload.Reason, Active: true}, nil
+}
+
+func (m *IService) GetByID(ctx context.Context, id string) (*legal_hold.Response, error) {
+	m.GetByIDCalled = true
+	if m.GetByIDErr != nil {
+		return nil, m.GetByIDErr
+	}
+	if m.GetByIDRes != nil {
+		return m.GetByIDRes, nil
+	}
+	return &legal_hold.Response{ID: id, Active: true}, nil
+}
+
+func (m *IService) Retrieve(ctx context.Context, filter legal_hold.Filter) (*legal_hold.PaginatedRes, error) {
+	m.RetrieveCalled = true
+	if m.RetrieveErr != nil {
+		return nil, m.RetrieveErr
+	}
+	if m.RetrieveRes != nil {
+		return m.RetrieveRes, nil
+	}
+	return &legal_hold.PaginatedRes{}, nil
+}
+
+func (m *IService) Update(ctx context.Context, id string, payload *legal_hold.Payload) (*legal_hold.Response, error) {
+	m.UpdateCalled = true
+	if m.UpdateErr != nil {
+		return nil, m.UpdateErr
+	}
+	return &legal_hold.Response{ID: id, TenantID: payload.TenantID, Reason: payload.Reason, Active: true}, nil
+}
+
+func (m *IService) Release(ctx context.Context, id string) error {
+	m.ReleaseCalled = true
+	return m.ReleaseErr
+}
@@ -0,0 +1,120 @@
+package legal_hold
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmontesinos91/omnilogger/domains/pagination"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/legal_hold"
+)
+
+func ToModel(payload *Payload, createdBy string) *legal_hold.Model {
+	date := time.Now().UTC()
+
+	model := &legal_hold.Model{
+		ID:        uuid.NewString(),
+		CreatedBy: createdBy,
+		CreatedAt: &date,
+	}
+	applyPayload(model, payload)
+
+	return model
+}
+
+// applyPayload copies the scope and reason of the payload to the model, resources are stored uppercased like logs
+func applyPayload(model *legal_hold.Model, payload *Payload) {
+	model.TenantID = payload.TenantID
+	model.UserID = strings.TrimSpace(payload.UserID)
+	model.Resource = strings.ToUpper(strings.TrimSpace(payload.Resource))
+	model.StartAt = toUTC(payload.StartAt)
+	model.EndAt = toUTC(payload.EndAt)
+	model.Reason = strings.TrimSpace(payload.Reason)
+}
+
+func toUTC(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	utc := t.UTC()
+	return &utc
+}
+
+func ToResponse(model *legal_hold.Model) *Response {
+	return &Response{
+		ID:         model.ID,
+		TenantID:   model.TenantID,
+		UserID:     model.UserID,
+		Resource:   model.Resource,
+		StartAt:    model.StartAt,
+		EndAt:      model.EndAt,
+		Reason:     model.Reason,
+		Active:     model.ReleasedAt == nil,
+		CreatedBy:  model.CreatedBy,
+		CreatedAt:  model.CreatedAt,
+		UpdatedAt:  model.UpdatedAt,
+		ReleasedBy: model.ReleasedBy,
+		ReleasedAt: model.ReleasedAt,
+	}
+}
+
+func ToRepoFilter(filter Filter) legal_hold.Filter {
+	from := ((filter.Page * filter.Size) - filter.Size) + 1
+
+	return legal_hold.Filter{
+		TenantID:        filter.TenantID,
+		IncludeReleased: filter.IncludeReleased,
+		From:            from,
+		Size:            filter.Size,
+	}
+}
+
+func ToParseFilterRequest(r *http.Request) (Filter, error) {
+	query := r.URL.Query()
+
+	var tenantIds []int
+	for _, str := range query["tenant_id[]"] {
+		id, err := strconv.Atoi(str)
+		if err != nil {
+			return Filter{}, err
+		}
+		tenantIds = append(tenantIds, id)
+	}
+
+	page := pagination.Filter{
+		Size: pagination.DefaultSizeValue,
+		Page: 1,
+	}
+
+	if query.Get("max") != "" {
+		size, err := strconv.Atoi(query.Get("max"))
+		if err != nil {
+			return Filter{}, err
+		}
+		page.Size = size
+	}
+
+	if query.Get("page") != "" {
+		pageNumber, err := strconv.Atoi(query.Get("page"))
+		if err != nil {
+			return Filter{}, err
+		}
+		page.Page = pageNumber
+	}
+
+	if err := page.SanitizePageFilter(); err != nil {
+		return Filter{}, err
+	}
+	if page.Page < 1 {
+		page.Page = 1
+	}
+
+	return Filter{
+		TenantID:        tenantIds,
+		IncludeReleased: strings.EqualFold(query.Get("include_released"), "true"),
+		Filter:          page,
+	}, nil
+}
@@ -0,0 +1,62 @@
+package legal_hold
+
+import (
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jmontesinos91/omnilogger/domains/pagination"
+	"github.com/jmontesinos91/terrors"
+)
+
+// Payload payload to create or update a legal hold, the scope is always narrowed to a tenant and
+// optionally to a user, a resource and a time range
+type Payload struct {
+	TenantID int        `json:"tenant_id" validate:"required"`
+	UserID   string     `json:"user_id" validate:"max=255"`
+	Resource string     `json:"resource" validate:"max=50"`
+	StartAt  *time.Time `json:"start_at"`
+	EndAt    *time.Time `json:"end_at"`
+	Reason   string     `json:"reason" validate:"required,max=500"`
+}
+
+// Response Holds the information of a legal hold
+type Response struct {
+	ID         string     `json:"id"`
+	TenantID   int        `json:"tenantId"`
+	UserID     string     `json:"userId,omitempty"`
+	Resource   string     `json:"resource,omitempty"`
+	StartAt    *time.Time `json:"startAt,omitempty"`
+	EndAt      *time.Time `json:"endAt,omitempty"`
+	Reason     string     `json:"reason"`
+	Active     bool       `json:"active"`
+	CreatedBy  string     `json:"createdBy"`
+	CreatedAt  *time.Time `json:"createdAt,omitempty"`
+	UpdatedAt  *time.Time `json:"updatedAt,omitempty"`
+	ReleasedBy string     `json:"releasedBy,omitempty"`
+	ReleasedAt *time.Time `json:"releasedAt,omitempty"`
+}
+
+type Filter struct {
+	TenantID        []int
+	IncludeReleased bool
+	pagination.Filter
+}
+
+type PaginatedRes struct {
+	Data  []Response `json:"data"`
+	Size  int        `json:"max"`
+	Total int        `json:"total"`
+	Page  int        `json:"currentPage"`
+}
+
+func (r *Payload) SanitizeAndValidate(validate *validator.Validate) error {
+	if err := validate.Struct(r); err != nil {
+		return terrors.New(terrors.ErrBadRequest, err.Error(), map[string]string{})
+	}
+
+	if r.StartAt != nil && r.EndAt != nil && r.EndAt.Before(*r.StartAt) {
+		return terrors.New(terrors.ErrBadRequest, "end_at must not be before start_at", map[string]string{})
+	}
+
+	return nil
+}
@@ -0,0 +1,14 @@
+package legal_hold
+
+import (
+	"context"
+)
+
+// IService Manage legal hold interfaces
+type IService interface {
+	Create(ctx context.Context, payload *Payload) (*Response, error)
+	GetByID(ctx context.Context, id string) (*Response, error)
+	Retrieve(ctx context.Context, filter Filter) (*PaginatedRes, error)
+	Update(ctx context.Context, id string, payload *Payload) (*Response, error)
+	Release(ctx context.Context, id string) error
+}
@@ -1,15 +1,22 @@
 package logs
 
 import (
+	"compress/gzip"
 	"context"
+	"io"
+	"slices"
+	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/jmontesinos91/oevents/eventfactory"
 	"github.com/jmontesinos91/ologs/logger"
 	tracekey "github.com/jmontesinos91/ologs/logger/v2"
+	"github.com/jmontesinos91/omnilogger/config"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/legal_hold"
 	"github.com/jmontesinos91/omnilogger/internal/repositories/logs"
+	"github.com/jmontesinos91/omnilogger/internal/services/access_log"
+	"github.com/jmontesinos91/omnilogger/internal/services/enricher"
 	"github.com/jmontesinos91/omnilogger/internal/utils/export"
-	"github.com/jmontesinos91/omnilogger/internal/utils/format"
 	"github.com/jmontesinos91/osecurity/sts"
 	"github.com/jmontesinos91/terrors"
 	lop "github.com/samber/lo/parallel"
@@ -18,20 +25,50 @@ import (
 
 // DefaultService struct
 type DefaultService struct {
-	log      *logger.ContextLogger
-	logsRepo logs.IRepository
+	log                *logger.ContextLogger
+	logsRepo           logs.IRepository
+	clockSkewThreshold time.Duration
+	labelLimits        config.LabelsConfigurations
+	enricher           enricher.IEnricher
+	signer             *export.Signer
+	holdsRepo          legal_hold.IRepository
+	accessLog          access_log.IService
 }
 
-// NewDefaultService creates a new instance of DefaultService log
-func NewDefaultService(l *logger.ContextLogger, s logs.IRepository) *DefaultService {
+// NewDefaultService creates a new instance of DefaultService log, e enriches every log before it is stored
+// and can be nil to store logs as they arrive, sg signs the export manifests and can be nil to leave them unsigned,
+// h reports the legal holds covering a log and can be nil when holds are not tracked, a records every read
+// and export of logs and can be nil when reads are not audited
+func NewDefaultService(l *logger.ContextLogger, s logs.IRepository, c config.LogsConfigurations, e enricher.IEnricher, sg *export.Signer, h legal_hold.IRepository, a access_log.IService) *DefaultService {
 	return &DefaultService{
-		log:      l,
-		logsRepo: s,
+		log:                l,
+		logsRepo:           s,
+		enricher:           e,
+		signer:             sg,
+		holdsRepo:          h,
+		accessLog:          a,
+		clockSkewThreshold: time.Duration(c.ClockSkewThresholdInSeconds) * time.Second,
+		labelLimits:        c.Labels,
 	}
 }
 
-// GetByID gets a records by ID
+// GetByID gets a records by ID, the read is recorded on the access logs
 func (s *DefaultService) GetByID(ctx context.Context, ID *string, filter Filter) (*Response, error) {
+	res, err := s.getByID(ctx, ID, filter)
+
+	entry := access_log.Entry{Action: access_log.ActionGet, Tenants: filter.TenantID, Filter: filter, Err: err}
+	if ID != nil {
+		entry.LogID = *ID
+	}
+	if res != nil {
+		entry.RowCount = 1
+	}
+	s.recordAccess(ctx, entry)
+
+	return res, err
+}
+
+func (s *DefaultService) getByID(ctx context.Context, ID *string, filter Filter) (*Response, error) {
 	requestID := ctx.Value(middleware.RequestIDKey).(string)
 
 	// Create logic of the controller
@@ -54,13 +91,43 @@ func (s *DefaultService) GetByID(ctx context.Context, ID *string, filter Filter)
 		return nil, terrors.New(terrors.ErrNotFound, "Log not found", map[string]string{})
 	}
 
-	return ToResponse(model, filter.Lang), nil
+	res := ToResponse(model, filter.Lang)
+
+	if s.holdsRepo != nil {
+		holds, err := s.holdsRepo.FindByLog(ctx, model.ID)
+		if err != nil {
+			s.log.WithContext(
+				logrus.ErrorLevel,
+				"GetByID",
+				"Error while retrieve log legal holds: %v",
+				logger.Context{
+					tracekey.TrackingID: requestID,
+				},
+				err)
+			return nil, terrors.New(terrors.ErrInternalService, "Internal error service", map[string]string{})
+		}
+		res.LegalHold = ToLegalHoldStatus(holds)
+	}
+
+	return res, nil
 }
 
 // Create model
 func (s *DefaultService) Create(ctx context.Context, payload *Payload) (*Response, error) {
 	requestID := ctx.Value(middleware.RequestIDKey).(string)
 
+	if err := ValidateLabels(payload.Labels, s.labelLimits); err != nil {
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			"Create",
+			"Invalid log labels: %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+			},
+			err)
+		return nil, err
+	}
+
 	// Create model for repository
 	model, err := ToModel(payload)
 	if err != nil {
@@ -73,6 +140,11 @@ func (s *DefaultService) Create(ctx context.Context, payload *Payload) (*Respons
 		return nil, terrors.InternalService("metadata_error", "Failed to map payload data to model", nil)
 	}
 
+	if err := s.enrich(ctx, model); err != nil {
+		return nil, err
+	}
+	model.ClockSkew = IsClockSkewed(model, s.clockSkewThreshold)
+
 	// Store in DB
 	err = s.logsRepo.Create(ctx, model)
 	if err != nil {
@@ -90,8 +162,20 @@ func (s *DefaultService) Create(ctx context.Context, payload *Payload) (*Respons
 	return ToResponse(model, payload.Lang), nil
 }
 
-// Retrieve logs with filter
+// Retrieve logs with filter, the read is recorded on the access logs
 func (s *DefaultService) Retrieve(ctx context.Context, filter Filter) (*PaginatedRes, error) {
+	res, err := s.retrieve(ctx, filter)
+
+	entry := access_log.Entry{Action: access_log.ActionRetrieve, Tenants: filter.TenantID, Filter: filter, Err: err}
+	if res != nil {
+		entry.RowCount = len(res.Data)
+	}
+	s.recordAccess(ctx, entry)
+
+	return res, err
+}
+
+func (s *DefaultService) retrieve(ctx context.Context, filter Filter) (*PaginatedRes, error) {
 	requestID := ctx.Value(middleware.RequestIDKey).(string)
 
 	repoFilter := ToRepoFilter(filter)
@@ -110,7 +194,9 @@ func (s *DefaultService) Retrieve(ctx context.Context, filter Filter) (*Paginate
 	}
 
 	items := lop.Map(res, func(p logs.Model, _ int) Response {
-		return *ToResponse(&p, filter.Lang)
+		item := ToResponse(&p, filter.Lang)
+		item.Archived = filter.Archived
+		return *item
 	})
 
 	currentPage := filter.Page
@@ -126,8 +212,235 @@ func (s *DefaultService) Retrieve(ctx context.Context, filter Filter) (*Paginate
 	}, nil
 }
 
-// CreateLogFromKafka creates a new log from kafka
-func (s *DefaultService) CreateLogFromKafka(ctx context.Context, payload *eventfactory.LogCreatedPayload) error {
+// LabelFacets counts the logs matching the filter per label value, filter.Size is the number of values per label key
+func (s *DefaultService) LabelFacets(ctx context.Context, filter Filter, keys []string) ([]LabelFacet, error) {
+	requestID := ctx.Value(middleware.RequestIDKey).(string)
+
+	repoFilter := ToRepoFilter(filter)
+
+	res, err := s.logsRepo.LabelFacets(ctx, repoFilter, keys, filter.Size)
+	if err != nil {
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			"LabelFacets",
+			"Error while retrieve label facets: %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+			},
+			err)
+		return nil, terrors.New(terrors.ErrInternalService, "Internal error service", map[string]string{})
+	}
+
+	return ToLabelFacets(res), nil
+}
+
+// Stats counts the logs per bucket and group value. The whole buckets already rolled up are read from the rollups
+// when the filter only narrows on their dimensions, the rest of the range is counted on the logs
+func (s *DefaultService) Stats(ctx context.Context, filter StatsFilter) (*StatsResponse, error) {
+	if err := ValidateStats(filter); err != nil {
+		return nil, err
+	}
+
+	points, source, err := s.stats(ctx, "Stats", filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StatsResponse{
+		Granularity: filter.Granularity,
+		GroupBy:     filter.GroupBy,
+		StartAt:     filter.StartAt,
+		EndAt:       filter.EndAt,
+		Source:      source,
+		Points:      points,
+	}, nil
+}
+
+// Dashboard summarizes the logs of the range, the timeline of the granularity and the top most frequent levels,
+// providers, actions, resources and messages
+func (s *DefaultService) Dashboard(ctx context.Context, filter StatsFilter, top int) (*Dashboard, error) {
+	filter.GroupBy = ""
+	if err := ValidateStats(filter); err != nil {
+		return nil, err
+	}
+
+	timeline, source, err := s.stats(ctx, "Dashboard", filter)
+	if err != nil {
+		return nil, err
+	}
+
+	dashboard := &Dashboard{
+		StartAt:     filter.StartAt,
+		EndAt:       filter.EndAt,
+		Granularity: filter.Granularity,
+		Source:      source,
+		Timeline:    timeline,
+	}
+	for _, point := range timeline {
+		dashboard.Total += point.Count
+	}
+
+	groups := []struct {
+		groupBy string
+		counts  *[]DashboardCount
+	}{
+		{groupBy: StatsGroupLevel, counts: &dashboard.Levels},
+		{groupBy: StatsGroupProvider, counts: &dashboard.Providers},
+		{groupBy: StatsGroupAction, counts: &dashboard.Actions},
+		{groupBy: StatsGroupResource, counts: &dashboard.Resources},
+		{groupBy: StatsGroupMessage, counts: &dashboard.Messages},
+	}
+	for _, group := range groups {
+		filter.GroupBy = group.groupBy
+		points, _, err := s.stats(ctx, "Dashboard", filter)
+		if err != nil {
+			return nil, err
+		}
+		*group.counts = topCounts(points, top)
+	}
+
+	return dashboard, nil
+}
+
+// stats counts the logs of every segment of the range and merges the points, it returns where they were read from
+func (s *DefaultService) stats(ctx context.Context, caller string, filter StatsFilter) ([]StatsPoint, string, error) {
+	requestID := ctx.Value(middleware.RequestIDKey).(string)
+
+	logError := func(err error) error {
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			caller,
+			"Error while counting logs: %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+			},
+			err)
+		return terrors.New(terrors.ErrInternalService, "Internal error service", map[string]string{})
+	}
+
+	var rolledUntil time.Time
+	rollup := rollupCompatible(filter)
+	if rollup {
+		watermarks, err := s.logsRepo.RollupWatermarks(ctx)
+		if err != nil {
+			return nil, "", logError(err)
+		}
+		rolledUntil = watermarks[filter.Granularity]
+	}
+
+	segments := statsSegments(filter.StartAt, filter.EndAt, statsSteps[filter.Granularity], rolledUntil, rollup)
+
+	var points []logs.StatsPoint
+	for _, segment := range segments {
+		res, err := s.logsRepo.Stats(ctx, ToRepoStatsFilter(filter, segment))
+		if err != nil {
+			return nil, "", logError(err)
+		}
+		points = append(points, res...)
+	}
+
+	return mergeStatsPoints(points), statsSource(segments), nil
+}
+
+// Verify walks the hash chain of a tenant and reports the first broken link, the predecessor of the first
+// entry in range, or the purged range it belongs to, is used to verify its link when it still exists
+func (s *DefaultService) Verify(ctx context.Context, filter VerifyFilter) (*VerifyResult, error) {
+	requestID := ctx.Value(middleware.RequestIDKey).(string)
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	if !slices.Contains(claims.Tenants, filter.TenantID) {
+		return nil, terrors.Unauthorized(terrors.ErrUnauthorized, "Tenant not allowed", map[string]string{})
+	}
+
+	logError := func(err error) error {
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			"Verify",
+			"Error while retrieve chain entries: %v",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+			},
+			err)
+		return terrors.New(terrors.ErrInternalService, "Internal error service", map[string]string{})
+	}
+
+	result := &VerifyResult{TenantID: filter.TenantID, Valid: true}
+	if !filter.From.IsZero() {
+		result.From = &filter.From
+	}
+	if !filter.To.IsZero() {
+		result.To = &filter.To
+	}
+
+	first, last, err := s.logsRepo.ChainBounds(ctx, filter.TenantID, filter.From, filter.To)
+	if err != nil {
+		return nil, logError(err)
+	}
+	if last == 0 {
+		return result, nil
+	}
+	result.FirstSeq = first
+	result.LastSeq = last
+
+	var previous *logs.Model
+	if first > 1 {
+		predecessor, err := s.logsRepo.ChainEntries(ctx, filter.TenantID, first-2, first-1, 1)
+		if err != nil {
+			return nil, logError(err)
+		}
+		if len(predecessor) > 0 {
+			previous = &predecessor[0]
+		} else {
+			purges, err := s.logsRepo.ChainPurges(ctx, filter.TenantID, first-1, first-1)
+			if err != nil {
+				return nil, logError(err)
+			}
+			previous, _ = BridgePurges(nil, purges)
+		}
+	}
+
+	for after := first - 1; after < last; {
+		entries, err := s.logsRepo.ChainEntries(ctx, filter.TenantID, after, last, verifyBatchSize)
+		if err != nil {
+			return nil, logError(err)
+		}
+		if len(entries) == 0 {
+			break
+		}
+
+		for i := range entries {
+			entry := &entries[i]
+
+			// Entries deleted by retention are bridged by their recorded ranges, any other gap is a missing entry
+			if previous != nil && entry.ChainSeq > previous.ChainSeq+1 {
+				purges, err := s.logsRepo.ChainPurges(ctx, filter.TenantID, previous.ChainSeq+1, entry.ChainSeq-1)
+				if err != nil {
+					return nil, logError(err)
+				}
+
+				var purged int64
+				previous, purged = BridgePurges(previous, purges)
+				result.Purged += purged
+			}
+
+			if broken := VerifyLink(previous, entry); broken != nil {
+				result.Valid = false
+				result.BrokenLink = broken
+				return result, nil
+			}
+
+			result.Checked++
+			previous = entry
+		}
+
+		after = entries[len(entries)-1].ChainSeq
+	}
+
+	return result, nil
+}
+
+// CreateLogFromKafka creates a new log from kafka, occurredAt is the producer time of the event
+func (s *DefaultService) CreateLogFromKafka(ctx context.Context, payload *eventfactory.LogCreatedPayload, occurredAt *time.Time) error {
 
 	tenantCatJSON, errBind := eventfactory.ToTenantCatJson(payload.TenantCat)
 	if errBind != nil {
@@ -153,6 +466,7 @@ func (s *DefaultService) CreateLogFromKafka(ctx context.Context, payload *eventf
 		OldData:     payload.OldData,
 		UserID:      payload.UserID,
 		TenantCat:   tenantCatJSON,
+		OccurredAt:  occurredAt,
 	}
 
 	// Create model for repository
@@ -167,6 +481,11 @@ func (s *DefaultService) CreateLogFromKafka(ctx context.Context, payload *eventf
 		return terrors.InternalService("metadata_error", "Failed to map payload data to model", nil)
 	}
 
+	if err := s.enrich(ctx, data); err != nil {
+		return err
+	}
+	data.ClockSkew = IsClockSkewed(data, s.clockSkewThreshold)
+
 	err = s.logsRepo.Create(ctx, data)
 	if err != nil {
 		s.log.Error(
@@ -181,65 +500,155 @@ func (s *DefaultService) CreateLogFromKafka(ctx context.Context, payload *eventf
 	return nil
 }
 
-func (s *DefaultService) Export(ctx context.Context, filter Filter) ([]byte, error) {
+// Export streams the file of the logs matching the filter to w and returns it with its signed manifest, logs are
+// written as they are read so the memory used does not depend on the number of logs exported. Nothing is written
+// to w before the search succeeded, so an error returned before the first write can still be sent to the client.
+// The export is recorded on the access logs
+func (s *DefaultService) Export(ctx context.Context, filter Filter, opts ExportOptions, w io.Writer) (*ExportFile, error) {
+	file, err := s.streamExport(ctx, filter, opts, w)
+
+	entry := access_log.Entry{Action: access_log.ActionExport, Tenants: filter.TenantID, Filter: filter, Err: err}
+	if file != nil && file.Manifest != nil {
+		entry.RowCount = file.Manifest.RowCount
+	}
+	s.recordAccess(ctx, entry)
+
+	return file, err
+}
+
+func (s *DefaultService) streamExport(ctx context.Context, filter Filter, opts ExportOptions, w io.Writer) (*ExportFile, error) {
 	requestID := ctx.Value(middleware.RequestIDKey).(string)
 	claims := ctx.Value(&sts.Claim).(sts.Claims)
 
 	repoFilter := ToRepoFilter(filter)
+	file := ToExportFile(opts)
+
+	// The columns of the xlsx and csv files are known before the first log is written, the label keys are only
+	// needed for the default columns and the data keys for the expanded exports
+	var labelKeys, dataKeys []string
+	if (file.Format == ExportFormatXLSX || file.Format == ExportFormatCSV) && len(opts.Columns) == 0 {
+		var err error
+		labelKeys, err = s.logsRepo.ExportLabelKeys(ctx, repoFilter)
+		if err != nil {
+			s.log.WithContext(
+				logrus.ErrorLevel,
+				"Export",
+				"Error while reading the label keys: %v",
+				logger.Context{
+					tracekey.TrackingID: requestID,
+				},
+				err)
+			return nil, terrors.New(terrors.ErrInternalService, "Internal error service", map[string]string{})
+		}
+	}
+	if (file.Format == ExportFormatXLSX || file.Format == ExportFormatCSV) && opts.ExpandData {
+		var err error
+		dataKeys, err = s.logsRepo.ExportDataKeys(ctx, repoFilter)
+		if err != nil {
+			s.log.WithContext(
+				logrus.ErrorLevel,
+				"Export",
+				"Error while reading the data keys: %v",
+				logger.Context{
+					tracekey.TrackingID: requestID,
+				},
+				err)
+			return nil, terrors.New(terrors.ErrInternalService, "Internal error service", map[string]string{})
+		}
+	}
 
-	res, err := s.logsRepo.Export(ctx, repoFilter)
+	// The digest covers the bytes of the file, after its compression
+	digest := export.NewDigestWriter(w)
+	var out io.Writer = digest
+	var gz *gzip.Writer
+	if opts.Compress {
+		gz = gzip.NewWriter(digest)
+		out = gz
+	}
+
+	var writer *exportWriter
+	rowCount := 0
+	err := s.logsRepo.EachExportLog(ctx, repoFilter, func(model logs.Model) error {
+		if writer == nil {
+			var err error
+			if writer, err = newExportWriter(out, file.Format, opts, labelKeys, dataKeys, filter.Lang); err != nil {
+				return err
+			}
+		}
+
+		rowCount++
+		if err := writer.write(*ToResponse(&model, filter.Lang)); err != nil {
+			return err
+		}
+		if opts.Progress != nil {
+			opts.Progress(rowCount)
+		}
+		return nil
+	})
+	if err == nil && writer == nil {
+		writer, err = newExportWriter(out, file.Format, opts, labelKeys, dataKeys, filter.Lang)
+	}
+	if err == nil {
+		err = writer.close()
+	} else if writer != nil {
+		writer.abort()
+	}
+	if err == nil && gz != nil {
+		err = gz.Close()
+	}
 	if err != nil {
-		s.log.WithContext(
-			logrus.ErrorLevel,
-			"Retrieve",
-			"Error while retrieve logs: %v",
+		s.log.WithContext(logrus.ErrorLevel,
+			"Export",
+			"Failed to export the logs to a "+file.Format+" file",
 			logger.Context{
 				tracekey.TrackingID: requestID,
+				tracekey.UserID:     claims.UserID,
+				tracekey.Role:       claims.Role,
 			},
 			err)
 		return nil, terrors.New(terrors.ErrInternalService, "Internal error service", map[string]string{})
 	}
 
-	items := lop.Map(res, func(p logs.Model, _ int) Response {
-		return *ToResponse(&p, filter.Lang)
-	})
-
-	genericMapper := func(item Response) format.ExcelRow {
-		return format.ExcelRow{
-			Cells: []interface{}{
-				item.ID,
-				item.IpAddress,
-				item.ClientHost,
-				item.Provider,
-				item.Level,
-				item.Message,
-				item.LogMessage,
-				item.Description,
-				item.Path,
-				item.Resource,
-				item.Action,
-				item.Data,
-				item.OldData,
-				item.TenantCat,
-				item.UserID,
-				item.CreatedAt,
-			},
-		}
+	manifest, err := export.NewDigestManifest(file.Name, file.Format, filter, rowCount, digest.Digest())
+	if err == nil {
+		manifest.RequestedBy = claims.User
+		err = s.signer.Sign(manifest)
 	}
-
-	excelBytes, err := export.DataToExcel("logs", items, genericMapper)
 	if err != nil {
 		s.log.WithContext(logrus.ErrorLevel,
-			"HandleExport",
-			"Failed to generate excel bytes from data",
+			"Export",
+			"Failed to sign export manifest",
 			logger.Context{
 				tracekey.TrackingID: requestID,
 				tracekey.UserID:     claims.UserID,
 				tracekey.Role:       claims.Role,
 			},
 			err)
-		return []byte{}, err
+		return nil, terrors.New(terrors.ErrInternalService, "Internal error service", map[string]string{})
 	}
 
-	return excelBytes, nil
+	file.Manifest = manifest
+
+	return file, nil
 }
+
+// recordAccess records a read or export of logs on the access logs
+func (s *DefaultService) recordAccess(ctx context.Context, entry access_log.Entry) {
+	if s.accessLog == nil {
+		return
+	}
+
+	s.accessLog.Record(ctx, entry)
+}
+
+// enrich runs the enrichment chain on the model, a fail closed stage error rejects the log
+func (s *DefaultService) enrich(ctx context.Context, model *logs.Model) error {
+	if s.enricher == nil {
+		return nil
+	}
+
+	return s.enricher.Enrich(ctx, model)
+}
+
+// verifyBatchSize chain entries read per query while verifying a chain
+const verifyBatchSize = 1000
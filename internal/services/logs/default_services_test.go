@@ -1,22 +1,37 @@
 package logs
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/json"
 	"errors"
 	"github.com/jmontesinos91/oevents/eventfactory"
+	"github.com/jmontesinos91/omnilogger/config"
 	"github.com/jmontesinos91/omnilogger/domains/pagination"
 	"github.com/jmontesinos91/omnilogger/internal/repositories/log_message"
 	"github.com/jmontesinos91/omnilogger/internal/repositories/logs"
 	"github.com/jmontesinos91/osecurity/sts"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/legal_hold"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/legal_hold/legalholdmock"
 	"github.com/jmontesinos91/omnilogger/internal/repositories/logs/logsmock"
+	"github.com/jmontesinos91/omnilogger/internal/services/access_log"
+	"github.com/jmontesinos91/omnilogger/internal/services/access_log/accesslogsvcmock"
+	"github.com/jmontesinos91/omnilogger/internal/services/enricher/enrichermock"
+	"github.com/jmontesinos91/omnilogger/internal/utils/export"
 	"github.com/jmontesinos91/terrors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/xuri/excelize/v2"
 )
 
 func TestCreate(t *testing.T) {
@@ -122,7 +137,7 @@ func TestCreate(t *testing.T) {
 				tc.repositoryOpts.logsRepo = tc.repositoryOpts.logsRepoFunc()
 			}
 
-			service := NewDefaultService(ctxLogger, tc.repositoryOpts.logsRepo)
+			service := NewDefaultService(ctxLogger, tc.repositoryOpts.logsRepo, config.LogsConfigurations{}, nil, nil, nil, nil)
 			result, err := service.Create(tc.args.ctx, tc.args.payload)
 
 			assertsParams := assertsParams{
@@ -375,7 +390,7 @@ func TestGetByID(t *testing.T) {
 				tc.repositoryOpts.logsRepo = tc.repositoryOpts.logsRepoFunc()
 			}
 
-			service := NewDefaultService(ctxLogger, tc.repositoryOpts.logsRepo)
+			service := NewDefaultService(ctxLogger, tc.repositoryOpts.logsRepo, config.LogsConfigurations{}, nil, nil, nil, nil)
 			result, err := service.GetByID(tc.args.ctx, tc.args.ID, tc.args.filter)
 
 			assertsParams := assertsParams{
@@ -540,7 +555,7 @@ func TestRetrieve(t *testing.T) {
 				tc.repositoryOpts.logsRepo = tc.repositoryOpts.logsRepoFunc()
 			}
 
-			service := NewDefaultService(ctxLogger, tc.repositoryOpts.logsRepo)
+			service := NewDefaultService(ctxLogger, tc.repositoryOpts.logsRepo, config.LogsConfigurations{}, nil, nil, nil, nil)
 			result, err := service.Retrieve(tc.args.ctx, tc.args.filter)
 
 			assertsParams := assertsParams{
@@ -667,8 +682,8 @@ func TestCreateLogFromKafka(t *testing.T) {
 				tc.repositoryOpts.logsRepo = tc.repositoryOpts.logsRepoFunc()
 			}
 
-			service := NewDefaultService(ctxLogger, tc.repositoryOpts.logsRepo)
-			err := service.CreateLogFromKafka(tc.args.ctx, tc.args.payload)
+			service := NewDefaultService(ctxLogger, tc.repositoryOpts.logsRepo, config.LogsConfigurations{}, nil, nil, nil, nil)
+			err := service.CreateLogFromKafka(tc.args.ctx, tc.args.payload, nil)
 
 			assertsParams := assertsParams{
 				repositoryOpts: tc.repositoryOpts,
@@ -705,7 +720,7 @@ func TestExport(t *testing.T) {
 	type assertsParams struct {
 		args
 		repositoryOpts
-		result []byte
+		result *ExportFile
 	}
 
 	cases := []struct {
@@ -720,8 +735,9 @@ func TestExport(t *testing.T) {
 			repositoryOpts: repositoryOpts{
 				logsRepoFunc: func() *logsmock.IRepository {
 					repositoryMock := &logsmock.IRepository{}
-					repositoryMock.On("Export", mock.Anything, mock.Anything).
-						Return([]logs.Model{
+					repositoryMock.On("ExportLabelKeys", mock.Anything, mock.Anything).Return([]string{}, nil)
+					repositoryMock.On("EachExportLog", mock.Anything, mock.Anything, mock.Anything).
+						Run(eachExportLog([]logs.Model{
 							{
 								ID:          "12345",
 								IpAddress:   "192.168.1.1",
@@ -734,7 +750,8 @@ func TestExport(t *testing.T) {
 								Resource:    "resource-path",
 								Action:      "RETRIEVE",
 							},
-						}, nil)
+						})).
+						Return(nil)
 					return repositoryMock
 				},
 			},
@@ -765,7 +782,7 @@ func TestExport(t *testing.T) {
 				return assert.NoError(t, err) &&
 					assert.NotNil(t, ap.result) &&
 					assert.NotEmpty(t, ap.result) &&
-					ap.logsRepo.AssertCalled(t, "Export", mock.Anything, mock.Anything)
+					ap.logsRepo.AssertCalled(t, "EachExportLog", mock.Anything, mock.Anything, mock.Anything)
 			},
 		},
 		{
@@ -773,8 +790,10 @@ func TestExport(t *testing.T) {
 			repositoryOpts: repositoryOpts{
 				logsRepoFunc: func() *logsmock.IRepository {
 					repositoryMock := &logsmock.IRepository{}
-					repositoryMock.On("Export", mock.Anything, mock.Anything).
-						Return([]logs.Model{}, nil)
+					repositoryMock.On("ExportLabelKeys", mock.Anything, mock.Anything).Return([]string{}, nil)
+					repositoryMock.On("EachExportLog", mock.Anything, mock.Anything, mock.Anything).
+						Run(eachExportLog([]logs.Model{})).
+						Return(nil)
 					return repositoryMock
 				},
 			},
@@ -786,7 +805,7 @@ func TestExport(t *testing.T) {
 				return assert.NoError(t, err) &&
 					assert.NotNil(t, ap.result) &&
 					assert.NotEmpty(t, ap.result) &&
-					ap.logsRepo.AssertCalled(t, "Export", mock.Anything, mock.Anything)
+					ap.logsRepo.AssertCalled(t, "EachExportLog", mock.Anything, mock.Anything, mock.Anything)
 			},
 		},
 		{
@@ -794,8 +813,9 @@ func TestExport(t *testing.T) {
 			repositoryOpts: repositoryOpts{
 				logsRepoFunc: func() *logsmock.IRepository {
 					repositoryMock := &logsmock.IRepository{}
-					repositoryMock.On("Export", mock.Anything, mock.Anything).
-						Return(nil, errors.New("internal_service: Internal error service"))
+					repositoryMock.On("ExportLabelKeys", mock.Anything, mock.Anything).Return([]string{}, nil)
+					repositoryMock.On("EachExportLog", mock.Anything, mock.Anything, mock.Anything).
+						Return(errors.New("internal_service: Internal error service"))
 					return repositoryMock
 				},
 			},
@@ -825,7 +845,7 @@ func TestExport(t *testing.T) {
 			asserts: func(t *testing.T, err error, ap assertsParams) bool {
 				return assert.Error(t, err) &&
 					assert.Contains(t, err.Error(), "internal_service: Internal error service") &&
-					ap.logsRepo.AssertCalled(t, "Export", mock.Anything, mock.Anything)
+					ap.logsRepo.AssertCalled(t, "EachExportLog", mock.Anything, mock.Anything, mock.Anything)
 			},
 		},
 	}
@@ -840,8 +860,8 @@ func TestExport(t *testing.T) {
 				tc.repositoryOpts.logsRepo = tc.repositoryOpts.logsRepoFunc()
 			}
 
-			trafficSvc := NewDefaultService(log, tc.repositoryOpts.logsRepo)
-			result, err := trafficSvc.Export(tc.args.ctx, tc.args.filter)
+			trafficSvc := NewDefaultService(log, tc.repositoryOpts.logsRepo, config.LogsConfigurations{}, nil, nil, nil, nil)
+			result, err := trafficSvc.Export(tc.args.ctx, tc.args.filter, ExportOptions{}, &bytes.Buffer{})
 			if (err != nil) != tc.err {
 				t.Errorf("DefaultService.HandleExport() error = %v, wantErr %v", err, tc.err)
 			}
@@ -862,3 +882,1026 @@ func TestExport(t *testing.T) {
 func stringPtr(s string) *string {
 	return &s
 }
+
+func TestCreate_ClockSkew(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestCreate_ClockSkew", "debug", logger.TextFormat)
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "test-request-id")
+
+	recent := time.Now().Add(-time.Minute)
+	old := time.Now().Add(-2 * time.Hour)
+
+	cases := []struct {
+		name       string
+		occurredAt *time.Time
+		skewed     bool
+	}{
+		{name: "Ingestion time", occurredAt: nil, skewed: false},
+		{name: "Within threshold", occurredAt: &recent, skewed: false},
+		{name: "Past threshold", occurredAt: &old, skewed: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repoMock := &logsmock.IRepository{}
+			repoMock.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+			service := NewDefaultService(ctxLogger, repoMock, config.LogsConfigurations{ClockSkewThresholdInSeconds: 300}, nil, nil, nil, nil)
+			res, err := service.Create(ctx, &Payload{Message: 1, OccurredAt: tc.occurredAt})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.skewed, res.ClockSkew)
+			assert.NotNil(t, res.OccurredAt)
+			repoMock.AssertCalled(t, "Create", mock.Anything, mock.MatchedBy(func(m *logs.Model) bool {
+				return m.ClockSkew == tc.skewed
+			}))
+		})
+	}
+}
+
+func TestCreate_Labels(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestCreate_Labels", "debug", logger.TextFormat)
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "test-request-id")
+
+	t.Run("Labels stored", func(t *testing.T) {
+		repoMock := &logsmock.IRepository{}
+		repoMock.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+		service := NewDefaultService(ctxLogger, repoMock, config.LogsConfigurations{}, nil, nil, nil, nil)
+		res, err := service.Create(ctx, &Payload{Message: 1, Labels: map[string]string{"env": "prod"}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "prod", res.Labels["env"])
+		repoMock.AssertCalled(t, "Create", mock.Anything, mock.MatchedBy(func(m *logs.Model) bool {
+			return m.Labels["env"] == "prod"
+		}))
+	})
+
+	t.Run("Labels over the limits", func(t *testing.T) {
+		repoMock := &logsmock.IRepository{}
+
+		service := NewDefaultService(ctxLogger, repoMock, config.LogsConfigurations{Labels: config.LabelsConfigurations{MaxCount: 1}}, nil, nil, nil, nil)
+		res, err := service.Create(ctx, &Payload{Message: 1, Labels: map[string]string{"env": "prod", "team": "ops"}})
+
+		assert.Nil(t, res)
+		assert.True(t, terrors.Is(err, terrors.ErrBadRequest))
+		repoMock.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}
+
+func TestLabelFacets(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestLabelFacets", "debug", logger.TextFormat)
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "test-request-id")
+
+	t.Run("Happy path", func(t *testing.T) {
+		repoMock := &logsmock.IRepository{}
+		repoMock.On("LabelFacets", mock.Anything, mock.MatchedBy(func(f logs.Filter) bool {
+			return f.Labels["env"][0] == "prod"
+		}), []string{"team"}, 5).
+			Return([]logs.LabelFacet{{Key: "team", Value: "ops", Count: 4}}, nil)
+
+		service := NewDefaultService(ctxLogger, repoMock, config.LogsConfigurations{}, nil, nil, nil, nil)
+		res, err := service.LabelFacets(ctx, Filter{
+			Labels: map[string][]string{"env": {"prod"}},
+			Filter: pagination.Filter{Page: 1, Size: 5},
+		}, []string{"team"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []LabelFacet{{Key: "team", Values: []FacetValue{{Value: "ops", Count: 4}}}}, res)
+	})
+
+	t.Run("Repository error", func(t *testing.T) {
+		repoMock := &logsmock.IRepository{}
+		repoMock.On("LabelFacets", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(nil, errors.New("db error"))
+
+		service := NewDefaultService(ctxLogger, repoMock, config.LogsConfigurations{}, nil, nil, nil, nil)
+		res, err := service.LabelFacets(ctx, Filter{}, nil)
+
+		assert.Nil(t, res)
+		assert.True(t, terrors.Is(err, terrors.ErrInternalService))
+	})
+}
+
+func TestExport_LabelColumns(t *testing.T) {
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "test-request-id")
+	ctx = context.WithValue(ctx, &sts.Claim, sts.Claims{UserID: 1})
+	ctxLogger := logger.NewContextLogger("TestExport_LabelColumns", "debug", logger.TextFormat)
+
+	repoMock := &logsmock.IRepository{}
+	repoMock.On("ExportLabelKeys", mock.Anything, mock.Anything).Return([]string{"env", "team"}, nil)
+	repoMock.On("EachExportLog", mock.Anything, mock.Anything, mock.Anything).
+		Run(eachExportLog([]logs.Model{
+			{ID: "1", Labels: map[string]string{"env": "prod"}},
+			{ID: "2", Labels: map[string]string{"team": "ops"}},
+		})).
+		Return(nil)
+
+	service := NewDefaultService(ctxLogger, repoMock, config.LogsConfigurations{}, nil, nil, nil, nil)
+	var content bytes.Buffer
+	_, err := service.Export(ctx, Filter{}, ExportOptions{}, &content)
+	assert.NoError(t, err)
+
+	f, err := excelize.OpenReader(&content)
+	assert.NoError(t, err)
+	rows, err := f.GetRows("logs")
+	assert.NoError(t, err)
+
+	assert.Len(t, rows, 3)
+	header := rows[0]
+	assert.Equal(t, []string{"label.env", "label.team"}, header[len(header)-2:])
+
+	cells := map[string][]string{}
+	for _, row := range rows[1:] {
+		cells[row[0]] = row
+	}
+	assert.Equal(t, "prod", cells["1"][len(header)-2])
+	assert.Equal(t, "ops", cells["2"][len(header)-1])
+}
+
+func TestExport_CSV(t *testing.T) {
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "test-request-id")
+	ctx = context.WithValue(ctx, &sts.Claim, sts.Claims{UserID: 1})
+	ctxLogger := logger.NewContextLogger("TestExport_CSV", "debug", logger.TextFormat)
+	createdAt := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	repoMock := &logsmock.IRepository{}
+	repoMock.On("ExportLabelKeys", mock.Anything, mock.Anything).Return([]string{"env"}, nil)
+	repoMock.On("EachExportLog", mock.Anything, mock.Anything, mock.Anything).
+		Run(eachExportLog([]logs.Model{
+			{
+				ID:         "1",
+				Message:    2,
+				Data:       `{"name":"a;b"}`,
+				CreatedAt:  &createdAt,
+				Labels:     map[string]string{"env": "prod"},
+				LogMessage: []*log_message.Model{{ID: 2, Message: "Usuario creado", Lang: "es"}},
+			},
+		})).
+		Return(nil)
+
+	service := NewDefaultService(ctxLogger, repoMock, config.LogsConfigurations{}, nil, nil, nil, nil)
+	var buffer bytes.Buffer
+	res, err := service.Export(ctx, Filter{Lang: "es"}, ExportOptions{Format: ExportFormatCSV, Delimiter: ';', BOM: true}, &buffer)
+	assert.NoError(t, err)
+	assert.Equal(t, ExportFileCSV, res.Name)
+	assert.Equal(t, ContentTypeCSV, res.ContentType)
+	assert.Equal(t, ExportFormatCSV, res.Manifest.Format)
+	assert.Equal(t, 1, res.Manifest.RowCount)
+
+	content := buffer.String()
+	assert.True(t, strings.HasPrefix(content, "\uFEFFID;Dirección IP;"))
+
+	lines := strings.Split(strings.TrimSuffix(content, "\r\n"), "\r\n")
+	assert.Len(t, lines, 2)
+	assert.True(t, strings.HasSuffix(lines[0], ";Desfase de reloj;label.env"))
+	assert.Contains(t, lines[1], ";Usuario creado;")
+	assert.Contains(t, lines[1], `;"{""name"":""a;b""}";`)
+	assert.Contains(t, lines[1], ";2024-01-15T10:00:00Z;")
+	assert.True(t, strings.HasSuffix(lines[1], ";prod"))
+}
+
+func TestExport_LocalizedMessage(t *testing.T) {
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "test-request-id")
+	ctx = context.WithValue(ctx, &sts.Claim, sts.Claims{UserID: 1})
+	ctxLogger := logger.NewContextLogger("TestExport_LocalizedMessage", "debug", logger.TextFormat)
+
+	filter, opts, err := ToParseExportRequest(&http.Request{URL: &url.URL{RawQuery: "max=10&page=1&format=csv&lang=es"}})
+	assert.NoError(t, err)
+
+	repoMock := &logsmock.IRepository{}
+	repoMock.On("ExportLabelKeys", mock.Anything, mock.Anything).Return([]string{}, nil)
+	repoMock.On("EachExportLog", mock.Anything, mock.MatchedBy(func(filter logs.Filter) bool { return filter.Lang == "es" }), mock.Anything).
+		Run(eachExportLog([]logs.Model{
+			{ID: "1", Message: 2, LogMessage: []*log_message.Model{{ID: 2, Message: "Usuario creado", Lang: "es"}}},
+		})).
+		Return(nil)
+
+	service := NewDefaultService(ctxLogger, repoMock, config.LogsConfigurations{}, nil, nil, nil, nil)
+	var buffer bytes.Buffer
+	_, err = service.Export(ctx, filter, opts, &buffer)
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\r\n"), "\r\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[1], ",Usuario creado,")
+}
+
+func TestExport_Columns(t *testing.T) {
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "test-request-id")
+	ctx = context.WithValue(ctx, &sts.Claim, sts.Claims{UserID: 1})
+	ctxLogger := logger.NewContextLogger("TestExport_Columns", "debug", logger.TextFormat)
+	createdAt := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	model := logs.Model{ID: "1", Action: "CREATE", UserID: "7", CreatedAt: &createdAt, Labels: map[string]string{"env": "prod"}}
+
+	cases := []struct {
+		name     string
+		lang     string
+		columns  []string
+		expected []string
+	}{
+		{
+			name:     "Selected and ordered",
+			lang:     "en",
+			columns:  []string{"createdAt", "userId", "action", "label.env"},
+			expected: []string{"CreatedAt,UserID,Action,label.env", "2024-01-15T10:00:00Z,7,CREATE,prod"},
+		},
+		{
+			name:     "Localized headers",
+			lang:     "pt",
+			columns:  []string{"action", "target"},
+			expected: []string{"Ação,Destino", "CREATE,"},
+		},
+		{
+			name:     "Language without translation",
+			lang:     "fr",
+			columns:  []string{"id", "clockSkew"},
+			expected: []string{"ID,ClockSkew", "1,false"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// The label keys are only read for the default columns
+			repoMock := &logsmock.IRepository{}
+			repoMock.On("EachExportLog", mock.Anything, mock.Anything, mock.Anything).Run(eachExportLog([]logs.Model{model})).Return(nil)
+
+			service := NewDefaultService(ctxLogger, repoMock, config.LogsConfigurations{}, nil, nil, nil, nil)
+			var buffer bytes.Buffer
+			_, err := service.Export(ctx, Filter{Lang: tc.lang}, ExportOptions{Format: ExportFormatCSV, Columns: tc.columns}, &buffer)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, strings.Split(strings.TrimSuffix(buffer.String(), "\r\n"), "\r\n"))
+			repoMock.AssertNotCalled(t, "ExportLabelKeys", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestExport_ExpandedData(t *testing.T) {
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "test-request-id")
+	ctx = context.WithValue(ctx, &sts.Claim, sts.Claims{UserID: 1})
+	ctxLogger := logger.NewContextLogger("TestExport_ExpandedData", "debug", logger.TextFormat)
+	models := []logs.Model{
+		{ID: "1", Level: 6, Action: "UPDATE", UserID: "7", Data: `{"name":"new","age":30,"tags":["a"]}`, OldData: `{"name":"old","age":30}`},
+		{ID: "2", Level: 6, Action: "UPDATE", UserID: "7", Data: `{"name":"same"}`, OldData: `{"name":"same"}`},
+		{ID: "3", Level: 3, Action: "DELETE", UserID: "9", Data: "null", OldData: `{"name":"gone"}`},
+		{ID: "4", Level: 6, Action: "CREATE", UserID: "8", Data: `{"name":"first"}`, OldData: ""},
+	}
+
+	t.Run("Csv columns", func(t *testing.T) {
+		repoMock := &logsmock.IRepository{}
+		repoMock.On("ExportDataKeys", mock.Anything, mock.Anything).Return([]string{"age", "name", "tags"}, nil)
+		repoMock.On("EachExportLog", mock.Anything, mock.Anything, mock.Anything).Run(eachExportLog(models[:1])).Return(nil)
+
+		service := NewDefaultService(ctxLogger, repoMock, config.LogsConfigurations{}, nil, nil, nil, nil)
+		var buffer bytes.Buffer
+		opts := ExportOptions{Format: ExportFormatCSV, Delimiter: ';', Columns: []string{"id", "oldData", "action", "data"}, ExpandData: true}
+		_, err := service.Export(ctx, Filter{Lang: "es"}, opts, &buffer)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			"ID;Datos anteriores;Acción;Datos;data.age (Antes);data.age (Después);data.name (Antes);data.name (Después);data.tags (Antes);data.tags (Después)",
+			`1;"{""name"":""old"",""age"":30}";UPDATE;"{""name"":""new"",""age"":30,""tags"":[""a""]}";30;30;old;new;;"[""a""]"`,
+		}, strings.Split(strings.TrimSuffix(buffer.String(), "\r\n"), "\r\n"))
+	})
+
+	t.Run("Data without keys", func(t *testing.T) {
+		repoMock := &logsmock.IRepository{}
+		repoMock.On("ExportDataKeys", mock.Anything, mock.Anything).Return([]string{}, nil)
+		repoMock.On("EachExportLog", mock.Anything, mock.Anything, mock.Anything).Run(eachExportLog(models[2:3])).Return(nil)
+
+		service := NewDefaultService(ctxLogger, repoMock, config.LogsConfigurations{}, nil, nil, nil, nil)
+		var buffer bytes.Buffer
+		opts := ExportOptions{Format: ExportFormatCSV, Columns: []string{"id", "data", "oldData"}, ExpandData: true}
+		_, err := service.Export(ctx, Filter{}, opts, &buffer)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			"ID,Data,OldData",
+			`3,null,"{""name"":""gone""}"`,
+		}, strings.Split(strings.TrimSuffix(buffer.String(), "\r\n"), "\r\n"))
+	})
+
+	t.Run("Highlighted xlsx cells and summary sheet", func(t *testing.T) {
+		repoMock := &logsmock.IRepository{}
+		repoMock.On("ExportLabelKeys", mock.Anything, mock.Anything).Return([]string{}, nil)
+		repoMock.On("ExportDataKeys", mock.Anything, mock.Anything).Return([]string{"name"}, nil)
+		repoMock.On("EachExportLog", mock.Anything, mock.Anything, mock.Anything).Run(eachExportLog(models)).Return(nil)
+
+		service := NewDefaultService(ctxLogger, repoMock, config.LogsConfigurations{}, nil, nil, nil, nil)
+		var content bytes.Buffer
+		_, err := service.Export(ctx, Filter{}, ExportOptions{Format: ExportFormatXLSX, ExpandData: true, Summary: true}, &content)
+		assert.NoError(t, err)
+
+		f, err := excelize.OpenReader(&content)
+		assert.NoError(t, err)
+		rows, err := f.GetRows("logs")
+		assert.NoError(t, err)
+		assert.Len(t, rows, 5)
+
+		// The data columns follow the data and old data columns, which are kept
+		header := rows[0]
+		dataIdx := slices.Index(header, "data.name (Before)")
+		assert.Equal(t, slices.Index(DefaultExportColumns, "oldData")+1, dataIdx)
+		assert.Equal(t, "data.name (After)", header[dataIdx+1])
+		assert.Contains(t, header, "Data")
+		assert.Contains(t, header, "OldData")
+		assert.Len(t, header, len(DefaultExportColumns)+2)
+
+		highlighted := func(row int) bool {
+			before, _ := excelize.CoordinatesToCellName(dataIdx+1, row)
+			after, _ := excelize.CoordinatesToCellName(dataIdx+2, row)
+			beforeStyle, err := f.GetCellStyle("logs", before)
+			assert.NoError(t, err)
+			afterStyle, err := f.GetCellStyle("logs", after)
+			assert.NoError(t, err)
+			assert.Equal(t, beforeStyle, afterStyle)
+			return beforeStyle != 0
+		}
+		assert.Equal(t, []string{"old", "new"}, rows[1][dataIdx:dataIdx+2])
+		assert.True(t, highlighted(2))
+		assert.False(t, highlighted(3))
+		assert.Equal(t, []string{"gone"}, rows[3][dataIdx:dataIdx+1])
+		assert.True(t, highlighted(4))
+		assert.Equal(t, []string{"", "first"}, rows[4][dataIdx:dataIdx+2])
+		assert.True(t, highlighted(5))
+
+		// The summary is a table, its repeated headers are numbered
+		summary, err := f.GetRows("summary")
+		assert.NoError(t, err)
+		assert.Equal(t, [][]string{
+			{"Level", "Count", "Action", "Count2", "UserID", "Count3"},
+			{"3", "1"},
+			{"", "", "DELETE", "1"},
+			{"", "", "", "", "9", "1"},
+			{"6", "3"},
+			{"", "", "CREATE", "1"},
+			{"", "", "", "", "8", "1"},
+			{"", "", "UPDATE", "2"},
+			{"", "", "", "", "7", "2"},
+		}, summary)
+		assert.Equal(t, "logs", f.GetSheetName(f.GetActiveSheetIndex()))
+	})
+
+	t.Run("Data keys error", func(t *testing.T) {
+		repoMock := &logsmock.IRepository{}
+		repoMock.On("ExportLabelKeys", mock.Anything, mock.Anything).Return([]string{}, nil)
+		repoMock.On("ExportDataKeys", mock.Anything, mock.Anything).Return(nil, errors.New("db error"))
+
+		service := NewDefaultService(ctxLogger, repoMock, config.LogsConfigurations{}, nil, nil, nil, nil)
+		var content bytes.Buffer
+		res, err := service.Export(ctx, Filter{}, ExportOptions{Format: ExportFormatXLSX, ExpandData: true}, &content)
+		assert.Nil(t, res)
+		assert.True(t, terrors.Is(err, terrors.ErrInternalService))
+		assert.Zero(t, content.Len())
+		repoMock.AssertNotCalled(t, "EachExportLog", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestExport_JSON(t *testing.T) {
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "test-request-id")
+	ctx = context.WithValue(ctx, &sts.Claim, sts.Claims{UserID: 1})
+	ctxLogger := logger.NewContextLogger("TestExport_JSON", "debug", logger.TextFormat)
+
+	repoMock := &logsmock.IRepository{}
+	repoMock.On("EachExportLog", mock.Anything, mock.Anything, mock.Anything).
+		Run(eachExportLog([]logs.Model{
+			{ID: "1", Data: `{"name":"new"}`, OldData: `{"name":"old"}`},
+			{ID: "2"},
+		})).
+		Return(nil)
+
+	service := NewDefaultService(ctxLogger, repoMock, config.LogsConfigurations{}, nil, nil, nil, nil)
+
+	cases := []struct {
+		name        string
+		opts        ExportOptions
+		file        string
+		contentType string
+		decode      func(t *testing.T, content []byte) []ExportRecord
+	}{
+		{
+			name:        "NDJSON",
+			opts:        ExportOptions{Format: ExportFormatNDJSON},
+			file:        ExportFileNDJSON,
+			contentType: ContentTypeNDJSON,
+			decode: func(t *testing.T, content []byte) []ExportRecord {
+				var records []ExportRecord
+				decoder := json.NewDecoder(bytes.NewReader(content))
+				for decoder.More() {
+					var record ExportRecord
+					assert.NoError(t, decoder.Decode(&record))
+					records = append(records, record)
+				}
+				return records
+			},
+		},
+		{
+			name:        "Gzip JSON",
+			opts:        ExportOptions{Format: ExportFormatJSON, Compress: true},
+			file:        ExportFileJSON + ExportExtGzip,
+			contentType: ContentTypeGzip,
+			decode: func(t *testing.T, content []byte) []ExportRecord {
+				gz, err := gzip.NewReader(bytes.NewReader(content))
+				assert.NoError(t, err)
+				var records []ExportRecord
+				assert.NoError(t, json.NewDecoder(gz).Decode(&records))
+				return records
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var content bytes.Buffer
+			res, err := service.Export(ctx, Filter{}, tc.opts, &content)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.file, res.Name)
+			assert.Equal(t, tc.contentType, res.ContentType)
+			assert.Equal(t, tc.opts.Format, res.Manifest.Format)
+			assert.Equal(t, export.Digest(content.Bytes()), res.Manifest.SHA256)
+			repoMock.AssertNotCalled(t, "ExportLabelKeys", mock.Anything, mock.Anything)
+
+			records := tc.decode(t, content.Bytes())
+			assert.Len(t, records, 2)
+			assert.Equal(t, "1", records[0].ID)
+			assert.JSONEq(t, `{"name":"new"}`, string(records[0].Data))
+			assert.JSONEq(t, `{"name":"old"}`, string(records[0].OldData))
+			assert.Equal(t, json.RawMessage("null"), records[1].Data)
+		})
+	}
+}
+
+func TestExport_Streaming(t *testing.T) {
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "test-request-id")
+	ctx = context.WithValue(ctx, &sts.Claim, sts.Claims{UserID: 1})
+	ctxLogger := logger.NewContextLogger("TestExport_Streaming", "debug", logger.TextFormat)
+
+	t.Run("Nothing written before the search succeeded", func(t *testing.T) {
+		repoMock := &logsmock.IRepository{}
+		repoMock.On("ExportLabelKeys", mock.Anything, mock.Anything).Return([]string{}, nil)
+		repoMock.On("EachExportLog", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("db down"))
+
+		service := NewDefaultService(ctxLogger, repoMock, config.LogsConfigurations{}, nil, nil, nil, nil)
+		var content bytes.Buffer
+		res, err := service.Export(ctx, Filter{}, ExportOptions{Format: ExportFormatCSV}, &content)
+		assert.Nil(t, res)
+		assert.True(t, terrors.Is(err, terrors.ErrInternalService))
+		assert.Zero(t, content.Len())
+	})
+
+	t.Run("Rows written as they are read", func(t *testing.T) {
+		var content bytes.Buffer
+		written := []int{}
+
+		repoMock := &logsmock.IRepository{}
+		repoMock.On("EachExportLog", mock.Anything, mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				fn := args.Get(2).(func(logs.Model) error)
+				for _, id := range []string{"1", "2", "3"} {
+					_ = fn(logs.Model{ID: id})
+					written = append(written, content.Len())
+				}
+			}).
+			Return(nil)
+
+		progress := []int{}
+		opts := ExportOptions{Format: ExportFormatNDJSON, Progress: func(rows int) { progress = append(progress, rows) }}
+
+		service := NewDefaultService(ctxLogger, repoMock, config.LogsConfigurations{}, nil, nil, nil, nil)
+		res, err := service.Export(ctx, Filter{}, opts, &content)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, res.Manifest.RowCount)
+		assert.Equal(t, []int{1, 2, 3}, progress)
+		assert.True(t, written[0] > 0 && written[0] < written[1] && written[1] < written[2])
+		assert.Equal(t, written[2], content.Len())
+	})
+
+	t.Run("Empty xlsx keeps its headers", func(t *testing.T) {
+		repoMock := &logsmock.IRepository{}
+		repoMock.On("ExportLabelKeys", mock.Anything, mock.Anything).Return([]string{}, nil)
+		repoMock.On("EachExportLog", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		service := NewDefaultService(ctxLogger, repoMock, config.LogsConfigurations{}, nil, nil, nil, nil)
+		var content bytes.Buffer
+		res, err := service.Export(ctx, Filter{}, ExportOptions{}, &content)
+		assert.NoError(t, err)
+		assert.Equal(t, ExportFileXLSX, res.Name)
+		assert.Equal(t, 0, res.Manifest.RowCount)
+
+		f, err := excelize.OpenReader(&content)
+		assert.NoError(t, err)
+		rows, err := f.GetRows("logs")
+		assert.NoError(t, err)
+		assert.Equal(t, [][]string{{"ID", "IpAddress", "ClientHost", "Provider", "Level", "Message", "LogMessage", "Description", "Path",
+			"Resource", "Action", "Data", "OldData", "TenantCat", "UserID", "CreatedAt", "OccurredAt", "ClockSkew"}}, rows)
+	})
+}
+
+// eachExportLog mocks the export cursor of the repository with the models
+func eachExportLog(models []logs.Model) func(args mock.Arguments) {
+	return func(args mock.Arguments) {
+		fn := args.Get(2).(func(logs.Model) error)
+		for _, model := range models {
+			if err := fn(model); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func TestCreate_Enrichment(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestCreate_Enrichment", "debug", logger.TextFormat)
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "test-request-id")
+
+	t.Run("Enriched model stored", func(t *testing.T) {
+		repoMock := &logsmock.IRepository{}
+		repoMock.On("Create", mock.Anything, mock.Anything).Return(nil)
+		enricherMock := &enrichermock.IEnricher{EnrichFunc: func(m *logs.Model) { m.Resource = "USER" }}
+
+		service := NewDefaultService(ctxLogger, repoMock, config.LogsConfigurations{}, enricherMock, nil, nil, nil)
+		res, err := service.Create(ctx, &Payload{Message: 1, Resource: "user"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "USER", res.Resource)
+		assert.Equal(t, 1, enricherMock.Calls)
+		repoMock.AssertCalled(t, "Create", mock.Anything, mock.MatchedBy(func(m *logs.Model) bool {
+			return m.Resource == "USER"
+		}))
+	})
+
+	t.Run("Rejected by the enrichment", func(t *testing.T) {
+		repoMock := &logsmock.IRepository{}
+		enricherMock := &enrichermock.IEnricher{EnrichErr: terrors.InternalService("enrichment_error", "Failed to enrich log", nil)}
+
+		service := NewDefaultService(ctxLogger, repoMock, config.LogsConfigurations{}, enricherMock, nil, nil, nil)
+		res, err := service.Create(ctx, &Payload{Message: 1})
+		assert.Nil(t, res)
+		assert.Error(t, err)
+
+		err = service.CreateLogFromKafka(ctx, &eventfactory.LogCreatedPayload{Message: 1}, nil)
+		assert.Error(t, err)
+		repoMock.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}
+
+// chainOf links the models in order the way the repository does on insert
+func chainOf(t *testing.T, tenantID int, models ...logs.Model) []logs.Model {
+	prevHash := ""
+	for i := range models {
+		models[i].ChainTenantID = tenantID
+		models[i].ChainSeq = int64(i + 1)
+		models[i].PrevHash = prevHash
+
+		hash, err := logs.ComputeHash(&models[i])
+		assert.NoError(t, err)
+		models[i].Hash = hash
+		prevHash = hash
+	}
+
+	return models
+}
+
+func TestVerify(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestVerify", "debug", logger.TextFormat)
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "test-request-id")
+	ctx = context.WithValue(ctx, &sts.Claim, sts.Claims{UserID: 1, Tenants: []int{1}})
+
+	newChain := func() []logs.Model {
+		return chainOf(t, 1,
+			logs.Model{ID: "a", Data: `{"n":1}`, TenantID: "[1]"},
+			logs.Model{ID: "b", Data: `{"n":2}`, TenantID: "[1]"},
+			logs.Model{ID: "c", Data: `{"n":3}`, TenantID: "[1]"},
+		)
+	}
+
+	cases := []struct {
+		name     string
+		filter   VerifyFilter
+		repoFunc func() *logsmock.IRepository
+		expected func(t *testing.T, res *VerifyResult)
+		err      string
+	}{
+		{
+			name:   "Valid chain",
+			filter: VerifyFilter{TenantID: 1},
+			repoFunc: func() *logsmock.IRepository {
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("ChainBounds", mock.Anything, 1, time.Time{}, time.Time{}).Return(int64(1), int64(3), nil)
+				repoMock.On("ChainEntries", mock.Anything, 1, int64(0), int64(3), verifyBatchSize).Return(newChain(), nil)
+				return repoMock
+			},
+			expected: func(t *testing.T, res *VerifyResult) {
+				assert.True(t, res.Valid)
+				assert.Equal(t, 3, res.Checked)
+				assert.Nil(t, res.BrokenLink)
+			},
+		},
+		{
+			name:   "Tampered content",
+			filter: VerifyFilter{TenantID: 1},
+			repoFunc: func() *logsmock.IRepository {
+				chain := newChain()
+				chain[1].Data = `{"n":20}`
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("ChainBounds", mock.Anything, 1, time.Time{}, time.Time{}).Return(int64(1), int64(3), nil)
+				repoMock.On("ChainEntries", mock.Anything, 1, int64(0), int64(3), verifyBatchSize).Return(chain, nil)
+				return repoMock
+			},
+			expected: func(t *testing.T, res *VerifyResult) {
+				assert.False(t, res.Valid)
+				assert.Equal(t, 1, res.Checked)
+				assert.Equal(t, "b", res.BrokenLink.ID)
+				assert.Equal(t, ReasonHashMismatch, res.BrokenLink.Reason)
+			},
+		},
+		{
+			name:   "Deleted entry",
+			filter: VerifyFilter{TenantID: 1},
+			repoFunc: func() *logsmock.IRepository {
+				chain := newChain()
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("ChainBounds", mock.Anything, 1, time.Time{}, time.Time{}).Return(int64(1), int64(3), nil)
+				repoMock.On("ChainEntries", mock.Anything, 1, int64(0), int64(3), verifyBatchSize).Return([]logs.Model{chain[0], chain[2]}, nil)
+				repoMock.On("ChainPurges", mock.Anything, 1, int64(2), int64(2)).Return([]logs.ChainPurge{}, nil)
+				return repoMock
+			},
+			expected: func(t *testing.T, res *VerifyResult) {
+				assert.False(t, res.Valid)
+				assert.Equal(t, "c", res.BrokenLink.ID)
+				assert.Equal(t, ReasonMissingEntry, res.BrokenLink.Reason)
+			},
+		},
+		{
+			name:   "Entry purged by retention",
+			filter: VerifyFilter{TenantID: 1},
+			repoFunc: func() *logsmock.IRepository {
+				chain := newChain()
+				purge := logs.ChainPurge{TenantID: 1, FromSeq: 2, ToSeq: 2, PrevHash: chain[0].Hash, Hash: chain[1].Hash}
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("ChainBounds", mock.Anything, 1, time.Time{}, time.Time{}).Return(int64(1), int64(3), nil)
+				repoMock.On("ChainEntries", mock.Anything, 1, int64(0), int64(3), verifyBatchSize).Return([]logs.Model{chain[0], chain[2]}, nil)
+				repoMock.On("ChainPurges", mock.Anything, 1, int64(2), int64(2)).Return([]logs.ChainPurge{purge}, nil)
+				return repoMock
+			},
+			expected: func(t *testing.T, res *VerifyResult) {
+				assert.True(t, res.Valid)
+				assert.Equal(t, 2, res.Checked)
+				assert.Equal(t, int64(1), res.Purged)
+			},
+		},
+		{
+			name:   "Forged purge",
+			filter: VerifyFilter{TenantID: 1},
+			repoFunc: func() *logsmock.IRepository {
+				chain := newChain()
+				purge := logs.ChainPurge{TenantID: 1, FromSeq: 2, ToSeq: 2, PrevHash: "forged", Hash: chain[1].Hash}
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("ChainBounds", mock.Anything, 1, time.Time{}, time.Time{}).Return(int64(1), int64(3), nil)
+				repoMock.On("ChainEntries", mock.Anything, 1, int64(0), int64(3), verifyBatchSize).Return([]logs.Model{chain[0], chain[2]}, nil)
+				repoMock.On("ChainPurges", mock.Anything, 1, int64(2), int64(2)).Return([]logs.ChainPurge{purge}, nil)
+				return repoMock
+			},
+			expected: func(t *testing.T, res *VerifyResult) {
+				assert.False(t, res.Valid)
+				assert.Equal(t, "c", res.BrokenLink.ID)
+				assert.Equal(t, ReasonMissingEntry, res.BrokenLink.Reason)
+				assert.Equal(t, int64(0), res.Purged)
+			},
+		},
+		{
+			name:   "Range starts after a purge",
+			filter: VerifyFilter{TenantID: 1, From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+			repoFunc: func() *logsmock.IRepository {
+				chain := newChain()
+				purge := logs.ChainPurge{TenantID: 1, FromSeq: 1, ToSeq: 2, Hash: chain[1].Hash}
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("ChainBounds", mock.Anything, 1, mock.Anything, time.Time{}).Return(int64(3), int64(3), nil)
+				repoMock.On("ChainEntries", mock.Anything, 1, int64(1), int64(2), 1).Return([]logs.Model{}, nil)
+				repoMock.On("ChainPurges", mock.Anything, 1, int64(2), int64(2)).Return([]logs.ChainPurge{purge}, nil)
+				repoMock.On("ChainEntries", mock.Anything, 1, int64(2), int64(3), verifyBatchSize).Return(chain[2:], nil)
+				return repoMock
+			},
+			expected: func(t *testing.T, res *VerifyResult) {
+				assert.True(t, res.Valid)
+				assert.Equal(t, 1, res.Checked)
+			},
+		},
+		{
+			name:   "Range checks the link to the predecessor",
+			filter: VerifyFilter{TenantID: 1, From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+			repoFunc: func() *logsmock.IRepository {
+				chain := newChain()
+				chain[1].PrevHash = "forged"
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("ChainBounds", mock.Anything, 1, mock.Anything, time.Time{}).Return(int64(2), int64(3), nil)
+				repoMock.On("ChainEntries", mock.Anything, 1, int64(0), int64(1), 1).Return([]logs.Model{chain[0]}, nil)
+				repoMock.On("ChainEntries", mock.Anything, 1, int64(1), int64(3), verifyBatchSize).Return(chain[1:], nil)
+				return repoMock
+			},
+			expected: func(t *testing.T, res *VerifyResult) {
+				assert.False(t, res.Valid)
+				assert.Equal(t, int64(2), res.FirstSeq)
+				assert.Equal(t, ReasonPrevHashMismatch, res.BrokenLink.Reason)
+				assert.Equal(t, "forged", res.BrokenLink.Actual)
+			},
+		},
+		{
+			name:   "Empty chain",
+			filter: VerifyFilter{TenantID: 1},
+			repoFunc: func() *logsmock.IRepository {
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("ChainBounds", mock.Anything, 1, time.Time{}, time.Time{}).Return(int64(0), int64(0), nil)
+				return repoMock
+			},
+			expected: func(t *testing.T, res *VerifyResult) {
+				assert.True(t, res.Valid)
+				assert.Equal(t, 0, res.Checked)
+			},
+		},
+		{
+			name:     "Tenant not allowed",
+			filter:   VerifyFilter{TenantID: 2},
+			repoFunc: func() *logsmock.IRepository { return &logsmock.IRepository{} },
+			err:      terrors.ErrUnauthorized,
+		},
+		{
+			name:   "Repository error",
+			filter: VerifyFilter{TenantID: 1},
+			repoFunc: func() *logsmock.IRepository {
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("ChainBounds", mock.Anything, 1, time.Time{}, time.Time{}).Return(int64(0), int64(0), errors.New("db error"))
+				return repoMock
+			},
+			err: terrors.ErrInternalService,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := NewDefaultService(ctxLogger, tc.repoFunc(), config.LogsConfigurations{}, nil, nil, nil, nil)
+			res, err := service.Verify(ctx, tc.filter)
+			if tc.err != "" {
+				assert.True(t, terrors.Is(err, tc.err))
+				return
+			}
+			assert.NoError(t, err)
+			tc.expected(t, res)
+		})
+	}
+}
+
+func TestGetByID_LegalHold(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestGetByID_LegalHold", "debug", logger.TextFormat)
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "test-request-id")
+	id := "12345"
+
+	cases := []struct {
+		name      string
+		holdsFunc func() *legalholdmock.IRepository
+		expected  *LegalHoldStatus
+		err       string
+	}{
+		{
+			name: "Held",
+			holdsFunc: func() *legalholdmock.IRepository {
+				holdsMock := &legalholdmock.IRepository{}
+				holdsMock.On("FindByLog", mock.Anything, id).Return([]legal_hold.Model{{ID: "hold-1", Reason: "Case 2024-17"}}, nil)
+				return holdsMock
+			},
+			expected: &LegalHoldStatus{Held: true, Holds: []LegalHoldRef{{ID: "hold-1", Reason: "Case 2024-17"}}},
+		},
+		{
+			name: "Not held",
+			holdsFunc: func() *legalholdmock.IRepository {
+				holdsMock := &legalholdmock.IRepository{}
+				holdsMock.On("FindByLog", mock.Anything, id).Return(nil, nil)
+				return holdsMock
+			},
+			expected: &LegalHoldStatus{Held: false, Holds: []LegalHoldRef{}},
+		},
+		{
+			name: "Holds repository error",
+			holdsFunc: func() *legalholdmock.IRepository {
+				holdsMock := &legalholdmock.IRepository{}
+				holdsMock.On("FindByLog", mock.Anything, id).Return(nil, errors.New("db error"))
+				return holdsMock
+			},
+			err: terrors.ErrInternalService,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repoMock := &logsmock.IRepository{}
+			repoMock.On("FindByID", mock.Anything, &id, mock.Anything).Return(&logs.Model{ID: id}, nil)
+
+			service := NewDefaultService(ctxLogger, repoMock, config.LogsConfigurations{}, nil, nil, tc.holdsFunc(), nil)
+			res, err := service.GetByID(ctx, &id, Filter{Lang: "en"})
+			if tc.err != "" {
+				assert.True(t, terrors.Is(err, tc.err))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, res.LegalHold)
+		})
+	}
+}
+
+func TestAccessLogRecorded(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestAccessLogRecorded", "debug", logger.TextFormat)
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "test-request-id")
+	ctx = context.WithValue(ctx, &sts.Claim, sts.Claims{UserID: 42, Tenants: []int{1}})
+	id := "12345"
+	filter := Filter{Lang: "en", TenantID: []int{1}, Filter: pagination.Filter{Page: 1, Size: 10}}
+
+	cases := []struct {
+		name     string
+		repoFunc func() *logsmock.IRepository
+		call     func(*DefaultService) error
+		expected access_log.Entry
+	}{
+		{
+			name: "Get by id",
+			repoFunc: func() *logsmock.IRepository {
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("FindByID", mock.Anything, &id, mock.Anything).Return(&logs.Model{ID: id}, nil)
+				return repoMock
+			},
+			call: func(s *DefaultService) error {
+				_, err := s.GetByID(ctx, &id, filter)
+				return err
+			},
+			expected: access_log.Entry{Action: access_log.ActionGet, LogID: id, Tenants: filter.TenantID, Filter: filter, RowCount: 1},
+		},
+		{
+			name: "Retrieve",
+			repoFunc: func() *logsmock.IRepository {
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("Retrieve", mock.Anything, mock.Anything).Return([]logs.Model{{ID: "1"}, {ID: "2"}}, 2, nil)
+				return repoMock
+			},
+			call: func(s *DefaultService) error {
+				_, err := s.Retrieve(ctx, filter)
+				return err
+			},
+			expected: access_log.Entry{Action: access_log.ActionRetrieve, Tenants: filter.TenantID, Filter: filter, RowCount: 2},
+		},
+		{
+			name: "Export",
+			repoFunc: func() *logsmock.IRepository {
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("ExportLabelKeys", mock.Anything, mock.Anything).Return([]string{}, nil)
+				repoMock.On("EachExportLog", mock.Anything, mock.Anything, mock.Anything).
+					Run(eachExportLog([]logs.Model{{ID: "1"}, {ID: "2"}, {ID: "3"}})).
+					Return(nil)
+				return repoMock
+			},
+			call: func(s *DefaultService) error {
+				_, err := s.Export(ctx, filter, ExportOptions{}, &bytes.Buffer{})
+				return err
+			},
+			expected: access_log.Entry{Action: access_log.ActionExport, Tenants: filter.TenantID, Filter: filter, RowCount: 3},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			accessLogMock := &accesslogsvcmock.IService{}
+			service := NewDefaultService(ctxLogger, tc.repoFunc(), config.LogsConfigurations{}, nil, nil, nil, accessLogMock)
+
+			assert.NoError(t, tc.call(service))
+			assert.Equal(t, []access_log.Entry{tc.expected}, accessLogMock.Entries)
+		})
+	}
+
+	t.Run("Failed read", func(t *testing.T) {
+		repoMock := &logsmock.IRepository{}
+		repoMock.On("FindByID", mock.Anything, &id, mock.Anything).Return(nil, errors.New("not found"))
+		accessLogMock := &accesslogsvcmock.IService{}
+		service := NewDefaultService(ctxLogger, repoMock, config.LogsConfigurations{}, nil, nil, nil, accessLogMock)
+
+		_, err := service.GetByID(ctx, &id, filter)
+
+		assert.Error(t, err)
+		assert.Len(t, accessLogMock.Entries, 1)
+		assert.Equal(t, 0, accessLogMock.Entries[0].RowCount)
+		assert.Equal(t, err, accessLogMock.Entries[0].Err)
+	})
+}
+
+func TestStats(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestStats", "debug", logger.TextFormat)
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "test-request-id")
+	start := time.Date(2024, 1, 1, 0, 30, 0, 0, time.UTC)
+	end := time.Date(2024, 1, 1, 6, 0, 0, 0, time.UTC)
+	hour := func(h int) time.Time { return time.Date(2024, 1, 1, h, 0, 0, 0, time.UTC) }
+
+	cases := []struct {
+		name     string
+		filter   StatsFilter
+		repoFunc func() *logsmock.IRepository
+		source   string
+		points   []StatsPoint
+		errCode  string
+	}{
+		{
+			name:   "Rollups and logs",
+			filter: StatsFilter{Filter: Filter{StartAt: start, EndAt: end}, Granularity: "hour", GroupBy: "level"},
+			repoFunc: func() *logsmock.IRepository {
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("RollupWatermarks", mock.Anything).Return(map[string]time.Time{"hour": hour(4)}, nil)
+				repoMock.On("Stats", mock.Anything, mock.MatchedBy(func(f logs.StatsFilter) bool {
+					return !f.Rollup && f.StartAt.Equal(start) && f.EndAt.Equal(hour(1))
+				})).Return([]logs.StatsPoint{{Bucket: hour(0), Key: "3", Count: 2}}, nil)
+				repoMock.On("Stats", mock.Anything, mock.MatchedBy(func(f logs.StatsFilter) bool {
+					return f.Rollup && f.StartAt.Equal(hour(1)) && f.EndAt.Equal(hour(4)) && f.GroupBy == "level"
+				})).Return([]logs.StatsPoint{{Bucket: hour(1), Key: "3", Count: 10}, {Bucket: hour(1), Key: "6", Count: 5}}, nil)
+				repoMock.On("Stats", mock.Anything, mock.MatchedBy(func(f logs.StatsFilter) bool {
+					return !f.Rollup && f.StartAt.Equal(hour(4)) && f.EndAt.Equal(end)
+				})).Return([]logs.StatsPoint{{Bucket: hour(4), Key: "3", Count: 1}}, nil)
+				return repoMock
+			},
+			source: StatsSourceMixed,
+			points: []StatsPoint{
+				{Bucket: hour(0), Key: "3", Count: 2},
+				{Bucket: hour(1), Key: "3", Count: 10},
+				{Bucket: hour(1), Key: "6", Count: 5},
+				{Bucket: hour(4), Key: "3", Count: 1},
+			},
+		},
+		{
+			name:   "Filter outside the rollups",
+			filter: StatsFilter{Filter: Filter{StartAt: start, EndAt: end, Path: "/users"}, Granularity: "hour"},
+			repoFunc: func() *logsmock.IRepository {
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("Stats", mock.Anything, mock.MatchedBy(func(f logs.StatsFilter) bool {
+					return !f.Rollup && f.StartAt.Equal(start) && f.EndAt.Equal(end)
+				})).Return([]logs.StatsPoint{{Bucket: hour(2), Count: 4}}, nil)
+				return repoMock
+			},
+			source: StatsSourceLogs,
+			points: []StatsPoint{{Bucket: hour(2), Count: 4}},
+		},
+		{
+			name:     "Missing range",
+			filter:   StatsFilter{Granularity: "hour"},
+			repoFunc: func() *logsmock.IRepository { return &logsmock.IRepository{} },
+			errCode:  terrors.ErrBadRequest,
+		},
+		{
+			name:     "Too many buckets",
+			filter:   StatsFilter{Filter: Filter{StartAt: start, EndAt: start.AddDate(1, 0, 0)}, Granularity: "minute"},
+			repoFunc: func() *logsmock.IRepository { return &logsmock.IRepository{} },
+			errCode:  terrors.ErrBadRequest,
+		},
+		{
+			name:   "Repository error",
+			filter: StatsFilter{Filter: Filter{StartAt: start, EndAt: end}, Granularity: "day"},
+			repoFunc: func() *logsmock.IRepository {
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("RollupWatermarks", mock.Anything).Return(nil, errors.New("db down"))
+				return repoMock
+			},
+			errCode: terrors.ErrInternalService,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repoMock := tc.repoFunc()
+			service := NewDefaultService(ctxLogger, repoMock, config.LogsConfigurations{}, nil, nil, nil, nil)
+
+			res, err := service.Stats(ctx, tc.filter)
+			if tc.errCode != "" {
+				assert.True(t, terrors.Is(err, tc.errCode), "unexpected error %v", err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.source, res.Source)
+			assert.Equal(t, tc.points, res.Points)
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDashboard(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestDashboard", "debug", logger.TextFormat)
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "test-request-id")
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	repoMock := &logsmock.IRepository{}
+	repoMock.On("RollupWatermarks", mock.Anything).Return(map[string]time.Time{"day": day.AddDate(0, 0, 3)}, nil)
+	repoMock.On("Stats", mock.Anything, mock.MatchedBy(func(f logs.StatsFilter) bool { return f.GroupBy == "" })).
+		Return([]logs.StatsPoint{{Bucket: day, Count: 5}, {Bucket: day.AddDate(0, 0, 1), Count: 7}}, nil)
+	repoMock.On("Stats", mock.Anything, mock.MatchedBy(func(f logs.StatsFilter) bool { return f.GroupBy == "level" })).
+		Return([]logs.StatsPoint{{Bucket: day, Key: "3", Count: 1}, {Bucket: day, Key: "6", Count: 4}, {Bucket: day.AddDate(0, 0, 1), Key: "3", Count: 7}}, nil)
+	repoMock.On("Stats", mock.Anything, mock.Anything).Return([]logs.StatsPoint{}, nil)
+
+	service := NewDefaultService(ctxLogger, repoMock, config.LogsConfigurations{}, nil, nil, nil, nil)
+	res, err := service.Dashboard(ctx, StatsFilter{Filter: Filter{StartAt: day, EndAt: day.AddDate(0, 0, 2)}, Granularity: "day"}, 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, StatsSourceRollups, res.Source)
+	assert.Equal(t, int64(12), res.Total)
+	assert.Len(t, res.Timeline, 2)
+	assert.Equal(t, []DashboardCount{{Key: "3", Count: 8}}, res.Levels)
+	assert.Empty(t, res.Providers)
+}
@@ -0,0 +1,353 @@
+package logs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/jmontesinos91/omnilogger/domains/lang"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/log_message"
+	"github.com/jmontesinos91/omnilogger/internal/utils/export"
+	"github.com/jmontesinos91/omnilogger/internal/utils/format"
+	"github.com/samber/lo"
+)
+
+// DefaultExportColumns columns of the xlsx and csv exports when none are requested, they are followed by one column
+// per label key
+var DefaultExportColumns = []string{
+	"id",
+	"ipAddress",
+	"clientHost",
+	"provider",
+	"level",
+	"message",
+	"logMessage",
+	"description",
+	"path",
+	"resource",
+	"action",
+	"data",
+	"oldData",
+	"tenantCat",
+	"userId",
+	"createdAt",
+	"occurredAt",
+	"clockSkew",
+}
+
+// exportColumns cells of the columns the xlsx and csv exports can select, keyed by the json name of the field. Label
+// columns are selected as label.<key>
+var exportColumns = map[string]func(item Response) interface{}{
+	"id":          func(item Response) interface{} { return item.ID },
+	"ipAddress":   func(item Response) interface{} { return item.IpAddress },
+	"clientHost":  func(item Response) interface{} { return item.ClientHost },
+	"provider":    func(item Response) interface{} { return item.Provider },
+	"level":       func(item Response) interface{} { return item.Level },
+	"message":     func(item Response) interface{} { return item.Message },
+	"logMessage":  logMessageText,
+	"description": func(item Response) interface{} { return item.Description },
+	"path":        func(item Response) interface{} { return item.Path },
+	"resource":    func(item Response) interface{} { return item.Resource },
+	"action":      func(item Response) interface{} { return item.Action },
+	"data":        func(item Response) interface{} { return item.Data },
+	"oldData":     func(item Response) interface{} { return item.OldData },
+	"tenantCat":   func(item Response) interface{} { return item.TenantCat },
+	"userId":      func(item Response) interface{} { return item.UserID },
+	"target":      func(item Response) interface{} { return item.Target },
+	"createdAt":   func(item Response) interface{} { return item.CreatedAt },
+	"occurredAt":  func(item Response) interface{} { return item.OccurredAt },
+	"clockSkew":   func(item Response) interface{} { return item.ClockSkew },
+}
+
+// DataColumnPrefix prefix of the headers of the expanded data columns
+const DataColumnPrefix = "data."
+
+// exportColumn header and cell of a column of the xlsx and csv exports, the cells of the expanded data columns are
+// given the parsed data of the log instead
+type exportColumn struct {
+	header   string
+	cell     func(item Response) interface{}
+	dataCell func(data exportData) interface{}
+}
+
+// exportData top level values of the json objects of the data and old data of a log
+type exportData struct {
+	before map[string]interface{}
+	after  map[string]interface{}
+}
+
+// newExportColumns columns of an export, the requested ones or the default ones followed by one column per label key.
+// Headers are translated to the language of the export, label columns keep their key. With data keys a before and an
+// after column per key follow the data and old data columns, which are kept for the values that are not json
+// objects, or come last when none of them is selected
+func newExportColumns(columns []string, labelKeys []string, dataKeys []string, lng string) []exportColumn {
+	if len(columns) == 0 {
+		columns = append(slices.Clone(DefaultExportColumns), lo.Map(labelKeys, func(key string, _ int) string {
+			return LabelParamPrefix + key
+		})...)
+	}
+
+	var dataColumns []exportColumn
+	dataIdx := len(columns) - 1
+	if len(dataKeys) > 0 {
+		dataColumns = newDataColumns(dataKeys, lng)
+		for i, column := range columns {
+			if column == "data" || column == "oldData" {
+				dataIdx = i
+			}
+		}
+	}
+
+	result := make([]exportColumn, 0, len(columns)+len(dataColumns))
+	for i, column := range columns {
+		if key, found := strings.CutPrefix(column, LabelParamPrefix); found {
+			result = append(result, exportColumn{
+				header: column,
+				cell:   func(item Response) interface{} { return item.Labels[key] },
+			})
+		} else {
+			result = append(result, exportColumn{header: lang.BuildHeader(column, lng), cell: exportColumns[column]})
+		}
+
+		if i == dataIdx {
+			result = append(result, dataColumns...)
+		}
+	}
+
+	return result
+}
+
+// newDataColumns before and after columns of the data keys, the before value comes from the old data. Both cells
+// are highlighted when the value of the key changed
+func newDataColumns(dataKeys []string, lng string) []exportColumn {
+	before := lang.BuildHeader("before", lng)
+	after := lang.BuildHeader("after", lng)
+
+	columns := make([]exportColumn, 0, 2*len(dataKeys))
+	for _, key := range dataKeys {
+		changed := func(data exportData) bool {
+			return !reflect.DeepEqual(data.before[key], data.after[key])
+		}
+
+		columns = append(columns,
+			exportColumn{
+				header: fmt.Sprintf("%s%s (%s)", DataColumnPrefix, key, before),
+				dataCell: func(data exportData) interface{} {
+					return format.ExcelCell{Value: dataCellValue(data.before[key]), Highlight: changed(data)}
+				},
+			},
+			exportColumn{
+				header: fmt.Sprintf("%s%s (%s)", DataColumnPrefix, key, after),
+				dataCell: func(data exportData) interface{} {
+					return format.ExcelCell{Value: dataCellValue(data.after[key]), Highlight: changed(data)}
+				},
+			},
+		)
+	}
+
+	return columns
+}
+
+// toExportData parses the data and old data of the log, values that are not json objects have no keys
+func toExportData(item Response) exportData {
+	return exportData{before: toDataValues(item.OldData), after: toDataValues(item.Data)}
+}
+
+func toDataValues(value string) map[string]interface{} {
+	decoder := json.NewDecoder(strings.NewReader(value))
+	decoder.UseNumber()
+
+	var values map[string]interface{}
+	if err := decoder.Decode(&values); err != nil {
+		return nil
+	}
+
+	return values
+}
+
+// dataCellValue cell of a data value, numbers are kept as numbers and objects and arrays are written as json
+func dataCellValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil, string, bool:
+		return v
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		if n, err := v.Float64(); err == nil {
+			return n
+		}
+		return v.String()
+	default:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(raw)
+	}
+}
+
+// logMessageText localized text of the message of a log
+func logMessageText(item Response) interface{} {
+	if message, ok := item.LogMessage.(*log_message.Model); ok && message != nil {
+		return message.Message
+	}
+
+	return ""
+}
+
+// exportWriter writes the logs of an export in the format of its file
+type exportWriter struct {
+	write func(item Response) error
+	close func() error
+	abort func()
+}
+
+// newExportWriter creates the writer of the format, the xlsx and csv files have a header row followed by a row of
+// cells per log. The data keys are only given to the expanded exports. The xlsx files of the summary exports end
+// with the summary sheet
+func newExportWriter(w io.Writer, fileFormat string, opts ExportOptions, labelKeys []string, dataKeys []string, lng string) (*exportWriter, error) {
+	columns := newExportColumns(opts.Columns, labelKeys, dataKeys, lng)
+	headers := lo.Map(columns, func(column exportColumn, _ int) string { return column.header })
+
+	toRow := func(item Response) format.ExcelRow {
+		var data exportData
+		if len(dataKeys) > 0 {
+			data = toExportData(item)
+		}
+
+		return format.ExcelRow{Cells: lo.Map(columns, func(column exportColumn, _ int) interface{} {
+			if column.dataCell != nil {
+				return column.dataCell(data)
+			}
+			return column.cell(item)
+		})}
+	}
+
+	switch fileFormat {
+	case ExportFormatCSV:
+		writer, err := export.NewCSVWriter(w, headers, export.CSVOptions{Delimiter: opts.Delimiter, BOM: opts.BOM})
+		if err != nil {
+			return nil, err
+		}
+		return &exportWriter{
+			write: func(item Response) error { return writer.WriteRow(toRow(item)) },
+			close: writer.Close,
+			abort: func() {},
+		}, nil
+	case ExportFormatNDJSON, ExportFormatJSON:
+		writer := export.NewJSONWriter(w, fileFormat == ExportFormatNDJSON)
+		return &exportWriter{
+			write: func(item Response) error { return writer.Write(ToExportRecord(item)) },
+			close: writer.Close,
+			abort: func() {},
+		}, nil
+	default:
+		writer, err := export.NewExcelWriter(w, "logs", headers)
+		if err != nil {
+			return nil, err
+		}
+		if !opts.Summary {
+			return &exportWriter{
+				write: func(item Response) error { return writer.WriteRow(toRow(item)) },
+				close: writer.Close,
+				abort: writer.Abort,
+			}, nil
+		}
+
+		summary := newExportSummary()
+		return &exportWriter{
+			write: func(item Response) error {
+				summary.add(item)
+				return writer.WriteRow(toRow(item))
+			},
+			close: func() error {
+				if err := writer.WriteSheet("summary", summary.headers(lng), summary.rows()); err != nil {
+					writer.Abort()
+					return err
+				}
+				return writer.Close()
+			},
+			abort: writer.Abort,
+		}, nil
+	}
+}
+
+// exportSummary counts of the exported logs by level, action and user
+type exportSummary struct {
+	counts map[int]map[string]map[string]int
+}
+
+func newExportSummary() *exportSummary {
+	return &exportSummary{counts: map[int]map[string]map[string]int{}}
+}
+
+// add counts the log
+func (s *exportSummary) add(item Response) {
+	actions, ok := s.counts[item.Level]
+	if !ok {
+		actions = map[string]map[string]int{}
+		s.counts[item.Level] = actions
+	}
+
+	users, ok := actions[item.Action]
+	if !ok {
+		users = map[string]int{}
+		actions[item.Action] = users
+	}
+
+	users[item.UserID]++
+}
+
+// headers of the summary sheet, a count follows the level, the action and the user
+func (s *exportSummary) headers(lng string) []string {
+	count := lang.BuildHeader("count", lng)
+
+	return []string{
+		lang.BuildHeader("level", lng), count,
+		lang.BuildHeader("action", lng), count,
+		lang.BuildHeader("userId", lng), count,
+	}
+}
+
+// rows of the summary sheet, every level is followed by the counts of its actions and every action by the counts
+// of its users. Levels, actions and users are sorted
+func (s *exportSummary) rows() []format.ExcelRow {
+	levels := lo.Keys(s.counts)
+	sort.Ints(levels)
+
+	rows := make([]format.ExcelRow, 0, len(levels))
+	for _, level := range levels {
+		actions := s.counts[level]
+		actionRows := make([]format.ExcelRow, 0, len(actions))
+		levelCount := 0
+
+		for _, action := range sortedKeys(actions) {
+			users := actions[action]
+			userRows := make([]format.ExcelRow, 0, len(users))
+			actionCount := 0
+
+			for _, user := range sortedKeys(users) {
+				userRows = append(userRows, format.ExcelRow{Cells: []interface{}{user, users[user]}})
+				actionCount += users[user]
+			}
+
+			actionRows = append(actionRows, format.ExcelRow{Cells: []interface{}{action, actionCount}, Groups: userRows})
+			levelCount += actionCount
+		}
+
+		rows = append(rows, format.ExcelRow{Cells: []interface{}{level, levelCount}, Groups: actionRows})
+	}
+
+	return rows
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := lo.Keys(values)
+	sort.Strings(keys)
+	return keys
+}
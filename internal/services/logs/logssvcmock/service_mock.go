@@ -4,13 +4,16 @@ import (
 	"context"
 	"github.com/jmontesinos91/oevents/eventfactory"
 	"github.com/jmontesinos91/omnilogger/internal/services/logs"
+	"io"
+	"time"
 )
 
 type IService struct {
 	// Create
-	CreateErr    error
-	CreateRes    *logs.Response
-	CreateCalled bool
+	CreateErr     error
+	CreateRes     *logs.Response
+	CreateCalled  bool
+	CreatePayload *logs.Payload
 
 	// GetByID
 	GetByIDErr    error
@@ -26,11 +29,40 @@ type IService struct {
 	CreateLogFromKafkaErr     error
 	CreateLogFromKafkaCalled  bool
 	CreateLogFromKafkaPayload *eventfactory.LogCreatedPayload
+	CreateLogFromKafkaTime    *time.Time
 
 	// Export
-	ExportErr    error
-	ExportRes    []byte
-	ExportCalled bool
+	ExportErr     error
+	ExportRes     *logs.ExportFile
+	ExportContent []byte
+	ExportCalled  bool
+	ExportOpts    logs.ExportOptions
+	ExportRows    int
+
+	// LabelFacets
+	LabelFacetsErr    error
+	LabelFacetsRes    []logs.LabelFacet
+	LabelFacetsCalled bool
+	LabelFacetsKeys   []string
+
+	// Verify
+	VerifyErr    error
+	VerifyRes    *logs.VerifyResult
+	VerifyCalled bool
+	VerifyFilter logs.VerifyFilter
+
+	// Stats
+	StatsErr    error
+	StatsRes    *logs.StatsResponse
+	StatsCalled bool
+	StatsFilter logs.StatsFilter
+
+	// Dashboard
+	DashboardErr    error
+	DashboardRes    *logs.Dashboard
+	DashboardCalled bool
+	DashboardFilter logs.StatsFilter
+	DashboardTop    int
 }
 
 func (m *IService) GetByID(ctx context.Context, id *string, filter logs.Filter) (*logs.Response, error) {
@@ -43,6 +75,7 @@ func (m *IService) GetByID(ctx context.Context, id *string, filter logs.Filter)
 
 func (m *IService) Create(ctx context.Context, payload *logs.Payload) (*logs.Response, error) {
 	m.CreateCalled = true
+	m.CreatePayload = payload
 	if m.CreateErr != nil {
 		return nil, m.CreateErr
 	}
@@ -61,20 +94,81 @@ func (m *IService) Retrieve(ctx context.Context, filter logs.Filter) (*logs.Pagi
 	return &logs.PaginatedRes{}, nil
 }
 
-func (m *IService) CreateLogFromKafka(ctx context.Context, logCreated *eventfactory.LogCreatedPayload) error {
+func (m *IService) CreateLogFromKafka(ctx context.Context, logCreated *eventfactory.LogCreatedPayload, occurredAt *time.Time) error {
 	m.CreateLogFromKafkaCalled = true
 	m.CreateLogFromKafkaPayload = logCreated
+	m.CreateLogFromKafkaTime = occurredAt
 	return m.CreateLogFromKafkaErr
 }
 
-func (m *IService) Export(ctx context.Context, filter logs.Filter) ([]byte, error) {
+func (m *IService) Export(ctx context.Context, filter logs.Filter, opts logs.ExportOptions, w io.Writer) (*logs.ExportFile, error) {
 	m.ExportCalled = true
+	m.ExportOpts = opts
 	if m.ExportErr != nil {
 		return nil, m.ExportErr
 	}
+	if _, err := w.Write(m.ExportContent); err != nil {
+		return nil, err
+	}
+	for rows := 1; rows <= m.ExportRows && opts.Progress != nil; rows++ {
+		opts.Progress(rows)
+	}
 	if m.ExportRes != nil {
 		return m.ExportRes, nil
 	}
 
-	return []byte{}, nil
+	return logs.ToExportFile(opts), nil
+}
+
+func (m *IService) LabelFacets(ctx context.Context, filter logs.Filter, keys []string) ([]logs.LabelFacet, error) {
+	m.LabelFacetsCalled = true
+	m.LabelFacetsKeys = keys
+	if m.LabelFacetsErr != nil {
+		return nil, m.LabelFacetsErr
+	}
+	if m.LabelFacetsRes != nil {
+		return m.LabelFacetsRes, nil
+	}
+
+	return []logs.LabelFacet{}, nil
+}
+
+func (m *IService) Verify(ctx context.Context, filter logs.VerifyFilter) (*logs.VerifyResult, error) {
+	m.VerifyCalled = true
+	m.VerifyFilter = filter
+	if m.VerifyErr != nil {
+		return nil, m.VerifyErr
+	}
+	if m.VerifyRes != nil {
+		return m.VerifyRes, nil
+	}
+
+	return &logs.VerifyResult{TenantID: filter.TenantID, Valid: true}, nil
+}
+
+func (m *IService) Stats(ctx context.Context, filter logs.StatsFilter) (*logs.StatsResponse, error) {
+	m.StatsCalled = true
+	m.StatsFilter = filter
+	if m.StatsErr != nil {
+		return nil, m.StatsErr
+	}
+	if m.StatsRes != nil {
+		return m.StatsRes, nil
+	}
+
+	return &logs.StatsResponse{Granularity: filter.Granularity, Points: []logs.StatsPoint{}}, nil
+}
+
+func (m *IService) Dashboard(ctx context.Context, filter logs.StatsFilter, top int) (*logs.Dashboard, error) {
+	m.DashboardCalled = true
+	m.DashboardFilter = filter
+	m.DashboardTop = top
+	if m.DashboardErr != nil {
+		return nil, m.DashboardErr
+	}
+	if m.DashboardRes != nil {
+		return m.DashboardRes, nil
+	}
+
+	return &logs.Dashboard{Granularity: filter.Granularity}, nil
 }
@@ -1,19 +1,47 @@
 package logs
 
 import (
+	"cmp"
 	"encoding/json"
 	"fmt"
 	"github.com/jmontesinos91/omnilogger/domains/lang"
 	"github.com/jmontesinos91/omnilogger/domains/pagination"
 	"github.com/jmontesinos91/omnilogger/internal/repositories/log_message"
 	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
+	"github.com/jmontesinos91/oevents"
+	"github.com/jmontesinos91/omnilogger/config"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/legal_hold"
 	"github.com/jmontesinos91/omnilogger/internal/repositories/logs"
+	"github.com/jmontesinos91/omnilogger/internal/utils/text"
+	"github.com/jmontesinos91/terrors"
 )
 
+// Column sizes of the origin columns, longer values are truncated
+const (
+	maxOriginIPLength           = 45
+	maxOriginForwardedForLength = 1024
+	maxOriginSubjectLength      = 255
+)
+
+// Label limits used when they are not configured
+const (
+	defaultMaxLabels           = 20
+	defaultMaxLabelKeyLength   = 64
+	defaultMaxLabelValueLength = 256
+)
+
+// labelKeyPattern label keys are restricted so they can be used as label.<key> query params
+var labelKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_./-]+$`)
+
 type Item struct {
 	ID   int    `json:"id"`
 	Name string `json:"name"`
@@ -27,7 +55,7 @@ func ToModel(payload *Payload) (*logs.Model, error) {
 		return nil, err
 	}
 
-	return &logs.Model{
+	model := &logs.Model{
 		ID:          uuid.NewString(),
 		IpAddress:   payload.IpAddress,
 		ClientHost:  payload.ClientHost,
@@ -45,7 +73,61 @@ func ToModel(payload *Payload) (*logs.Model, error) {
 		UserID:      payload.UserID,
 		Target:      payload.Target,
 		CreatedAt:   &date,
-	}, nil
+		OccurredAt:  &date,
+		Labels:      map[string]string{},
+	}
+
+	for key, value := range payload.Labels {
+		model.Labels[key] = value
+	}
+
+	if payload.OccurredAt != nil && !payload.OccurredAt.IsZero() {
+		occurredAt := payload.OccurredAt.UTC()
+		model.OccurredAt = &occurredAt
+	}
+
+	if payload.Origin != nil {
+		model.OriginIP = text.Truncate(payload.Origin.IP, maxOriginIPLength)
+		model.OriginForwardedFor = text.Truncate(payload.Origin.ForwardedFor, maxOriginForwardedForLength)
+		model.OriginSubject = text.Truncate(payload.Origin.Subject, maxOriginSubjectLength)
+		if len(payload.Origin.TenantID) > 0 {
+			tenantID, err := json.Marshal(payload.Origin.TenantID)
+			if err != nil {
+				return nil, err
+			}
+			model.OriginTenantID = string(tenantID)
+		}
+	}
+
+	return model, nil
+}
+
+// ToOrigin maps the server observed origin of a stored log, nil when the log has none
+func ToOrigin(model *logs.Model) *Origin {
+	if model.OriginIP == "" && model.OriginForwardedFor == "" && model.OriginSubject == "" && model.OriginTenantID == "" {
+		return nil
+	}
+
+	origin := &Origin{
+		IP:           model.OriginIP,
+		ForwardedFor: model.OriginForwardedFor,
+		Subject:      model.OriginSubject,
+	}
+	if model.OriginTenantID != "" {
+		_ = json.Unmarshal([]byte(model.OriginTenantID), &origin.TenantID)
+	}
+
+	return origin
+}
+
+// ToLegalHoldStatus maps the active legal holds covering a log
+func ToLegalHoldStatus(holds []legal_hold.Model) *LegalHoldStatus {
+	status := &LegalHoldStatus{Held: len(holds) > 0, Holds: []LegalHoldRef{}}
+	for _, hold := range holds {
+		status.Holds = append(status.Holds, LegalHoldRef{ID: hold.ID, Reason: hold.Reason})
+	}
+
+	return status
 }
 
 func ToResponse(model *logs.Model, lng string) *Response {
@@ -96,8 +178,60 @@ func ToResponse(model *logs.Model, lng string) *Response {
 		TenantCat:   string(tenantCat),
 		UserID:      model.UserID,
 		CreatedAt:   model.CreatedAt,
+		OccurredAt:  model.OccurredAt,
+		ClockSkew:   model.ClockSkew,
+		Labels:      model.Labels,
 		LogMessage:  LogMessage,
+		Origin:      ToOrigin(model),
+		ChainSeq:    model.ChainSeq,
+		PrevHash:    model.PrevHash,
+		Hash:        model.Hash,
+	}
+}
+
+// ToExportFile name and content type of the export file of the options, xlsx is the default format
+func ToExportFile(opts ExportOptions) *ExportFile {
+	var file *ExportFile
+	switch opts.Format {
+	case ExportFormatCSV:
+		file = &ExportFile{Name: ExportFileCSV, Format: ExportFormatCSV, ContentType: ContentTypeCSV}
+	case ExportFormatNDJSON:
+		file = &ExportFile{Name: ExportFileNDJSON, Format: ExportFormatNDJSON, ContentType: ContentTypeNDJSON}
+	case ExportFormatJSON:
+		file = &ExportFile{Name: ExportFileJSON, Format: ExportFormatJSON, ContentType: ContentTypeJSON}
+	default:
+		file = &ExportFile{Name: ExportFileXLSX, Format: ExportFormatXLSX, ContentType: ContentTypeXLSX}
+	}
+
+	if opts.Compress {
+		file.Name += ExportExtGzip
+		file.ContentType = ContentTypeGzip
 	}
+
+	return file
+}
+
+// ToExportRecord json export record of a log, data that is not valid json is kept as a json string
+func ToExportRecord(item Response) ExportRecord {
+	return ExportRecord{
+		Response: item,
+		Data:     toRawJSON(item.Data),
+		OldData:  toRawJSON(item.OldData),
+	}
+}
+
+// toRawJSON embeds value as json, empty values are null
+func toRawJSON(value string) json.RawMessage {
+	if value == "" {
+		return json.RawMessage("null")
+	}
+
+	if json.Valid([]byte(value)) {
+		return json.RawMessage(value)
+	}
+
+	raw, _ := json.Marshal(value)
+	return raw
 }
 
 func ToRepoFilter(filter Filter) logs.Filter {
@@ -119,15 +253,200 @@ func ToRepoFilter(filter Filter) logs.Filter {
 		EndAt:    filter.EndAt,
 		From:     from,
 		Size:     filter.Size,
+
+		DateField: filter.DateField,
+		SortBy:    filter.SortBy,
+		ClockSkew: filter.ClockSkew,
+		Labels:    filter.Labels,
+		Archived:  filter.Archived,
 	}
 }
 
 func ToParseFilterRequest(r *http.Request) (Filter, error) {
+	query := r.URL.Query()
+
+	filter, err := toSearchFilter(query)
+	if err != nil {
+		return Filter{}, err
+	}
+
+	size, err := strconv.Atoi(query.Get("max"))
+	if err != nil {
+		return Filter{}, err
+	}
+
+	pageNumber, err := strconv.Atoi(query.Get("page"))
+	if err != nil {
+		return Filter{}, err
+	}
+
+	filter.Filter = pagination.Filter{
+		Size: size,
+		Page: pageNumber,
+	}
+
+	return filter, nil
+}
+
+// ToParseExportRequest parses the filter of an export and its format, the page given by max and page is optional
+// and format defaults to xlsx. Csv files take
+// a single character delimiter, "tab" for tab separated files, and an encoding of utf-8 or utf-8-bom. Any format
+// can be gzip compressed through compress=gzip. Xlsx and csv files expand the data keys through expand=data and
+// xlsx files add a summary sheet through summary=true. The log messages are exported in lang, english by default
+func ToParseExportRequest(r *http.Request) (Filter, ExportOptions, error) {
+	query := r.URL.Query()
+
+	filter, err := toSearchFilter(query)
+	if err != nil {
+		return Filter{}, ExportOptions{}, err
+	}
+
+	// Exports are streamed, without max every log matching the filter is exported
+	if query.Get("max") != "" || query.Get("page") != "" {
+		size, err := strconv.Atoi(query.Get("max"))
+		if err != nil || size < 1 {
+			return Filter{}, ExportOptions{}, terrors.New(terrors.ErrBadRequest, "Invalid max param", map[string]string{})
+		}
+
+		page := 1
+		if query.Get("page") != "" {
+			page, err = strconv.Atoi(query.Get("page"))
+			if err != nil || page < 1 {
+				return Filter{}, ExportOptions{}, terrors.New(terrors.ErrBadRequest, "Invalid page param", map[string]string{})
+			}
+		}
+
+		filter.Filter = pagination.Filter{
+			Size: size,
+			Page: page,
+		}
+	}
+
+	opts := ExportOptions{Format: strings.ToLower(query.Get("format"))}
+
+	switch opts.Format {
+	case "":
+		opts.Format = ExportFormatXLSX
+	case ExportFormatXLSX, ExportFormatNDJSON, ExportFormatJSON:
+	case ExportFormatCSV:
+		opts.Delimiter, err = toDelimiter(query.Get("delimiter"))
+		if err != nil {
+			return Filter{}, ExportOptions{}, err
+		}
+
+		switch strings.ToLower(query.Get("encoding")) {
+		case "", EncodingUTF8:
+		case EncodingUTF8BOM:
+			opts.BOM = true
+		default:
+			return Filter{}, ExportOptions{}, terrors.New(terrors.ErrBadRequest, "Invalid encoding param", map[string]string{})
+		}
+	default:
+		return Filter{}, ExportOptions{}, terrors.New(terrors.ErrBadRequest, "Invalid format param", map[string]string{})
+	}
+
+	switch strings.ToLower(query.Get("compress")) {
+	case "":
+	case CompressGzip:
+		opts.Compress = true
+	default:
+		return Filter{}, ExportOptions{}, terrors.New(terrors.ErrBadRequest, "Invalid compress param", map[string]string{})
+	}
+
+	opts.Columns, err = toExportColumns(query["columns"])
+	if err != nil {
+		return Filter{}, ExportOptions{}, err
+	}
+	if len(opts.Columns) > 0 && opts.Format != ExportFormatXLSX && opts.Format != ExportFormatCSV {
+		return Filter{}, ExportOptions{}, terrors.New(terrors.ErrBadRequest, "Columns only apply to xlsx and csv exports", map[string]string{})
+	}
+
+	switch strings.ToLower(query.Get("expand")) {
+	case "":
+	case ExpandData:
+		if opts.Format != ExportFormatXLSX && opts.Format != ExportFormatCSV {
+			return Filter{}, ExportOptions{}, terrors.New(terrors.ErrBadRequest, "Expand only applies to xlsx and csv exports", map[string]string{})
+		}
+		opts.ExpandData = true
+	default:
+		return Filter{}, ExportOptions{}, terrors.New(terrors.ErrBadRequest, "Invalid expand param", map[string]string{})
+	}
+
+	if query.Get("summary") != "" {
+		opts.Summary, err = strconv.ParseBool(query.Get("summary"))
+		if err != nil {
+			return Filter{}, ExportOptions{}, terrors.New(terrors.ErrBadRequest, "Invalid summary param", map[string]string{})
+		}
+		if opts.Summary && opts.Format != ExportFormatXLSX {
+			return Filter{}, ExportOptions{}, terrors.New(terrors.ErrBadRequest, "Summary only applies to xlsx exports", map[string]string{})
+		}
+	}
+
+	// The language of the log messages and of the headers
+	if filter.Lang = query.Get("lang"); filter.Lang == "" {
+		filter.Lang = "en"
+	}
+
+	return filter, opts, nil
+}
+
+// toExportColumns parses the comma separated columns of an export in their order, columns are the json names of
+// the log fields or label.<key>. Names are case insensitive, none returns the default columns
+func toExportColumns(values []string) ([]string, error) {
+	known := make(map[string]string, len(exportColumns))
+	for column := range exportColumns {
+		known[strings.ToLower(column)] = column
+	}
+
+	var columns []string
+	for _, value := range values {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+
+			column, ok := known[strings.ToLower(name)]
+			if key, found := strings.CutPrefix(name, LabelParamPrefix); found {
+				column, ok = name, labelKeyPattern.MatchString(key)
+			}
+			if !ok {
+				return nil, terrors.New(terrors.ErrBadRequest, "Invalid export column "+name, map[string]string{})
+			}
+
+			if slices.Contains(columns, column) {
+				return nil, terrors.New(terrors.ErrBadRequest, "Duplicated export column "+name, map[string]string{})
+			}
+			columns = append(columns, column)
+		}
+	}
+
+	return columns, nil
+}
+
+// toDelimiter parses the delimiter of a csv export, it can not be a quote, a line break or a replacement character
+func toDelimiter(value string) (rune, error) {
+	switch value {
+	case "":
+		return ',', nil
+	case "tab", "\\t":
+		return '\t', nil
+	}
+
+	runes := []rune(value)
+	if len(runes) != 1 || runes[0] == '"' || runes[0] == '\r' || runes[0] == '\n' || runes[0] == utf8.RuneError {
+		return 0, terrors.New(terrors.ErrBadRequest, "Invalid delimiter param", map[string]string{})
+	}
+
+	return runes[0], nil
+}
+
+// toSearchFilter reads the search params of the logs, every param but the pagination
+func toSearchFilter(query url.Values) (Filter, error) {
 	var startAt time.Time
 	var endAt time.Time
 	var format = "2006-01-02T15:04:05"
 
-	query := r.URL.Query()
 	provider := query["provider[]"]
 	level := query["level[]"]
 	action := query["action[]"]
@@ -140,45 +459,62 @@ func ToParseFilterRequest(r *http.Request) (Filter, error) {
 	startAtString := query.Get("start_at")
 	endAtString := query.Get("end_at")
 
-	tenantIds, err := strArrToIntArr(tenantId)
+	dateField, err := toTimeField(query.Get("date_field"))
 	if err != nil {
 		return Filter{}, err
 	}
 
-	messageIds, err := strArrToIntArr(message)
+	sortBy, err := toTimeField(query.Get("sort_by"))
 	if err != nil {
 		return Filter{}, err
 	}
 
-	if startAtString != "" {
-		parsedDate, err := time.Parse(format, startAtString)
+	var clockSkew *bool
+	if query.Get("clock_skew") != "" {
+		value, err := strconv.ParseBool(query.Get("clock_skew"))
 		if err != nil {
-			return Filter{}, err
+			return Filter{}, terrors.New(terrors.ErrBadRequest, "Invalid clock_skew param", map[string]string{})
 		}
-		startAt = parsedDate
+		clockSkew = &value
 	}
 
-	if endAtString != "" {
-		parsedDate, err := time.Parse(format, endAtString)
+	var archived bool
+	if query.Get("archived") != "" {
+		archived, err = strconv.ParseBool(query.Get("archived"))
 		if err != nil {
-			return Filter{}, err
+			return Filter{}, terrors.New(terrors.ErrBadRequest, "Invalid archived param", map[string]string{})
 		}
-		endAt = parsedDate
 	}
 
-	size, err := strconv.Atoi(query.Get("max"))
+	labels, err := toLabelFilter(query)
 	if err != nil {
 		return Filter{}, err
 	}
 
-	pageNumber, err := strconv.Atoi(query.Get("page"))
+	tenantIds, err := strArrToIntArr(tenantId)
 	if err != nil {
 		return Filter{}, err
 	}
 
-	page := pagination.Filter{
-		Size: size,
-		Page: pageNumber,
+	messageIds, err := strArrToIntArr(message)
+	if err != nil {
+		return Filter{}, err
+	}
+
+	if startAtString != "" {
+		parsedDate, err := time.Parse(format, startAtString)
+		if err != nil {
+			return Filter{}, err
+		}
+		startAt = parsedDate
+	}
+
+	if endAtString != "" {
+		parsedDate, err := time.Parse(format, endAtString)
+		if err != nil {
+			return Filter{}, err
+		}
+		endAt = parsedDate
 	}
 
 	return Filter{
@@ -193,10 +529,249 @@ func ToParseFilterRequest(r *http.Request) (Filter, error) {
 		Target:   target,
 		StartAt:  startAt,
 		EndAt:    endAt,
-		Filter:   page,
+
+		DateField: dateField,
+		SortBy:    sortBy,
+		ClockSkew: clockSkew,
+		Labels:    labels,
+		Archived:  archived,
 	}, nil
 }
 
+// toLabelFilter reads the label.<key>=<value> params, a key can be repeated to accept several values
+func toLabelFilter(query url.Values) (map[string][]string, error) {
+	var labels map[string][]string
+
+	for param, values := range query {
+		key, found := strings.CutPrefix(param, LabelParamPrefix)
+		if !found {
+			continue
+		}
+
+		if !labelKeyPattern.MatchString(key) {
+			return nil, terrors.New(terrors.ErrBadRequest, "Invalid label filter "+param, map[string]string{})
+		}
+
+		if labels == nil {
+			labels = make(map[string][]string)
+		}
+		labels[key] = append(labels[key], values...)
+	}
+
+	return labels, nil
+}
+
+// ValidateLabels validates the labels of a log against the configured limits
+func ValidateLabels(labels map[string]string, limits config.LabelsConfigurations) error {
+	maxCount := limitOrDefault(limits.MaxCount, defaultMaxLabels)
+	maxKeyLength := limitOrDefault(limits.MaxKeyLength, defaultMaxLabelKeyLength)
+	maxValueLength := limitOrDefault(limits.MaxValueLength, defaultMaxLabelValueLength)
+
+	if len(labels) > maxCount {
+		return terrors.BadRequest(terrors.ErrBadRequest, "Too many labels, the maximum is "+strconv.Itoa(maxCount), map[string]string{})
+	}
+
+	for key, value := range labels {
+		if len(key) > maxKeyLength || !labelKeyPattern.MatchString(key) {
+			return terrors.BadRequest(terrors.ErrBadRequest, "Invalid label key "+text.Truncate(key, maxKeyLength), map[string]string{})
+		}
+
+		if len(value) > maxValueLength {
+			return terrors.BadRequest(terrors.ErrBadRequest, "Label "+key+" exceeds "+strconv.Itoa(maxValueLength)+" bytes", map[string]string{})
+		}
+	}
+
+	return nil
+}
+
+// ToLabelFacets groups the label value counts by label key keeping the repository order
+func ToLabelFacets(facets []logs.LabelFacet) []LabelFacet {
+	res := []LabelFacet{}
+
+	for _, facet := range facets {
+		if len(res) == 0 || res[len(res)-1].Key != facet.Key {
+			res = append(res, LabelFacet{Key: facet.Key, Values: []FacetValue{}})
+		}
+
+		last := &res[len(res)-1]
+		last.Values = append(last.Values, FacetValue{Value: facet.Value, Count: facet.Count})
+	}
+
+	return res
+}
+
+func limitOrDefault(limit, def int) int {
+	if limit <= 0 {
+		return def
+	}
+
+	return limit
+}
+
+// VerifyLink validates the stored hash of the entry and its link to the previous entry, previous is nil
+// for the first entry of a chain or when the previous entry no longer exists
+func VerifyLink(previous, entry *logs.Model) *BrokenLink {
+	if previous != nil {
+		if entry.ChainSeq != previous.ChainSeq+1 {
+			return &BrokenLink{
+				ID:       entry.ID,
+				Seq:      entry.ChainSeq,
+				Reason:   ReasonMissingEntry,
+				Expected: strconv.FormatInt(previous.ChainSeq+1, 10),
+				Actual:   strconv.FormatInt(entry.ChainSeq, 10),
+			}
+		}
+
+		if entry.PrevHash != previous.Hash {
+			return &BrokenLink{ID: entry.ID, Seq: entry.ChainSeq, Reason: ReasonPrevHashMismatch, Expected: previous.Hash, Actual: entry.PrevHash}
+		}
+	} else if entry.ChainSeq == 1 && entry.PrevHash != "" {
+		return &BrokenLink{ID: entry.ID, Seq: entry.ChainSeq, Reason: ReasonPrevHashMismatch, Expected: "", Actual: entry.PrevHash}
+	}
+
+	hash, err := logs.ComputeHash(entry)
+	if err != nil || hash != entry.Hash {
+		return &BrokenLink{ID: entry.ID, Seq: entry.ChainSeq, Reason: ReasonHashMismatch, Expected: hash, Actual: entry.Hash}
+	}
+
+	return nil
+}
+
+// BridgePurges links previous to the purged ranges following it, it returns the last purged link to check the next
+// entry against and the number of entries purged. previous is returned unchanged when the ranges do not follow it
+// seamlessly so the next entry is reported as missing
+func BridgePurges(previous *logs.Model, purges []logs.ChainPurge) (*logs.Model, int64) {
+	link := previous
+	var purged int64
+	for _, purge := range purges {
+		if link != nil && (purge.FromSeq != link.ChainSeq+1 || purge.PrevHash != link.Hash) {
+			return previous, 0
+		}
+
+		link = &logs.Model{ChainTenantID: purge.TenantID, ChainSeq: purge.ToSeq, Hash: purge.Hash}
+		purged += purge.ToSeq - purge.FromSeq + 1
+	}
+
+	return link, purged
+}
+
+// ToParseVerifyRequest parses the tenant_id, from and to params of a chain verification
+func ToParseVerifyRequest(r *http.Request) (VerifyFilter, error) {
+	query := r.URL.Query()
+
+	tenantID, err := strconv.Atoi(query.Get("tenant_id"))
+	if err != nil {
+		return VerifyFilter{}, terrors.New(terrors.ErrBadRequest, "Invalid tenant_id param", map[string]string{})
+	}
+
+	from, err := toQueryTime(query.Get("from"))
+	if err != nil {
+		return VerifyFilter{}, terrors.New(terrors.ErrBadRequest, "Invalid from param", map[string]string{})
+	}
+
+	to, err := toQueryTime(query.Get("to"))
+	if err != nil {
+		return VerifyFilter{}, terrors.New(terrors.ErrBadRequest, "Invalid to param", map[string]string{})
+	}
+
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		return VerifyFilter{}, terrors.New(terrors.ErrBadRequest, "from must be before to", map[string]string{})
+	}
+
+	return VerifyFilter{TenantID: tenantID, From: from, To: to}, nil
+}
+
+// toQueryTime parses the filters date format or RFC 3339, an empty value is a zero time
+func toQueryTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if parsed, err := time.Parse("2006-01-02T15:04:05", value); err == nil {
+		return parsed, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return parsed.UTC(), nil
+}
+
+// toTimeField validates the time column requested by a filter, the repository uses created_at when empty
+func toTimeField(field string) (string, error) {
+	switch field {
+	case "", FieldCreatedAt, FieldOccurredAt:
+		return field, nil
+	default:
+		return "", terrors.New(terrors.ErrBadRequest, "Invalid time field "+field, map[string]string{})
+	}
+}
+
+// IsClockSkewed validates whether the producer time of a log differs from its ingestion time by more than the threshold,
+// a zero threshold disables the check
+func IsClockSkewed(model *logs.Model, threshold time.Duration) bool {
+	if threshold <= 0 || model.OccurredAt == nil || model.CreatedAt == nil {
+		return false
+	}
+
+	skew := model.CreatedAt.Sub(*model.OccurredAt)
+	if skew < 0 {
+		skew = -skew
+	}
+
+	return skew > threshold
+}
+
+// ToEventOccurredAt reads the producer time of a kafka event, it uses occurred_at from the event data
+// and falls back to the event timestamp
+func ToEventOccurredAt(event oevents.OmniViewEvent) *time.Time {
+	for _, key := range []string{"OccurredAt", "occurred_at"} {
+		if occurredAt := toTime(event.Data[key]); occurredAt != nil {
+			return occurredAt
+		}
+	}
+
+	return toTime(event.Timestamp)
+}
+
+func toTime(value interface{}) *time.Time {
+	var res time.Time
+
+	switch v := value.(type) {
+	case time.Time:
+		res = v
+	case *time.Time:
+		if v == nil {
+			return nil
+		}
+		res = *v
+	case string:
+		parsed, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return nil
+		}
+		res = parsed
+	case float64:
+		// Unix epoch in milliseconds, as produced by json encoders
+		res = time.UnixMilli(int64(v))
+	case int64:
+		res = time.UnixMilli(v)
+	case int:
+		res = time.UnixMilli(int64(v))
+	default:
+		return nil
+	}
+
+	if res.IsZero() {
+		return nil
+	}
+	res = res.UTC()
+
+	return &res
+}
+
 func strArrToIntArr(strArr []string) ([]int, error) {
 	var intArray []int
 	for _, str := range strArr {
@@ -226,3 +801,217 @@ func payloadToTenantIds(payload *Payload) ([]byte, error) {
 		return []byte{}, nil
 	}
 }
+
+// ToParseStatsRequest parses the stats filter, the search params of the logs with granularity and group_by
+func ToParseStatsRequest(r *http.Request) (StatsFilter, error) {
+	query := r.URL.Query()
+
+	filter, err := toSearchFilter(query)
+	if err != nil {
+		return StatsFilter{}, err
+	}
+
+	granularity := query.Get("granularity")
+	if granularity == "" {
+		granularity = logs.GranularityHour
+	}
+
+	return StatsFilter{
+		Filter:      filter,
+		Granularity: granularity,
+		GroupBy:     query.Get("group_by"),
+	}, nil
+}
+
+// ToParseDashboardRequest parses the dashboard filter and the number of values listed per group
+func ToParseDashboardRequest(r *http.Request) (StatsFilter, int, error) {
+	filter, err := ToParseStatsRequest(r)
+	if err != nil {
+		return StatsFilter{}, 0, err
+	}
+
+	top := defaultDashboardTop
+	if value := r.URL.Query().Get("top"); value != "" {
+		top, err = strconv.Atoi(value)
+		if err != nil || top < 1 {
+			return StatsFilter{}, 0, terrors.New(terrors.ErrBadRequest, "Invalid top param", map[string]string{})
+		}
+	}
+
+	return filter, top, nil
+}
+
+// ValidateStats validates the range, the granularity and the group of the stats
+func ValidateStats(filter StatsFilter) error {
+	if filter.StartAt.IsZero() || filter.EndAt.IsZero() {
+		return terrors.New(terrors.ErrBadRequest, "start_at and end_at are required", map[string]string{})
+	}
+
+	if !filter.EndAt.After(filter.StartAt) {
+		return terrors.New(terrors.ErrBadRequest, "end_at must be after start_at", map[string]string{})
+	}
+
+	step, ok := statsSteps[filter.Granularity]
+	if !ok {
+		return terrors.New(terrors.ErrBadRequest, "Invalid granularity "+filter.Granularity, map[string]string{})
+	}
+
+	if filter.EndAt.Sub(filter.StartAt)/step > maxStatsBuckets {
+		return terrors.New(terrors.ErrBadRequest, "Too many buckets, use a coarser granularity or a shorter range", map[string]string{})
+	}
+
+	switch filter.GroupBy {
+	case "", StatsGroupTenant, StatsGroupLevel, StatsGroupProvider, StatsGroupAction, StatsGroupResource, StatsGroupMessage:
+	default:
+		return terrors.New(terrors.ErrBadRequest, "Invalid group_by "+filter.GroupBy, map[string]string{})
+	}
+
+	if filter.Archived {
+		return terrors.New(terrors.ErrBadRequest, "Stats of archived logs are not supported", map[string]string{})
+	}
+
+	return nil
+}
+
+// statsSteps length of the buckets of every granularity
+var statsSteps = map[string]time.Duration{
+	logs.GranularityMinute: time.Minute,
+	logs.GranularityHour:   time.Hour,
+	logs.GranularityDay:    24 * time.Hour,
+}
+
+// statsSegment part of the range of the stats counted from the rollups or from the logs
+type statsSegment struct {
+	From   time.Time
+	To     time.Time
+	Rollup bool
+}
+
+// rollupCompatible tells whether the rollups hold every dimension the filter narrows on
+func rollupCompatible(filter StatsFilter) bool {
+	if filter.Granularity != logs.GranularityHour && filter.Granularity != logs.GranularityDay {
+		return false
+	}
+
+	return filter.Path == "" &&
+		len(filter.UserID) == 0 &&
+		len(filter.Target) == 0 &&
+		len(filter.Labels) == 0 &&
+		filter.ClockSkew == nil &&
+		(filter.DateField == "" || filter.DateField == FieldCreatedAt)
+}
+
+// statsSegments splits the range of the stats, the whole buckets rolled up before rolledUntil are read from the
+// rollups and the partial buckets at both ends and the buckets not rolled up yet from the logs
+func statsSegments(start, end time.Time, step time.Duration, rolledUntil time.Time, rollup bool) []statsSegment {
+	from := start.Truncate(step)
+	if from.Before(start) {
+		from = from.Add(step)
+	}
+
+	to := end.Truncate(step)
+	if rolledUntil.Before(to) {
+		to = rolledUntil.Truncate(step)
+	}
+
+	if !rollup || !from.Before(to) {
+		return []statsSegment{{From: start, To: end}}
+	}
+
+	var segments []statsSegment
+	if start.Before(from) {
+		segments = append(segments, statsSegment{From: start, To: from})
+	}
+	segments = append(segments, statsSegment{From: from, To: to, Rollup: true})
+	if to.Before(end) {
+		segments = append(segments, statsSegment{From: to, To: end})
+	}
+
+	return segments
+}
+
+// statsSource tells where the stats of the segments were read from
+func statsSource(segments []statsSegment) string {
+	rollups := 0
+	for _, segment := range segments {
+		if segment.Rollup {
+			rollups++
+		}
+	}
+
+	switch rollups {
+	case 0:
+		return StatsSourceLogs
+	case len(segments):
+		return StatsSourceRollups
+	default:
+		return StatsSourceMixed
+	}
+}
+
+// ToRepoStatsFilter maps the stats filter of a segment
+func ToRepoStatsFilter(filter StatsFilter, segment statsSegment) logs.StatsFilter {
+	repoFilter := ToRepoFilter(filter.Filter)
+	repoFilter.StartAt = segment.From
+	repoFilter.EndAt = segment.To
+
+	return logs.StatsFilter{
+		Filter:      repoFilter,
+		Granularity: filter.Granularity,
+		GroupBy:     filter.GroupBy,
+		Rollup:      segment.Rollup,
+	}
+}
+
+// mergeStatsPoints adds up the points of the same bucket and key, sorted by bucket then key
+func mergeStatsPoints(points []logs.StatsPoint) []StatsPoint {
+	type pointKey struct {
+		bucket time.Time
+		key    string
+	}
+
+	counts := make(map[pointKey]int64, len(points))
+	for _, point := range points {
+		counts[pointKey{bucket: point.Bucket.UTC(), key: point.Key}] += point.Count
+	}
+
+	merged := make([]StatsPoint, 0, len(counts))
+	for key, count := range counts {
+		merged = append(merged, StatsPoint{Bucket: key.bucket, Key: key.key, Count: count})
+	}
+
+	slices.SortFunc(merged, func(a, b StatsPoint) int {
+		if c := a.Bucket.Compare(b.Bucket); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	return merged
+}
+
+// topCounts adds up the points per key and keeps the top most frequent keys
+func topCounts(points []StatsPoint, top int) []DashboardCount {
+	totals := make(map[string]int64)
+	for _, point := range points {
+		totals[point.Key] += point.Count
+	}
+
+	counts := make([]DashboardCount, 0, len(totals))
+	for key, count := range totals {
+		counts = append(counts, DashboardCount{Key: key, Count: count})
+	}
+
+	slices.SortFunc(counts, func(a, b DashboardCount) int {
+		if a.Count != b.Count {
+			return cmp.Compare(b.Count, a.Count)
+		}
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	if len(counts) > top {
+		counts = counts[:top]
+	}
+
+	return counts
+}
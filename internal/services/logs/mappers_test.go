@@ -1,14 +1,18 @@
 package logs
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/url"
 	"testing"
 	"time"
 
+	"github.com/jmontesinos91/oevents"
+	"github.com/jmontesinos91/omnilogger/config"
 	"github.com/jmontesinos91/omnilogger/domains/pagination"
 	"github.com/jmontesinos91/omnilogger/internal/repositories/log_message"
 	"github.com/jmontesinos91/omnilogger/internal/repositories/logs"
+	"github.com/jmontesinos91/terrors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -88,6 +92,46 @@ func TestToModel(t *testing.T) {
 				UserID:     "12",
 			}},
 		},
+		{
+			name: "With server observed origin",
+			args: args{
+				payload: &Payload{
+					IpAddress:  "10.0.0.1",
+					ClientHost: "spoofed-host",
+					Provider:   "TestProvider",
+					Level:      1,
+					Message:    100,
+					Path:       "/v1/resource",
+					Resource:   "RESOURCE",
+					Action:     "CREATE",
+					Data:       "{}",
+					UserID:     "12",
+					Origin: &Origin{
+						IP:           "203.0.113.7",
+						ForwardedFor: "203.0.113.7, 172.16.0.2",
+						Subject:      "user:12",
+						TenantID:     []int{1, 2},
+					},
+				},
+			},
+			empty: false,
+			expected: expected{model: &logs.Model{
+				IpAddress:          "10.0.0.1",
+				ClientHost:         "spoofed-host",
+				Provider:           "TestProvider",
+				Level:              1,
+				Message:            100,
+				Path:               "/v1/resource",
+				Resource:           "RESOURCE",
+				Action:             "CREATE",
+				Data:               "{}",
+				UserID:             "12",
+				OriginIP:           "203.0.113.7",
+				OriginForwardedFor: "203.0.113.7, 172.16.0.2",
+				OriginSubject:      "user:12",
+				OriginTenantID:     "[1,2]",
+			}},
+		},
 	}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -105,6 +149,10 @@ func TestToModel(t *testing.T) {
 				assert.Equal(t, tc.expected.model.Action, result.Action)
 				assert.Equal(t, tc.expected.model.Data, result.Data)
 				assert.Equal(t, tc.expected.model.UserID, result.UserID)
+				assert.Equal(t, tc.expected.model.OriginIP, result.OriginIP)
+				assert.Equal(t, tc.expected.model.OriginForwardedFor, result.OriginForwardedFor)
+				assert.Equal(t, tc.expected.model.OriginSubject, result.OriginSubject)
+				assert.Equal(t, tc.expected.model.OriginTenantID, result.OriginTenantID)
 				assert.NoError(t, err)
 			} else {
 				assert.NotNil(t, result)
@@ -499,3 +547,429 @@ func TestToParseFilterRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestToOrigin(t *testing.T) {
+	assert.Nil(t, ToOrigin(&logs.Model{IpAddress: "10.0.0.1"}))
+
+	origin := ToOrigin(&logs.Model{
+		OriginIP:       "203.0.113.7",
+		OriginSubject:  "api_key:key-1",
+		OriginTenantID: "[7]",
+	})
+	assert.Equal(t, &Origin{IP: "203.0.113.7", Subject: "api_key:key-1", TenantID: []int{7}}, origin)
+}
+
+func TestToModel_OccurredAt(t *testing.T) {
+	occurredAt := time.Date(2024, 1, 1, 10, 0, 0, 0, time.FixedZone("CST", -6*3600))
+
+	model, err := ToModel(&Payload{OccurredAt: &occurredAt})
+	assert.NoError(t, err)
+	assert.Equal(t, occurredAt.UTC(), *model.OccurredAt)
+	assert.Equal(t, time.UTC, model.OccurredAt.Location())
+
+	model, err = ToModel(&Payload{})
+	assert.NoError(t, err)
+	assert.Equal(t, model.CreatedAt, model.OccurredAt)
+}
+
+func TestIsClockSkewed(t *testing.T) {
+	createdAt := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	before := createdAt.Add(-10 * time.Minute)
+	after := createdAt.Add(10 * time.Minute)
+
+	assert.False(t, IsClockSkewed(&logs.Model{CreatedAt: &createdAt, OccurredAt: &before}, 0))
+	assert.False(t, IsClockSkewed(&logs.Model{CreatedAt: &createdAt, OccurredAt: &before}, time.Hour))
+	assert.True(t, IsClockSkewed(&logs.Model{CreatedAt: &createdAt, OccurredAt: &before}, time.Minute))
+	assert.True(t, IsClockSkewed(&logs.Model{CreatedAt: &createdAt, OccurredAt: &after}, time.Minute))
+	assert.False(t, IsClockSkewed(&logs.Model{CreatedAt: &createdAt}, time.Minute))
+}
+
+func TestToEventOccurredAt(t *testing.T) {
+	expected := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name     string
+		event    oevents.OmniViewEvent
+		expected *time.Time
+	}{
+		{
+			name:     "RFC3339 occurred_at",
+			event:    oevents.OmniViewEvent{Data: map[string]interface{}{"occurred_at": "2024-01-01T04:00:00-06:00"}, Timestamp: "2024-02-01T00:00:00Z"},
+			expected: &expected,
+		},
+		{
+			name:     "Epoch milliseconds OccurredAt",
+			event:    oevents.OmniViewEvent{Data: map[string]interface{}{"OccurredAt": float64(expected.UnixMilli())}},
+			expected: &expected,
+		},
+		{
+			name:     "Event timestamp fallback",
+			event:    oevents.OmniViewEvent{Data: map[string]interface{}{"occurred_at": "yesterday"}, Timestamp: "2024-01-01T10:00:00Z"},
+			expected: &expected,
+		},
+		{
+			name:  "No time",
+			event: oevents.OmniViewEvent{Data: map[string]interface{}{}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, ToEventOccurredAt(tc.event))
+		})
+	}
+}
+
+func TestToParseFilterRequest_TimeFields(t *testing.T) {
+	cases := []struct {
+		name      string
+		query     string
+		dateField string
+		sortBy    string
+		clockSkew *bool
+		err       bool
+	}{
+		{name: "Defaults", query: "max=10&page=1"},
+		{name: "Occurred at", query: "max=10&page=1&date_field=occurred_at&sort_by=occurred_at&clock_skew=true", dateField: FieldOccurredAt, sortBy: FieldOccurredAt, clockSkew: func() *bool { b := true; return &b }()},
+		{name: "Invalid date field", query: "max=10&page=1&date_field=updated_at", err: true},
+		{name: "Invalid clock skew", query: "max=10&page=1&clock_skew=maybe", err: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := &http.Request{URL: &url.URL{RawQuery: tc.query}}
+			filter, err := ToParseFilterRequest(req)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.dateField, filter.DateField)
+			assert.Equal(t, tc.sortBy, filter.SortBy)
+			assert.Equal(t, tc.clockSkew, filter.ClockSkew)
+		})
+	}
+}
+
+func TestToParseFilterRequest_Archived(t *testing.T) {
+	cases := []struct {
+		name     string
+		query    string
+		archived bool
+		err      bool
+	}{
+		{name: "Live logs", query: "max=10&page=1"},
+		{name: "Archived logs", query: "max=10&page=1&archived=true", archived: true},
+		{name: "Invalid archived", query: "max=10&page=1&archived=maybe", err: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := &http.Request{URL: &url.URL{RawQuery: tc.query}}
+			filter, err := ToParseFilterRequest(req)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.archived, filter.Archived)
+			assert.Equal(t, tc.archived, ToRepoFilter(filter).Archived)
+		})
+	}
+}
+
+func TestToParseExportRequest(t *testing.T) {
+	cases := []struct {
+		name     string
+		query    string
+		expected ExportOptions
+		err      bool
+	}{
+		{name: "Default xlsx", query: "max=10&page=1", expected: ExportOptions{Format: ExportFormatXLSX}},
+		{name: "Default csv", query: "max=10&page=1&format=csv", expected: ExportOptions{Format: ExportFormatCSV, Delimiter: ','}},
+		{name: "Csv for Excel", query: "max=10&page=1&format=CSV&delimiter=%3B&encoding=utf-8-bom", expected: ExportOptions{Format: ExportFormatCSV, Delimiter: ';', BOM: true}},
+		{name: "Tab separated", query: "max=10&page=1&format=csv&delimiter=tab", expected: ExportOptions{Format: ExportFormatCSV, Delimiter: '\t'}},
+		{name: "NDJSON", query: "max=10&page=1&format=ndjson", expected: ExportOptions{Format: ExportFormatNDJSON}},
+		{name: "Gzip JSON", query: "max=10&page=1&format=json&compress=gzip", expected: ExportOptions{Format: ExportFormatJSON, Compress: true}},
+		{name: "Invalid format", query: "max=10&page=1&format=pdf", err: true},
+		{name: "Invalid compress", query: "max=10&page=1&format=json&compress=zstd", err: true},
+		{name: "Invalid max", query: "max=0&page=1", err: true},
+		{name: "Invalid page", query: "max=10&page=x", err: true},
+		{name: "Invalid delimiter", query: "max=10&page=1&format=csv&delimiter=%22", err: true},
+		{name: "Long delimiter", query: "max=10&page=1&format=csv&delimiter=ab", err: true},
+		{name: "Invalid encoding", query: "max=10&page=1&format=csv&encoding=latin1", err: true},
+		{name: "Columns", query: "columns=createdAt,USERID,%20action,label.env", expected: ExportOptions{Format: ExportFormatXLSX, Columns: []string{"createdAt", "userId", "action", "label.env"}}},
+		{name: "Repeated columns param", query: "format=csv&columns=id&columns=level", expected: ExportOptions{Format: ExportFormatCSV, Delimiter: ',', Columns: []string{"id", "level"}}},
+		{name: "Unknown column", query: "columns=id,password", err: true},
+		{name: "Invalid label column", query: "columns=label.", err: true},
+		{name: "Duplicated column", query: "columns=id,ID", err: true},
+		{name: "Columns of a json export", query: "format=ndjson&columns=id", err: true},
+		{name: "Expanded data with summary", query: "expand=DATA&summary=true", expected: ExportOptions{Format: ExportFormatXLSX, ExpandData: true, Summary: true}},
+		{name: "Expanded csv data", query: "format=csv&expand=data&summary=false", expected: ExportOptions{Format: ExportFormatCSV, Delimiter: ',', ExpandData: true}},
+		{name: "Invalid expand", query: "expand=labels", err: true},
+		{name: "Expanded data of a json export", query: "format=json&expand=data", err: true},
+		{name: "Invalid summary", query: "summary=maybe", err: true},
+		{name: "Summary of a csv export", query: "format=csv&summary=true", err: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := &http.Request{URL: &url.URL{RawQuery: tc.query}}
+			_, opts, err := ToParseExportRequest(req)
+			if tc.err {
+				assert.True(t, terrors.Is(err, terrors.ErrBadRequest))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, opts)
+		})
+	}
+
+	// Without max every log is exported, in english by default
+	filter, _, err := ToParseExportRequest(&http.Request{URL: &url.URL{RawQuery: "format=ndjson&level[]=3"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"3"}, filter.Level)
+	assert.Equal(t, "en", filter.Lang)
+	assert.Equal(t, 0, ToRepoFilter(filter).Size)
+	assert.Equal(t, 1, ToRepoFilter(filter).From)
+
+	filter, _, err = ToParseExportRequest(&http.Request{URL: &url.URL{RawQuery: "max=50&lang=es"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "es", filter.Lang)
+	assert.Equal(t, 50, ToRepoFilter(filter).Size)
+	assert.Equal(t, 1, ToRepoFilter(filter).From)
+}
+
+func TestToExportRecord(t *testing.T) {
+	record := ToExportRecord(Response{ID: "1", Data: `{"name":"a"}`, OldData: "not json"})
+
+	raw, err := json.Marshal(record)
+	assert.NoError(t, err)
+
+	var decoded map[string]interface{}
+	assert.NoError(t, json.Unmarshal(raw, &decoded))
+	assert.Equal(t, "1", decoded["id"])
+	assert.Equal(t, map[string]interface{}{"name": "a"}, decoded["data"])
+	assert.Equal(t, "not json", decoded["oldData"])
+
+	record = ToExportRecord(Response{ID: "2"})
+	assert.Equal(t, json.RawMessage("null"), record.Data)
+	assert.Equal(t, json.RawMessage("null"), record.OldData)
+}
+
+func TestToModel_Labels(t *testing.T) {
+	model, err := ToModel(&Payload{Labels: map[string]string{"env": "prod"}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "prod"}, model.Labels)
+	assert.Equal(t, map[string]string{"env": "prod"}, ToResponse(model, "en").Labels)
+
+	// Logs without labels are stored with an empty object
+	model, err = ToModel(&Payload{})
+	assert.NoError(t, err)
+	assert.NotNil(t, model.Labels)
+	assert.Empty(t, model.Labels)
+}
+
+func TestValidateLabels(t *testing.T) {
+	limits := config.LabelsConfigurations{MaxCount: 2, MaxKeyLength: 5, MaxValueLength: 4}
+
+	cases := []struct {
+		name   string
+		labels map[string]string
+		limits config.LabelsConfigurations
+		err    bool
+	}{
+		{name: "No labels", labels: nil, limits: limits},
+		{name: "Within limits", labels: map[string]string{"env": "prod", "team": "ops"}, limits: limits},
+		{name: "Too many labels", labels: map[string]string{"a": "1", "b": "2", "c": "3"}, limits: limits, err: true},
+		{name: "Key too long", labels: map[string]string{"feature": "x"}, limits: limits, err: true},
+		{name: "Invalid key", labels: map[string]string{"a=b": "x"}, limits: limits, err: true},
+		{name: "Empty key", labels: map[string]string{"": "x"}, limits: limits, err: true},
+		{name: "Value too long", labels: map[string]string{"env": "staging"}, limits: limits, err: true},
+		{name: "Default limits", labels: map[string]string{"ticket": "OPS-1234", "k8s.io/app": "omnilogger"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateLabels(tc.labels, tc.limits)
+			if tc.err {
+				assert.True(t, terrors.Is(err, terrors.ErrBadRequest))
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestToParseFilterRequest_Labels(t *testing.T) {
+	cases := []struct {
+		name   string
+		query  string
+		labels map[string][]string
+		err    bool
+	}{
+		{name: "No labels", query: "max=10&page=1"},
+		{name: "Single label", query: "max=10&page=1&label.env=prod", labels: map[string][]string{"env": {"prod"}}},
+		{
+			name:   "Several values and keys",
+			query:  "max=10&page=1&label.env=prod&label.env=staging&label.ticket=OPS-1",
+			labels: map[string][]string{"env": {"prod", "staging"}, "ticket": {"OPS-1"}},
+		},
+		{name: "Invalid key", query: "max=10&page=1&label.=prod", err: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := &http.Request{URL: &url.URL{RawQuery: tc.query}}
+			filter, err := ToParseFilterRequest(req)
+			if tc.err {
+				assert.True(t, terrors.Is(err, terrors.ErrBadRequest))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.labels, filter.Labels)
+			assert.Equal(t, tc.labels, ToRepoFilter(filter).Labels)
+		})
+	}
+}
+
+func TestToLabelFacets(t *testing.T) {
+	res := ToLabelFacets([]logs.LabelFacet{
+		{Key: "env", Value: "prod", Count: 10},
+		{Key: "env", Value: "staging", Count: 3},
+		{Key: "team", Value: "ops", Count: 7},
+	})
+
+	assert.Equal(t, []LabelFacet{
+		{Key: "env", Values: []FacetValue{{Value: "prod", Count: 10}, {Value: "staging", Count: 3}}},
+		{Key: "team", Values: []FacetValue{{Value: "ops", Count: 7}}},
+	}, res)
+	assert.Equal(t, []LabelFacet{}, ToLabelFacets(nil))
+}
+
+func TestToParseVerifyRequest(t *testing.T) {
+	cases := []struct {
+		name     string
+		query    string
+		expected VerifyFilter
+		err      bool
+	}{
+		{name: "Tenant only", query: "tenant_id=3", expected: VerifyFilter{TenantID: 3}},
+		{
+			name:  "Range",
+			query: "tenant_id=3&from=2024-01-01T00:00:00&to=2024-01-02T10:00:00-06:00",
+			expected: VerifyFilter{
+				TenantID: 3,
+				From:     time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				To:       time.Date(2024, 1, 2, 16, 0, 0, 0, time.UTC),
+			},
+		},
+		{name: "Missing tenant", query: "from=2024-01-01T00:00:00", err: true},
+		{name: "Invalid from", query: "tenant_id=3&from=yesterday", err: true},
+		{name: "Inverted range", query: "tenant_id=3&from=2024-01-02T00:00:00&to=2024-01-01T00:00:00", err: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := &http.Request{URL: &url.URL{RawQuery: tc.query}}
+			filter, err := ToParseVerifyRequest(req)
+			if tc.err {
+				assert.True(t, terrors.Is(err, terrors.ErrBadRequest))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, filter)
+		})
+	}
+}
+
+func TestStatsSegments(t *testing.T) {
+	hour := func(h, m int) time.Time { return time.Date(2024, 1, 1, h, m, 0, 0, time.UTC) }
+
+	cases := []struct {
+		name        string
+		start       time.Time
+		end         time.Time
+		rolledUntil time.Time
+		rollup      bool
+		expected    []statsSegment
+	}{
+		{
+			name:        "Aligned and rolled up",
+			start:       hour(0, 0),
+			end:         hour(3, 0),
+			rolledUntil: hour(5, 0),
+			rollup:      true,
+			expected:    []statsSegment{{From: hour(0, 0), To: hour(3, 0), Rollup: true}},
+		},
+		{
+			name:        "Partial buckets and tail not rolled up",
+			start:       hour(0, 15),
+			end:         hour(6, 45),
+			rolledUntil: hour(4, 0),
+			rollup:      true,
+			expected: []statsSegment{
+				{From: hour(0, 15), To: hour(1, 0)},
+				{From: hour(1, 0), To: hour(4, 0), Rollup: true},
+				{From: hour(4, 0), To: hour(6, 45)},
+			},
+		},
+		{
+			name:     "Nothing rolled up",
+			start:    hour(0, 0),
+			end:      hour(3, 0),
+			rollup:   true,
+			expected: []statsSegment{{From: hour(0, 0), To: hour(3, 0)}},
+		},
+		{
+			name:        "Rollups not usable",
+			start:       hour(0, 0),
+			end:         hour(3, 0),
+			rolledUntil: hour(5, 0),
+			expected:    []statsSegment{{From: hour(0, 0), To: hour(3, 0)}},
+		},
+		{
+			name:        "Inside one bucket",
+			start:       hour(1, 10),
+			end:         hour(1, 50),
+			rolledUntil: hour(5, 0),
+			rollup:      true,
+			expected:    []statsSegment{{From: hour(1, 10), To: hour(1, 50)}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, statsSegments(tc.start, tc.end, time.Hour, tc.rolledUntil, tc.rollup))
+		})
+	}
+}
+
+func TestRollupCompatible(t *testing.T) {
+	clockSkew := true
+
+	assert.True(t, rollupCompatible(StatsFilter{Filter: Filter{TenantID: []int{1}, Level: []string{"3"}, Resource: "user"}, Granularity: "day"}))
+	assert.False(t, rollupCompatible(StatsFilter{Granularity: "minute"}))
+	assert.False(t, rollupCompatible(StatsFilter{Filter: Filter{UserID: []string{"7"}}, Granularity: "hour"}))
+	assert.False(t, rollupCompatible(StatsFilter{Filter: Filter{ClockSkew: &clockSkew}, Granularity: "hour"}))
+	assert.False(t, rollupCompatible(StatsFilter{Filter: Filter{DateField: FieldOccurredAt}, Granularity: "hour"}))
+}
+
+func TestToParseDashboardRequest(t *testing.T) {
+	req := &http.Request{URL: &url.URL{RawQuery: "start_at=2024-01-01T00:00:00&end_at=2024-01-08T00:00:00&granularity=day&level[]=3&top=5"}}
+	filter, top, err := ToParseDashboardRequest(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5, top)
+	assert.Equal(t, "day", filter.Granularity)
+	assert.Equal(t, []string{"3"}, filter.Level)
+	assert.NoError(t, ValidateStats(filter))
+
+	_, _, err = ToParseDashboardRequest(&http.Request{URL: &url.URL{RawQuery: "top=0"}})
+	assert.Error(t, err)
+
+	filter, err = ToParseStatsRequest(&http.Request{URL: &url.URL{RawQuery: "start_at=2024-01-01T00:00:00&end_at=2024-01-02T00:00:00&group_by=path"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "hour", filter.Granularity)
+	assert.Error(t, ValidateStats(filter))
+}
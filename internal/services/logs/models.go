@@ -1,9 +1,11 @@
 package logs
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/jmontesinos91/omnilogger/domains/pagination"
+	"github.com/jmontesinos91/omnilogger/internal/utils/export"
 )
 
 // Payload payload example
@@ -23,27 +25,65 @@ type Payload struct {
 	UserID      string `json:"user_id" validate:"required"`
 	Target      string `json:"target" validate:"required"`
 	Lang        string `json:"lang"`
+
+	// OccurredAt time the event happened according to the producer, defaults to the ingestion time
+	OccurredAt *time.Time `json:"occurred_at"`
+
+	// Labels free-form key/value pairs such as environment, feature or ticket
+	Labels map[string]string `json:"labels"`
+
+	// Origin is set by the server, it is never read from the request body
+	Origin *Origin `json:"-"`
+}
+
+// Origin Holds the origin of a log as observed by the server
+type Origin struct {
+	IP           string `json:"ip,omitempty"`
+	ForwardedFor string `json:"forwardedFor,omitempty"`
+	Subject      string `json:"subject,omitempty"`
+	TenantID     []int  `json:"tenantId,omitempty"`
 }
 
 // Response Holds the response for a created payout
 type Response struct {
-	ID          string      `json:"id"`
-	IpAddress   string      `json:"ipAddress"`
-	ClientHost  string      `json:"clientHost"`
-	Provider    string      `json:"provider"`
-	Level       int         `json:"level"`
-	Message     int         `json:"message"`
-	Description string      `json:"description"`
-	Path        string      `json:"path"`
-	Resource    string      `json:"resource"`
-	Action      string      `json:"action"`
-	Data        string      `json:"data"`
-	OldData     string      `json:"oldData"`
-	TenantCat   string      `json:"tenantCat"`
-	UserID      string      `json:"userId"`
-	Target      string      `json:"target"`
-	CreatedAt   *time.Time  `json:"createdAt,omitempty"`
-	LogMessage  interface{} `json:"logMessage"`
+	ID          string            `json:"id"`
+	IpAddress   string            `json:"ipAddress"`
+	ClientHost  string            `json:"clientHost"`
+	Provider    string            `json:"provider"`
+	Level       int               `json:"level"`
+	Message     int               `json:"message"`
+	Description string            `json:"description"`
+	Path        string            `json:"path"`
+	Resource    string            `json:"resource"`
+	Action      string            `json:"action"`
+	Data        string            `json:"data"`
+	OldData     string            `json:"oldData"`
+	TenantCat   string            `json:"tenantCat"`
+	UserID      string            `json:"userId"`
+	Target      string            `json:"target"`
+	CreatedAt   *time.Time        `json:"createdAt,omitempty"`
+	OccurredAt  *time.Time        `json:"occurredAt,omitempty"`
+	ClockSkew   bool              `json:"clockSkew"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	LogMessage  interface{}       `json:"logMessage"`
+	Origin      *Origin           `json:"origin,omitempty"`
+	ChainSeq    int64             `json:"chainSeq,omitempty"`
+	PrevHash    string            `json:"prevHash,omitempty"`
+	Hash        string            `json:"hash,omitempty"`
+	LegalHold   *LegalHoldStatus  `json:"legalHold,omitempty"`
+	Archived    bool              `json:"archived,omitempty"`
+}
+
+// LegalHoldStatus tells whether a log is under legal hold, held logs are never purged nor anonymized
+type LegalHoldStatus struct {
+	Held  bool           `json:"held"`
+	Holds []LegalHoldRef `json:"holds"`
+}
+
+// LegalHoldRef active legal hold covering a log
+type LegalHoldRef struct {
+	ID     string `json:"id"`
+	Reason string `json:"reason"`
 }
 
 type Filter struct {
@@ -60,6 +100,208 @@ type Filter struct {
 	StartAt  time.Time
 	EndAt    time.Time
 	pagination.Filter
+
+	DateField string
+	SortBy    string
+	ClockSkew *bool
+
+	// Labels values requested per label key through label.<key>=<value> params
+	Labels map[string][]string
+
+	// Archived searches the logs of the restored archive files through archived=true
+	Archived bool
+}
+
+// Time columns a filter or a sort can use
+const (
+	FieldCreatedAt  = "created_at"
+	FieldOccurredAt = "occurred_at"
+)
+
+// Query param prefix of the label filters, e.g. label.env=prod
+const LabelParamPrefix = "label."
+
+// LabelFacet values of a label key with the number of logs carrying each of them
+type LabelFacet struct {
+	Key    string       `json:"key"`
+	Values []FacetValue `json:"values"`
+}
+
+// FacetValue number of logs carrying a label value
+type FacetValue struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// VerifyFilter chain of a tenant to verify, from and to limit the entries by creation time
+type VerifyFilter struct {
+	TenantID int
+	From     time.Time
+	To       time.Time
+}
+
+// Reasons of a broken link
+const (
+	ReasonHashMismatch     = "hash_mismatch"
+	ReasonPrevHashMismatch = "prev_hash_mismatch"
+	ReasonMissingEntry     = "missing_entry"
+)
+
+// VerifyResult result of walking the hash chain of a tenant, Purged counts the entries deleted by retention
+// between the checked ones
+type VerifyResult struct {
+	TenantID   int         `json:"tenantId"`
+	From       *time.Time  `json:"from,omitempty"`
+	To         *time.Time  `json:"to,omitempty"`
+	Valid      bool        `json:"valid"`
+	Checked    int         `json:"checked"`
+	Purged     int64       `json:"purged"`
+	FirstSeq   int64       `json:"firstSeq,omitempty"`
+	LastSeq    int64       `json:"lastSeq,omitempty"`
+	BrokenLink *BrokenLink `json:"brokenLink,omitempty"`
+}
+
+// BrokenLink first entry of the chain that does not match its stored hash or its predecessor
+type BrokenLink struct {
+	ID       string `json:"id"`
+	Seq      int64  `json:"seq"`
+	Reason   string `json:"reason"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// Groups of the stats, logs shared by several tenants are counted under each of the tenants of the user
+const (
+	StatsGroupTenant   = "tenant"
+	StatsGroupLevel    = "level"
+	StatsGroupProvider = "provider"
+	StatsGroupAction   = "action"
+	StatsGroupResource = "resource"
+	StatsGroupMessage  = "message"
+)
+
+// Sources the stats are counted from
+const (
+	StatsSourceRollups = "rollups"
+	StatsSourceLogs    = "logs"
+	StatsSourceMixed   = "mixed"
+)
+
+// maxStatsBuckets buckets a stats request can span, longer ranges need a coarser granularity
+const maxStatsBuckets = 10000
+
+// defaultDashboardTop values per group listed by the dashboard when top is not requested
+const defaultDashboardTop = 10
+
+// StatsFilter counts the logs matching the filter per bucket of the granularity, minute, hour or day, and per value
+// of the group. StartAt is inclusive and EndAt exclusive
+type StatsFilter struct {
+	Filter
+	Granularity string
+	GroupBy     string
+}
+
+// StatsPoint number of logs of a bucket with the value of the group
+type StatsPoint struct {
+	Bucket time.Time `json:"bucket"`
+	Key    string    `json:"key,omitempty"`
+	Count  int64     `json:"count"`
+}
+
+// StatsResponse counts of the logs, Source tells whether they were read from the rollups, the logs or both
+type StatsResponse struct {
+	Granularity string       `json:"granularity"`
+	GroupBy     string       `json:"groupBy,omitempty"`
+	StartAt     time.Time    `json:"startAt"`
+	EndAt       time.Time    `json:"endAt"`
+	Source      string       `json:"source"`
+	Points      []StatsPoint `json:"points"`
+}
+
+// DashboardCount number of logs with a value of a group
+type DashboardCount struct {
+	Key   string `json:"key"`
+	Count int64  `json:"count"`
+}
+
+// Dashboard summary of the logs of a range, the timeline and the most frequent values of every group
+type Dashboard struct {
+	StartAt     time.Time        `json:"startAt"`
+	EndAt       time.Time        `json:"endAt"`
+	Granularity string           `json:"granularity"`
+	Source      string           `json:"source"`
+	Total       int64            `json:"total"`
+	Timeline    []StatsPoint     `json:"timeline"`
+	Levels      []DashboardCount `json:"levels"`
+	Providers   []DashboardCount `json:"providers"`
+	Actions     []DashboardCount `json:"actions"`
+	Resources   []DashboardCount `json:"resources"`
+	Messages    []DashboardCount `json:"messages"`
+}
+
+// Export files
+const (
+	ExportFormatXLSX = "xlsx"
+	ExportFileXLSX   = "logs.xlsx"
+	ContentTypeXLSX  = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+	ExportFormatCSV = "csv"
+	ExportFileCSV   = "logs.csv"
+	ContentTypeCSV  = "text/csv; charset=utf-8"
+
+	ExportFormatNDJSON = "ndjson"
+	ExportFileNDJSON   = "logs.ndjson"
+	ContentTypeNDJSON  = "application/x-ndjson"
+
+	ExportFormatJSON = "json"
+	ExportFileJSON   = "logs.json"
+	ContentTypeJSON  = "application/json"
+
+	// Gzip compressed files take the .gz extension after the one of their format
+	CompressGzip    = "gzip"
+	ExportExtGzip   = ".gz"
+	ContentTypeGzip = "application/gzip"
+)
+
+// Encodings of the csv exports, Excel only detects UTF-8 csv files starting with a BOM
+const (
+	EncodingUTF8    = "utf-8"
+	EncodingUTF8BOM = "utf-8-bom"
+)
+
+// ExpandData value of the expand param of the exports expanding the data keys
+const ExpandData = "data"
+
+// ExportOptions format of an export, Delimiter and BOM only apply to csv files and Columns to xlsx and csv files.
+// ExpandData adds a before and an after column per data key next to the data and old data columns of the xlsx and
+// csv files, Summary adds the counts by level, action and user to the xlsx files. Compress gzips the file itself,
+// the manifest then describes the compressed file. Progress, when set, is called with the number of logs written
+// after each of them
+type ExportOptions struct {
+	Format     string
+	Columns    []string
+	ExpandData bool
+	Summary    bool
+	Delimiter  rune
+	BOM        bool
+	Compress   bool
+	Progress   func(rows int)
+}
+
+// ExportRecord log of the json exports, data and old data are embedded as json instead of escaped strings
+type ExportRecord struct {
+	Response
+	Data    json.RawMessage `json:"data"`
+	OldData json.RawMessage `json:"oldData"`
+}
+
+// ExportFile exported logs with the manifest that proves their origin, the manifest is only known once the file
+// is written
+type ExportFile struct {
+	Name        string
+	Format      string
+	ContentType string
+	Manifest    *export.Manifest
 }
 
 type PaginatedRes struct {
@@ -2,6 +2,9 @@ package logs
 
 import (
 	"context"
+	"io"
+	"time"
+
 	"github.com/jmontesinos91/oevents/eventfactory"
 )
 
@@ -10,6 +13,10 @@ type IService interface {
 	Create(ctx context.Context, payload *Payload) (*Response, error)
 	GetByID(ctx context.Context, id *string, filter Filter) (*Response, error)
 	Retrieve(ctx context.Context, filter Filter) (*PaginatedRes, error)
-	CreateLogFromKafka(ctx context.Context, logCreated *eventfactory.LogCreatedPayload) error
-	Export(ctx context.Context, filter Filter) ([]byte, error)
+	CreateLogFromKafka(ctx context.Context, logCreated *eventfactory.LogCreatedPayload, occurredAt *time.Time) error
+	Export(ctx context.Context, filter Filter, opts ExportOptions, w io.Writer) (*ExportFile, error)
+	LabelFacets(ctx context.Context, filter Filter, keys []string) ([]LabelFacet, error)
+	Verify(ctx context.Context, filter VerifyFilter) (*VerifyResult, error)
+	Stats(ctx context.Context, filter StatsFilter) (*StatsResponse, error)
+	Dashboard(ctx context.Context, filter StatsFilter, top int) (*Dashboard, error)
 }
@@ -0,0 +1,196 @@
+package partition
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/omnilogger/config"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/logs"
+	"github.com/jmontesinos91/omnilogger/internal/services/retention"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrAlreadyRunning a run was requested while another one is in progress
+var ErrAlreadyRunning = errors.New("partition: manager already running")
+
+var (
+	createdMetric = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "logs_partitions_created_total",
+		Help: "The total number of logs partitions created by the partition manager",
+	})
+	retiredMetric = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "logs_partitions_retired_total",
+		Help: "The total number of expired logs partitions retired by the partition manager, partitioned by action detach or drop",
+	}, []string{"action"})
+	heldMetric = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "logs_partitions_held",
+		Help: "The number of expired logs partitions kept during the last run because they hold logs under legal hold or not archived yet",
+	})
+	failuresMetric = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "logs_partition_manager_failures_total",
+		Help: "The total number of partition manager runs that failed",
+	})
+)
+
+// DefaultService keeps the partitions of the logs table ahead of time and retires the expired ones
+type DefaultService struct {
+	log           *logger.ContextLogger
+	logsRepo      logs.IRepository
+	config        config.PartitionsConfigurations
+	retentionDays int
+	expiring      bool
+	archivedOnly  bool
+	running       atomic.Bool
+	now           func() time.Time
+}
+
+// NewDefaultService creates a new instance of DefaultService partition, expired partitions are only retired while the
+// retention purge deletes logs and no policy keeps them forever, once their logs are archived when the purge only
+// deletes archived logs. It fails when the settings are not valid
+func NewDefaultService(l *logger.ContextLogger, r logs.IRepository, c config.PartitionsConfigurations, rc config.RetentionConfigurations) (*DefaultService, error) {
+	switch c.Granularity {
+	case "":
+		c.Granularity = GranularityMonthly
+	case GranularityMonthly, GranularityDaily:
+	default:
+		return nil, fmt.Errorf("invalid partitions granularity %q, use %s or %s", c.Granularity, GranularityMonthly, GranularityDaily)
+	}
+
+	switch c.ExpiredAction {
+	case "":
+		c.ExpiredAction = ExpiredActionNone
+	case ExpiredActionNone, ExpiredActionDetach, ExpiredActionDrop:
+	default:
+		return nil, fmt.Errorf("invalid partitions expired action %q, use %s, %s or %s", c.ExpiredAction, ExpiredActionNone, ExpiredActionDetach, ExpiredActionDrop)
+	}
+
+	if c.Premake <= 0 {
+		c.Premake = defaultPremake
+	}
+
+	days, expiring, err := retention.LongestRetention(rc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DefaultService{
+		log:           l,
+		logsRepo:      r,
+		config:        c,
+		retentionDays: days,
+		expiring:      expiring,
+		archivedOnly:  rc.ArchivedOnly,
+		now:           time.Now,
+	}, nil
+}
+
+// Start runs the manager in background every interval, it stops once ctx is done
+func (s *DefaultService) Start(ctx context.Context) {
+	if !s.config.Enabled {
+		s.log.Log(logrus.WarnLevel, "Start", "Partition manager not enabled, logs outside the existing partitions go to the default partition")
+		return
+	}
+
+	interval := defaultInterval
+	if s.config.IntervalInMinutes > 0 {
+		interval = time.Duration(s.config.IntervalInMinutes) * time.Minute
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := s.Run(ctx); err != nil && !errors.Is(err, ErrAlreadyRunning) {
+				s.log.Error(logrus.ErrorLevel, "Start", "Partition manager run failed", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	s.log.Log(logrus.InfoLevel, "Start", "Partition manager scheduled every "+interval.String()+" with "+s.config.Granularity+" partitions")
+}
+
+// Run creates the partitions missing for the current period and the premake next ones, then detaches or drops the
+// partitions past the retention of every policy. Partitions holding logs under legal hold, or logs not archived yet
+// when the purge only deletes archived logs, are kept
+func (s *DefaultService) Run(ctx context.Context) (*RunResult, error) {
+	if !s.running.CompareAndSwap(false, true) {
+		return nil, ErrAlreadyRunning
+	}
+	defer s.running.Store(false)
+
+	now := s.now().UTC()
+	result := &RunResult{StartedAt: now}
+
+	err := s.manage(ctx, now, result)
+
+	finishedAt := s.now().UTC()
+	result.FinishedAt = &finishedAt
+	if err != nil {
+		result.Error = err.Error()
+		failuresMetric.Inc()
+	}
+	heldMetric.Set(float64(len(result.Held)))
+
+	s.log.Log(logrus.InfoLevel, "Run", result.summary())
+
+	return result, err
+}
+
+func (s *DefaultService) manage(ctx context.Context, now time.Time, result *RunResult) error {
+	existing, err := s.logsRepo.Partitions(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, partition := range missingPartitions(existing, s.config.Granularity, now, s.config.Premake) {
+		if err := s.logsRepo.CreatePartition(ctx, partition); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		result.Created = append(result.Created, partition.Name)
+		createdMetric.Inc()
+		s.log.Log(logrus.InfoLevel, "Run", "Partition "+partition.Name+" created")
+	}
+
+	if s.config.ExpiredAction == ExpiredActionNone || !s.expiring {
+		return errors.Join(errs...)
+	}
+
+	drop := s.config.ExpiredAction == ExpiredActionDrop
+	horizon := now.AddDate(0, 0, -s.retentionDays)
+	for _, partition := range expiredPartitions(existing, horizon) {
+		if ctx.Err() != nil {
+			errs = append(errs, ctx.Err())
+			break
+		}
+
+		retired, err := s.logsRepo.RetirePartition(ctx, partition.Name, drop, s.archivedOnly)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !retired {
+			result.Held = append(result.Held, partition.Name)
+			continue
+		}
+		result.Retired = append(result.Retired, partition.Name)
+		retiredMetric.WithLabelValues(s.config.ExpiredAction).Inc()
+		s.log.Log(logrus.InfoLevel, "Run", "Partition "+partition.Name+" retired ("+s.config.ExpiredAction+")")
+	}
+
+	return errors.Join(errs...)
+}
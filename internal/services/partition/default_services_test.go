@@ -0,0 +1,160 @@
+package partition
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/omnilogger/config"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/logs"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/logs/logsmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRun(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestRun", "debug", logger.TextFormat)
+	retentionConfig := config.RetentionConfigurations{Enabled: true, Policy: config.RetentionPolicyConfigurations{Days: 30}}
+	existing := []logs.Partition{
+		{Name: "logs_default", Default: true},
+		{Name: "logs_p202403", From: day(2024, 3, 1), To: day(2024, 4, 1)},
+		{Name: "logs_p202404", From: day(2024, 4, 1), To: day(2024, 5, 1)},
+		{Name: "logs_p202405", From: day(2024, 5, 1), To: day(2024, 6, 1)},
+		{Name: "logs_p202406", From: day(2024, 6, 1), To: day(2024, 7, 1)},
+	}
+
+	cases := []struct {
+		name      string
+		config    config.PartitionsConfigurations
+		retention config.RetentionConfigurations
+		repoFunc  func() *logsmock.IRepository
+		expected  RunResult
+		err       bool
+	}{
+		{
+			name:      "Create ahead and drop expired",
+			config:    config.PartitionsConfigurations{Premake: 1, ExpiredAction: ExpiredActionDrop},
+			retention: retentionConfig,
+			repoFunc: func() *logsmock.IRepository {
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("Partitions", mock.Anything).Return(existing, nil)
+				repoMock.On("CreatePartition", mock.Anything, logs.Partition{Name: "logs_p202407", From: day(2024, 7, 1), To: day(2024, 8, 1)}).Return(nil)
+				repoMock.On("RetirePartition", mock.Anything, "logs_p202403", true, false).Return(false, nil)
+				repoMock.On("RetirePartition", mock.Anything, "logs_p202404", true, false).Return(true, nil)
+				return repoMock
+			},
+			expected: RunResult{Created: []string{"logs_p202407"}, Retired: []string{"logs_p202404"}, Held: []string{"logs_p202403"}},
+		},
+		{
+			name:      "Expired kept without action",
+			config:    config.PartitionsConfigurations{Premake: 1},
+			retention: retentionConfig,
+			repoFunc: func() *logsmock.IRepository {
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("Partitions", mock.Anything).Return(existing, nil)
+				repoMock.On("CreatePartition", mock.Anything, mock.Anything).Return(nil)
+				return repoMock
+			},
+			expected: RunResult{Created: []string{"logs_p202407"}},
+		},
+		{
+			name:      "Expired kept while retention is not purging",
+			config:    config.PartitionsConfigurations{Premake: 1, ExpiredAction: ExpiredActionDetach},
+			retention: config.RetentionConfigurations{Enabled: true, DryRun: true, Policy: config.RetentionPolicyConfigurations{Days: 30}},
+			repoFunc: func() *logsmock.IRepository {
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("Partitions", mock.Anything).Return(existing, nil)
+				repoMock.On("CreatePartition", mock.Anything, mock.Anything).Return(nil)
+				return repoMock
+			},
+			expected: RunResult{Created: []string{"logs_p202407"}},
+		},
+		{
+			name:      "Unarchived partitions kept while retention only purges archived logs",
+			config:    config.PartitionsConfigurations{Premake: 1, ExpiredAction: ExpiredActionDrop},
+			retention: config.RetentionConfigurations{Enabled: true, ArchivedOnly: true, Policy: config.RetentionPolicyConfigurations{Days: 30}},
+			repoFunc: func() *logsmock.IRepository {
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("Partitions", mock.Anything).Return(existing, nil)
+				repoMock.On("CreatePartition", mock.Anything, mock.Anything).Return(nil)
+				repoMock.On("RetirePartition", mock.Anything, "logs_p202403", true, true).Return(true, nil)
+				repoMock.On("RetirePartition", mock.Anything, "logs_p202404", true, true).Return(false, nil)
+				return repoMock
+			},
+			expected: RunResult{Created: []string{"logs_p202407"}, Retired: []string{"logs_p202403"}, Held: []string{"logs_p202404"}},
+		},
+		{
+			name:      "Create error does not stop the run",
+			config:    config.PartitionsConfigurations{Premake: 2, ExpiredAction: ExpiredActionDetach},
+			retention: retentionConfig,
+			repoFunc: func() *logsmock.IRepository {
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("Partitions", mock.Anything).Return(existing, nil)
+				repoMock.On("CreatePartition", mock.Anything, mock.MatchedBy(func(p logs.Partition) bool { return p.Name == "logs_p202407" })).Return(errors.New("db down"))
+				repoMock.On("CreatePartition", mock.Anything, mock.MatchedBy(func(p logs.Partition) bool { return p.Name == "logs_p202408" })).Return(nil)
+				repoMock.On("RetirePartition", mock.Anything, mock.Anything, false, false).Return(true, nil)
+				return repoMock
+			},
+			expected: RunResult{Created: []string{"logs_p202408"}, Retired: []string{"logs_p202403", "logs_p202404"}, Error: "db down"},
+			err:      true,
+		},
+		{
+			name:      "Partitions error",
+			config:    config.PartitionsConfigurations{},
+			retention: retentionConfig,
+			repoFunc: func() *logsmock.IRepository {
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("Partitions", mock.Anything).Return(nil, errors.New("db down"))
+				return repoMock
+			},
+			expected: RunResult{Error: "db down"},
+			err:      true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repoMock := tc.repoFunc()
+			svc, err := NewDefaultService(ctxLogger, repoMock, tc.config, tc.retention)
+			assert.NoError(t, err)
+			startedAt := time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC)
+			svc.now = func() time.Time { return startedAt }
+
+			res, err := svc.Run(context.Background())
+			if tc.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			tc.expected.StartedAt = startedAt
+			tc.expected.FinishedAt = &startedAt
+			assert.Equal(t, &tc.expected, res)
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
+
+func TestNewDefaultService_InvalidSettings(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestNewDefaultService", "debug", logger.TextFormat)
+
+	cases := []struct {
+		name      string
+		config    config.PartitionsConfigurations
+		retention config.RetentionConfigurations
+	}{
+		{name: "Granularity", config: config.PartitionsConfigurations{Granularity: "weekly"}},
+		{name: "Expired action", config: config.PartitionsConfigurations{ExpiredAction: "archive"}},
+		{name: "Retention policy", retention: config.RetentionConfigurations{Enabled: true, Policy: config.RetentionPolicyConfigurations{Days: 30, Levels: map[string]int{"9": 1}}}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewDefaultService(ctxLogger, &logsmock.IRepository{}, tc.config, tc.retention)
+
+			assert.Error(t, err)
+		})
+	}
+}
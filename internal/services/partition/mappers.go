@@ -0,0 +1,95 @@
+package partition
+
+import (
+	"slices"
+	"time"
+
+	"github.com/jmontesinos91/omnilogger/internal/repositories/logs"
+)
+
+// periodStart start of the monthly or daily period holding t
+func periodStart(t time.Time, granularity string) time.Time {
+	t = t.UTC()
+	if granularity == GranularityDaily {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// nextPeriod start of the period following the one starting at start
+func nextPeriod(start time.Time, granularity string) time.Time {
+	if granularity == GranularityDaily {
+		return start.AddDate(0, 0, 1)
+	}
+
+	return start.AddDate(0, 1, 0)
+}
+
+// partitionName logs_pYYYYMM for monthly partitions and logs_pYYYYMMDD for daily ones
+func partitionName(from time.Time, granularity string) string {
+	if granularity == GranularityDaily {
+		return "logs_p" + from.Format("20060102")
+	}
+
+	return "logs_p" + from.Format("200601")
+}
+
+// missingPartitions partitions to create so the current period and the premake next ones are covered, ranges
+// already covered by partitions of another granularity are skipped and only the gaps left are created
+func missingPartitions(existing []logs.Partition, granularity string, now time.Time, premake int) []logs.Partition {
+	ranges := make([]logs.Partition, 0, len(existing))
+	for _, partition := range existing {
+		if !partition.Default {
+			ranges = append(ranges, partition)
+		}
+	}
+	slices.SortFunc(ranges, func(a, b logs.Partition) int { return a.From.Compare(b.From) })
+
+	var missing []logs.Partition
+	start := periodStart(now, granularity)
+	for i := 0; i <= premake; i++ {
+		end := nextPeriod(start, granularity)
+
+		cursor := start
+		for _, partition := range ranges {
+			if !partition.To.After(cursor) || !partition.From.Before(end) {
+				continue
+			}
+			if partition.From.After(cursor) {
+				missing = append(missing, gap(cursor, partition.From, start, end, granularity))
+			}
+			cursor = partition.To
+		}
+		if cursor.Before(end) {
+			missing = append(missing, gap(cursor, end, start, end, granularity))
+		}
+
+		start = end
+	}
+
+	return missing
+}
+
+// gap partition covering from to to inside the period, gaps smaller than the period are named after their first day
+func gap(from, to, start, end time.Time, granularity string) logs.Partition {
+	name := partitionName(from, granularity)
+	if !from.Equal(start) || !to.Equal(end) {
+		name = partitionName(from, GranularityDaily)
+	}
+
+	return logs.Partition{Name: name, From: from, To: to}
+}
+
+// expiredPartitions partitions whose whole range is older than horizon, oldest first, the default partition never expires
+func expiredPartitions(existing []logs.Partition, horizon time.Time) []logs.Partition {
+	var expired []logs.Partition
+	for _, partition := range existing {
+		if !partition.Default && !partition.To.After(horizon) {
+			expired = append(expired, partition)
+		}
+	}
+	slices.SortFunc(expired, func(a, b logs.Partition) int { return a.From.Compare(b.From) })
+
+	return expired
+}
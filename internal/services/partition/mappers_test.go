@@ -0,0 +1,102 @@
+package partition
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jmontesinos91/omnilogger/internal/repositories/logs"
+	"github.com/stretchr/testify/assert"
+)
+
+func day(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestMissingPartitions(t *testing.T) {
+	now := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	cases := []struct {
+		name        string
+		existing    []logs.Partition
+		granularity string
+		premake     int
+		expected    []logs.Partition
+	}{
+		{
+			name:        "Monthly from scratch",
+			existing:    []logs.Partition{{Name: "logs_default", Default: true}},
+			granularity: GranularityMonthly,
+			premake:     2,
+			expected: []logs.Partition{
+				{Name: "logs_p202401", From: day(2024, 1, 1), To: day(2024, 2, 1)},
+				{Name: "logs_p202402", From: day(2024, 2, 1), To: day(2024, 3, 1)},
+				{Name: "logs_p202403", From: day(2024, 3, 1), To: day(2024, 4, 1)},
+			},
+		},
+		{
+			name: "Already covered",
+			existing: []logs.Partition{
+				{Name: "logs_p202401", From: day(2024, 1, 1), To: day(2024, 2, 1)},
+				{Name: "logs_p202402", From: day(2024, 2, 1), To: day(2024, 3, 1)},
+			},
+			granularity: GranularityMonthly,
+			premake:     1,
+		},
+		{
+			name: "Daily inside monthly partitions",
+			existing: []logs.Partition{
+				{Name: "logs_p202401", From: day(2024, 1, 1), To: day(2024, 2, 1)},
+			},
+			granularity: GranularityDaily,
+			premake:     2,
+		},
+		{
+			name: "Daily after the monthly partitions",
+			existing: []logs.Partition{
+				{Name: "logs_p202312", From: day(2023, 12, 1), To: day(2024, 1, 1)},
+			},
+			granularity: GranularityDaily,
+			premake:     1,
+			expected: []logs.Partition{
+				{Name: "logs_p20240115", From: day(2024, 1, 15), To: day(2024, 1, 16)},
+				{Name: "logs_p20240116", From: day(2024, 1, 16), To: day(2024, 1, 17)},
+			},
+		},
+		{
+			name: "Monthly around daily partitions",
+			existing: []logs.Partition{
+				{Name: "logs_p20240115", From: day(2024, 1, 15), To: day(2024, 1, 16)},
+				{Name: "logs_p20240116", From: day(2024, 1, 16), To: day(2024, 1, 17)},
+			},
+			granularity: GranularityMonthly,
+			premake:     1,
+			expected: []logs.Partition{
+				{Name: "logs_p20240101", From: day(2024, 1, 1), To: day(2024, 1, 15)},
+				{Name: "logs_p20240117", From: day(2024, 1, 17), To: day(2024, 2, 1)},
+				{Name: "logs_p202402", From: day(2024, 2, 1), To: day(2024, 3, 1)},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, missingPartitions(tc.existing, tc.granularity, now, tc.premake))
+		})
+	}
+}
+
+func TestExpiredPartitions(t *testing.T) {
+	existing := []logs.Partition{
+		{Name: "logs_p202403", From: day(2024, 3, 1), To: day(2024, 4, 1)},
+		{Name: "logs_default", Default: true},
+		{Name: "logs_p202401", From: day(2024, 1, 1), To: day(2024, 2, 1)},
+		{Name: "logs_p202402", From: day(2024, 2, 1), To: day(2024, 3, 1)},
+	}
+
+	expired := expiredPartitions(existing, day(2024, 3, 1))
+
+	assert.Equal(t, []logs.Partition{
+		{Name: "logs_p202401", From: day(2024, 1, 1), To: day(2024, 2, 1)},
+		{Name: "logs_p202402", From: day(2024, 2, 1), To: day(2024, 3, 1)},
+	}, expired)
+}
@@ -0,0 +1,40 @@
+package partition
+
+import (
+	"fmt"
+	"time"
+)
+
+// Granularities of the logs partitions
+const (
+	GranularityMonthly = "monthly"
+	GranularityDaily   = "daily"
+)
+
+// Actions taken on the partitions past the retention of every policy
+const (
+	ExpiredActionNone   = "none"
+	ExpiredActionDetach = "detach"
+	ExpiredActionDrop   = "drop"
+)
+
+// defaultPremake number of partitions created ahead of the current one when premake is not configured
+const defaultPremake = 3
+
+// defaultInterval time between runs when interval-in-minutes is not configured
+const defaultInterval = time.Hour
+
+// RunResult outcome of a partition manager run, Held lists the expired partitions kept because they hold
+// logs under legal hold or logs not archived yet
+type RunResult struct {
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Created    []string   `json:"created"`
+	Retired    []string   `json:"retired"`
+	Held       []string   `json:"held"`
+	Error      string     `json:"error,omitempty"`
+}
+
+func (r *RunResult) summary() string {
+	return fmt.Sprintf("Partitions: %d created, %d retired, %d held", len(r.Created), len(r.Retired), len(r.Held))
+}
@@ -0,0 +1,10 @@
+package partition
+
+import (
+	"context"
+)
+
+// IService Manage partitions interfaces
+type IService interface {
+	Run(ctx context.Context) (*RunResult, error)
+}
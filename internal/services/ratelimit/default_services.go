@@ -0,0 +1,250 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/omnilogger/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// idleBucketTTL buckets not used for this long are released
+	idleBucketTTL = 10 * time.Minute
+	// defaultMaxWait used by the wait policy when max-wait-in-seconds is not configured
+	defaultMaxWait = 30 * time.Second
+)
+
+var (
+	allowedMetric = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ingestion_rate_limit_allowed_total",
+		Help: "The total number of logs allowed by the ingestion rate limiter, partitioned by source",
+	}, []string{"source"})
+	limitedMetric = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ingestion_rate_limited_total",
+		Help: "The total number of logs rejected or dropped by the ingestion rate limiter, partitioned by source, dimension and tenant",
+	}, []string{"source", "dimension", "tenant"})
+	waitMetric = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "ingestion_rate_limit_wait_seconds",
+		Help: "How long the workers were throttled by the ingestion rate limiter, partitioned by source",
+	}, []string{"source"})
+	bucketsMetric = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "ingestion_rate_limit_buckets",
+		Help: "The number of token buckets currently tracked by the ingestion rate limiter",
+	})
+)
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// DefaultService in memory token bucket rate limiter
+type DefaultService struct {
+	log       *logger.ContextLogger
+	config    config.RateLimitConfigurations
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewDefaultService creates a new instance of DefaultService rate limit
+func NewDefaultService(l *logger.ContextLogger, c config.RateLimitConfigurations) *DefaultService {
+	if c.Enabled {
+		l.Log(logrus.InfoLevel, "NewDefaultService", "Ingestion rate limiting enabled")
+	}
+
+	return &DefaultService{
+		log:     l,
+		config:  c,
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow takes n tokens from every bucket of the producer, nothing is taken when any of them is exhausted
+func (s *DefaultService) Allow(source string, key Key, n int) Decision {
+	if !s.config.Enabled {
+		return Decision{Allowed: true}
+	}
+
+	reservations, decision := s.reserve(key, n)
+	if decision.Allowed && decision.RetryAfter > 0 {
+		decision.Allowed = false
+	}
+
+	if !decision.Allowed {
+		s.cancel(reservations)
+		limitedMetric.WithLabelValues(source, decision.Dimension, tenantLabel(key)).Add(float64(n))
+		return decision
+	}
+
+	allowedMetric.WithLabelValues(source).Add(float64(n))
+
+	return Decision{Allowed: true}
+}
+
+// Wait blocks until the producer has a token again, when the wait is longer than the configured
+// maximum nothing is taken and the decision is not allowed
+func (s *DefaultService) Wait(ctx context.Context, source string, key Key) (Decision, error) {
+	if !s.config.Enabled {
+		return Decision{Allowed: true}, nil
+	}
+
+	reservations, decision := s.reserve(key, 1)
+	if !decision.Allowed || decision.RetryAfter > s.maxWait() {
+		s.cancel(reservations)
+		decision.Allowed = false
+		limitedMetric.WithLabelValues(source, decision.Dimension, tenantLabel(key)).Inc()
+		return decision, nil
+	}
+
+	if decision.RetryAfter > 0 {
+		timer := time.NewTimer(decision.RetryAfter)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			s.cancel(reservations)
+			return Decision{}, ctx.Err()
+		case <-timer.C:
+		}
+
+		waitMetric.WithLabelValues(source).Observe(decision.RetryAfter.Seconds())
+	}
+
+	allowedMetric.WithLabelValues(source).Inc()
+
+	return Decision{Allowed: true}, nil
+}
+
+// Policy overflow policy the workers should apply
+func (s *DefaultService) Policy() string {
+	if s.config.Worker.Policy == PolicyDrop {
+		return PolicyDrop
+	}
+
+	return PolicyWait
+}
+
+// reserve reserves n tokens on every limited dimension of the key, the decision holds the longest delay
+func (s *DefaultService) reserve(key Key, n int) ([]*rate.Reservation, Decision) {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	decision := Decision{Allowed: true}
+	var reservations []*rate.Reservation
+
+	for _, dim := range s.dimensions(key) {
+		if dim.limit.Rate <= 0 || dim.value == "" {
+			continue
+		}
+
+		b := s.bucket(dim.name+":"+dim.value, dim.limit, now)
+		r := b.limiter.ReserveN(now, n)
+		if !r.OK() {
+			// More tokens than the bucket can ever hold
+			decision.Allowed = false
+			decision.Dimension = dim.name
+			decision.RetryAfter = time.Duration(float64(n) / dim.limit.Rate * float64(time.Second))
+			return reservations, decision
+		}
+		reservations = append(reservations, r)
+
+		if delay := r.DelayFrom(now); delay > decision.RetryAfter {
+			decision.RetryAfter = delay
+			decision.Dimension = dim.name
+		}
+	}
+
+	return reservations, decision
+}
+
+func (s *DefaultService) cancel(reservations []*rate.Reservation) {
+	now := s.now()
+	for _, r := range reservations {
+		r.CancelAt(now)
+	}
+}
+
+type dimension struct {
+	name  string
+	value string
+	limit config.LimitConfigurations
+}
+
+func (s *DefaultService) dimensions(key Key) []dimension {
+	var tenant string
+	tenantLimit := s.config.Tenant
+	if key.TenantID != 0 {
+		tenant = strconv.Itoa(key.TenantID)
+		if override, ok := s.config.Tenants[tenant]; ok {
+			tenantLimit = override
+		}
+	}
+
+	return []dimension{
+		{name: DimensionTenant, value: tenant, limit: tenantLimit},
+		{name: DimensionProvider, value: key.Provider, limit: s.config.Provider},
+		{name: DimensionClient, value: key.Client, limit: s.config.Client},
+	}
+}
+
+// bucket returns the bucket of the given id, it must be called holding the lock
+func (s *DefaultService) bucket(id string, limit config.LimitConfigurations, now time.Time) *bucket {
+	b, ok := s.buckets[id]
+	if !ok {
+		burst := limit.Burst
+		if burst <= 0 {
+			burst = 1
+		}
+		b = &bucket{limiter: rate.NewLimiter(rate.Limit(limit.Rate), burst)}
+		s.buckets[id] = b
+		bucketsMetric.Set(float64(len(s.buckets)))
+	}
+	b.lastSeen = now
+
+	return b
+}
+
+// sweep releases idle buckets, it must be called holding the lock
+func (s *DefaultService) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < idleBucketTTL {
+		return
+	}
+	s.lastSweep = now
+
+	for id, b := range s.buckets {
+		if now.Sub(b.lastSeen) >= idleBucketTTL {
+			delete(s.buckets, id)
+		}
+	}
+	bucketsMetric.Set(float64(len(s.buckets)))
+}
+
+func (s *DefaultService) maxWait() time.Duration {
+	if s.config.Worker.MaxWaitInSeconds <= 0 {
+		return defaultMaxWait
+	}
+
+	return time.Duration(s.config.Worker.MaxWaitInSeconds) * time.Second
+}
+
+func tenantLabel(key Key) string {
+	if key.TenantID == 0 {
+		return ""
+	}
+
+	return strconv.Itoa(key.TenantID)
+}
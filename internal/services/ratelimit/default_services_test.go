@@ -0,0 +1,174 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/omnilogger/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestService(c config.RateLimitConfigurations, now *time.Time) *DefaultService {
+	ctxLogger := logger.NewContextLogger("TestRateLimit", "debug", logger.TextFormat)
+	svc := NewDefaultService(ctxLogger, c)
+	svc.now = func() time.Time { return *now }
+	return svc
+}
+
+func TestAllow(t *testing.T) {
+	c := config.RateLimitConfigurations{
+		Enabled:  true,
+		Tenant:   config.LimitConfigurations{Rate: 1, Burst: 2},
+		Provider: config.LimitConfigurations{Rate: 1, Burst: 3},
+		Tenants: map[string]config.LimitConfigurations{
+			"9": {Rate: 10, Burst: 10},
+		},
+	}
+
+	cases := []struct {
+		name      string
+		config    config.RateLimitConfigurations
+		calls     []Key
+		n         int
+		allowed   []bool
+		dimension string
+	}{
+		{
+			name:    "Disabled",
+			config:  config.RateLimitConfigurations{Tenant: config.LimitConfigurations{Rate: 1, Burst: 1}},
+			calls:   []Key{{TenantID: 1}, {TenantID: 1}, {TenantID: 1}},
+			n:       1,
+			allowed: []bool{true, true, true},
+		},
+		{
+			name:      "Tenant burst exhausted",
+			config:    c,
+			calls:     []Key{{TenantID: 1}, {TenantID: 1}, {TenantID: 1}},
+			n:         1,
+			allowed:   []bool{true, true, false},
+			dimension: DimensionTenant,
+		},
+		{
+			name:    "Tenants have independent buckets",
+			config:  c,
+			calls:   []Key{{TenantID: 1}, {TenantID: 1}, {TenantID: 2}},
+			n:       1,
+			allowed: []bool{true, true, true},
+		},
+		{
+			name:    "Tenant override",
+			config:  c,
+			calls:   []Key{{TenantID: 9}, {TenantID: 9}, {TenantID: 9}},
+			n:       1,
+			allowed: []bool{true, true, true},
+		},
+		{
+			name:      "Provider shared by tenants",
+			config:    c,
+			calls:     []Key{{TenantID: 1, Provider: "billing"}, {TenantID: 2, Provider: "billing"}, {TenantID: 3, Provider: "billing"}, {TenantID: 4, Provider: "billing"}},
+			n:         1,
+			allowed:   []bool{true, true, true, false},
+			dimension: DimensionProvider,
+		},
+		{
+			name:      "Batch larger than the bucket",
+			config:    c,
+			calls:     []Key{{TenantID: 1}},
+			n:         5,
+			allowed:   []bool{false},
+			dimension: DimensionTenant,
+		},
+		{
+			name:    "Client without limit",
+			config:  c,
+			calls:   []Key{{Client: "api_key:1"}, {Client: "api_key:1"}, {Client: "api_key:1"}},
+			n:       1,
+			allowed: []bool{true, true, true},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+			svc := newTestService(tc.config, &now)
+
+			var last Decision
+			for i, key := range tc.calls {
+				last = svc.Allow(SourceHTTP, key, tc.n)
+				assert.Equal(t, tc.allowed[i], last.Allowed, "call %d", i)
+			}
+
+			if tc.dimension != "" {
+				assert.Equal(t, tc.dimension, last.Dimension)
+				assert.Greater(t, last.RetryAfter, time.Duration(0))
+			}
+		})
+	}
+}
+
+func TestAllow_RejectedRequestsDoNotTakeTokens(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	svc := newTestService(config.RateLimitConfigurations{
+		Enabled:  true,
+		Tenant:   config.LimitConfigurations{Rate: 1, Burst: 5},
+		Provider: config.LimitConfigurations{Rate: 1, Burst: 1},
+	}, &now)
+
+	assert.True(t, svc.Allow(SourceHTTP, Key{TenantID: 1, Provider: "a"}, 1).Allowed)
+
+	// Provider "a" is exhausted, the tenant bucket must be left untouched
+	for i := 0; i < 3; i++ {
+		assert.False(t, svc.Allow(SourceHTTP, Key{TenantID: 1, Provider: "a"}, 1).Allowed)
+	}
+	for i := 0; i < 4; i++ {
+		assert.True(t, svc.Allow(SourceHTTP, Key{TenantID: 1}, 1).Allowed, "call %d", i)
+	}
+	assert.False(t, svc.Allow(SourceHTTP, Key{TenantID: 1}, 1).Allowed)
+
+	// Tokens are refilled over time
+	now = now.Add(time.Second)
+	assert.True(t, svc.Allow(SourceHTTP, Key{TenantID: 2, Provider: "a"}, 1).Allowed)
+}
+
+func TestWait(t *testing.T) {
+	c := config.RateLimitConfigurations{
+		Enabled:  true,
+		Tenant:   config.LimitConfigurations{Rate: 1, Burst: 1},
+		Provider: config.LimitConfigurations{Rate: 0.25, Burst: 1},
+		Worker:   config.WorkerRateLimitConfigurations{Policy: PolicyWait, MaxWaitInSeconds: 1},
+	}
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	svc := newTestService(c, &now)
+
+	decision, err := svc.Wait(context.Background(), SourceKafka, Key{TenantID: 1})
+	assert.NoError(t, err)
+	assert.True(t, decision.Allowed)
+
+	// The next token is one second away, the wait is cancelled by the context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = svc.Wait(ctx, SourceKafka, Key{TenantID: 1})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// Waits longer than the maximum are not allowed
+	assert.True(t, svc.Allow(SourceKafka, Key{Provider: "billing"}, 1).Allowed)
+	decision, err = svc.Wait(context.Background(), SourceKafka, Key{Provider: "billing"})
+	assert.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, DimensionProvider, decision.Dimension)
+}
+
+func TestPolicy(t *testing.T) {
+	now := time.Now()
+	assert.Equal(t, PolicyWait, newTestService(config.RateLimitConfigurations{}, &now).Policy())
+	assert.Equal(t, PolicyDrop, newTestService(config.RateLimitConfigurations{Worker: config.WorkerRateLimitConfigurations{Policy: PolicyDrop}}, &now).Policy())
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	assert.Equal(t, "1", Decision{}.RetryAfterSeconds())
+	assert.Equal(t, "1", Decision{RetryAfter: 200 * time.Millisecond}.RetryAfterSeconds())
+	assert.Equal(t, "3", Decision{RetryAfter: 2100 * time.Millisecond}.RetryAfterSeconds())
+}
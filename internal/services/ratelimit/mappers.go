@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strconv"
+
+	"github.com/jmontesinos91/oevents/eventfactory"
+	"github.com/jmontesinos91/omnilogger/internal/services/api_key"
+	"github.com/jmontesinos91/omnilogger/internal/services/logs"
+	"github.com/jmontesinos91/osecurity/sts"
+)
+
+// ToKey builds the key of a log received over http, the client is the api key or the user that sent it. The tenant
+// is the authenticated one, the tenant catalog of the payload is sent by the client so it only picks one of the
+// tenants of the user
+func ToKey(ctx context.Context, payload *logs.Payload) Key {
+	key := Key{Provider: payload.Provider}
+
+	if principal, ok := api_key.PrincipalFromContext(ctx); ok {
+		key.TenantID = principal.TenantID
+		key.Client = "api_key:" + principal.KeyID
+	} else if claims, ok := ctx.Value(&sts.Claim).(sts.Claims); ok {
+		key.TenantID = userTenant(payload.TenantCat, claims.Tenants)
+		if claims.UserID != 0 {
+			key.Client = "user:" + strconv.Itoa(claims.UserID)
+		}
+	}
+
+	return key
+}
+
+// ToEventKey builds the key of a log received from kafka, events have no api client
+func ToEventKey(payload *eventfactory.LogCreatedPayload) Key {
+	key := Key{Provider: payload.Provider}
+
+	if len(payload.TenantCat) > 0 {
+		key.TenantID = payload.TenantCat[0].ID
+	} else if id, err := strconv.Atoi(payload.TenantID); err == nil {
+		key.TenantID = id
+	}
+
+	return key
+}
+
+// userTenant returns the first tenant of a tenant catalog json the user belongs to, logs are limited by their owner
+// tenant. Logs of tenants the user does not belong to are limited by the first tenant of the user
+func userTenant(tenantCat string, tenants []int) int {
+	var items []logs.Item
+	if tenantCat != "" {
+		_ = json.Unmarshal([]byte(tenantCat), &items)
+	}
+
+	for _, item := range items {
+		if slices.Contains(tenants, item.ID) {
+			return item.ID
+		}
+	}
+
+	if len(tenants) > 0 {
+		return tenants[0]
+	}
+
+	return 0
+}
@@ -0,0 +1,50 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmontesinos91/oevents/eventfactory"
+	"github.com/jmontesinos91/omnilogger/internal/services/api_key"
+	"github.com/jmontesinos91/omnilogger/internal/services/logs"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToKey(t *testing.T) {
+	payload := &logs.Payload{Provider: "billing", TenantCat: `[{"id":7,"name":"Acme"},{"id":8,"name":"Other"}]`}
+
+	userCtx := context.WithValue(context.Background(), &sts.Claim, sts.Claims{UserID: 42, Tenants: []int{8, 7}})
+	apiKeyCtx := api_key.StorePrincipalInContext(userCtx, &api_key.Principal{KeyID: "key-1", TenantID: 9})
+	foreignCtx := context.WithValue(context.Background(), &sts.Claim, sts.Claims{UserID: 43, Tenants: []int{5}})
+	noTenantCtx := context.WithValue(context.Background(), &sts.Claim, sts.Claims{UserID: 44})
+
+	cases := []struct {
+		name     string
+		ctx      context.Context
+		payload  *logs.Payload
+		expected Key
+	}{
+		{name: "User", ctx: userCtx, payload: payload, expected: Key{TenantID: 7, Provider: "billing", Client: "user:42"}},
+		{name: "Api key", ctx: apiKeyCtx, payload: payload, expected: Key{TenantID: 9, Provider: "billing", Client: "api_key:key-1"}},
+		{name: "User of another tenant", ctx: foreignCtx, payload: payload, expected: Key{TenantID: 5, Provider: "billing", Client: "user:43"}},
+		{name: "User without tenant", ctx: noTenantCtx, payload: payload, expected: Key{Provider: "billing", Client: "user:44"}},
+		{name: "Anonymous without tenant", ctx: context.Background(), payload: &logs.Payload{TenantCat: "not json"}, expected: Key{}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, ToKey(tc.ctx, tc.payload))
+		})
+	}
+}
+
+func TestToEventKey(t *testing.T) {
+	assert.Equal(t, Key{TenantID: 3, Provider: "billing"}, ToEventKey(&eventfactory.LogCreatedPayload{
+		Provider:  "billing",
+		TenantCat: []eventfactory.TenantItem{{ID: 3}},
+		TenantID:  "4",
+	}))
+	assert.Equal(t, Key{TenantID: 4}, ToEventKey(&eventfactory.LogCreatedPayload{TenantID: "4"}))
+	assert.Equal(t, Key{}, ToEventKey(&eventfactory.LogCreatedPayload{}))
+}
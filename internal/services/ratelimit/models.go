@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"strconv"
+	"time"
+)
+
+// Dimensions a producer is limited by
+const (
+	DimensionTenant   = "tenant"
+	DimensionProvider = "provider"
+	DimensionClient   = "client"
+)
+
+// Sources logs are ingested from
+const (
+	SourceHTTP  = "http"
+	SourceOTLP  = "otlp"
+	SourceKafka = "kafka"
+)
+
+// Overflow policies of the kafka workers
+const (
+	// PolicyWait throttles the consumer until the producer has tokens again
+	PolicyWait = "wait"
+	// PolicyDrop discards the events of producers over their limit
+	PolicyDrop = "drop"
+)
+
+// Key identifies the producer of a log, empty values are not limited
+type Key struct {
+	TenantID int
+	Provider string
+	Client   string
+}
+
+// Decision result of a rate limit check
+type Decision struct {
+	Allowed    bool
+	RetryAfter time.Duration
+	Dimension  string
+}
+
+// RetryAfterSeconds value of the Retry-After header, rounded up to at least one second
+func (d Decision) RetryAfterSeconds() string {
+	seconds := int((d.RetryAfter + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	return strconv.Itoa(seconds)
+}
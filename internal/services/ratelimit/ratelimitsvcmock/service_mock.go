@@ -0,0 +1,49 @@
+package ratelimitsvcmock
+
+import (
+	"context"
+
+	"github.com/jmontesinos91/omnilogger/internal/services/ratelimit"
+)
+
+type IService struct {
+	// Allow
+	AllowRes    *ratelimit.Decision
+	AllowCalled bool
+	AllowKeys   []ratelimit.Key
+
+	// Wait
+	WaitErr    error
+	WaitRes    *ratelimit.Decision
+	WaitCalled bool
+
+	// Policy
+	PolicyRes string
+}
+
+func (m *IService) Allow(source string, key ratelimit.Key, n int) ratelimit.Decision {
+	m.AllowCalled = true
+	m.AllowKeys = append(m.AllowKeys, key)
+	if m.AllowRes != nil {
+		return *m.AllowRes
+	}
+	return ratelimit.Decision{Allowed: true}
+}
+
+func (m *IService) Wait(ctx context.Context, source string, key ratelimit.Key) (ratelimit.Decision, error) {
+	m.WaitCalled = true
+	if m.WaitErr != nil {
+		return ratelimit.Decision{}, m.WaitErr
+	}
+	if m.WaitRes != nil {
+		return *m.WaitRes, nil
+	}
+	return ratelimit.Decision{Allowed: true}, nil
+}
+
+func (m *IService) Policy() string {
+	if m.PolicyRes != "" {
+		return m.PolicyRes
+	}
+	return ratelimit.PolicyWait
+}
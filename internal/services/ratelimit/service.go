@@ -0,0 +1,12 @@
+package ratelimit
+
+import (
+	"context"
+)
+
+// IService rate limits ingestion by tenant, provider and api client
+type IService interface {
+	Allow(source string, key Key, n int) Decision
+	Wait(ctx context.Context, source string, key Key) (Decision, error)
+	Policy() string
+}
@@ -0,0 +1,208 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/omnilogger/config"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/logs"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrAlreadyRunning a purge run was requested while another one is in progress
+var ErrAlreadyRunning = errors.New("retention: purge already running")
+
+var (
+	deletedMetric = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "retention_purge_deleted_total",
+		Help: "The total number of logs deleted by the retention purge",
+	})
+	batchesMetric = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "retention_purge_batches_total",
+		Help: "The total number of batches deleted by the retention purge",
+	})
+	failuresMetric = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "retention_purge_failures_total",
+		Help: "The total number of retention purge runs that failed",
+	})
+	pendingMetric = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "retention_purge_pending_rows",
+		Help: "The number of logs past their retention during the current purge run, partitioned by state expired or held",
+	}, []string{"state"})
+	lastRunMetric = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "retention_purge_last_run_timestamp_seconds",
+		Help: "The time the last retention purge run finished",
+	})
+	lastRunDurationMetric = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "retention_purge_last_run_duration_seconds",
+		Help: "How long the last retention purge run took",
+	})
+)
+
+// DefaultService purges the logs past their retention in small batches
+type DefaultService struct {
+	log      *logger.ContextLogger
+	logsRepo logs.IRepository
+	config   config.RetentionConfigurations
+	rules    []logs.RetentionRule
+	running  atomic.Bool
+	mu       sync.Mutex
+	lastRun  *RunResult
+	now      func() time.Time
+}
+
+// NewDefaultService creates a new instance of DefaultService retention, it fails when a policy is not valid
+func NewDefaultService(l *logger.ContextLogger, r logs.IRepository, c config.RetentionConfigurations) (*DefaultService, error) {
+	rules, err := ToRetentionRules(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaultBatchSize
+	}
+
+	return &DefaultService{
+		log:      l,
+		logsRepo: r,
+		config:   c,
+		rules:    rules,
+		now:      time.Now,
+	}, nil
+}
+
+// Start runs the purge in background every interval, it stops once ctx is done
+func (s *DefaultService) Start(ctx context.Context) {
+	if !s.config.Enabled {
+		s.log.Log(logrus.WarnLevel, "Start", "Retention not enabled, logs are kept forever")
+		return
+	}
+
+	interval := defaultInterval
+	if s.config.IntervalInMinutes > 0 {
+		interval = time.Duration(s.config.IntervalInMinutes) * time.Minute
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := s.Run(ctx); err != nil && !errors.Is(err, ErrAlreadyRunning) {
+				s.log.Error(logrus.ErrorLevel, "Start", "Retention purge failed", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	mode := "purge"
+	if s.config.DryRun {
+		mode = "dry-run"
+	}
+	s.log.Log(logrus.InfoLevel, "Start", "Retention "+mode+" scheduled every "+interval.String())
+}
+
+// Run counts the logs past their retention and deletes them batch by batch unless dry-run is enabled,
+// held logs are never deleted. A run stops early once ctx is done
+func (s *DefaultService) Run(ctx context.Context) (*RunResult, error) {
+	if !s.running.CompareAndSwap(false, true) {
+		return nil, ErrAlreadyRunning
+	}
+	defer s.running.Store(false)
+
+	now := s.now().UTC()
+	result := &RunResult{StartedAt: now, DryRun: s.config.DryRun}
+
+	err := s.purge(ctx, now, result)
+
+	finishedAt := s.now().UTC()
+	result.FinishedAt = &finishedAt
+	if err != nil {
+		result.Error = err.Error()
+		failuresMetric.Inc()
+	}
+
+	pendingMetric.Reset()
+	lastRunMetric.Set(float64(finishedAt.Unix()))
+	lastRunDurationMetric.Set(finishedAt.Sub(now).Seconds())
+
+	s.mu.Lock()
+	s.lastRun = result
+	s.mu.Unlock()
+
+	s.log.Log(logrus.InfoLevel, "Run", result.summary())
+
+	return result, err
+}
+
+func (s *DefaultService) purge(ctx context.Context, now time.Time, result *RunResult) error {
+	if !hasExpiringRule(s.rules) {
+		return nil
+	}
+
+	count, err := s.logsRepo.CountExpired(ctx, s.rules, now, s.config.ArchivedOnly)
+	if err != nil {
+		return err
+	}
+	result.Expired = count.Expired
+	result.Held = count.Held
+	pendingMetric.WithLabelValues("expired").Set(float64(count.Expired))
+	pendingMetric.WithLabelValues("held").Set(float64(count.Held))
+
+	if s.config.DryRun {
+		return nil
+	}
+
+	for ctx.Err() == nil {
+		deleted, err := s.logsRepo.PurgeExpired(ctx, s.rules, now, s.config.BatchSize, s.config.ArchivedOnly)
+		if err != nil {
+			return err
+		}
+
+		if deleted > 0 {
+			result.Deleted += deleted
+			result.Batches++
+			deletedMetric.Add(float64(deleted))
+			batchesMetric.Inc()
+			pendingMetric.WithLabelValues("expired").Set(float64(max(result.Expired-result.Deleted, 0)))
+		}
+
+		if deleted < s.config.BatchSize {
+			return nil
+		}
+	}
+
+	return ctx.Err()
+}
+
+// Status returns the retention settings and the results of the last purge run
+func (s *DefaultService) Status() *Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var lastRun *RunResult
+	if s.lastRun != nil {
+		copied := *s.lastRun
+		lastRun = &copied
+	}
+
+	return &Status{
+		Enabled:           s.config.Enabled,
+		DryRun:            s.config.DryRun,
+		IntervalInMinutes: s.config.IntervalInMinutes,
+		BatchSize:         s.config.BatchSize,
+		Running:           s.running.Load(),
+		LastRun:           lastRun,
+	}
+}
@@ -0,0 +1,108 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/omnilogger/config"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/logs"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/logs/logsmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRun(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestRun", "debug", logger.TextFormat)
+	policy := config.RetentionPolicyConfigurations{Days: 30}
+
+	cases := []struct {
+		name     string
+		config   config.RetentionConfigurations
+		repoFunc func() *logsmock.IRepository
+		expected RunResult
+		err      bool
+	}{
+		{
+			name:   "Purge in batches",
+			config: config.RetentionConfigurations{BatchSize: 2, Policy: policy},
+			repoFunc: func() *logsmock.IRepository {
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("CountExpired", mock.Anything, mock.Anything, mock.Anything, false).Return(logs.RetentionCount{Expired: 6, Held: 1}, nil)
+				repoMock.On("PurgeExpired", mock.Anything, mock.Anything, mock.Anything, 2, false).Return(2, nil).Twice()
+				repoMock.On("PurgeExpired", mock.Anything, mock.Anything, mock.Anything, 2, false).Return(1, nil).Once()
+				return repoMock
+			},
+			expected: RunResult{Expired: 6, Held: 1, Deleted: 5, Batches: 3},
+		},
+		{
+			name:   "Dry run only counts",
+			config: config.RetentionConfigurations{DryRun: true, Policy: policy},
+			repoFunc: func() *logsmock.IRepository {
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("CountExpired", mock.Anything, mock.Anything, mock.Anything, false).Return(logs.RetentionCount{Expired: 6}, nil)
+				return repoMock
+			},
+			expected: RunResult{DryRun: true, Expired: 6},
+		},
+		{
+			name:   "Nothing expires",
+			config: config.RetentionConfigurations{},
+			repoFunc: func() *logsmock.IRepository {
+				return &logsmock.IRepository{}
+			},
+		},
+		{
+			name:   "Purge error",
+			config: config.RetentionConfigurations{BatchSize: 2, Policy: policy},
+			repoFunc: func() *logsmock.IRepository {
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("CountExpired", mock.Anything, mock.Anything, mock.Anything, false).Return(logs.RetentionCount{Expired: 6}, nil)
+				repoMock.On("PurgeExpired", mock.Anything, mock.Anything, mock.Anything, 2, false).Return(2, nil).Once()
+				repoMock.On("PurgeExpired", mock.Anything, mock.Anything, mock.Anything, 2, false).Return(0, errors.New("db down")).Once()
+				return repoMock
+			},
+			expected: RunResult{Expired: 6, Deleted: 2, Batches: 1, Error: "db down"},
+			err:      true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repoMock := tc.repoFunc()
+			svc, err := NewDefaultService(ctxLogger, repoMock, tc.config)
+			assert.NoError(t, err)
+			startedAt := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+			svc.now = func() time.Time { return startedAt }
+
+			res, err := svc.Run(context.Background())
+			if tc.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			tc.expected.StartedAt = startedAt
+			tc.expected.FinishedAt = &startedAt
+			assert.Equal(t, &tc.expected, res)
+			assert.Equal(t, res, svc.Status().LastRun)
+			assert.False(t, svc.Status().Running)
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRun_AlreadyRunning(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestRun_AlreadyRunning", "debug", logger.TextFormat)
+	svc, err := NewDefaultService(ctxLogger, &logsmock.IRepository{}, config.RetentionConfigurations{})
+	assert.NoError(t, err)
+
+	svc.running.Store(true)
+	_, err = svc.Run(context.Background())
+
+	assert.ErrorIs(t, err, ErrAlreadyRunning)
+	assert.True(t, svc.Status().Running)
+	assert.Nil(t, svc.Status().LastRun)
+}
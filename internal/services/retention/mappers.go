@@ -0,0 +1,132 @@
+package retention
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jmontesinos91/omnilogger/config"
+	"github.com/jmontesinos91/omnilogger/domains/level"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/logs"
+)
+
+// ToRetentionRules builds the ordered retention rules of the configured policies, every tenant gets the global
+// level and resource overrides merged with its own, resource overrides come before level overrides and both
+// before the days of the tenant, the global rules apply to the logs of the remaining tenants
+func ToRetentionRules(c config.RetentionConfigurations) ([]logs.RetentionRule, error) {
+	var rules []logs.RetentionRule
+
+	tenantIDs := make([]int, 0, len(c.Tenants))
+	for key := range c.Tenants {
+		id, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retention tenant id %q", key)
+		}
+		tenantIDs = append(tenantIDs, id)
+	}
+	slices.Sort(tenantIDs)
+
+	for _, id := range tenantIDs {
+		policy := mergePolicies(c.Policy, c.Tenants[strconv.Itoa(id)])
+		tenantRules, err := policyRules(&id, policy)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, tenantRules...)
+	}
+
+	globalRules, err := policyRules(nil, c.Policy)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(rules, globalRules...), nil
+}
+
+// mergePolicies overrides the global policy with the values set on the tenant policy
+func mergePolicies(global, tenant config.RetentionPolicyConfigurations) config.RetentionPolicyConfigurations {
+	merged := config.RetentionPolicyConfigurations{
+		Days:      global.Days,
+		Levels:    maps.Clone(global.Levels),
+		Resources: maps.Clone(global.Resources),
+	}
+	if tenant.Days != 0 {
+		merged.Days = tenant.Days
+	}
+	if merged.Levels == nil {
+		merged.Levels = map[string]int{}
+	}
+	if merged.Resources == nil {
+		merged.Resources = map[string]int{}
+	}
+	maps.Copy(merged.Levels, tenant.Levels)
+	for resource, days := range tenant.Resources {
+		// Resources are stored uppercased, the tenant wins whatever the case used on each policy
+		for key := range merged.Resources {
+			if strings.EqualFold(key, resource) {
+				delete(merged.Resources, key)
+			}
+		}
+		merged.Resources[resource] = days
+	}
+
+	return merged
+}
+
+func policyRules(tenantID *int, policy config.RetentionPolicyConfigurations) ([]logs.RetentionRule, error) {
+	var rules []logs.RetentionRule
+
+	for _, resource := range slices.Sorted(maps.Keys(policy.Resources)) {
+		if days := policy.Resources[resource]; days != 0 {
+			rules = append(rules, logs.RetentionRule{TenantID: tenantID, Resource: strings.ToUpper(resource), Days: days})
+		}
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(policy.Levels)) {
+		value, err := strconv.Atoi(key)
+		if err != nil || value < level.Emergency || value > level.Debug {
+			return nil, fmt.Errorf("invalid retention level %q, levels go from %d to %d", key, level.Emergency, level.Debug)
+		}
+		if days := policy.Levels[key]; days != 0 {
+			rules = append(rules, logs.RetentionRule{TenantID: tenantID, Level: &value, Days: days})
+		}
+	}
+
+	return append(rules, logs.RetentionRule{TenantID: tenantID, Days: policy.Days}), nil
+}
+
+// hasExpiringRule whether any rule deletes logs, when every rule keeps logs forever there is nothing to purge
+func hasExpiringRule(rules []logs.RetentionRule) bool {
+	for _, rule := range rules {
+		if rule.Days > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LongestRetention days after which every log is past its retention whatever policy applies to it, false when
+// the purge is not deleting logs or some logs are kept forever
+func LongestRetention(c config.RetentionConfigurations) (int, bool, error) {
+	if !c.Enabled || c.DryRun {
+		return 0, false, nil
+	}
+
+	rules, err := ToRetentionRules(c)
+	if err != nil {
+		return 0, false, err
+	}
+
+	longest := 0
+	for _, rule := range rules {
+		if rule.Days <= 0 {
+			return 0, false, nil
+		}
+		longest = max(longest, rule.Days)
+	}
+
+	return longest, true, nil
+}
@@ -0,0 +1,149 @@
+package retention
+
+import (
+	"testing"
+
+	"github.com/jmontesinos91/omnilogger/config"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/logs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToRetentionRules(t *testing.T) {
+	tenant := 5
+	debug := 7
+	info := 6
+
+	cases := []struct {
+		name     string
+		config   config.RetentionConfigurations
+		expected []logs.RetentionRule
+		err      bool
+	}{
+		{
+			name:     "Keep forever by default",
+			expected: []logs.RetentionRule{{Days: 0}},
+		},
+		{
+			name: "Global overrides",
+			config: config.RetentionConfigurations{Policy: config.RetentionPolicyConfigurations{
+				Days:      365,
+				Levels:    map[string]int{"7": 7, "6": 0},
+				Resources: map[string]int{"auth": -1},
+			}},
+			expected: []logs.RetentionRule{
+				{Resource: "AUTH", Days: -1},
+				{Level: &debug, Days: 7},
+				{Days: 365},
+			},
+		},
+		{
+			name: "Tenant merged with the global policy",
+			config: config.RetentionConfigurations{
+				Policy: config.RetentionPolicyConfigurations{
+					Days:      365,
+					Levels:    map[string]int{"7": 7},
+					Resources: map[string]int{"AUTH": 730},
+				},
+				Tenants: map[string]config.RetentionPolicyConfigurations{
+					"5": {Days: 90, Levels: map[string]int{"6": 30}, Resources: map[string]int{"auth": -1}},
+				},
+			},
+			expected: []logs.RetentionRule{
+				{TenantID: &tenant, Resource: "AUTH", Days: -1},
+				{TenantID: &tenant, Level: &info, Days: 30},
+				{TenantID: &tenant, Level: &debug, Days: 7},
+				{TenantID: &tenant, Days: 90},
+				{Resource: "AUTH", Days: 730},
+				{Level: &debug, Days: 7},
+				{Days: 365},
+			},
+		},
+		{
+			name: "Tenant inherits the global days",
+			config: config.RetentionConfigurations{
+				Policy:  config.RetentionPolicyConfigurations{Days: 365},
+				Tenants: map[string]config.RetentionPolicyConfigurations{"5": {Levels: map[string]int{"7": 7}}},
+			},
+			expected: []logs.RetentionRule{
+				{TenantID: &tenant, Level: &debug, Days: 7},
+				{TenantID: &tenant, Days: 365},
+				{Days: 365},
+			},
+		},
+		{
+			name: "Invalid tenant",
+			config: config.RetentionConfigurations{
+				Tenants: map[string]config.RetentionPolicyConfigurations{"acme": {Days: 1}},
+			},
+			err: true,
+		},
+		{
+			name: "Invalid level",
+			config: config.RetentionConfigurations{
+				Policy: config.RetentionPolicyConfigurations{Levels: map[string]int{"debug": 1}},
+			},
+			err: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rules, err := ToRetentionRules(tc.config)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, rules)
+		})
+	}
+}
+
+func TestLongestRetention(t *testing.T) {
+	cases := []struct {
+		name     string
+		config   config.RetentionConfigurations
+		days     int
+		expiring bool
+	}{
+		{
+			name:     "Longest of every policy",
+			config:   config.RetentionConfigurations{Enabled: true, Policy: config.RetentionPolicyConfigurations{Days: 90, Levels: map[string]int{"7": 7}}, Tenants: map[string]config.RetentionPolicyConfigurations{"5": {Days: 365}}},
+			days:     365,
+			expiring: true,
+		},
+		{
+			name:   "Resource kept forever",
+			config: config.RetentionConfigurations{Enabled: true, Policy: config.RetentionPolicyConfigurations{Days: 90, Resources: map[string]int{"auth": -1}}},
+		},
+		{
+			name:   "Global policy keeps forever",
+			config: config.RetentionConfigurations{Enabled: true, Tenants: map[string]config.RetentionPolicyConfigurations{"5": {Days: 30}}},
+		},
+		{
+			name:   "Dry run",
+			config: config.RetentionConfigurations{Enabled: true, DryRun: true, Policy: config.RetentionPolicyConfigurations{Days: 90}},
+		},
+		{
+			name:     "Archived only",
+			config:   config.RetentionConfigurations{Enabled: true, ArchivedOnly: true, Policy: config.RetentionPolicyConfigurations{Days: 90}},
+			days:     90,
+			expiring: true,
+		},
+		{
+			name:   "Disabled",
+			config: config.RetentionConfigurations{Policy: config.RetentionPolicyConfigurations{Days: 90}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			days, expiring, err := LongestRetention(tc.config)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.days, days)
+			assert.Equal(t, tc.expiring, expiring)
+		})
+	}
+}
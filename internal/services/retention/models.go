@@ -0,0 +1,43 @@
+package retention
+
+import (
+	"fmt"
+	"time"
+)
+
+// defaultBatchSize number of logs deleted per batch when batch-size is not configured
+const defaultBatchSize = 1000
+
+// defaultInterval time between purges when interval-in-minutes is not configured
+const defaultInterval = time.Hour
+
+// RunResult outcome of a purge run, Expired counts every log past its retention and Held the ones kept
+// by a legal hold, in dry-run mode nothing is deleted
+type RunResult struct {
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	DryRun     bool       `json:"dryRun"`
+	Expired    int        `json:"expired"`
+	Held       int        `json:"held"`
+	Deleted    int        `json:"deleted"`
+	Batches    int        `json:"batches"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// Status retention settings and the results of the last purge run
+type Status struct {
+	Enabled           bool       `json:"enabled"`
+	DryRun            bool       `json:"dryRun"`
+	IntervalInMinutes int        `json:"intervalInMinutes"`
+	BatchSize         int        `json:"batchSize"`
+	Running           bool       `json:"running"`
+	LastRun           *RunResult `json:"lastRun"`
+}
+
+func (r *RunResult) summary() string {
+	if r.DryRun {
+		return fmt.Sprintf("Retention dry-run: %d logs expired, %d held", r.Expired, r.Held)
+	}
+
+	return fmt.Sprintf("Retention purge: %d logs expired, %d held, %d deleted in %d batches", r.Expired, r.Held, r.Deleted, r.Batches)
+}
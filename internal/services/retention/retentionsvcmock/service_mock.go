@@ -0,0 +1,37 @@
+package retentionsvcmock
+
+import (
+	"context"
+
+	"github.com/jmontesinos91/omnilogger/internal/services/retention"
+)
+
+type IService struct {
+	// Run
+	RunErr    error
+	RunRes    *retention.RunResult
+	RunCalled bool
+
+	// Status
+	StatusRes    *retention.Status
+	StatusCalled bool
+}
+
+func (m *IService) Run(ctx context.Context) (*retention.RunResult, error) {
+	m.RunCalled = true
+	if m.RunErr != nil {
+		return nil, m.RunErr
+	}
+	if m.RunRes != nil {
+		return m.RunRes, nil
+	}
+	return &retention.RunResult{}, nil
+}
+
+func (m *IService) Status() *retention.Status {
+	m.StatusCalled = true
+	if m.StatusRes != nil {
+		return m.StatusRes
+	}
+	return &retention.Status{}
+}
@@ -0,0 +1,11 @@
+package retention
+
+import (
+	"context"
+)
+
+// IService Manage retention interfaces
+type IService interface {
+	Run(ctx context.Context) (*RunResult, error)
+	Status() *Status
+}
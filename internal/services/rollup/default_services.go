@@ -0,0 +1,175 @@
+package rollup
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/omnilogger/config"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/logs"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrAlreadyRunning a run was requested while another one is in progress
+var ErrAlreadyRunning = errors.New("rollup: already running")
+
+var (
+	bucketsMetric = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "logs_rollup_buckets_total",
+		Help: "The total number of buckets rolled up, partitioned by granularity hour or day",
+	}, []string{"granularity"})
+	lagMetric = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "logs_rollup_lag_seconds",
+		Help: "The time between the last run and the watermark of the rollups, partitioned by granularity hour or day",
+	}, []string{"granularity"})
+	failuresMetric = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "logs_rollup_failures_total",
+		Help: "The total number of rollup runs that failed",
+	})
+)
+
+// DefaultService keeps the hourly and daily rollups of the logs up to date
+type DefaultService struct {
+	log      *logger.ContextLogger
+	logsRepo logs.IRepository
+	config   config.RollupsConfigurations
+	lag      time.Duration
+	running  atomic.Bool
+	now      func() time.Time
+}
+
+// NewDefaultService creates a new instance of DefaultService rollup
+func NewDefaultService(l *logger.ContextLogger, r logs.IRepository, c config.RollupsConfigurations) *DefaultService {
+	lag := defaultLag
+	if c.LagInMinutes > 0 {
+		lag = time.Duration(c.LagInMinutes) * time.Minute
+	}
+
+	if c.BackfillDays <= 0 {
+		c.BackfillDays = defaultBackfillDays
+	}
+
+	if c.MaxHoursPerRun <= 0 {
+		c.MaxHoursPerRun = defaultMaxHoursPerRun
+	}
+
+	return &DefaultService{
+		log:      l,
+		logsRepo: r,
+		config:   c,
+		lag:      lag,
+		now:      time.Now,
+	}
+}
+
+// Start runs the rollups in background every interval, it stops once ctx is done
+func (s *DefaultService) Start(ctx context.Context) {
+	if !s.config.Enabled {
+		s.log.Log(logrus.WarnLevel, "Start", "Rollups not enabled, stats are counted on the logs")
+		return
+	}
+
+	interval := defaultInterval
+	if s.config.IntervalInMinutes > 0 {
+		interval = time.Duration(s.config.IntervalInMinutes) * time.Minute
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := s.Run(ctx); err != nil && !errors.Is(err, ErrAlreadyRunning) {
+				s.log.Error(logrus.ErrorLevel, "Start", "Rollup run failed", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	s.log.Log(logrus.InfoLevel, "Start", "Rollups scheduled every "+interval.String())
+}
+
+// Run rolls up the hours closed since the last run, then the days whose hours are all rolled up. Buckets are
+// rebuilt as a whole so a run interrupted halfway is completed by the next one
+func (s *DefaultService) Run(ctx context.Context) (*RunResult, error) {
+	if !s.running.CompareAndSwap(false, true) {
+		return nil, ErrAlreadyRunning
+	}
+	defer s.running.Store(false)
+
+	now := s.now().UTC()
+	result := &RunResult{StartedAt: now}
+
+	err := s.rollup(ctx, now, result)
+
+	finishedAt := s.now().UTC()
+	result.FinishedAt = &finishedAt
+	if err != nil {
+		result.Error = err.Error()
+		failuresMetric.Inc()
+	}
+
+	s.log.Log(logrus.InfoLevel, "Run", result.summary())
+
+	return result, err
+}
+
+func (s *DefaultService) rollup(ctx context.Context, now time.Time, result *RunResult) error {
+	watermarks, err := s.logsRepo.RollupWatermarks(ctx)
+	if err != nil {
+		return err
+	}
+
+	backfill := startOfDay(now).AddDate(0, 0, -s.config.BackfillDays)
+	closed := now.Add(-s.lag).Truncate(time.Hour)
+
+	hourly, found := watermarks[logs.GranularityHour]
+	from, to, ok := nextRange(hourly, found, backfill, closed, time.Duration(s.config.MaxHoursPerRun)*time.Hour)
+	if ok {
+		rows, err := s.logsRepo.Rollup(ctx, logs.GranularityHour, from, to)
+		if err != nil {
+			return err
+		}
+		hours := int(to.Sub(from) / time.Hour)
+		result.Hours += hours
+		result.Rows += rows
+		bucketsMetric.WithLabelValues(logs.GranularityHour).Add(float64(hours))
+		hourly, found = to, true
+	}
+	if found {
+		lagMetric.WithLabelValues(logs.GranularityHour).Set(now.Sub(hourly).Seconds())
+	}
+
+	// Days are added up from their hours, only the days whose hours are all rolled up are closed
+	if !found {
+		return nil
+	}
+
+	daily, dailyFound := watermarks[logs.GranularityDay]
+	from, to, ok = nextRange(daily, dailyFound, backfill, startOfDay(hourly), 0)
+	if ok {
+		rows, err := s.logsRepo.Rollup(ctx, logs.GranularityDay, from, to)
+		if err != nil {
+			return err
+		}
+		days := int(to.Sub(from) / (24 * time.Hour))
+		result.Days += days
+		result.Rows += rows
+		bucketsMetric.WithLabelValues(logs.GranularityDay).Add(float64(days))
+		daily, dailyFound = to, true
+	}
+	if dailyFound {
+		lagMetric.WithLabelValues(logs.GranularityDay).Set(now.Sub(daily).Seconds())
+	}
+
+	return nil
+}
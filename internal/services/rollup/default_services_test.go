@@ -0,0 +1,106 @@
+package rollup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/omnilogger/config"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/logs"
+	"github.com/jmontesinos91/omnilogger/internal/repositories/logs/logsmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRun(t *testing.T) {
+	ctxLogger := logger.NewContextLogger("TestRun", "debug", logger.TextFormat)
+	now := time.Date(2024, 6, 10, 12, 3, 0, 0, time.UTC)
+	closed := time.Date(2024, 6, 10, 11, 0, 0, 0, time.UTC)
+	today := time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name     string
+		config   config.RollupsConfigurations
+		repoFunc func() *logsmock.IRepository
+		expected RunResult
+		err      bool
+	}{
+		{
+			name:   "First run backfills",
+			config: config.RollupsConfigurations{BackfillDays: 2, MaxHoursPerRun: 100},
+			repoFunc: func() *logsmock.IRepository {
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("RollupWatermarks", mock.Anything).Return(map[string]time.Time{}, nil)
+				repoMock.On("Rollup", mock.Anything, logs.GranularityHour, today.AddDate(0, 0, -2), closed).Return(40, nil)
+				repoMock.On("Rollup", mock.Anything, logs.GranularityDay, today.AddDate(0, 0, -2), today).Return(10, nil)
+				return repoMock
+			},
+			expected: RunResult{Hours: 59, Days: 2, Rows: 50},
+		},
+		{
+			name:   "Incremental",
+			config: config.RollupsConfigurations{},
+			repoFunc: func() *logsmock.IRepository {
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("RollupWatermarks", mock.Anything).Return(map[string]time.Time{
+					logs.GranularityHour: closed.Add(-time.Hour),
+					logs.GranularityDay:  today,
+				}, nil)
+				repoMock.On("Rollup", mock.Anything, logs.GranularityHour, closed.Add(-time.Hour), closed).Return(3, nil)
+				return repoMock
+			},
+			expected: RunResult{Hours: 1, Rows: 3},
+		},
+		{
+			name:   "Capped per run",
+			config: config.RollupsConfigurations{MaxHoursPerRun: 24},
+			repoFunc: func() *logsmock.IRepository {
+				repoMock := &logsmock.IRepository{}
+				start := today.AddDate(0, 0, -5)
+				repoMock.On("RollupWatermarks", mock.Anything).Return(map[string]time.Time{
+					logs.GranularityHour: start,
+					logs.GranularityDay:  start,
+				}, nil)
+				repoMock.On("Rollup", mock.Anything, logs.GranularityHour, start, start.AddDate(0, 0, 1)).Return(24, nil)
+				repoMock.On("Rollup", mock.Anything, logs.GranularityDay, start, start.AddDate(0, 0, 1)).Return(1, nil)
+				return repoMock
+			},
+			expected: RunResult{Hours: 24, Days: 1, Rows: 25},
+		},
+		{
+			name:   "Rollup error",
+			config: config.RollupsConfigurations{},
+			repoFunc: func() *logsmock.IRepository {
+				repoMock := &logsmock.IRepository{}
+				repoMock.On("RollupWatermarks", mock.Anything).Return(map[string]time.Time{logs.GranularityHour: closed.Add(-time.Hour)}, nil)
+				repoMock.On("Rollup", mock.Anything, logs.GranularityHour, mock.Anything, mock.Anything).Return(0, errors.New("db down"))
+				return repoMock
+			},
+			err: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repoMock := tc.repoFunc()
+			svc := NewDefaultService(ctxLogger, repoMock, tc.config)
+			svc.now = func() time.Time { return now }
+
+			res, err := svc.Run(context.Background())
+			if tc.err {
+				assert.Error(t, err)
+				assert.Equal(t, err.Error(), res.Error)
+				res.Error = ""
+			} else {
+				assert.NoError(t, err)
+			}
+
+			tc.expected.StartedAt = now
+			tc.expected.FinishedAt = &now
+			assert.Equal(t, &tc.expected, res)
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
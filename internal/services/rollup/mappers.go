@@ -0,0 +1,25 @@
+package rollup
+
+import (
+	"time"
+)
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// nextRange range of buckets to roll up, it starts at the watermark or at the backfill start when there is none
+// and ends at the limit, at most max after its start. It returns false when there is nothing to roll up
+func nextRange(watermark time.Time, found bool, backfill, limit time.Time, max time.Duration) (time.Time, time.Time, bool) {
+	from := backfill
+	if found {
+		from = watermark
+	}
+
+	to := limit
+	if max > 0 && to.Sub(from) > max {
+		to = from.Add(max)
+	}
+
+	return from, to, to.After(from)
+}
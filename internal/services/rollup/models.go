@@ -0,0 +1,32 @@
+package rollup
+
+import (
+	"fmt"
+	"time"
+)
+
+// defaultInterval time between runs when interval-in-minutes is not configured
+const defaultInterval = 5 * time.Minute
+
+// defaultLag time an hour must be closed for before it is rolled up when lag-in-minutes is not configured
+const defaultLag = 5 * time.Minute
+
+// defaultBackfillDays days rolled up by the first run when backfill-days is not configured
+const defaultBackfillDays = 30
+
+// defaultMaxHoursPerRun hours rolled up per run when max-hours-per-run is not configured
+const defaultMaxHoursPerRun = 7 * 24
+
+// RunResult outcome of a rollup run, Hours and Days count the buckets rolled up and Rows the rollup rows written
+type RunResult struct {
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Hours      int        `json:"hours"`
+	Days       int        `json:"days"`
+	Rows       int        `json:"rows"`
+	Error      string     `json:"error,omitempty"`
+}
+
+func (r *RunResult) summary() string {
+	return fmt.Sprintf("Rollups: %d hours and %d days rolled up in %d rows", r.Hours, r.Days, r.Rows)
+}
@@ -0,0 +1,10 @@
+package rollup
+
+import (
+	"context"
+)
+
+// IService Manage rollup interfaces
+type IService interface {
+	Run(ctx context.Context) (*RunResult, error)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/jmontesinos91/ologs/logger"
 	tracekey "github.com/jmontesinos91/ologs/logger/v2"
 	"github.com/jmontesinos91/omnilogger/internal/services/logs"
+	"github.com/jmontesinos91/omnilogger/internal/services/ratelimit"
 	"github.com/sirupsen/logrus"
 )
 
@@ -16,14 +17,16 @@ import (
 type LogCreatedWorker struct {
 	log          *logger.ContextLogger
 	logSvc       logs.IService
+	rateLimitSvc ratelimit.IService
 	streamClient broker.MessagingBrokerProvider
 }
 
 // NewLogCreatedWorker Generates a LogCreatedWorker instance
-func NewLogCreatedWorker(l *logger.ContextLogger, ls logs.IService, sc broker.MessagingBrokerProvider) *LogCreatedWorker {
+func NewLogCreatedWorker(l *logger.ContextLogger, ls logs.IService, rls ratelimit.IService, sc broker.MessagingBrokerProvider) *LogCreatedWorker {
 	return &LogCreatedWorker{
 		log:          l,
 		logSvc:       ls,
+		rateLimitSvc: rls,
 		streamClient: sc,
 	}
 }
@@ -52,7 +55,15 @@ func (w *LogCreatedWorker) Handle(ctx context.Context, event oevents.OmniViewEve
 		},
 		err)
 
-	errCFK := w.logSvc.CreateLogFromKafka(ctx, eventPayload)
+	allowed, err := w.throttle(ctx, event, eventPayload)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return nil
+	}
+
+	errCFK := w.logSvc.CreateLogFromKafka(ctx, eventPayload, logs.ToEventOccurredAt(event))
 	if errCFK != nil {
 		w.log.WithContext(
 			logrus.ErrorLevel,
@@ -70,3 +81,42 @@ func (w *LogCreatedWorker) Handle(ctx context.Context, event oevents.OmniViewEve
 
 	return nil
 }
+
+// throttle applies the overflow policy to producers over their limit, with the wait policy the consumer
+// is blocked until the producer has tokens again, events that would wait longer than the maximum wait
+// are discarded, with the drop policy they are discarded right away
+func (w *LogCreatedWorker) throttle(ctx context.Context, event oevents.OmniViewEvent, payload *eventfactory.LogCreatedPayload) (bool, error) {
+	key := ratelimit.ToEventKey(payload)
+
+	if w.rateLimitSvc.Policy() == ratelimit.PolicyDrop {
+		decision := w.rateLimitSvc.Allow(ratelimit.SourceKafka, key, 1)
+		if !decision.Allowed {
+			w.log.WithContext(
+				logrus.WarnLevel,
+				"Handle",
+				"Rate limit exceeded by "+decision.Dimension+", event dropped",
+				logger.Context{
+					tracekey.EventID: event.ID,
+				},
+				nil)
+		}
+		return decision.Allowed, nil
+	}
+
+	decision, err := w.rateLimitSvc.Wait(ctx, ratelimit.SourceKafka, key)
+	if err != nil {
+		return false, err
+	}
+	if !decision.Allowed {
+		w.log.WithContext(
+			logrus.WarnLevel,
+			"Handle",
+			"Rate limit exceeded by "+decision.Dimension+" after max wait, event dropped",
+			logger.Context{
+				tracekey.EventID: event.ID,
+			},
+			nil)
+	}
+
+	return decision.Allowed, nil
+}
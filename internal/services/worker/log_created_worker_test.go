@@ -7,8 +7,11 @@ import (
 	"github.com/jmontesinos91/oevents"
 	"github.com/jmontesinos91/oevents/broker/brokermock"
 	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/omnilogger/config"
 	"github.com/jmontesinos91/omnilogger/internal/repositories/logs/logsmock"
 	"github.com/jmontesinos91/omnilogger/internal/services/logs"
+	"github.com/jmontesinos91/omnilogger/internal/services/ratelimit"
+	"github.com/jmontesinos91/omnilogger/internal/services/ratelimit/ratelimitsvcmock"
 	"github.com/jmontesinos91/terrors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -20,6 +23,7 @@ func TestLogCreatedWorker_Handle(t *testing.T) {
 
 	type fields struct {
 		logsRepo     *logsmock.IRepository
+		rateLimitSvc *ratelimitsvcmock.IService
 		streamClient *brokermock.MessagingBrokerProvider
 	}
 	type args struct {
@@ -225,12 +229,89 @@ func TestLogCreatedWorker_Handle(t *testing.T) {
 				repoMock.AssertCalled(t, "Create", mock.Anything, mock.Anything)
 			},
 		},
+		{
+			name: "Producer over its limit with drop policy",
+			fields: fields{
+				logsRepo: new(logsmock.IRepository),
+				rateLimitSvc: &ratelimitsvcmock.IService{
+					PolicyRes: ratelimit.PolicyDrop,
+					AllowRes:  &ratelimit.Decision{Allowed: false, Dimension: ratelimit.DimensionTenant},
+				},
+			},
+			args: args{
+				event: oevents.OmniViewEvent{
+					ID: "12345",
+					Data: map[string]any{
+						"Provider": "example",
+						"Message":  1,
+						"TenantCat": []map[string]interface{}{
+							{"id": 1, "name": "Tenant A"},
+						},
+					},
+				},
+			},
+			wantErr: false,
+			asserts: func(t *testing.T, err error, repoMock *logsmock.IRepository) {
+				assert.NoError(t, err)
+				repoMock.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name: "Producer over its limit after max wait",
+			fields: fields{
+				logsRepo: new(logsmock.IRepository),
+				rateLimitSvc: &ratelimitsvcmock.IService{
+					WaitRes: &ratelimit.Decision{Allowed: false, Dimension: ratelimit.DimensionProvider},
+				},
+			},
+			args: args{
+				event: oevents.OmniViewEvent{
+					ID: "12345",
+					Data: map[string]any{
+						"Provider": "example",
+						"Message":  1,
+					},
+				},
+			},
+			wantErr: false,
+			asserts: func(t *testing.T, err error, repoMock *logsmock.IRepository) {
+				assert.NoError(t, err)
+				repoMock.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name: "Wait cancelled",
+			fields: fields{
+				logsRepo: new(logsmock.IRepository),
+				rateLimitSvc: &ratelimitsvcmock.IService{
+					WaitErr: context.Canceled,
+				},
+			},
+			args: args{
+				event: oevents.OmniViewEvent{
+					ID: "12345",
+					Data: map[string]any{
+						"Provider": "example",
+						"Message":  1,
+					},
+				},
+			},
+			wantErr: true,
+			asserts: func(t *testing.T, err error, repoMock *logsmock.IRepository) {
+				assert.ErrorIs(t, err, context.Canceled)
+				repoMock.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+			},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			logSvc := logs.NewDefaultService(ctxLogger, tt.fields.logsRepo)
-			worker := NewLogCreatedWorker(ctxLogger, logSvc, tt.fields.streamClient)
+			rateLimitSvc := tt.fields.rateLimitSvc
+			if rateLimitSvc == nil {
+				rateLimitSvc = &ratelimitsvcmock.IService{}
+			}
+			logSvc := logs.NewDefaultService(ctxLogger, tt.fields.logsRepo, config.LogsConfigurations{}, nil, nil, nil, nil)
+			worker := NewLogCreatedWorker(ctxLogger, logSvc, rateLimitSvc, tt.fields.streamClient)
 
 			err := worker.Handle(ctx, tt.args.event)
 			if (err != nil) != tt.wantErr {
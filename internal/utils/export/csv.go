@@ -0,0 +1,111 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/jmontesinos91/omnilogger/internal/utils/format"
+)
+
+// utf8BOM byte order mark Excel needs to open a csv file as UTF-8
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// CSVOptions options of the csv files, the zero value writes comma separated UTF-8 without BOM
+type CSVOptions struct {
+	Delimiter rune
+	BOM       bool
+}
+
+// CSVWriter streams the rows of a csv file to the underlying writer as RFC 4180, the rows are laid out the same way
+// the excel files do. Dates are written in RFC 3339 so the file can be read back without a locale
+type CSVWriter struct {
+	writer *csv.Writer
+}
+
+// NewCSVWriter writes the BOM and the headers of the csv file, no header row is written when headers is empty
+func NewCSVWriter(w io.Writer, headers []string, opts CSVOptions) (*CSVWriter, error) {
+	if opts.BOM {
+		if _, err := w.Write(utf8BOM); err != nil {
+			return nil, err
+		}
+	}
+
+	writer := csv.NewWriter(w)
+	writer.UseCRLF = true
+	if opts.Delimiter != 0 {
+		writer.Comma = opts.Delimiter
+	}
+
+	if len(headers) > 0 {
+		if err := writer.Write(headers); err != nil {
+			return nil, err
+		}
+	}
+
+	return &CSVWriter{writer: writer}, nil
+}
+
+// WriteRow writes the cells of the row then its subgroups, each subgroup starts after the columns of its parent
+func (c *CSVWriter) WriteRow(row format.ExcelRow) error {
+	return c.writeRow(row, 0)
+}
+
+func (c *CSVWriter) writeRow(row format.ExcelRow, level int) error {
+	record := make([]string, level, level+len(row.Cells))
+	for _, cell := range row.Cells {
+		record = append(record, CellText(cell))
+	}
+	if err := c.writer.Write(record); err != nil {
+		return err
+	}
+
+	for _, group := range row.Groups {
+		if err := c.writeRow(group, level+len(row.Cells)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close flushes the rows buffered by the csv writer
+func (c *CSVWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// CellText text of an export cell, nil values are empty and dates are written in RFC 3339. Highlights are dropped
+func CellText(cell interface{}) string {
+	if cell == nil {
+		return ""
+	}
+
+	if highlighted, ok := cell.(format.ExcelCell); ok {
+		return CellText(highlighted.Value)
+	}
+
+	v := reflect.ValueOf(cell)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		cell = v.Elem().Interface()
+	}
+
+	switch v := cell.(type) {
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format(time.RFC3339)
+	case string:
+		if v == "<nil>" {
+			return ""
+		}
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
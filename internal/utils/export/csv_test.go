@@ -0,0 +1,69 @@
+package export_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/jmontesinos91/omnilogger/internal/utils/export"
+	"github.com/jmontesinos91/omnilogger/internal/utils/format"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSVWriter(t *testing.T) {
+	date := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	record := "Crashed; \"twice\"\nsame day"
+
+	owners := []Owner{
+		{FirstName: "John", LastName: "Doe", Acquisition: &date, CriminalRecord: &record},
+		{FirstName: "Jane", LastName: "Roe"},
+	}
+	mapper := func(o Owner) format.ExcelRow {
+		return format.ExcelRow{
+			Cells:  []interface{}{o.FirstName, o.LastName, o.Acquisition, o.CriminalRecord},
+			Groups: []format.ExcelRow{{Cells: []interface{}{o.Crashes}}},
+		}
+	}
+	headers := []string{"FirstName", "LastName", "Acquisition", "CriminalRecord", "Crashes"}
+
+	tests := []struct {
+		name     string
+		opts     export.CSVOptions
+		expected string
+	}{
+		{
+			name: "Comma separated without BOM",
+			opts: export.CSVOptions{},
+			expected: "FirstName,LastName,Acquisition,CriminalRecord,Crashes\r\n" +
+				"John,Doe,2024-01-15T10:00:00Z,\"Crashed; \"\"twice\"\"\r\nsame day\"\r\n" +
+				",,,,\r\n" +
+				"Jane,Roe,,\r\n" +
+				",,,,\r\n",
+		},
+		{
+			name: "Semicolon separated with BOM",
+			opts: export.CSVOptions{Delimiter: ';', BOM: true},
+			expected: "\uFEFFFirstName;LastName;Acquisition;CriminalRecord;Crashes\r\n" +
+				"John;Doe;2024-01-15T10:00:00Z;\"Crashed; \"\"twice\"\"\r\nsame day\"\r\n" +
+				";;;;\r\n" +
+				"Jane;Roe;;\r\n" +
+				";;;;\r\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var content bytes.Buffer
+			writer, err := export.NewCSVWriter(&content, headers, tc.opts)
+			assert.NoError(t, err)
+			for _, owner := range owners {
+				assert.NoError(t, writer.WriteRow(mapper(owner)))
+			}
+			assert.NoError(t, writer.Close())
+			assert.Equal(t, tc.expected, content.String())
+		})
+	}
+
+	_, err := export.NewCSVWriter(&bytes.Buffer{}, headers, export.CSVOptions{Delimiter: '"'})
+	assert.Error(t, err)
+}
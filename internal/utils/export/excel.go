@@ -3,30 +3,106 @@ package export
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"reflect"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/jmontesinos91/omnilogger/internal/utils/format"
 	"github.com/xuri/excelize/v2"
 )
 
-// DataToExcel returns ExcelRow information represented in byte array to be sent via acted-stream
+// DataToExcel returns ExcelRow information represented in byte array to be sent via acted-stream,
+// headers are the field names of T. The sheet is laid out the way ExcelWriter does
 func DataToExcel[T any](sheetName string, data []T, mapperOf func(T) format.ExcelRow) ([]byte, error) {
+	var headers []string
+
+	if len(data) > 0 {
+		// Get first element of data
+		typ := reflect.TypeOf(data[0])
+		if typ.Kind() == reflect.Ptr {
+			// validate if the first element it's a pointer and get its value
+			typ = typ.Elem()
+		}
+		headers = ExtractHeadersRecursively(typ)
+	}
 
+	return DataToExcelWithHeaders(sheetName, headers, data, mapperOf)
+}
+
+// DataToExcelWithHeaders same as DataToExcel using the given headers, for mappers whose columns do not follow the fields of T
+func DataToExcelWithHeaders[T any](sheetName string, headers []string, data []T, mapperOf func(T) format.ExcelRow) ([]byte, error) {
 	var buffer bytes.Buffer
 
-	// WriteRow is a recursive function to print data into the spreadsheet in different levels
-	var writeRow func(row format.ExcelRow, level int) error
+	// Empty data gives an empty sheet
+	if len(data) == 0 {
+		headers = nil
+	}
+
+	writer, err := NewExcelWriter(&buffer, sheetName, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range data {
+		if err := writer.WriteRow(mapperOf(item)); err != nil {
+			writer.Abort()
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
 
+// Layout of the sheets, the widths of the columns are sized from the headers and the first rows since the stream
+// can not change them once a row is written
+const (
+	autosizeRows   = 100
+	minColumnWidth = 10
+	maxColumnWidth = 60
+	tableStyle     = "TableStyleMedium2"
+)
+
+// ExcelWriter streams the rows of a spreadsheet, rows are flushed to a temporary file past the excelize chunk
+// size so memory stays flat whatever the number of rows. Sheets with headers get a frozen header row and are
+// formatted as a table, which adds the autofilter. The spreadsheet is written to w on Close
+type ExcelWriter struct {
+	w                  io.Writer
+	file               *excelize.File
+	stream             *excelize.StreamWriter
+	sheet              int
+	headerStyle        int
+	dateStyle          int
+	highlightStyle     int
+	highlightDateStyle int
+	headers            []string
+	pending            [][]interface{}
+	started            bool
+	columns            int
+	rowIdx             int
+}
+
+// NewExcelWriter creates the sheet and writes its headers, no header row is written when headers is empty
+func NewExcelWriter(w io.Writer, sheetName string, headers []string) (*ExcelWriter, error) {
 	f := excelize.NewFile()
 
-	// Wrap f.Close() in a closure
-	defer func() {
+	writer, err := newExcelWriter(w, f, sheetName, headers)
+	if err != nil {
 		if err := f.Close(); err != nil {
 			fmt.Printf("Error closing the file: %v\n", err)
 		}
-	}()
+		return nil, err
+	}
+
+	return writer, nil
+}
 
+func newExcelWriter(w io.Writer, f *excelize.File, sheetName string, headers []string) (*ExcelWriter, error) {
 	sheet, err := f.NewSheet(sheetName)
 	if err != nil {
 		return nil, err
@@ -59,132 +135,310 @@ func DataToExcel[T any](sheetName string, data []T, mapperOf func(T) format.Exce
 		return nil, err
 	}
 
-	if err := f.SetColWidth(sheetName, "A", "Z", 40); err != nil {
+	// Highlight style, for plain values and dates
+	highlight := excelize.Style{
+		Font: &excelize.Font{Color: "#9C5700"},
+		Fill: excelize.Fill{
+			Type:    "pattern",
+			Color:   []string{"#FFEB9C"},
+			Pattern: 1,
+		},
+	}
+	highlightStyle, err := f.NewStyle(&highlight)
+	if err != nil {
+		return nil, err
+	}
+	highlight.NumFmt = 22
+	highlightDateStyle, err := f.NewStyle(&highlight)
+	if err != nil {
 		return nil, err
 	}
 
-	// Validate empty data
-	if len(data) == 0 {
-		if err := f.Write(&buffer); err != nil {
-			return nil, err
+	writer := &ExcelWriter{
+		w:                  w,
+		file:               f,
+		sheet:              sheet,
+		headerStyle:        headerStyle,
+		dateStyle:          dateStyle,
+		highlightStyle:     highlightStyle,
+		highlightDateStyle: highlightDateStyle,
+	}
+
+	if err := writer.startSheet(sheetName, headers); err != nil {
+		return nil, err
+	}
+
+	return writer, nil
+}
+
+// startSheet opens the stream of the sheet, its headers are written with the first rows
+func (e *ExcelWriter) startSheet(sheetName string, headers []string) error {
+	stream, err := e.file.NewStreamWriter(sheetName)
+	if err != nil {
+		return err
+	}
+
+	e.stream = stream
+	e.headers = uniqueHeaders(headers)
+	e.pending = nil
+	e.started = false
+	e.columns = len(headers)
+	e.rowIdx = 1
+	if len(headers) > 0 {
+		e.rowIdx++
+	}
+
+	return nil
+}
+
+// WriteSheet adds a sheet with its headers and rows, the rows of the previous sheet are complete and can not be
+// written anymore. The sheet created by NewExcelWriter stays the active one
+func (e *ExcelWriter) WriteSheet(sheetName string, headers []string, rows []format.ExcelRow) error {
+	if err := e.finishSheet(); err != nil {
+		return err
+	}
+
+	if _, err := e.file.NewSheet(sheetName); err != nil {
+		return err
+	}
+	if err := e.startSheet(sheetName, headers); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err := e.WriteRow(row); err != nil {
+			return err
 		}
-		return buffer.Bytes(), nil
 	}
 
-	// Starter row index
-	rowIdx := 1
+	return nil
+}
 
-	// Write struct field names as headers (based on reflection of the first element's type)
-	if len(data) > 0 {
+// WriteRow writes the cells of the row then its subgroups, each subgroup starts after the columns of its parent
+func (e *ExcelWriter) WriteRow(row format.ExcelRow) error {
+	return e.writeRow(row, 0)
+}
 
-		// Get first element of data
-		typ := reflect.TypeOf(data[0])
-		if typ.Kind() == reflect.Ptr {
-			// validate if the first element it's a pointer and get its value
-			typ = typ.Elem()
+func (e *ExcelWriter) writeRow(row format.ExcelRow, level int) error {
+	values := make([]interface{}, level, level+len(row.Cells))
+	for _, cell := range row.Cells {
+		values = append(values, e.cellValue(cell))
+	}
+
+	if err := e.setRow(values); err != nil {
+		return err
+	}
+
+	// Count all columns to maintain proper alignment
+	columnsUsed := len(row.Cells)
+
+	// Write subgroups
+	for _, group := range row.Groups {
+		if err := e.writeRow(group, level+columnsUsed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setRow writes the values on the next row, the first rows are held until the widths of the columns are known
+func (e *ExcelWriter) setRow(values []interface{}) error {
+	e.columns = max(e.columns, len(values))
+
+	if !e.started {
+		e.pending = append(e.pending, values)
+		if len(e.pending) < autosizeRows {
+			return nil
+		}
+		return e.start()
+	}
+
+	cellPosition, err := excelize.CoordinatesToCellName(1, e.rowIdx)
+	if err != nil {
+		return err
+	}
+	if err := e.stream.SetRow(cellPosition, values); err != nil {
+		return err
+	}
+	e.rowIdx++
+
+	return nil
+}
+
+// start sizes the columns from the headers and the held rows, freezes the header row and writes the held rows
+func (e *ExcelWriter) start() error {
+	widths := make([]int, e.columns)
+	for i, header := range e.headers {
+		// The header is bold and larger, and holds the filter button
+		widths[i] = utf8.RuneCountInString(header)*3/2 + 4
+	}
+	for _, values := range e.pending {
+		for i, value := range values {
+			widths[i] = max(widths[i], utf8.RuneCountInString(CellText(cellContent(value)))+2)
+		}
+	}
+	for i, width := range widths {
+		if err := e.stream.SetColWidth(i+1, i+1, float64(min(max(width, minColumnWidth), maxColumnWidth))); err != nil {
+			return err
+		}
+	}
+
+	if len(e.headers) > 0 {
+		err := e.stream.SetPanes(&excelize.Panes{
+			Freeze:      true,
+			YSplit:      1,
+			TopLeftCell: "A2",
+			ActivePane:  "bottomLeft",
+		})
+		if err != nil {
+			return err
+		}
+
+		values := make([]interface{}, len(e.headers))
+		for i, header := range e.headers {
+			values[i] = excelize.Cell{StyleID: e.headerStyle, Value: header}
 		}
-		headers := ExtractHeadersRecursively(typ)
-
-		// Save initial headers
-		headerRow := format.ExcelRow{Cells: make([]interface{}, len(headers))}
-		for i, header := range headers {
-			headerRow.Cells[i] = header
-		}
-		colIdx := 0
-		for _, cell := range headerRow.Cells {
-			cellPosition := fmt.Sprintf("%s%d", string(rune('A'+colIdx)), rowIdx)
-			if cell != nil {
-				if err := f.SetCellValue(sheetName, cellPosition, cell); err != nil {
-					return nil, err
-				}
-				if err := f.SetCellStyle(sheetName, cellPosition, cellPosition, headerStyle); err != nil {
-					return nil, err
-				}
-			}
-			colIdx++
-		}
-		rowIdx++
-	}
-
-	writeRow = func(row format.ExcelRow, level int) error {
-		// Write the row cells
-		colIdx := 0
-		for _, cell := range row.Cells {
-
-			// Calculate the cell position based on column and level
-			cellPosition := fmt.Sprintf("%s%d", string(rune('A'+colIdx+level)), rowIdx)
-
-			if cell != nil && !(reflect.ValueOf(cell).Kind() == reflect.Ptr && reflect.ValueOf(cell).IsNil()) { //nolint:staticcheck
-
-				v := reflect.ValueOf(cell)
-				if v.Kind() == reflect.Ptr && !v.IsNil() {
-					// get the pointer value and sets it to cell
-					cell = v.Elem().Interface()
-				}
-
-				switch v := cell.(type) {
-				case time.Time:
-					if err := f.SetCellValue(sheetName, cellPosition, v); err != nil {
-						return err
-					}
-
-					// Set date format
-					if err := f.SetCellStyle(sheetName, cellPosition, cellPosition, dateStyle); err != nil {
-						return err
-					}
-				case *time.Time:
-					if v != nil {
-						if err := f.SetCellValue(sheetName, cellPosition, *v); err != nil {
-							return err
-						}
-
-						// Set date format
-						if err := f.SetCellStyle(sheetName, cellPosition, cellPosition, dateStyle); err != nil {
-							return err
-						}
-					}
-				default:
-					if cell != "<nil>" {
-						if err := f.SetCellValue(sheetName, cellPosition, cell); err != nil {
-							return err
-						}
-					}
-
-				}
-
-			}
-			colIdx++
-		}
-		rowIdx++
-
-		// Count all columns to maintain proper alignment
-		columnsUsed := len(row.Cells)
-
-		// Write subgroups
-		for _, group := range row.Groups {
-			if err := writeRow(group, level+columnsUsed); err != nil {
-				return err
-			}
+		if err := e.stream.SetRow("A1", values); err != nil {
+			return err
 		}
+	}
+
+	pending := e.pending
+	e.pending = nil
+	e.started = true
+	e.rowIdx = 1
+	if len(e.headers) > 0 {
+		e.rowIdx++
+	}
+
+	for _, values := range pending {
+		if err := e.setRow(values); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// finishSheet writes the held rows, formats the headers and rows of the sheet as a table then flushes the stream
+func (e *ExcelWriter) finishSheet() error {
+	if !e.started {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+
+	if len(e.headers) > 0 {
+		lastCell, err := excelize.CoordinatesToCellName(len(e.headers), max(e.rowIdx-1, 2))
+		if err != nil {
+			return err
+		}
+		stripes := true
+		err = e.stream.AddTable(&excelize.Table{
+			Range:          "A1:" + lastCell,
+			StyleName:      tableStyle,
+			ShowRowStripes: &stripes,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return e.stream.Flush()
+}
+
+// uniqueHeaders headers of the table, the names of its columns must be unique so repeated headers are numbered
+// the way Excel does and empty ones are named after their column
+func uniqueHeaders(headers []string) []string {
+	result := make([]string, len(headers))
+	seen := make(map[string]bool, len(headers))
+	for i, header := range headers {
+		if header == "" {
+			header = fmt.Sprintf("Column%d", i+1)
+		}
+
+		name := header
+		for n := 2; seen[strings.ToLower(name)]; n++ {
+			name = fmt.Sprintf("%s%d", header, n)
+		}
+		seen[strings.ToLower(name)] = true
+		result[i] = name
+	}
+
+	return result
+}
+
+// cellContent value of a written cell, without its style
+func cellContent(value interface{}) interface{} {
+	if cell, ok := value.(excelize.Cell); ok {
+		return cell.Value
+	}
+
+	return value
+}
+
+// cellValue value written for the cell, nil values are left empty and dates take the date style. Highlighted cells
+// take the highlight style, even when empty
+func (e *ExcelWriter) cellValue(cell interface{}) interface{} {
+	if cell == nil {
 		return nil
 	}
 
-	// Process all generic data
-	for _, item := range data {
-		row := mapperOf(item)
-		if err := writeRow(row, 0); err != nil {
-			return nil, err
+	if highlighted, ok := cell.(format.ExcelCell); ok {
+		value := e.cellValue(highlighted.Value)
+		if !highlighted.Highlight {
+			return value
+		}
+		if date, ok := value.(excelize.Cell); ok {
+			return excelize.Cell{StyleID: e.highlightDateStyle, Value: date.Value}
 		}
+		return excelize.Cell{StyleID: e.highlightStyle, Value: value}
 	}
 
-	f.SetActiveSheet(sheet)
+	v := reflect.ValueOf(cell)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		// get the pointer value and sets it to cell
+		cell = v.Elem().Interface()
+	}
 
-	// f.Write writes all spreadsheet bytes into buffer
-	if err := f.Write(&buffer); err != nil {
-		return nil, err
+	switch v := cell.(type) {
+	case time.Time:
+		return excelize.Cell{StyleID: e.dateStyle, Value: v}
+	case string:
+		if v == "<nil>" {
+			return nil
+		}
 	}
 
-	return buffer.Bytes(), nil
+	return cell
+}
+
+// Close completes the spreadsheet and writes it to the writer
+func (e *ExcelWriter) Close() error {
+	defer e.closeFile()
+
+	if err := e.finishSheet(); err != nil {
+		return err
+	}
+
+	e.file.SetActiveSheet(e.sheet)
+
+	// f.Write writes all spreadsheet bytes into the writer
+	return e.file.Write(e.w)
+}
+
+// Abort releases the spreadsheet without writing it
+func (e *ExcelWriter) Abort() {
+	e.closeFile()
+}
+
+func (e *ExcelWriter) closeFile() {
+	if err := e.file.Close(); err != nil {
+		fmt.Printf("Error closing the file: %v\n", err)
+	}
 }
 
 func ExtractHeadersRecursively(typ reflect.Type) []string {
@@ -604,3 +604,137 @@ func TestDataToExcel_WithPointerRoot(t *testing.T) {
 		})
 	}
 }
+
+func TestDataToExcelWithHeaders(t *testing.T) {
+	headers := []string{"Amount", "label.env"}
+
+	data := []Payment{{Amount: 10.5}}
+	mapper := func(payment Payment) format.ExcelRow {
+		return format.ExcelRow{Cells: []interface{}{payment.Amount, "prod"}}
+	}
+
+	excelBytes, err := export.DataToExcelWithHeaders("payments", headers, data, mapper)
+	assert.NoError(t, err)
+
+	f, err := excelize.OpenReader(bytes.NewReader(excelBytes))
+	assert.NoError(t, err)
+
+	rows, err := f.GetRows("payments")
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{headers, {"10.5", "prod"}}, rows)
+}
+
+func TestExcelWriter_WriteSheet(t *testing.T) {
+	var buffer bytes.Buffer
+	writer, err := export.NewExcelWriter(&buffer, "payments", []string{"Amount", "Previous"})
+	assert.NoError(t, err)
+
+	assert.NoError(t, writer.WriteRow(format.ExcelRow{Cells: []interface{}{
+		format.ExcelCell{Value: 10.5, Highlight: true},
+		format.ExcelCell{Value: 8.0},
+	}}))
+	assert.NoError(t, writer.WriteSheet("totals", []string{"Total"}, []format.ExcelRow{
+		{Cells: []interface{}{10.5}, Groups: []format.ExcelRow{{Cells: []interface{}{"one payment"}}}},
+	}))
+	assert.NoError(t, writer.Close())
+
+	f, err := excelize.OpenReader(&buffer)
+	assert.NoError(t, err)
+
+	rows, err := f.GetRows("payments")
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"Amount", "Previous"}, {"10.5", "8"}}, rows)
+
+	highlighted, err := f.GetCellStyle("payments", "A2")
+	assert.NoError(t, err)
+	assert.NotZero(t, highlighted)
+	plain, err := f.GetCellStyle("payments", "B2")
+	assert.NoError(t, err)
+	assert.Zero(t, plain)
+
+	totals, err := f.GetRows("totals")
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"Total"}, {"10.5"}, {"", "one payment"}}, totals)
+	assert.Equal(t, "payments", f.GetSheetName(f.GetActiveSheetIndex()))
+}
+
+func TestExcelWriter_Layout(t *testing.T) {
+	var buffer bytes.Buffer
+	writer, err := export.NewExcelWriter(&buffer, "payments", []string{"Id", "Description", "Id", ""})
+	assert.NoError(t, err)
+
+	// More rows than the ones sizing the columns
+	for i := 0; i < 150; i++ {
+		description := "short"
+		if i == 10 {
+			description = "a description longer than the header"
+		}
+		assert.NoError(t, writer.WriteRow(format.ExcelRow{Cells: []interface{}{i, description, i, nil}}))
+	}
+	assert.NoError(t, writer.Close())
+
+	f, err := excelize.OpenReader(&buffer)
+	assert.NoError(t, err)
+
+	rows, err := f.GetRows("payments")
+	assert.NoError(t, err)
+	assert.Len(t, rows, 151)
+	assert.Equal(t, []string{"Id", "Description", "Id2", "Column4"}, rows[0])
+	assert.Equal(t, []string{"149", "short", "149"}, rows[150])
+
+	idWidth, err := f.GetColWidth("payments", "A")
+	assert.NoError(t, err)
+	descriptionWidth, err := f.GetColWidth("payments", "B")
+	assert.NoError(t, err)
+	assert.Equal(t, 10.0, idWidth)
+	assert.Equal(t, 38.0, descriptionWidth)
+
+	panes, err := f.GetPanes("payments")
+	assert.NoError(t, err)
+	assert.True(t, panes.Freeze)
+	assert.Equal(t, 1, panes.YSplit)
+	assert.Equal(t, "A2", panes.TopLeftCell)
+
+	tables, err := f.GetTables("payments")
+	assert.NoError(t, err)
+	assert.Len(t, tables, 1)
+	assert.Equal(t, "A1:D151", tables[0].Range)
+	assert.Equal(t, "TableStyleMedium2", tables[0].StyleName)
+}
+
+func TestDataToExcelWithHeaders_BeyondColumnZ(t *testing.T) {
+	// More columns than letters in the alphabet
+	headers := make([]string, 30)
+	for i := range headers {
+		headers[i] = fmt.Sprintf("Column%d", i+1)
+	}
+
+	data := []Payment{{Amount: 10.5}}
+	mapper := func(payment Payment) format.ExcelRow {
+		cells := make([]interface{}, len(headers))
+		for i := range cells {
+			cells[i] = payment.Amount
+		}
+		return format.ExcelRow{Cells: cells}
+	}
+
+	excelBytes, err := export.DataToExcelWithHeaders("payments", headers, data, mapper)
+	assert.NoError(t, err)
+
+	f, err := excelize.OpenReader(bytes.NewReader(excelBytes))
+	assert.NoError(t, err)
+
+	rows, err := f.GetRows("payments")
+	assert.NoError(t, err)
+	assert.Len(t, rows, 2)
+	assert.Equal(t, headers, rows[0])
+	assert.Len(t, rows[1], len(headers))
+
+	value, err := f.GetCellValue("payments", "AD2")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.5", value)
+
+	width, err := f.GetColWidth("payments", "AD")
+	assert.NoError(t, err)
+	assert.Equal(t, 16.0, width)
+}
@@ -0,0 +1,64 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// JSONWriter streams json records to the underlying writer, as newline delimited json or as the elements of a
+// json array with one record per line
+type JSONWriter struct {
+	w       io.Writer
+	lines   bool
+	written int
+}
+
+// NewJSONWriter creates a writer of newline delimited json when lines is true, of a json array otherwise
+func NewJSONWriter(w io.Writer, lines bool) *JSONWriter {
+	return &JSONWriter{w: w, lines: lines}
+}
+
+// Write writes the json record of v
+func (j *JSONWriter) Write(v interface{}) error {
+	record, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var prefix string
+	switch {
+	case j.lines:
+	case j.written == 0:
+		prefix = "[\n"
+	default:
+		prefix = ",\n"
+	}
+	if _, err := io.WriteString(j.w, prefix); err != nil {
+		return err
+	}
+	if _, err := j.w.Write(record); err != nil {
+		return err
+	}
+	if j.lines {
+		if _, err := io.WriteString(j.w, "\n"); err != nil {
+			return err
+		}
+	}
+
+	j.written++
+	return nil
+}
+
+// Close ends the json array, it writes nothing for newline delimited json
+func (j *JSONWriter) Close() error {
+	switch {
+	case j.lines:
+		return nil
+	case j.written == 0:
+		_, err := io.WriteString(j.w, "[\n]\n")
+		return err
+	default:
+		_, err := io.WriteString(j.w, "\n]\n")
+		return err
+	}
+}
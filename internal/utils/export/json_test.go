@@ -0,0 +1,62 @@
+package export_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/jmontesinos91/omnilogger/internal/utils/export"
+	"github.com/stretchr/testify/assert"
+)
+
+type record struct {
+	Name string          `json:"name"`
+	Data json.RawMessage `json:"data"`
+}
+
+func TestJSONWriter(t *testing.T) {
+	data := []record{
+		{Name: "a", Data: json.RawMessage(`{"id":1}`)},
+		{Name: "b", Data: json.RawMessage(`[1,2]`)},
+	}
+
+	tests := []struct {
+		name     string
+		lines    bool
+		data     []record
+		expected string
+	}{
+		{
+			name:     "NDJSON",
+			lines:    true,
+			data:     data,
+			expected: "{\"name\":\"a\",\"data\":{\"id\":1}}\n{\"name\":\"b\",\"data\":[1,2]}\n",
+		},
+		{
+			name:     "Empty NDJSON",
+			lines:    true,
+			expected: "",
+		},
+		{
+			name:     "JSON",
+			data:     data,
+			expected: "[\n{\"name\":\"a\",\"data\":{\"id\":1}},\n{\"name\":\"b\",\"data\":[1,2]}\n]\n",
+		},
+		{
+			name:     "Empty JSON",
+			expected: "[\n]\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			writer := export.NewJSONWriter(&buffer, tc.lines)
+			for _, item := range tc.data {
+				assert.NoError(t, writer.Write(item))
+			}
+			assert.NoError(t, writer.Close())
+			assert.Equal(t, tc.expected, buffer.String())
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package export
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+	"time"
+)
+
+// ManifestVersion version of the manifest format
+const ManifestVersion = 1
+
+// AlgorithmEd25519 algorithm of the manifest signatures
+const AlgorithmEd25519 = "Ed25519"
+
+// Manifest describes an export file, the signature covers every other field of the manifest
+type Manifest struct {
+	Version     int             `json:"version"`
+	Service     string          `json:"service"`
+	File        string          `json:"file"`
+	Format      string          `json:"format"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	RequestedBy string          `json:"requestedBy,omitempty"`
+	Filter      json.RawMessage `json:"filter"`
+	RowCount    int             `json:"rowCount"`
+	SHA256      string          `json:"sha256"`
+	KeyID       string          `json:"keyId,omitempty"`
+	Algorithm   string          `json:"algorithm,omitempty"`
+	Signature   string          `json:"signature,omitempty"`
+}
+
+// NewManifest creates the manifest of an export file with its digest, it is not signed
+func NewManifest(file, format string, filter interface{}, rowCount int, content []byte) (*Manifest, error) {
+	return NewDigestManifest(file, format, filter, rowCount, Digest(content))
+}
+
+// NewDigestManifest same as NewManifest for a file whose digest was computed while it was written
+func NewDigestManifest(file, format string, filter interface{}, rowCount int, digest string) (*Manifest, error) {
+	rawFilter, err := json.Marshal(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Manifest{
+		Version:   ManifestVersion,
+		Service:   "omnilogger",
+		File:      file,
+		Format:    format,
+		CreatedAt: time.Now().UTC(),
+		Filter:    rawFilter,
+		RowCount:  rowCount,
+		SHA256:    digest,
+	}, nil
+}
+
+// Digest hex encoded SHA-256 of the content
+func Digest(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// DigestWriter computes the SHA-256 of the bytes written through it
+type DigestWriter struct {
+	w    io.Writer
+	hash hash.Hash
+}
+
+// NewDigestWriter creates a DigestWriter writing to w
+func NewDigestWriter(w io.Writer) *DigestWriter {
+	return &DigestWriter{w: w, hash: sha256.New()}
+}
+
+// Write writes p to the underlying writer, only the bytes written are digested
+func (d *DigestWriter) Write(p []byte) (int, error) {
+	n, err := d.w.Write(p)
+	d.hash.Write(p[:n])
+	return n, err
+}
+
+// Digest hex encoded SHA-256 of the bytes written so far
+func (d *DigestWriter) Digest() string {
+	return hex.EncodeToString(d.hash.Sum(nil))
+}
+
+// signingPayload bytes covered by the signature, the manifest without its signature fields
+func (m *Manifest) signingPayload() ([]byte, error) {
+	unsigned := *m
+	unsigned.Signature = ""
+
+	return json.Marshal(unsigned)
+}
+
+// Encode base64 encoded json of the manifest, used to send it in a header
+func (m *Manifest) Encode() (string, error) {
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(raw), nil
+}
+
+// ParseManifest reads a manifest either as json or as the base64 encoded json sent in the export header
+func ParseManifest(raw []byte) (*Manifest, error) {
+	trimmed := strings.TrimSpace(string(raw))
+	if !strings.HasPrefix(trimmed, "{") {
+		decoded, err := base64.StdEncoding.DecodeString(trimmed)
+		if err != nil {
+			return nil, fmt.Errorf("manifest is neither json nor base64 -> %v", err)
+		}
+		trimmed = string(decoded)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal([]byte(trimmed), &manifest); err != nil {
+		return nil, err
+	}
+
+	return &manifest, nil
+}
+
+// Signer signs export manifests with the service Ed25519 key, a signer without key leaves them unsigned
+type Signer struct {
+	key   ed25519.PrivateKey
+	keyID string
+}
+
+// NewSigner creates a new instance of Signer from a base64 Ed25519 private key or seed
+func NewSigner(privateKey, keyID string) (*Signer, error) {
+	if privateKey == "" {
+		return &Signer{}, nil
+	}
+
+	key, err := ParsePrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Signer{key: key, keyID: keyID}, nil
+}
+
+// Enabled whether the signer has a key
+func (s *Signer) Enabled() bool {
+	return s != nil && s.key != nil
+}
+
+// Sign signs the manifest, it does nothing when the signer has no key
+func (s *Signer) Sign(m *Manifest) error {
+	if !s.Enabled() {
+		return nil
+	}
+
+	m.KeyID = s.keyID
+	m.Algorithm = AlgorithmEd25519
+
+	payload, err := m.signingPayload()
+	if err != nil {
+		return err
+	}
+	m.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(s.key, payload))
+
+	return nil
+}
+
+// PublicKey base64 public key of the signer
+func (s *Signer) PublicKey() string {
+	if !s.Enabled() {
+		return ""
+	}
+
+	return base64.StdEncoding.EncodeToString(s.key.Public().(ed25519.PublicKey))
+}
+
+// Verify validates the manifest signature with the public key and the digest of the content
+func Verify(m *Manifest, content []byte, publicKey ed25519.PublicKey) error {
+	if m.Signature == "" {
+		return errors.New("manifest is not signed")
+	}
+
+	if m.Algorithm != AlgorithmEd25519 {
+		return fmt.Errorf("unsupported signature algorithm %q", m.Algorithm)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(m.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding -> %v", err)
+	}
+
+	payload, err := m.signingPayload()
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(publicKey, payload, signature) {
+		return errors.New("invalid manifest signature")
+	}
+
+	if digest := Digest(content); digest != m.SHA256 {
+		return fmt.Errorf("file digest %s does not match the manifest digest %s", digest, m.SHA256)
+	}
+
+	return nil
+}
+
+// GenerateKey creates a new key pair, both base64 encoded
+func GenerateKey() (string, string, error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(privateKey.Seed()), base64.StdEncoding.EncodeToString(publicKey), nil
+}
+
+// ParsePrivateKey parses a base64 Ed25519 seed or private key
+func ParsePrivateKey(encoded string) (ed25519.PrivateKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("invalid signing key encoding -> %v", err)
+	}
+
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	default:
+		return nil, fmt.Errorf("invalid signing key size %d", len(raw))
+	}
+}
+
+// ParsePublicKey parses a base64 Ed25519 public key
+func ParsePublicKey(encoded string) (ed25519.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key encoding -> %v", err)
+	}
+
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key size %d", len(raw))
+	}
+
+	return ed25519.PublicKey(raw), nil
+}
@@ -0,0 +1,146 @@
+package export_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/jmontesinos91/omnilogger/internal/utils/export"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManifest_SignAndVerify(t *testing.T) {
+	privateKey, publicKey, err := export.GenerateKey()
+	assert.NoError(t, err)
+
+	signer, err := export.NewSigner(privateKey, "2024-01")
+	assert.NoError(t, err)
+	assert.True(t, signer.Enabled())
+	assert.Equal(t, publicKey, signer.PublicKey())
+
+	key, err := export.ParsePublicKey(publicKey)
+	assert.NoError(t, err)
+
+	_, otherPublicKey, _ := export.GenerateKey()
+	otherKey, _ := export.ParsePublicKey(otherPublicKey)
+
+	content := []byte("exported logs")
+
+	newManifest := func() *export.Manifest {
+		manifest, err := export.NewManifest("logs.xlsx", "xlsx", map[string]interface{}{"tenant_id": []int{1}}, 3, content)
+		assert.NoError(t, err)
+		assert.NoError(t, signer.Sign(manifest))
+		return manifest
+	}
+
+	cases := []struct {
+		name     string
+		manifest func() *export.Manifest
+		content  []byte
+		key      ed25519.PublicKey
+		err      bool
+	}{
+		{name: "Valid", manifest: newManifest, content: content, key: key},
+		{name: "Modified file", manifest: newManifest, content: []byte("exported logs!"), key: key, err: true},
+		{
+			name: "Modified manifest",
+			manifest: func() *export.Manifest {
+				manifest := newManifest()
+				manifest.RowCount = 2
+				return manifest
+			},
+			content: content,
+			key:     key,
+			err:     true,
+		},
+		{name: "Other key", manifest: newManifest, content: content, key: otherKey, err: true},
+		{
+			name: "Unsigned",
+			manifest: func() *export.Manifest {
+				manifest, _ := export.NewManifest("logs.xlsx", "xlsx", nil, 3, content)
+				return manifest
+			},
+			content: content,
+			key:     key,
+			err:     true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := export.Verify(tc.manifest(), tc.content, tc.key)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestParseManifest(t *testing.T) {
+	privateKey, publicKey, _ := export.GenerateKey()
+	signer, _ := export.NewSigner(privateKey, "")
+	key, _ := export.ParsePublicKey(publicKey)
+	content := []byte("exported logs")
+
+	manifest, err := export.NewManifest("logs.xlsx", "xlsx", map[string]int{"page": 1}, 1, content)
+	assert.NoError(t, err)
+	assert.NoError(t, signer.Sign(manifest))
+
+	encoded, err := manifest.Encode()
+	assert.NoError(t, err)
+	pretty, err := json.MarshalIndent(manifest, "", "  ")
+	assert.NoError(t, err)
+
+	for name, raw := range map[string][]byte{"Header value": []byte(encoded + "\n"), "Indented json": pretty} {
+		t.Run(name, func(t *testing.T) {
+			parsed, err := export.ParseManifest(raw)
+			assert.NoError(t, err)
+			assert.NoError(t, export.Verify(parsed, content, key))
+		})
+	}
+
+	_, err = export.ParseManifest([]byte("not a manifest"))
+	assert.Error(t, err)
+}
+
+func TestNewSigner(t *testing.T) {
+	seed := make([]byte, ed25519.SeedSize)
+	fullKey := ed25519.NewKeyFromSeed(seed)
+
+	signer, err := export.NewSigner("", "")
+	assert.NoError(t, err)
+	assert.False(t, signer.Enabled())
+
+	// Unsigned manifests are left untouched
+	manifest := &export.Manifest{}
+	assert.NoError(t, signer.Sign(manifest))
+	assert.Empty(t, manifest.Signature)
+
+	fromSeed, err := export.NewSigner(base64.StdEncoding.EncodeToString(seed), "")
+	assert.NoError(t, err)
+	fromFullKey, err := export.NewSigner(base64.StdEncoding.EncodeToString(fullKey), "")
+	assert.NoError(t, err)
+	assert.Equal(t, fromSeed.PublicKey(), fromFullKey.PublicKey())
+
+	_, err = export.NewSigner(base64.StdEncoding.EncodeToString([]byte("short")), "")
+	assert.Error(t, err)
+	_, err = export.NewSigner("%%%", "")
+	assert.Error(t, err)
+}
+
+func TestDigestWriter(t *testing.T) {
+	var buffer bytes.Buffer
+	writer := export.NewDigestWriter(&buffer)
+
+	_, err := writer.Write([]byte("first,"))
+	assert.NoError(t, err)
+	_, err = writer.Write([]byte("second"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "first,second", buffer.String())
+	assert.Equal(t, export.Digest([]byte("first,second")), writer.Digest())
+}
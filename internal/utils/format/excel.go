@@ -5,3 +5,9 @@ type ExcelRow struct {
 	Cells  []interface{}
 	Groups []ExcelRow
 }
+
+// ExcelCell cell of an ExcelRow written with the highlight style, such as the changed values of a log
+type ExcelCell struct {
+	Value     interface{}
+	Highlight bool
+}
@@ -0,0 +1,15 @@
+package text
+
+// Truncate cuts s to its first max runes, so a multibyte character is never split and the result fits a varchar
+// column of max characters
+func Truncate(s string, max int) string {
+	count := 0
+	for i := range s {
+		if count == max {
+			return s[:i]
+		}
+		count++
+	}
+
+	return s
+}
@@ -0,0 +1,30 @@
+package text
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTruncate(t *testing.T) {
+	cases := []struct {
+		name     string
+		s        string
+		max      int
+		expected string
+	}{
+		{name: "Shorter", s: "abc", max: 5, expected: "abc"},
+		{name: "Exact", s: "abc", max: 3, expected: "abc"},
+		{name: "Longer", s: "abcdef", max: 3, expected: "abc"},
+		{name: "Multibyte", s: "añoñ", max: 2, expected: "añ"},
+		{name: "Multibyte fits", s: "año", max: 3, expected: "año"},
+		{name: "Empty", s: "", max: 3, expected: ""},
+		{name: "Zero", s: "abc", max: 0, expected: ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, Truncate(tc.s, tc.max))
+		})
+	}
+}
@@ -0,0 +1,16 @@
+-- Ranges of chain entries deleted by retention, the chain stays verifiable across them: prev_hash is the link of the
+-- first purged entry and hash the one of the last, so the entries around a range still link to each other through it
+CREATE TABLE public.log_chain_purges (
+    tenant_id integer NOT NULL,
+    from_seq bigint NOT NULL,
+    to_seq bigint NOT NULL,
+    prev_hash varchar(64) NOT NULL DEFAULT '',
+    hash varchar(64) NOT NULL,
+    purged_at timestamp NOT NULL,
+    PRIMARY KEY (tenant_id, from_seq)
+);
+
+-- Ranges are append-only like the logs, only the maintenance path records them
+REVOKE UPDATE, DELETE, TRUNCATE ON public.log_chain_purges FROM PUBLIC;
+GRANT SELECT ON public.log_chain_purges TO omnilogger_app;
+GRANT INSERT ON public.log_chain_purges TO omnilogger_maintenance;
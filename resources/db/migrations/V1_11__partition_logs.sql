@@ -0,0 +1,219 @@
+-- Logs are range partitioned on created_at. The table is rebuilt: existing rows are copied into monthly partitions
+-- covering them up to three months ahead, the partition manager of the service keeps creating the next ones and the
+-- default partition only catches rows outside every partition.
+--
+-- Downtime: the copy runs in the migration transaction with the logs table locked, ingestion and reads block until it
+-- commits and the copy needs as much free disk as the table and its indexes. Stop the service, or its ingestion
+-- consumers, while the migration runs and plan the maintenance window from the size of the logs table.
+ALTER TABLE public.logs RENAME TO logs_legacy;
+
+CREATE TABLE public.logs (LIKE public.logs_legacy INCLUDING DEFAULTS INCLUDING CONSTRAINTS)
+PARTITION BY RANGE (created_at);
+
+CREATE TABLE public.logs_default PARTITION OF public.logs DEFAULT;
+
+DO $$
+DECLARE
+    period_start timestamp;
+    period_end   timestamp := date_trunc('month', now() AT TIME ZONE 'UTC') + interval '3 months';
+BEGIN
+    SELECT coalesce(date_trunc('month', min(created_at)), date_trunc('month', now() AT TIME ZONE 'UTC'))
+    INTO period_start
+    FROM public.logs_legacy;
+
+    WHILE period_start < period_end LOOP
+        EXECUTE format('CREATE TABLE public.%I PARTITION OF public.logs FOR VALUES FROM (%L) TO (%L)',
+            'logs_p' || to_char(period_start, 'YYYYMM'), period_start, period_start + interval '1 month');
+        period_start := period_start + interval '1 month';
+    END LOOP;
+END;
+$$;
+
+INSERT INTO public.logs SELECT * FROM public.logs_legacy;
+
+DROP TABLE public.logs_legacy;
+
+CREATE INDEX logs_id_idx ON public.logs (id);
+CREATE INDEX logs_occurred_at_idx ON public.logs (occurred_at);
+CREATE INDEX logs_created_at_idx ON public.logs (created_at);
+CREATE INDEX logs_labels_idx ON public.logs USING GIN (labels jsonb_path_ops);
+-- Unique indexes of a partitioned table must include created_at, log_chain_seqs keeps chain_seq unique per tenant
+-- across partitions: every chain entry claims its sequence there when inserted and the claim is never released
+CREATE UNIQUE INDEX logs_chain_idx ON public.logs (chain_tenant_id, chain_seq, created_at);
+
+CREATE TABLE public.log_chain_seqs (
+    tenant_id integer NOT NULL,
+    seq bigint NOT NULL,
+    PRIMARY KEY (tenant_id, seq)
+);
+
+INSERT INTO public.log_chain_seqs (tenant_id, seq)
+SELECT chain_tenant_id, chain_seq
+FROM public.logs
+WHERE chain_seq IS NOT NULL;
+
+CREATE FUNCTION public.logs_claim_chain_seq() RETURNS trigger
+LANGUAGE plpgsql SECURITY DEFINER SET search_path = public, pg_temp AS $$
+BEGIN
+    IF NEW.chain_seq IS NOT NULL THEN
+        INSERT INTO public.log_chain_seqs (tenant_id, seq) VALUES (NEW.chain_tenant_id, NEW.chain_seq);
+    END IF;
+    RETURN NULL;
+END;
+$$;
+
+CREATE TRIGGER logs_chain_seq_trg
+AFTER INSERT ON public.logs
+FOR EACH ROW EXECUTE FUNCTION public.logs_claim_chain_seq();
+
+REVOKE ALL ON public.log_chain_seqs FROM PUBLIC;
+REVOKE ALL ON FUNCTION public.logs_claim_chain_seq() FROM PUBLIC;
+
+CREATE TRIGGER logs_legal_hold_trg
+BEFORE UPDATE OR DELETE ON public.logs
+FOR EACH ROW EXECUTE FUNCTION public.logs_reject_held();
+
+CREATE TRIGGER logs_legal_hold_truncate_trg
+BEFORE TRUNCATE ON public.logs
+FOR EACH STATEMENT EXECUTE FUNCTION public.logs_reject_truncate_held();
+
+CREATE TRIGGER logs_append_only_trg
+BEFORE UPDATE OR DELETE ON public.logs
+FOR EACH ROW EXECUTE FUNCTION public.logs_reject_changes();
+
+CREATE TRIGGER logs_append_only_truncate_trg
+BEFORE TRUNCATE ON public.logs
+FOR EACH STATEMENT EXECUTE FUNCTION public.logs_reject_changes();
+
+-- Row triggers are cloned on every partition, truncate triggers are not so each partition gets its own
+CREATE FUNCTION public.logs_protect_partition(partition_name text) RETURNS void
+LANGUAGE plpgsql AS $$
+BEGIN
+    EXECUTE format('CREATE TRIGGER logs_legal_hold_truncate_trg BEFORE TRUNCATE ON public.%I '
+        'FOR EACH STATEMENT EXECUTE FUNCTION public.logs_reject_truncate_held()', partition_name);
+    EXECUTE format('CREATE TRIGGER logs_append_only_truncate_trg BEFORE TRUNCATE ON public.%I '
+        'FOR EACH STATEMENT EXECUTE FUNCTION public.logs_reject_changes()', partition_name);
+    EXECUTE format('ALTER TABLE public.%I ENABLE ALWAYS TRIGGER logs_legal_hold_trg', partition_name);
+    EXECUTE format('ALTER TABLE public.%I ENABLE ALWAYS TRIGGER logs_legal_hold_truncate_trg', partition_name);
+    EXECUTE format('ALTER TABLE public.%I ENABLE ALWAYS TRIGGER logs_append_only_trg', partition_name);
+    EXECUTE format('ALTER TABLE public.%I ENABLE ALWAYS TRIGGER logs_append_only_truncate_trg', partition_name);
+    EXECUTE format('ALTER TABLE public.%I ENABLE ALWAYS TRIGGER logs_chain_seq_trg', partition_name);
+END;
+$$;
+
+ALTER TABLE public.logs ENABLE ALWAYS TRIGGER logs_append_only_trg;
+ALTER TABLE public.logs ENABLE ALWAYS TRIGGER logs_append_only_truncate_trg;
+ALTER TABLE public.logs ENABLE ALWAYS TRIGGER logs_legal_hold_trg;
+ALTER TABLE public.logs ENABLE ALWAYS TRIGGER logs_legal_hold_truncate_trg;
+ALTER TABLE public.logs ENABLE ALWAYS TRIGGER logs_chain_seq_trg;
+
+SELECT public.logs_protect_partition(c.relname)
+FROM pg_inherits i
+JOIN pg_class c ON c.oid = i.inhrelid
+WHERE i.inhparent = 'public.logs'::regclass;
+
+REVOKE UPDATE, DELETE, TRUNCATE ON public.logs FROM PUBLIC;
+GRANT SELECT, INSERT ON public.logs TO omnilogger_app;
+GRANT UPDATE, DELETE ON public.logs TO omnilogger_maintenance;
+
+-- Partitions are owned by the migration role, the service creates and retires them through these functions only
+CREATE FUNCTION public.logs_create_partition(partition_name text, range_from timestamp, range_to timestamp)
+RETURNS void
+LANGUAGE plpgsql SECURITY DEFINER SET search_path = public, pg_temp AS $$
+BEGIN
+    IF partition_name !~ '^logs_p[0-9]{6}([0-9]{2})?$' THEN
+        RAISE EXCEPTION 'invalid logs partition name %', partition_name USING ERRCODE = 'invalid_name';
+    END IF;
+    IF to_regclass('public.' || partition_name) IS NOT NULL THEN
+        RETURN;
+    END IF;
+
+    EXECUTE format('CREATE TABLE public.%I PARTITION OF public.logs FOR VALUES FROM (%L) TO (%L)',
+        partition_name, range_from, range_to);
+    PERFORM public.logs_protect_partition(partition_name);
+END;
+$$;
+
+-- Detaches an expired partition, and drops it when asked, on the maintenance path. Partitions holding logs under
+-- legal hold are kept and false is returned, holds can not be placed while the check runs.
+CREATE FUNCTION public.logs_retire_partition(partition_name text, drop_partition boolean)
+RETURNS boolean
+LANGUAGE plpgsql SECURITY DEFINER SET search_path = public, pg_temp AS $$
+DECLARE
+    held boolean;
+BEGIN
+    IF coalesce(current_setting('omnilogger.maintenance', true), '') <> 'on' THEN
+        RAISE EXCEPTION 'logs partitions can only be retired on the maintenance path' USING ERRCODE = 'insufficient_privilege';
+    END IF;
+    IF partition_name = 'logs_default' OR NOT EXISTS (
+        SELECT 1
+        FROM pg_inherits i
+        JOIN pg_class c ON c.oid = i.inhrelid
+        WHERE i.inhparent = 'public.logs'::regclass AND c.relname = partition_name
+    ) THEN
+        RAISE EXCEPTION 'logs partition % not found', partition_name USING ERRCODE = 'undefined_table';
+    END IF;
+
+    LOCK TABLE public.legal_holds IN SHARE MODE;
+    EXECUTE format('SELECT EXISTS (SELECT 1 FROM public.%I WHERE public.log_is_held(tenant_id, user_id, "resource", created_at))',
+        partition_name) INTO held;
+    IF held THEN
+        RETURN false;
+    END IF;
+
+    EXECUTE format('ALTER TABLE public.logs DETACH PARTITION public.%I', partition_name);
+    IF drop_partition THEN
+        EXECUTE format('DROP TABLE public.%I', partition_name);
+    END IF;
+    RETURN true;
+END;
+$$;
+
+REVOKE ALL ON FUNCTION public.logs_protect_partition(text) FROM PUBLIC;
+REVOKE ALL ON FUNCTION public.logs_create_partition(text, timestamp, timestamp) FROM PUBLIC;
+REVOKE ALL ON FUNCTION public.logs_retire_partition(text, boolean) FROM PUBLIC;
+GRANT EXECUTE ON FUNCTION public.logs_create_partition(text, timestamp, timestamp) TO omnilogger_app;
+GRANT EXECUTE ON FUNCTION public.logs_retire_partition(text, boolean) TO omnilogger_maintenance;
+
+-- Drops run inside logs_retire_partition as the owner of the function, current_user is then that owner and not the
+-- caller, so the login calling it must be granted omnilogger_maintenance instead. session_user is the login and can
+-- not be changed by SET ROLE or by a SECURITY DEFINER function.
+CREATE FUNCTION public.logs_drop_allowed() RETURNS boolean
+LANGUAGE sql STABLE AS $$
+    SELECT coalesce(current_setting('omnilogger.maintenance', true), '') = 'on'
+       AND (pg_has_role(current_user, 'omnilogger_maintenance', 'MEMBER')
+            OR pg_has_role(session_user, 'omnilogger_maintenance', 'MEMBER'))
+$$;
+
+-- Dropping a partition skips every trigger, drops of the logs table or its partitions need the maintenance path.
+--
+-- A maintenance login retires and drops a partition with, the owner of the functions does not need to be a
+-- superuser nor a member of omnilogger_maintenance:
+--
+--     GRANT omnilogger_maintenance TO <login>;
+--     BEGIN;
+--     SELECT set_config('omnilogger.maintenance', 'on', true);
+--     SELECT public.logs_retire_partition('logs_p202401', true);
+--     COMMIT;
+CREATE FUNCTION public.logs_reject_drop() RETURNS event_trigger
+LANGUAGE plpgsql AS $$
+DECLARE
+    dropped record;
+BEGIN
+    FOR dropped IN
+        SELECT object_name
+        FROM pg_event_trigger_dropped_objects()
+        WHERE object_type = 'table'
+          AND schema_name = 'public'
+          AND (object_name IN ('logs', 'logs_default') OR object_name ~ '^logs_p[0-9]+$')
+    LOOP
+        IF NOT public.logs_drop_allowed() THEN
+            RAISE EXCEPTION 'logs are append-only, dropping % is not allowed', dropped.object_name
+                USING ERRCODE = 'insufficient_privilege';
+        END IF;
+    END LOOP;
+END;
+$$;
+
+CREATE EVENT TRIGGER logs_drop_guard_trg ON sql_drop EXECUTE FUNCTION public.logs_reject_drop();
+ALTER EVENT TRIGGER logs_drop_guard_trg ENABLE ALWAYS;
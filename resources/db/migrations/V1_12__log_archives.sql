@@ -0,0 +1,21 @@
+-- Archive files written per tenant and day, logs shared by several tenants are in the file of each of them and
+-- tenant 0 holds the logs without tenant
+CREATE TABLE public.log_archives (
+    id varchar(36) NOT NULL PRIMARY KEY,
+    tenant_id integer NOT NULL,
+    "day" date NOT NULL,
+    format varchar(20) NOT NULL,
+    object_key varchar(500) NOT NULL,
+    manifest_key varchar(500) NOT NULL,
+    row_count integer NOT NULL,
+    size_bytes bigint NOT NULL,
+    sha256 varchar(64) NOT NULL,
+    purged_rows integer NOT NULL DEFAULT 0,
+    created_at timestamp NOT NULL,
+    purged_at timestamp NULL,
+    UNIQUE (tenant_id, "day")
+);
+
+CREATE INDEX log_archives_day_idx ON public.log_archives ("day");
+
+GRANT SELECT, INSERT, UPDATE ON public.log_archives TO omnilogger_app;
@@ -0,0 +1,41 @@
+-- Archive files restored on demand, their logs are loaded into restored_logs and searchable until expires_at
+CREATE TABLE public.log_restores (
+    id varchar(36) NOT NULL PRIMARY KEY,
+    archive_id varchar(36) NOT NULL REFERENCES public.log_archives (id),
+    tenant_id integer NOT NULL,
+    "day" date NOT NULL,
+    status varchar(20) NOT NULL,
+    row_count integer NOT NULL DEFAULT 0,
+    error varchar(500) NULL,
+    requested_by varchar(255) NOT NULL,
+    created_at timestamp NOT NULL,
+    ready_at timestamp NULL,
+    expires_at timestamp NOT NULL,
+    expired_at timestamp NULL
+);
+
+CREATE INDEX log_restores_active_idx ON public.log_restores (archive_id) WHERE expired_at IS NULL;
+
+-- Restored logs are read-only, they are only deleted on the maintenance path once their restore expires
+CREATE TABLE public.restored_logs (LIKE public.logs INCLUDING DEFAULTS);
+ALTER TABLE public.restored_logs ADD COLUMN restore_id varchar(36) NOT NULL;
+
+CREATE INDEX restored_logs_restore_idx ON public.restored_logs (restore_id);
+CREATE INDEX restored_logs_created_at_idx ON public.restored_logs (created_at);
+CREATE INDEX restored_logs_labels_idx ON public.restored_logs USING GIN (labels jsonb_path_ops);
+
+CREATE TRIGGER restored_logs_read_only_trg
+BEFORE UPDATE OR DELETE ON public.restored_logs
+FOR EACH ROW EXECUTE FUNCTION public.logs_reject_changes();
+
+CREATE TRIGGER restored_logs_read_only_truncate_trg
+BEFORE TRUNCATE ON public.restored_logs
+FOR EACH STATEMENT EXECUTE FUNCTION public.logs_reject_changes();
+
+ALTER TABLE public.restored_logs ENABLE ALWAYS TRIGGER restored_logs_read_only_trg;
+ALTER TABLE public.restored_logs ENABLE ALWAYS TRIGGER restored_logs_read_only_truncate_trg;
+
+REVOKE UPDATE, DELETE, TRUNCATE ON public.restored_logs FROM PUBLIC;
+GRANT SELECT, INSERT ON public.restored_logs TO omnilogger_app;
+GRANT DELETE ON public.restored_logs TO omnilogger_maintenance;
+GRANT SELECT, INSERT, UPDATE ON public.log_restores TO omnilogger_app;
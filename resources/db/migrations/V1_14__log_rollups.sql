@@ -0,0 +1,26 @@
+-- Log counts pre-aggregated per hour and per day for the stats and dashboard endpoints. Rows keep the tenant_id array
+-- of the logs so they are filtered by tenant like the logs, they are rebuilt per bucket by the rollup worker and
+-- outlive the logs deleted by the retention purge
+CREATE TABLE public.log_rollups (
+    granularity varchar(10) NOT NULL,
+    bucket timestamp NOT NULL,
+    tenant_id jsonb NULL,
+    "level" smallint NULL,
+    "provider" varchar(20) NULL,
+    "action" varchar(50) NULL,
+    "resource" varchar(50) NULL,
+    "message" integer NULL,
+    "count" bigint NOT NULL
+);
+
+CREATE INDEX log_rollups_bucket_idx ON public.log_rollups (granularity, bucket);
+
+-- Rollups of each granularity are complete for the buckets before rolled_until
+CREATE TABLE public.log_rollup_watermarks (
+    granularity varchar(10) NOT NULL PRIMARY KEY,
+    rolled_until timestamp NOT NULL,
+    updated_at timestamp NOT NULL
+);
+
+GRANT SELECT, INSERT, DELETE ON public.log_rollups TO omnilogger_app;
+GRANT SELECT, INSERT, UPDATE ON public.log_rollup_watermarks TO omnilogger_app;
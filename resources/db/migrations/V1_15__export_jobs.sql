@@ -0,0 +1,28 @@
+-- Asynchronous exports, their files are kept in the export store until expires_at. Jobs are only visible to the
+-- user who requested them and for the tenants they covered
+CREATE TABLE public.export_jobs (
+    id varchar(36) NOT NULL PRIMARY KEY,
+    status varchar(20) NOT NULL,
+    format varchar(20) NOT NULL,
+    file_name varchar(255) NOT NULL,
+    content_type varchar(100) NOT NULL,
+    filter jsonb NOT NULL,
+    requested_by varchar(255) NOT NULL,
+    user_id integer NOT NULL,
+    tenant_ids integer[] NOT NULL,
+    row_count integer NOT NULL DEFAULT 0,
+    size_bytes bigint NOT NULL DEFAULT 0,
+    object_key varchar(500) NULL,
+    sha256 varchar(64) NULL,
+    manifest text NULL,
+    error varchar(500) NULL,
+    created_at timestamp NOT NULL,
+    started_at timestamp NULL,
+    finished_at timestamp NULL,
+    expires_at timestamp NOT NULL,
+    expired_at timestamp NULL
+);
+
+CREATE INDEX export_jobs_expires_at_idx ON public.export_jobs (expires_at) WHERE expired_at IS NULL;
+
+GRANT SELECT, INSERT, UPDATE ON public.export_jobs TO omnilogger_app;
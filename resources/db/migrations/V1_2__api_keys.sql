@@ -0,0 +1,16 @@
+CREATE TABLE public.api_keys (
+    id varchar(36) NOT NULL PRIMARY KEY,
+    "name" varchar(100) NOT NULL,
+    tenant_id integer NOT NULL,
+    tenant_name varchar(100) NULL,
+    prefix varchar(16) NOT NULL,
+    key_hash varchar(64) NOT NULL,
+    scopes jsonb NOT NULL DEFAULT '[]',
+    created_by varchar(255) NULL,
+    created_at timestamp NOT NULL,
+    last_used_at timestamp NULL,
+    revoked_at timestamp NULL,
+    UNIQUE(key_hash)
+);
+
+CREATE INDEX api_keys_tenant_id_idx ON public.api_keys (tenant_id);
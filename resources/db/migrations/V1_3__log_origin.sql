@@ -0,0 +1,6 @@
+-- Origin observed by the server, client reported ip_address and client_host are kept untouched
+ALTER TABLE public.logs
+ADD COLUMN origin_ip varchar(45) NULL,
+ADD COLUMN origin_forwarded_for varchar(1024) NULL,
+ADD COLUMN origin_subject varchar(255) NULL,
+ADD COLUMN origin_tenant_id jsonb NULL;
@@ -0,0 +1,9 @@
+-- occurred_at is the time reported by the producer, created_at stays as the ingestion time
+ALTER TABLE public.logs
+ADD COLUMN occurred_at timestamp NULL,
+ADD COLUMN clock_skew boolean NOT NULL DEFAULT false;
+
+UPDATE public.logs SET occurred_at = created_at WHERE occurred_at IS NULL;
+
+CREATE INDEX logs_occurred_at_idx ON public.logs (occurred_at);
+CREATE INDEX logs_created_at_idx ON public.logs (created_at);
@@ -0,0 +1,6 @@
+-- labels are free-form key/value pairs attached by the producer, e.g. {"env": "prod", "ticket": "OPS-12"}
+ALTER TABLE public.logs
+ADD COLUMN labels jsonb NOT NULL DEFAULT '{}'::jsonb;
+
+-- jsonb_path_ops serves the containment queries used by the label.<key>=<value> filters
+CREATE INDEX logs_labels_idx ON public.logs USING GIN (labels jsonb_path_ops);
@@ -0,0 +1,17 @@
+-- Every log is linked to the previous log of its tenant: hash = sha256(canonical content, prev_hash)
+ALTER TABLE public.logs
+ADD COLUMN chain_tenant_id integer NULL,
+ADD COLUMN chain_seq bigint NULL,
+ADD COLUMN prev_hash varchar(64) NULL,
+ADD COLUMN hash varchar(64) NULL;
+
+CREATE UNIQUE INDEX logs_chain_idx ON public.logs (chain_tenant_id, chain_seq);
+
+-- Last link of every chain, its row is locked while a log is appended so links are never forked
+CREATE TABLE public.log_chain_heads (
+    tenant_id integer NOT NULL,
+    seq bigint NOT NULL DEFAULT 0,
+    hash varchar(64) NOT NULL DEFAULT '',
+    updated_at timestamp NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id)
+);
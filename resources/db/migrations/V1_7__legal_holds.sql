@@ -0,0 +1,72 @@
+-- Logs covered by an active legal hold must not be purged nor anonymized until the hold is released
+CREATE TABLE public.legal_holds (
+    id varchar(36) NOT NULL PRIMARY KEY,
+    tenant_id integer NOT NULL,
+    user_id varchar(255) NULL,
+    "resource" varchar(50) NULL,
+    start_at timestamp NULL,
+    end_at timestamp NULL,
+    reason varchar(500) NOT NULL,
+    created_by varchar(255) NULL,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NULL,
+    released_by varchar(255) NULL,
+    released_at timestamp NULL
+);
+
+CREATE INDEX legal_holds_active_idx ON public.legal_holds (tenant_id) WHERE released_at IS NULL;
+
+-- Active holds covering a log, an empty user, resource or time bound matches every log of the tenant
+CREATE FUNCTION public.log_legal_holds(log_tenant_id jsonb, log_user_id varchar, log_resource varchar, log_created_at timestamp)
+RETURNS SETOF public.legal_holds
+LANGUAGE sql STABLE AS $$
+    SELECT h.*
+    FROM public.legal_holds h
+    WHERE h.released_at IS NULL
+      AND log_tenant_id @> jsonb_build_array(h.tenant_id)
+      AND (h.user_id IS NULL OR h.user_id = log_user_id)
+      AND (h."resource" IS NULL OR UPPER(h."resource") = UPPER(log_resource))
+      AND (h.start_at IS NULL OR log_created_at >= h.start_at)
+      AND (h.end_at IS NULL OR log_created_at <= h.end_at)
+$$;
+
+-- Deletion and anonymization paths filter with NOT log_is_held(tenant_id, user_id, resource, created_at)
+CREATE FUNCTION public.log_is_held(log_tenant_id jsonb, log_user_id varchar, log_resource varchar, log_created_at timestamp)
+RETURNS boolean
+LANGUAGE sql STABLE AS $$
+    SELECT EXISTS (SELECT 1 FROM public.log_legal_holds(log_tenant_id, log_user_id, log_resource, log_created_at))
+$$;
+
+-- Last line of defense, held logs can not be changed or deleted by any path, including manual statements
+CREATE FUNCTION public.logs_reject_held() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    IF public.log_is_held(OLD.tenant_id, OLD.user_id, OLD."resource", OLD.created_at) THEN
+        RAISE EXCEPTION 'log % is under legal hold', OLD.id USING ERRCODE = 'check_violation';
+    END IF;
+
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+    RETURN NEW;
+END;
+$$;
+
+CREATE TRIGGER logs_legal_hold_trg
+BEFORE UPDATE OR DELETE ON public.logs
+FOR EACH ROW EXECUTE FUNCTION public.logs_reject_held();
+
+-- Truncate skips row triggers, it is rejected while any hold is active
+CREATE FUNCTION public.logs_reject_truncate_held() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM public.legal_holds WHERE released_at IS NULL) THEN
+        RAISE EXCEPTION 'logs can not be truncated while legal holds are active' USING ERRCODE = 'check_violation';
+    END IF;
+    RETURN NULL;
+END;
+$$;
+
+CREATE TRIGGER logs_legal_hold_truncate_trg
+BEFORE TRUNCATE ON public.logs
+FOR EACH STATEMENT EXECUTE FUNCTION public.logs_reject_truncate_held();
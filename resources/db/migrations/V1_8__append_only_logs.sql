@@ -0,0 +1,57 @@
+-- Logs are append-only, updates, deletes and truncates are rejected unless they run on the maintenance path:
+-- a transaction that sets omnilogger.maintenance = 'on' locally, by a role granted omnilogger_maintenance.
+-- Only retention jobs take that path, application code and operators can only insert and read logs.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'omnilogger_app') THEN
+        CREATE ROLE omnilogger_app NOLOGIN;
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'omnilogger_maintenance') THEN
+        CREATE ROLE omnilogger_maintenance NOLOGIN;
+    END IF;
+END;
+$$;
+
+-- Logins used by the service are granted omnilogger_app, and omnilogger_maintenance where retention jobs run
+REVOKE UPDATE, DELETE, TRUNCATE ON public.logs FROM PUBLIC;
+GRANT SELECT, INSERT ON public.logs TO omnilogger_app;
+GRANT SELECT, INSERT, UPDATE, DELETE ON public.log_messages, public.api_keys, public.legal_holds, public.log_chain_heads TO omnilogger_app;
+GRANT omnilogger_app TO omnilogger_maintenance;
+GRANT UPDATE, DELETE ON public.logs TO omnilogger_maintenance;
+
+CREATE FUNCTION public.logs_maintenance_allowed() RETURNS boolean
+LANGUAGE sql STABLE AS $$
+    SELECT coalesce(current_setting('omnilogger.maintenance', true), '') = 'on'
+       AND pg_has_role(current_user, 'omnilogger_maintenance', 'MEMBER')
+$$;
+
+CREATE FUNCTION public.logs_reject_changes() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    IF NOT public.logs_maintenance_allowed() THEN
+        RAISE EXCEPTION 'logs are append-only, % is not allowed', TG_OP USING ERRCODE = 'insufficient_privilege';
+    END IF;
+
+    IF TG_LEVEL = 'STATEMENT' THEN
+        RETURN NULL;
+    END IF;
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+    RETURN NEW;
+END;
+$$;
+
+CREATE TRIGGER logs_append_only_trg
+BEFORE UPDATE OR DELETE ON public.logs
+FOR EACH ROW EXECUTE FUNCTION public.logs_reject_changes();
+
+CREATE TRIGGER logs_append_only_truncate_trg
+BEFORE TRUNCATE ON public.logs
+FOR EACH STATEMENT EXECUTE FUNCTION public.logs_reject_changes();
+
+-- Fire even with session_replication_role = replica so the protection can not be switched off per session
+ALTER TABLE public.logs ENABLE ALWAYS TRIGGER logs_append_only_trg;
+ALTER TABLE public.logs ENABLE ALWAYS TRIGGER logs_append_only_truncate_trg;
+ALTER TABLE public.logs ENABLE ALWAYS TRIGGER logs_legal_hold_trg;
+ALTER TABLE public.logs ENABLE ALWAYS TRIGGER logs_legal_hold_truncate_trg;
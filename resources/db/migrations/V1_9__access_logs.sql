@@ -0,0 +1,31 @@
+-- Who read which logs, kept apart from business logs and append-only like them
+CREATE TABLE public.access_logs (
+    id varchar(36) NOT NULL PRIMARY KEY,
+    request_id varchar(100) NULL,
+    user_id integer NULL,
+    user_name varchar(255) NULL,
+    "role" varchar(100) NULL,
+    tenants integer[] NOT NULL DEFAULT '{}',
+    "action" varchar(50) NOT NULL,
+    log_id varchar(36) NULL,
+    filter jsonb NOT NULL DEFAULT '{}',
+    row_count integer NOT NULL DEFAULT 0,
+    outcome varchar(20) NOT NULL,
+    created_at timestamp NOT NULL
+);
+
+CREATE INDEX access_logs_created_at_idx ON public.access_logs (created_at);
+CREATE INDEX access_logs_tenants_idx ON public.access_logs USING GIN (tenants);
+
+GRANT SELECT, INSERT ON public.access_logs TO omnilogger_app;
+
+CREATE TRIGGER access_logs_append_only_trg
+BEFORE UPDATE OR DELETE ON public.access_logs
+FOR EACH ROW EXECUTE FUNCTION public.logs_reject_changes();
+
+CREATE TRIGGER access_logs_append_only_truncate_trg
+BEFORE TRUNCATE ON public.access_logs
+FOR EACH STATEMENT EXECUTE FUNCTION public.logs_reject_changes();
+
+ALTER TABLE public.access_logs ENABLE ALWAYS TRIGGER access_logs_append_only_trg;
+ALTER TABLE public.access_logs ENABLE ALWAYS TRIGGER access_logs_append_only_truncate_trg;